@@ -38,6 +38,7 @@ func init() {
 	beeAdminApp.Route("/qps", qpsIndex)
 	beeAdminApp.Route("/prof", profIndex)
 	beeAdminApp.Route("/healthcheck", healthcheck)
+	beeAdminApp.Route("/health", toolbox.HealthCheckHandler)
 	beeAdminApp.Route("/task", taskStatus)
 	beeAdminApp.Route("/runtask", runTask)
 	beeAdminApp.Route("/listconf", listConf)
@@ -55,6 +56,7 @@ func adminIndex(rw http.ResponseWriter, r *http.Request) {
 	rw.Write([]byte("4. Get current task infomation from taskhttp://localhost:8088/task \n"))
 	rw.Write([]byte("5. To run a task passed a param http://localhost:8088/runtask\n"))
 	rw.Write([]byte("6. Get all confige & router infomation http://localhost:8088/listconf\n"))
+	rw.Write([]byte("7. Get healthcheck result as json, 503 if any fails, http://localhost:8088/health\n"))
 
 }
 
@@ -205,11 +207,11 @@ func profIndex(rw http.ResponseWriter, r *http.Request) {
 // Healthcheck is a http.Handler calling health checking and showing the result.
 // it's in "/healthcheck" pattern in admin module.
 func healthcheck(rw http.ResponseWriter, req *http.Request) {
-	for name, h := range toolbox.AdminCheckList {
-		if err := h.Check(); err != nil {
+	for name, result := range toolbox.RunHealthChecks(toolbox.HealthCheckTimeout).Checks {
+		if result.OK {
 			fmt.Fprintf(rw, "%s : ok\n", name)
 		} else {
-			fmt.Fprintf(rw, "%s : %s\n", name, err.Error())
+			fmt.Fprintf(rw, "%s : %s\n", name, result.Error)
 		}
 	}
 }
@@ -264,6 +266,6 @@ func (admin *adminApp) Run() {
 	}
 	err := http.ListenAndServe(addr, nil)
 	if err != nil {
-		BeeLogger.Critical("Admin ListenAndServe: ", err)
+		BeeLogger.Critical("Admin ListenAndServe: %v", err)
 	}
 }
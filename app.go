@@ -47,7 +47,7 @@ func (app *App) Run() {
 			l, err = net.Listen("tcp", addr)
 		}
 		if err != nil {
-			BeeLogger.Critical("Listen: ", err)
+			BeeLogger.Critical("Listen: %v", err)
 		}
 		err = fcgi.Serve(l, app.Handlers)
 	} else {
@@ -59,7 +59,7 @@ func (app *App) Run() {
 			}
 			laddr, err := net.ResolveTCPAddr("tcp", addr)
 			if nil != err {
-				BeeLogger.Critical("ResolveTCPAddr:", err)
+				BeeLogger.Critical("ResolveTCPAddr: %v", err)
 			}
 			l, err = GetInitListener(laddr)
 			theStoppable = newStoppable(l)
@@ -82,7 +82,7 @@ func (app *App) Run() {
 	}
 
 	if err != nil {
-		BeeLogger.Critical("ListenAndServe: ", err)
+		BeeLogger.Critical("ListenAndServe: %v", err)
 		time.Sleep(100 * time.Microsecond)
 	}
 }
@@ -110,6 +110,14 @@ func (app *App) Router(path string, c ControllerInterface, mappingMethods ...str
 	return app
 }
 
+// Group returns a router group, routers added to it get the prefix and run filters before controller.
+// usage:
+//  api := beego.BeeApp.Group("/api/v1", auth)
+//  api.Router("/user", &UserController{})
+func (app *App) Group(prefix string, filters ...FilterFunc) *RouterGroup {
+	return app.Handlers.Group(prefix, filters...)
+}
+
 // AutoRouter adds beego-defined controller handler.
 // if beego.AddAuto(&MainContorlller{}) and MainController has methods List and Page,
 // visit the url /main/list to exec List function or /main/page to exec Page function.
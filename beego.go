@@ -102,6 +102,12 @@ func RESTRouter(rootpath string, c ControllerInterface) *App {
 	return BeeApp
 }
 
+// Group returns a router group of BeeApp with prefix and filters.
+// it's same to App.Group.
+func Group(prefix string, filters ...FilterFunc) *RouterGroup {
+	return BeeApp.Group(prefix, filters...)
+}
+
 // AutoRouter adds defined controller handler to BeeApp.
 // it's same to App.AutoRouter.
 func AutoRouter(c ControllerInterface) *App {
@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
+	"time"
 )
 
 // Cache interface contains all behaviors for cache adapter.
@@ -33,6 +35,118 @@ type Cache interface {
 	StartAndGC(config string) error
 }
 
+// Incrementer is implemented by cache adapters increasing counters atomically.
+type Incrementer interface {
+	// increase cached int value by delta and return the new value.
+	// delta can be negative to decrease it.
+	IncrBy(key string, delta int64) (int64, error)
+}
+
+// IncrBy increases cached int value by delta and returns the new value,
+// it returns error if the adapter doesn't implement Incrementer.
+func IncrBy(c Cache, key string, delta int64) (int64, error) {
+	if ic, ok := c.(Incrementer); ok {
+		return ic.IncrBy(key, delta)
+	}
+	return 0, errors.New("cache: adapter doesn't support IncrBy")
+}
+
+// MultiCache is implemented by cache adapters reading and writing many keys at once.
+type MultiCache interface {
+	// get cached values by keys in order, nil for missing keys.
+	GetMulti(keys []string) ([]interface{}, error)
+	// set cached values with the same expire time.
+	SetMulti(values map[string]interface{}, timeout int64) error
+}
+
+// GetMulti gets cached values by keys in order, nil for missing keys.
+// it calls Get for every key if the adapter doesn't implement MultiCache.
+func GetMulti(c Cache, keys []string) ([]interface{}, error) {
+	if mc, ok := c.(MultiCache); ok {
+		return mc.GetMulti(keys)
+	}
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = c.Get(key)
+	}
+	return values, nil
+}
+
+// SetMulti sets cached values with the same expire time.
+// it calls Put for every key if the adapter doesn't implement MultiCache.
+func SetMulti(c Cache, values map[string]interface{}, timeout int64) error {
+	if mc, ok := c.(MultiCache); ok {
+		return mc.SetMulti(values, timeout)
+	}
+	for key, val := range values {
+		if err := c.Put(key, val, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NoExpiry is the TTL of cached values which never expire.
+const NoExpiry time.Duration = -1
+
+// Expirer is implemented by cache adapters tracking the expire time of values.
+type Expirer interface {
+	// get remaining time to live of key, NoExpiry if it never expires.
+	// it returns error if key doesn't exist.
+	TTL(key string) (time.Duration, error)
+	// set cached value with ttl. the ttl is absolute from now,
+	// or sliding if sliding is true: every Get of key resets it to ttl.
+	SetSliding(key string, val interface{}, ttl time.Duration, sliding bool) error
+}
+
+// TTL returns the remaining time to live of key,
+// it returns error if the adapter doesn't implement Expirer.
+func TTL(c Cache, key string) (time.Duration, error) {
+	if ec, ok := c.(Expirer); ok {
+		return ec.TTL(key)
+	}
+	return 0, errors.New("cache: adapter doesn't support TTL")
+}
+
+// SetSliding sets cached value with absolute or sliding ttl.
+// absolute ttl falls back to Put if the adapter doesn't implement Expirer, sliding returns error.
+func SetSliding(c Cache, key string, val interface{}, ttl time.Duration, sliding bool) error {
+	if ec, ok := c.(Expirer); ok {
+		return ec.SetSliding(key, val, ttl, sliding)
+	}
+	if sliding {
+		return errors.New("cache: adapter doesn't support sliding ttl")
+	}
+	return c.Put(key, val, timeoutSeconds(ttl))
+}
+
+// Tagger is implemented by cache adapters invalidating values by tags.
+type Tagger interface {
+	// set cached value with expire time and tags.
+	// a value set again with Put or PutWithTags replaces its tags.
+	PutWithTags(key string, val interface{}, timeout int64, tags []string) error
+	// delete all cached values with tag.
+	InvalidateTag(tag string) error
+}
+
+// PutWithTags sets cached value with expire time and tags,
+// it returns error if the adapter doesn't implement Tagger.
+func PutWithTags(c Cache, key string, val interface{}, timeout int64, tags []string) error {
+	if tc, ok := c.(Tagger); ok {
+		return tc.PutWithTags(key, val, timeout, tags)
+	}
+	return errors.New("cache: adapter doesn't support tags")
+}
+
+// InvalidateTag deletes all cached values with tag,
+// it returns error if the adapter doesn't implement Tagger.
+func InvalidateTag(c Cache, tag string) error {
+	if tc, ok := c.(Tagger); ok {
+		return tc.InvalidateTag(tag)
+	}
+	return errors.New("cache: adapter doesn't support tags")
+}
+
 var adapters = make(map[string]Cache)
 
 // Register makes a cache adapter available by the adapter name.
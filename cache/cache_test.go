@@ -1,6 +1,11 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -99,3 +104,354 @@ func TestFileCache(t *testing.T) {
 		t.Error("get err")
 	}
 }
+
+func TestMemoryIncrBy(t *testing.T) {
+	bm := NewMemoryCache()
+	if _, err := IncrBy(bm, "counter", 1); err == nil {
+		t.Error("IncrBy should fail without key")
+	}
+	bm.Put("counter", 0, 60)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := bm.IncrBy("counter", 2); err != nil {
+					t.Error("IncrBy Error", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if v := bm.Get("counter"); v.(int) != 10000 {
+		t.Error("concurrent IncrBy err", v)
+	}
+
+	if n, err := IncrBy(bm, "counter", -10000); err != nil || n != 0 {
+		t.Error("IncrBy should return new value", n, err)
+	}
+	bm.Put("ucounter", uint(1), 60)
+	if _, err := bm.IncrBy("ucounter", -2); err == nil {
+		t.Error("uint counter should not be less than 0")
+	}
+}
+
+func TestMemoryMulti(t *testing.T) {
+	bm := NewMemoryCache()
+	if err := SetMulti(bm, map[string]interface{}{"k1": 1, "k3": "v3"}, 60); err != nil {
+		t.Error("SetMulti Error", err)
+	}
+	bm.Put("expired", 2, -1)
+	values, err := GetMulti(bm, []string{"k1", "k2", "k3", "expired"})
+	if err != nil {
+		t.Error("GetMulti Error", err)
+	}
+	if len(values) != 4 || values[0].(int) != 1 || values[1] != nil || values[2].(string) != "v3" || values[3] != nil {
+		t.Error("GetMulti should keep order with nil for missing keys", values)
+	}
+}
+
+func TestFileMulti(t *testing.T) {
+	bm, err := NewCache("file", `{"CachePath":"/cache","FileSuffix":".bin","DirectoryLevel":2,"EmbedExpiry":0}`)
+	if err != nil {
+		t.Error("init err")
+	}
+	if err = SetMulti(bm, map[string]interface{}{"k1": 1, "k3": "v3"}, 10); err != nil {
+		t.Error("SetMulti Error", err)
+	}
+	bm.Delete("k2")
+	values, err := GetMulti(bm, []string{"k1", "k2", "k3"})
+	if err != nil {
+		t.Error("GetMulti Error", err)
+	}
+	if len(values) != 3 || values[0].(int) != 1 || values[1] != nil || values[2].(string) != "v3" {
+		t.Error("GetMulti should keep order with nil for missing keys", values)
+	}
+}
+
+func BenchmarkMemoryGetLoop(b *testing.B) {
+	bm, keys := benchmarkMemoryCache()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			bm.Get(key)
+		}
+	}
+}
+
+func BenchmarkMemoryGetMulti(b *testing.B) {
+	bm, keys := benchmarkMemoryCache()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bm.GetMulti(keys)
+	}
+}
+
+func benchmarkMemoryCache() (*MemoryCache, []string) {
+	bm := NewMemoryCache()
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+		bm.Put(keys[i], i, 60)
+	}
+	return bm, keys
+}
+
+func TestGetOrSet(t *testing.T) {
+	bm := NewMemoryCache()
+	var calls int32
+	loader := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := GetOrSet(bm, "hot", time.Minute, loader)
+			if err != nil || v.(string) != "value" {
+				t.Error("GetOrSet should share loaded value", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Error("loader should run once, not", n)
+	}
+	if v := bm.Get("hot"); v == nil || v.(string) != "value" {
+		t.Error("loaded value should be cached", v)
+	}
+	if v, _ := GetOrSet(bm, "hot", time.Minute, loader); v.(string) != "value" || atomic.LoadInt32(&calls) != 1 {
+		t.Error("cached value should not be loaded again")
+	}
+}
+
+func TestGetOrSetError(t *testing.T) {
+	bm := NewMemoryCache()
+	errLoad := errors.New("load failed")
+	if _, err := GetOrSet(bm, "bad", time.Minute, func() (interface{}, error) {
+		return nil, errLoad
+	}); err != errLoad {
+		t.Error("GetOrSet should return loader error", err)
+	}
+	if bm.IsExist("bad") {
+		t.Error("loader error should not be cached")
+	}
+	if _, err := GetOrSet(bm, "panic", time.Minute, func() (interface{}, error) {
+		panic("boom")
+	}); err == nil {
+		t.Error("GetOrSet should return loader panic as error")
+	}
+	v, err := GetOrSet(bm, "bad", time.Minute, func() (interface{}, error) {
+		return 1, nil
+	})
+	if err != nil || v.(int) != 1 {
+		t.Error("GetOrSet should load again after error", v, err)
+	}
+}
+
+func TestGetOrSetContext(t *testing.T) {
+	bm := NewMemoryCache()
+	release := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := GetOrSetContext(ctx, bm, "slow", time.Minute, func() (interface{}, error) {
+		<-release
+		return "slow", nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Error("GetOrSetContext should return ctx error", err)
+	}
+	close(release)
+	// the loader keeps running for other callers
+	v, err := GetOrSet(bm, "slow", time.Minute, func() (interface{}, error) {
+		return "other", nil
+	})
+	if err != nil || (v.(string) != "slow" && v.(string) != "other") {
+		t.Error("GetOrSet after canceled caller", v, err)
+	}
+
+	if timeoutSeconds(1500*time.Millisecond) != 2 || timeoutSeconds(time.Minute) != 60 {
+		t.Error("ttl should be rounded up to seconds")
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	bm := NewMemoryCache()
+	app1 := WithPrefix(bm, "app1:")
+	app2 := WithPrefix(bm, "app2:")
+
+	app1.Put("name", "one", 60)
+	app2.Put("name", "two", 60)
+	bm.Put("name", "raw", 60)
+	if v := app1.Get("name"); v.(string) != "one" {
+		t.Error("app1 get err", v)
+	}
+	if v := app2.Get("name"); v.(string) != "two" {
+		t.Error("app2 get err", v)
+	}
+	if v := bm.Get("app1:name"); v.(string) != "one" {
+		t.Error("key should be prefixed in backend", v)
+	}
+
+	app1.Put("counter", 1, 60)
+	if n, err := IncrBy(app1, "counter", 2); err != nil || n != 3 {
+		t.Error("IncrBy through prefix", n, err)
+	}
+	if app2.IsExist("counter") {
+		t.Error("app2 should not see app1 counter")
+	}
+	if err := SetMulti(app2, map[string]interface{}{"k1": 1, "k2": 2}, 60); err != nil {
+		t.Error("SetMulti Error", err)
+	}
+	values, _ := GetMulti(app2, []string{"k1", "name", "k3"})
+	if values[0].(int) != 1 || values[1].(string) != "two" || values[2] != nil {
+		t.Error("GetMulti through prefix", values)
+	}
+	app2.Delete("name")
+	if !app1.IsExist("name") || app2.IsExist("name") {
+		t.Error("Delete should only delete the prefixed key")
+	}
+
+	if err := app1.ClearAll(); err != nil {
+		t.Error("ClearAll Error", err)
+	}
+	if app1.IsExist("name") || app1.IsExist("counter") {
+		t.Error("ClearAll should delete keys under prefix")
+	}
+	if !app2.IsExist("k1") || !bm.IsExist("name") {
+		t.Error("ClearAll should keep keys of other prefixes")
+	}
+
+	fc, _ := NewCache("file", `{"CachePath":"/cache","FileSuffix":".bin","DirectoryLevel":2,"EmbedExpiry":0}`)
+	if err := WithPrefix(fc, "app1:").ClearAll(); err == nil {
+		t.Error("ClearAll should fail if adapter can't clear by prefix")
+	}
+}
+
+type jsonUser struct {
+	Name string   `json:"name"`
+	Age  int      `json:"age"`
+	Tags []string `json:"tags"`
+}
+
+func TestJSONStruct(t *testing.T) {
+	bm := NewMemoryCache()
+	in := jsonUser{Name: "astaxie", Age: 30, Tags: []string{"a", "b"}}
+	if err := PutStruct(bm, "user", in, time.Minute); err != nil {
+		t.Fatal("PutStruct error", err)
+	}
+	var out jsonUser
+	if ok, err := GetInto(bm, "user", &out); !ok || err != nil {
+		t.Fatal("GetInto err", ok, err)
+	}
+	if out.Name != in.Name || out.Age != in.Age || len(out.Tags) != 2 || out.Tags[1] != "b" {
+		t.Error("GetInto should decode the struct", out)
+	}
+
+	if ok, err := GetInto(bm, "missing", &out); ok || err != nil {
+		t.Error("missing key should not be found without error", ok, err)
+	}
+	bm.Put("broken", []byte("{not json"), 60)
+	if ok, err := GetInto(bm, "broken", &out); ok || err == nil {
+		t.Error("broken json should return error", ok, err)
+	}
+	bm.Put("counter", 1, 60)
+	if _, err := GetInto(bm, "counter", &out); err == nil {
+		t.Error("value not put as json should return error")
+	}
+	if err := PutStruct(bm, "func", func() {}, time.Minute); err == nil {
+		t.Error("PutStruct of unsupported value should return error")
+	}
+}
+
+func TestMemoryTTL(t *testing.T) {
+	bm := NewMemoryCache()
+	if _, err := TTL(bm, "missing"); err == nil {
+		t.Error("TTL of missing key should return error")
+	}
+	SetSliding(bm, "absolute", 1, 3*time.Second, false)
+	SetSliding(bm, "sliding", 1, 3*time.Second, true)
+	before, err := TTL(bm, "absolute")
+	if err != nil || before <= 2*time.Second || before > 3*time.Second {
+		t.Fatal("TTL should be about ttl", before, err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if ttl, _ := TTL(bm, "absolute"); ttl > before-time.Second {
+		t.Error("absolute ttl should decrease", ttl)
+	}
+	if ttl, _ := TTL(bm, "sliding"); ttl > 2*time.Second {
+		t.Error("sliding ttl should decrease without reads", ttl)
+	}
+	bm.Get("absolute")
+	if ttl, _ := TTL(bm, "absolute"); ttl > 2*time.Second {
+		t.Error("Get should not reset absolute ttl", ttl)
+	}
+	if v := bm.Get("sliding"); v.(int) != 1 {
+		t.Error("get err")
+	}
+	if ttl, _ := TTL(bm, "sliding"); ttl <= 2900*time.Millisecond {
+		t.Error("Get should reset sliding ttl", ttl)
+	}
+
+	pc := WithPrefix(bm, "app:")
+	SetSliding(pc, "sliding", 1, 3*time.Second, true)
+	if _, err := TTL(bm, "app:sliding"); err != nil {
+		t.Error("prefix cache should set ttl of prefixed key", err)
+	}
+}
+
+func TestMemoryTags(t *testing.T) {
+	bm := NewMemoryCache()
+	PutWithTags(bm, "profile", 1, 60, []string{"user:1"})
+	PutWithTags(bm, "orders", 2, 60, []string{"user:1", "shop"})
+	PutWithTags(bm, "friends", 3, 60, []string{"user:1"})
+	PutWithTags(bm, "stock", 4, 60, []string{"shop"})
+	bm.Put("other", 5, 60)
+
+	if err := InvalidateTag(bm, "user:1"); err != nil {
+		t.Fatal("InvalidateTag error", err)
+	}
+	for _, key := range []string{"profile", "orders", "friends"} {
+		if bm.IsExist(key) {
+			t.Error("InvalidateTag should delete tagged key", key)
+		}
+	}
+	for _, key := range []string{"stock", "other"} {
+		if !bm.IsExist(key) {
+			t.Error("InvalidateTag should keep key", key)
+		}
+	}
+	if _, ok := bm.tags["user:1"]; ok {
+		t.Error("InvalidateTag should delete the tag")
+	}
+	if len(bm.tags["shop"]) != 1 {
+		t.Error("deleted keys should be dropped from other tags", bm.tags["shop"])
+	}
+
+	// expired and replaced keys drop their tags.
+	bm.items["stock"].Lastaccess = time.Now().Add(-time.Hour)
+	bm.item_expired("stock")
+	if _, ok := bm.tags["shop"]; ok {
+		t.Error("expired key should be dropped from its tags")
+	}
+	PutWithTags(bm, "price", 6, 60, []string{"shop"})
+	bm.Put("price", 7, 60)
+	if _, ok := bm.tags["shop"]; ok {
+		t.Error("Put should replace the tags of key")
+	}
+
+	pc := WithPrefix(bm, "app:")
+	PutWithTags(pc, "profile", 1, 60, []string{"user:1"})
+	PutWithTags(bm, "profile", 1, 60, []string{"user:1"})
+	InvalidateTag(pc, "user:1")
+	if pc.IsExist("profile") || !bm.IsExist("profile") {
+		t.Error("prefix cache should only invalidate its own tags")
+	}
+}
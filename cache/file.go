@@ -143,6 +143,34 @@ func (this *FileCache) Get(key string) interface{} {
 	return to.Data
 }
 
+// Get values from file cache by keys in order.
+// non-exist or expired keys are nil.
+func (this *FileCache) GetMulti(keys []string) ([]interface{}, error) {
+	values := make([]interface{}, len(keys))
+	now := time.Now().Unix()
+	for i, key := range keys {
+		filedata, err := File_get_contents(this.getCacheFileName(key))
+		if err != nil {
+			continue
+		}
+		var to FileCacheItem
+		if Gob_decode(filedata, &to) == nil && to.Expired >= now {
+			values[i] = to.Data
+		}
+	}
+	return values, nil
+}
+
+// Put values into file cache with the same timeout.
+func (this *FileCache) SetMulti(values map[string]interface{}, timeout int64) error {
+	for key, val := range values {
+		if err := this.Put(key, val, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Put value into file cache.
 // timeout means how long to keep this file, unit of ms.
 // if timeout equals FileCacheEmbedExpiry(default is 0), cache this item forever.
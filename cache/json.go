@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PutStruct caches v as json with ttl, it can be read back by GetInto from any adapter.
+func PutStruct(c Cache, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Put(key, data, timeoutSeconds(ttl))
+}
+
+// GetInto unmarshals the json cached by key into dest.
+// it returns false if key is missing, and an error if the cached value isn't valid json of dest.
+func GetInto(c Cache, key string, dest interface{}) (bool, error) {
+	v := c.Get(key)
+	if !isCached(v) {
+		return false, nil
+	}
+	if err, ok := v.(error); ok {
+		return false, err
+	}
+	data := getByteArray(v)
+	if data == nil {
+		return false, fmt.Errorf("cache: value of %s is %T, not json", key, v)
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return false, fmt.Errorf("cache: decode value of %s: %v", key, err)
+	}
+	return true, nil
+}
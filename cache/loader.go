@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Loader loads the value of a missing cache key, e.g. from database.
+type Loader func() (interface{}, error)
+
+// GetOrSet gets cached value by key, or loads and caches it with ttl if it's missing.
+// only one loader runs per cache and key at a time, concurrent callers wait for it and share the result,
+// so an expired hot key doesn't hammer the database. errors of loader are returned and not cached.
+func GetOrSet(c Cache, key string, ttl time.Duration, loader Loader) (interface{}, error) {
+	return GetOrSetContext(context.Background(), c, key, ttl, loader)
+}
+
+// GetOrSetContext is GetOrSet returning ctx.Err() once ctx is done.
+// the loader keeps running for other callers and its value is still cached.
+func GetOrSetContext(ctx context.Context, c Cache, key string, ttl time.Duration, loader Loader) (interface{}, error) {
+	if v := c.Get(key); isCached(v) {
+		return v, nil
+	}
+	call := loaders.do(flightKey{c, key}, func() (interface{}, error) {
+		// the value may be set by a loader just finished.
+		if v := c.Get(key); isCached(v) {
+			return v, nil
+		}
+		v, err := loader()
+		if err != nil {
+			return nil, err
+		}
+		return v, c.Put(key, v, timeoutSeconds(ttl))
+	})
+	select {
+	case <-call.done:
+		return call.val, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// check value got from cache is not missing.
+// file adapter returns empty string for missing keys.
+func isCached(v interface{}) bool {
+	return v != nil && v != ""
+}
+
+// get timeout of Put in seconds, ttl less than a second is rounded up.
+func timeoutSeconds(ttl time.Duration) int64 {
+	timeout := int64(ttl / time.Second)
+	if ttl%time.Second > 0 {
+		timeout++
+	}
+	return timeout
+}
+
+var loaders = &flightGroup{calls: make(map[flightKey]*flightCall)}
+
+type flightKey struct {
+	c   Cache
+	key string
+}
+
+// one running loader and its result.
+type flightCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// flightGroup runs one function per key at a time, like golang.org/x/sync/singleflight.
+type flightGroup struct {
+	lock  sync.Mutex
+	calls map[flightKey]*flightCall
+}
+
+// run fn in new goroutine unless it's running for key, the call is done when fn returns.
+func (g *flightGroup) do(key flightKey, fn Loader) *flightCall {
+	g.lock.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.lock.Unlock()
+		return call
+	}
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.lock.Unlock()
+
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				call.val, call.err = nil, fmt.Errorf("cache: loader panic: %v", err)
+			}
+			g.lock.Lock()
+			delete(g.calls, key)
+			g.lock.Unlock()
+			close(call.done)
+		}()
+		call.val, call.err = fn()
+	}()
+	return call
+}
@@ -1,8 +1,15 @@
 package cache
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/beego/memcache"
 
@@ -13,6 +20,9 @@ import (
 type MemcacheCache struct {
 	c        *memcache.Connection
 	conninfo string
+	lock     sync.Mutex // lock of raw
+	raw      *bufio.ReadWriter
+	rawConn  net.Conn
 }
 
 // create new memcache adapter.
@@ -81,6 +91,79 @@ func (rc *MemcacheCache) Incr(key string) error {
 	return errors.New("not support in memcache")
 }
 
+// increase counter in memcache by delta and return the new value.
+// it uses memcache incr and decr, so the value must be a decimal string and keeps its expiration.
+// memcache counters are unsigned, decreasing stops at 0.
+// a missing counter is added with delta, or 0 if delta is negative, and never expires.
+func (rc *MemcacheCache) IncrBy(key string, delta int64) (int64, error) {
+	if rc.c == nil {
+		var err error
+		rc.c, err = rc.connectInit()
+		if err != nil {
+			return 0, err
+		}
+	}
+	cmd := fmt.Sprintf("incr %s %d", key, delta)
+	if delta < 0 {
+		cmd = fmt.Sprintf("decr %s %d", key, -delta)
+	}
+	for {
+		reply, err := rc.command(cmd)
+		if err != nil {
+			return 0, err
+		}
+		if reply != "NOT_FOUND" {
+			n, err := strconv.ParseInt(reply, 10, 64)
+			if err != nil {
+				return 0, errors.New("memcache: " + reply)
+			}
+			return n, nil
+		}
+		n := delta
+		if n < 0 {
+			n = 0
+		}
+		stored, err := rc.c.Add(key, 0, 0, []byte(strconv.FormatInt(n, 10)))
+		if err != nil {
+			return 0, err
+		}
+		// added by another client after the incr, increase it again.
+		if stored {
+			return n, nil
+		}
+	}
+}
+
+// send a memcache text protocol command with a one line reply, e.g. incr, the client doesn't have them.
+// it's sent on a connection of its own, which is dialed again after errors.
+func (rc *MemcacheCache) command(cmd string) (string, error) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if rc.raw == nil {
+		conn, err := net.DialTimeout("tcp", rc.conninfo, 5*time.Second)
+		if err != nil {
+			return "", err
+		}
+		rc.rawConn = conn
+		rc.raw = bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	}
+	rc.rawConn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err := rc.raw.WriteString(cmd + "\r\n")
+	if err == nil {
+		err = rc.raw.Flush()
+	}
+	var line string
+	if err == nil {
+		line, err = rc.raw.ReadString('\n')
+	}
+	if err != nil {
+		rc.rawConn.Close()
+		rc.raw = nil
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
 // [Not Support]
 // decrease counter.
 func (rc *MemcacheCache) Decr(key string) error {
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer speaks the memcache text protocol commands used by IncrBy: set, add, incr and decr.
+type fakeServer struct {
+	lock    sync.Mutex
+	values  map[string]string
+	expires map[string]string // exptime of storage commands
+}
+
+func (s *fakeServer) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				fmt.Fprint(conn, s.handle(strings.Fields(line), r))
+			}
+		}()
+	}
+}
+
+func (s *fakeServer) handle(f []string, r *bufio.Reader) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch f[0] {
+	case "set", "add":
+		size, _ := strconv.Atoi(f[4])
+		data := make([]byte, size+2)
+		io.ReadFull(r, data)
+		if _, ok := s.values[f[1]]; ok && f[0] == "add" {
+			return "NOT_STORED\r\n"
+		}
+		s.values[f[1]] = string(data[:size])
+		s.expires[f[1]] = f[3]
+		return "STORED\r\n"
+	case "incr", "decr":
+		v, ok := s.values[f[1]]
+		if !ok {
+			return "NOT_FOUND\r\n"
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"
+		}
+		d, _ := strconv.ParseUint(f[2], 10, 64)
+		if f[0] == "incr" {
+			n += d
+		} else if d > n {
+			n = 0
+		} else {
+			n -= d
+		}
+		s.values[f[1]] = strconv.FormatUint(n, 10)
+		return s.values[f[1]] + "\r\n"
+	}
+	return "ERROR\r\n"
+}
+
+func TestMemcacheIncrBy(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	s := &fakeServer{values: make(map[string]string), expires: make(map[string]string)}
+	go s.serve(l)
+
+	rc := NewMemCache()
+	if err = rc.StartAndGC(`{"conn":"` + l.Addr().String() + `"}`); err != nil {
+		t.Fatal("StartAndGC error", err)
+	}
+	if err = rc.Put("limit", "5", 60); err != nil {
+		t.Fatal("Put error", err)
+	}
+	if n, err := rc.IncrBy("limit", 2); err != nil || n != 7 {
+		t.Fatal("IncrBy should increase the counter", n, err)
+	}
+	if n, err := rc.IncrBy("limit", -10); err != nil || n != 0 {
+		t.Fatal("decreasing should stop at 0", n, err)
+	}
+	s.lock.Lock()
+	exptime := s.expires["limit"]
+	s.lock.Unlock()
+	if exptime != "60" {
+		t.Error("IncrBy should keep the expiration", exptime)
+	}
+	if n, err := rc.IncrBy("new", 3); err != nil || n != 3 {
+		t.Error("missing counter should be added with delta", n, err)
+	}
+	rc.Put("name", "astaxie", 0)
+	if _, err := rc.IncrBy("name", 1); err == nil {
+		t.Error("IncrBy of non-numeric value should fail")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,6 +19,8 @@ type MemoryItem struct {
 	val        interface{}
 	Lastaccess time.Time
 	expired    int64
+	sliding    bool // Get resets Lastaccess
+	tags       []string
 }
 
 // Memory cache adapter.
@@ -26,21 +29,27 @@ type MemoryCache struct {
 	lock  sync.RWMutex
 	dur   time.Duration
 	items map[string]*MemoryItem
-	Every int // run an expiration check Every clock time
+	tags  map[string]map[string]bool // tag => keys
+	Every int                        // run an expiration check Every clock time
 }
 
 // NewMemoryCache returns a new MemoryCache.
 func NewMemoryCache() *MemoryCache {
-	cache := MemoryCache{items: make(map[string]*MemoryItem)}
+	cache := MemoryCache{items: make(map[string]*MemoryItem), tags: make(map[string]map[string]bool)}
 	return &cache
 }
 
 // Get cache from memory.
 // if non-existed or expired, return nil.
+// the ttl of sliding items is reset.
 func (bc *MemoryCache) Get(name string) interface{} {
 	bc.lock.RLock()
-	defer bc.lock.RUnlock()
 	itm, ok := bc.items[name]
+	if ok && itm.sliding {
+		bc.lock.RUnlock()
+		return bc.getSliding(name)
+	}
+	defer bc.lock.RUnlock()
 	if !ok {
 		return nil
 	}
@@ -51,6 +60,56 @@ func (bc *MemoryCache) Get(name string) interface{} {
 	return itm.val
 }
 
+// get sliding item and reset its Lastaccess under write lock.
+func (bc *MemoryCache) getSliding(name string) interface{} {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	itm, ok := bc.items[name]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if (now.Unix() - itm.Lastaccess.Unix()) > itm.expired {
+		bc.remove(name)
+		return nil
+	}
+	itm.Lastaccess = now
+	return itm.val
+}
+
+// Get caches from memory by keys in order.
+// non-existed or expired keys are nil, the ttl of sliding items is reset.
+func (bc *MemoryCache) GetMulti(names []string) ([]interface{}, error) {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	now := time.Now()
+	values := make([]interface{}, len(names))
+	for i, name := range names {
+		if itm, ok := bc.items[name]; ok && now.Unix()-itm.Lastaccess.Unix() <= itm.expired {
+			values[i] = itm.val
+			if itm.sliding {
+				itm.Lastaccess = now
+			}
+		}
+	}
+	return values, nil
+}
+
+// Put caches to memory with the same expire time.
+func (bc *MemoryCache) SetMulti(values map[string]interface{}, expired int64) error {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	now := time.Now()
+	for name, value := range values {
+		bc.set(name, &MemoryItem{
+			val:        value,
+			Lastaccess: now,
+			expired:    expired,
+		})
+	}
+	return nil
+}
+
 // Put cache to memory.
 // if expired is 0, it will be cleaned by next gc operation ( default gc clock is 1 minute).
 func (bc *MemoryCache) Put(name string, value interface{}, expired int64) error {
@@ -61,10 +120,98 @@ func (bc *MemoryCache) Put(name string, value interface{}, expired int64) error
 		Lastaccess: time.Now(),
 		expired:    expired,
 	}
-	bc.items[name] = &t
+	bc.set(name, &t)
 	return nil
 }
 
+// Put cache to memory with tags.
+func (bc *MemoryCache) PutWithTags(name string, value interface{}, expired int64, tags []string) error {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	bc.set(name, &MemoryItem{
+		val:        value,
+		Lastaccess: time.Now(),
+		expired:    expired,
+		tags:       tags,
+	})
+	return nil
+}
+
+// delete all caches in memory with tag.
+func (bc *MemoryCache) InvalidateTag(tag string) error {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	for name := range bc.tags[tag] {
+		bc.remove(name)
+	}
+	return nil
+}
+
+// save item as name, tags of the replaced item are dropped.
+// the caller must hold the write lock.
+func (bc *MemoryCache) set(name string, itm *MemoryItem) {
+	bc.untag(name)
+	bc.items[name] = itm
+	if len(itm.tags) > 0 && bc.tags == nil {
+		bc.tags = make(map[string]map[string]bool)
+	}
+	for _, tag := range itm.tags {
+		if bc.tags[tag] == nil {
+			bc.tags[tag] = make(map[string]bool)
+		}
+		bc.tags[tag][name] = true
+	}
+}
+
+// delete item and its tags, the caller must hold the write lock.
+func (bc *MemoryCache) remove(name string) {
+	bc.untag(name)
+	delete(bc.items, name)
+}
+
+// remove name from the keys of its tags, tags without keys are deleted.
+func (bc *MemoryCache) untag(name string) {
+	itm, ok := bc.items[name]
+	if !ok {
+		return
+	}
+	for _, tag := range itm.tags {
+		delete(bc.tags[tag], name)
+		if len(bc.tags[tag]) == 0 {
+			delete(bc.tags, tag)
+		}
+	}
+}
+
+// Put cache to memory with absolute or sliding ttl, ttl is rounded up to seconds.
+func (bc *MemoryCache) SetSliding(name string, value interface{}, ttl time.Duration, sliding bool) error {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	bc.set(name, &MemoryItem{
+		val:        value,
+		Lastaccess: time.Now(),
+		expired:    timeoutSeconds(ttl),
+		sliding:    sliding,
+	})
+	return nil
+}
+
+// get remaining ttl of cache in memory.
+func (bc *MemoryCache) TTL(name string) (time.Duration, error) {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	itm, ok := bc.items[name]
+	now := time.Now()
+	if !ok || (now.Unix()-itm.Lastaccess.Unix()) > itm.expired {
+		return 0, errors.New("key not exist")
+	}
+	ttl := time.Duration(itm.expired)*time.Second - now.Sub(itm.Lastaccess)
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl, nil
+}
+
 /// Delete cache in memory.
 func (bc *MemoryCache) Delete(name string) error {
 	bc.lock.Lock()
@@ -72,7 +219,7 @@ func (bc *MemoryCache) Delete(name string) error {
 	if _, ok := bc.items[name]; !ok {
 		return errors.New("key not exist")
 	}
-	delete(bc.items, name)
+	bc.remove(name)
 	_, valid := bc.items[name]
 	if valid {
 		return errors.New("delete key error")
@@ -83,8 +230,8 @@ func (bc *MemoryCache) Delete(name string) error {
 // Increase cache counter in memory.
 // it supports int,int64,int32,uint,uint64,uint32.
 func (bc *MemoryCache) Incr(key string) error {
-	bc.lock.RLock()
-	defer bc.lock.RUnlock()
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
 	itm, ok := bc.items[key]
 	if !ok {
 		return errors.New("key not exist")
@@ -110,8 +257,8 @@ func (bc *MemoryCache) Incr(key string) error {
 
 // Decrease counter in memory.
 func (bc *MemoryCache) Decr(key string) error {
-	bc.lock.RLock()
-	defer bc.lock.RUnlock()
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
 	itm, ok := bc.items[key]
 	if !ok {
 		return errors.New("key not exist")
@@ -147,6 +294,47 @@ func (bc *MemoryCache) Decr(key string) error {
 	return nil
 }
 
+// Increase cache counter in memory by delta and return the new value.
+// it supports int,int64,int32,uint,uint64,uint32.
+func (bc *MemoryCache) IncrBy(key string, delta int64) (int64, error) {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	itm, ok := bc.items[key]
+	if !ok {
+		return 0, errors.New("key not exist")
+	}
+	var n int64
+	switch v := itm.val.(type) {
+	case int:
+		n = int64(v) + delta
+		itm.val = int(n)
+	case int64:
+		n = v + delta
+		itm.val = n
+	case int32:
+		n = int64(v) + delta
+		itm.val = int32(n)
+	case uint:
+		if n = int64(v) + delta; n < 0 {
+			return 0, errors.New("item val is less than 0")
+		}
+		itm.val = uint(n)
+	case uint32:
+		if n = int64(v) + delta; n < 0 {
+			return 0, errors.New("item val is less than 0")
+		}
+		itm.val = uint32(n)
+	case uint64:
+		if n = int64(v) + delta; n < 0 {
+			return 0, errors.New("item val is less than 0")
+		}
+		itm.val = uint64(n)
+	default:
+		return 0, errors.New("item val is not int int64 int32")
+	}
+	return n, nil
+}
+
 // check cache exist in memory.
 func (bc *MemoryCache) IsExist(name string) bool {
 	bc.lock.RLock()
@@ -160,6 +348,19 @@ func (bc *MemoryCache) ClearAll() error {
 	bc.lock.Lock()
 	defer bc.lock.Unlock()
 	bc.items = make(map[string]*MemoryItem)
+	bc.tags = make(map[string]map[string]bool)
+	return nil
+}
+
+// delete cache in memory with key starting with prefix.
+func (bc *MemoryCache) ClearPrefix(prefix string) error {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	for name := range bc.items {
+		if strings.HasPrefix(name, prefix) {
+			bc.remove(name)
+		}
+	}
 	return nil
 }
 
@@ -207,7 +408,7 @@ func (bc *MemoryCache) item_expired(name string) bool {
 	}
 	sec := time.Now().Unix() - itm.Lastaccess.Unix()
 	if sec >= itm.expired {
-		delete(bc.items, name)
+		bc.remove(name)
 		return true
 	}
 	return false
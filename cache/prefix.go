@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// PrefixClearer is implemented by cache adapters deleting keys by prefix.
+type PrefixClearer interface {
+	// delete all cached values with key starting with prefix.
+	ClearPrefix(prefix string) error
+}
+
+// WithPrefix returns a cache prepending prefix to every key of c,
+// so apps sharing one backend don't collide on keys.
+// ClearAll only deletes keys under the prefix and returns error if c doesn't implement PrefixClearer.
+// c must be started already, StartAndGC of the returned cache does nothing.
+func WithPrefix(c Cache, prefix string) Cache {
+	return &prefixCache{c: c, prefix: prefix}
+}
+
+// cache with key prefix.
+type prefixCache struct {
+	c      Cache
+	prefix string
+}
+
+var (
+	_ Incrementer   = new(prefixCache)
+	_ MultiCache    = new(prefixCache)
+	_ PrefixClearer = new(prefixCache)
+	_ Expirer       = new(prefixCache)
+	_ Tagger        = new(prefixCache)
+)
+
+func (pc *prefixCache) Get(key string) interface{} {
+	return pc.c.Get(pc.prefix + key)
+}
+
+func (pc *prefixCache) Put(key string, val interface{}, timeout int64) error {
+	return pc.c.Put(pc.prefix+key, val, timeout)
+}
+
+func (pc *prefixCache) Delete(key string) error {
+	return pc.c.Delete(pc.prefix + key)
+}
+
+func (pc *prefixCache) Incr(key string) error {
+	return pc.c.Incr(pc.prefix + key)
+}
+
+func (pc *prefixCache) Decr(key string) error {
+	return pc.c.Decr(pc.prefix + key)
+}
+
+func (pc *prefixCache) IncrBy(key string, delta int64) (int64, error) {
+	return IncrBy(pc.c, pc.prefix+key, delta)
+}
+
+func (pc *prefixCache) IsExist(key string) bool {
+	return pc.c.IsExist(pc.prefix + key)
+}
+
+func (pc *prefixCache) GetMulti(keys []string) ([]interface{}, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = pc.prefix + key
+	}
+	return GetMulti(pc.c, prefixed)
+}
+
+func (pc *prefixCache) SetMulti(values map[string]interface{}, timeout int64) error {
+	prefixed := make(map[string]interface{}, len(values))
+	for key, val := range values {
+		prefixed[pc.prefix+key] = val
+	}
+	return SetMulti(pc.c, prefixed, timeout)
+}
+
+func (pc *prefixCache) TTL(key string) (time.Duration, error) {
+	return TTL(pc.c, pc.prefix+key)
+}
+
+func (pc *prefixCache) SetSliding(key string, val interface{}, ttl time.Duration, sliding bool) error {
+	return SetSliding(pc.c, pc.prefix+key, val, ttl, sliding)
+}
+
+// tags are prefixed too, so tags of other prefixes aren't invalidated.
+func (pc *prefixCache) PutWithTags(key string, val interface{}, timeout int64, tags []string) error {
+	prefixed := make([]string, len(tags))
+	for i, tag := range tags {
+		prefixed[i] = pc.prefix + tag
+	}
+	return PutWithTags(pc.c, pc.prefix+key, val, timeout, prefixed)
+}
+
+func (pc *prefixCache) InvalidateTag(tag string) error {
+	return InvalidateTag(pc.c, pc.prefix+tag)
+}
+
+// delete keys under the prefix only.
+func (pc *prefixCache) ClearAll() error {
+	return pc.ClearPrefix("")
+}
+
+func (pc *prefixCache) ClearPrefix(prefix string) error {
+	if c, ok := pc.c.(PrefixClearer); ok {
+		return c.ClearPrefix(pc.prefix + prefix)
+	}
+	return errors.New("cache: adapter doesn't support ClearPrefix")
+}
+
+func (pc *prefixCache) StartAndGC(config string) error {
+	return nil
+}
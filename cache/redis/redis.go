@@ -3,6 +3,7 @@ package cache
 import (
 	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/beego/redigo/redis"
@@ -35,26 +36,285 @@ func (rc *RedisCache) do(commandName string, args ...interface{}) (reply interfa
 	return c.Do(commandName, args...)
 }
 
+// values with ttl can't be hash fields, they are kept in keys of their own:
+// prefix:ttl:key holds the value and prefix:sliding:key the sliding ttl in milliseconds.
+func (rc *RedisCache) ttlKey(key string) string {
+	return rc.key + ":ttl:" + key
+}
+
+func (rc *RedisCache) slidingKey(key string) string {
+	return rc.key + ":sliding:" + key
+}
+
+// prefix:tag:tag is a sorted set of the keys with tag, scored by their expire time in unix milliseconds.
+func (rc *RedisCache) tagKey(tag string) string {
+	return rc.key + ":tag:" + tag
+}
+
+// prefix:keytags:key is the set of tags of key, so the key is removed from its tag sets when it's set again.
+func (rc *RedisCache) keyTagsKey(key string) string {
+	return rc.key + ":keytags:" + key
+}
+
+// lua function of scripts setting or deleting a value, it removes key from the tag sets of its tags.
+const untagLua = `
+local function untag(tagPrefix, tagsKey, key)
+	for _, tag in ipairs(redis.call('SMEMBERS', tagsKey)) do
+		redis.call('ZREM', tagPrefix .. tag, key)
+	end
+	redis.call('DEL', tagsKey)
+end
+`
+
+// put value to the collection and drop its tags.
+var putScript = redis.NewScript(-1, untagLua+`
+for i = 2, #KEYS do
+	untag(ARGV[1], KEYS[i], ARGV[2*i-2])
+	redis.call('HSET', KEYS[1], ARGV[2*i-2], ARGV[2*i-1])
+end
+return 1
+`)
+
+// delete value in the collection and with ttl, and drop its tags.
+var deleteScript = redis.NewScript(4, untagLua+`
+untag(ARGV[2], KEYS[4], ARGV[1])
+redis.call('HDEL', KEYS[1], ARGV[1])
+redis.call('DEL', KEYS[2], KEYS[3])
+return 1
+`)
+
+// get value with ttl, both keys of a sliding value are expired again.
+var getScript = redis.NewScript(2, `
+local v = redis.call('GET', KEYS[1])
+if v then
+	local ttl = redis.call('GET', KEYS[2])
+	if ttl then
+		redis.call('PEXPIRE', KEYS[1], ttl)
+		redis.call('PEXPIRE', KEYS[2], ttl)
+	end
+end
+return v
+`)
+
+// set value with ttl and drop the hash field shadowing it and its tags.
+var setScript = redis.NewScript(4, untagLua+`
+untag(ARGV[5], KEYS[4], ARGV[1])
+redis.call('HDEL', KEYS[1], ARGV[1])
+redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+if ARGV[4] == '1' then
+	redis.call('SET', KEYS[3], ARGV[3], 'PX', ARGV[3])
+else
+	redis.call('DEL', KEYS[3])
+end
+return 1
+`)
+
+// increase counter with ttl if it exists, the counter in the collection otherwise.
+// both keys of a sliding counter are expired again.
+var incrScript = redis.NewScript(3, `
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	return redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2])
+end
+local v = redis.call('INCRBY', KEYS[2], ARGV[2])
+local ttl = redis.call('GET', KEYS[3])
+if ttl then
+	redis.call('PEXPIRE', KEYS[2], ttl)
+	redis.call('PEXPIRE', KEYS[3], ttl)
+end
+return v
+`)
+
+// ttl in milliseconds, -1 for hash fields, -2 for missing keys.
+var ttlScript = redis.NewScript(2, `
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+	return -1
+end
+return redis.call('PTTL', KEYS[2])
+`)
+
+// set value with tags, it's kept in a key of its own if it expires.
+// the key is removed from the sets of its old tags and added to the ones of its new tags,
+// expired keys are dropped from the tag sets, which expire with their last key.
+var tagScript = redis.NewScript(-1, untagLua+`
+local expires = '+inf'
+untag(ARGV[5], KEYS[4], ARGV[1])
+redis.call('DEL', KEYS[3])
+if tonumber(ARGV[3]) > 0 then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+	redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+	expires = tonumber(ARGV[4]) + tonumber(ARGV[3])
+else
+	redis.call('DEL', KEYS[2])
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+for i = 5, #KEYS do
+	redis.call('SADD', KEYS[4], ARGV[i+1])
+	redis.call('ZREMRANGEBYSCORE', KEYS[i], '-inf', '(' .. ARGV[4])
+	redis.call('ZADD', KEYS[i], expires, ARGV[1])
+	local last = redis.call('ZRANGE', KEYS[i], -1, -1, 'WITHSCORES')
+	if last[2] == 'inf' then
+		redis.call('PERSIST', KEYS[i])
+	else
+		redis.call('PEXPIREAT', KEYS[i], last[2])
+	end
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('PEXPIRE', KEYS[4], ARGV[3])
+end
+return 1
+`)
+
+// delete the values of keys in the tag set and the set, the keys are removed from their other tag sets.
+var invalidateScript = redis.NewScript(2, untagLua+`
+local keys = redis.call('ZRANGE', KEYS[2], 0, -1)
+for _, k in ipairs(keys) do
+	untag(ARGV[3], ARGV[4] .. k, k)
+	redis.call('HDEL', KEYS[1], k)
+	redis.call('DEL', ARGV[1] .. k, ARGV[2] .. k)
+end
+redis.call('DEL', KEYS[2])
+return #keys
+`)
+
 // Get cache from redis.
+// values set by SetSliding are read if key isn't in the collection, the ttl of sliding ones is reset.
 func (rc *RedisCache) Get(key string) interface{} {
 	v, err := rc.do("HGET", rc.key, key)
 	if err != nil {
 		return nil
 	}
+	if v == nil {
+		c := rc.p.Get()
+		defer c.Close()
+		if v, err = getScript.Do(c, rc.ttlKey(key), rc.slidingKey(key)); err != nil {
+			return nil
+		}
+	}
 
 	return v
 }
 
-// put cache to redis.
+// Get caches from redis by keys in one HMGET, missing keys are nil.
+// keys missing in the collection are read like Get from values set by SetSliding.
+func (rc *RedisCache) GetMulti(keys []string) ([]interface{}, error) {
+	if len(keys) == 0 {
+		return []interface{}{}, nil
+	}
+	args := make([]interface{}, 0, len(keys)+1)
+	args = append(args, rc.key)
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	c := rc.p.Get()
+	defer c.Close()
+	values, err := redis.Values(c.Do("HMGET", args...))
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		if v != nil || i >= len(keys) {
+			continue
+		}
+		if values[i], err = getScript.Do(c, rc.ttlKey(keys[i]), rc.slidingKey(keys[i])); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// put caches to redis in one script, their tags are dropped.
+// timeout is ignored.
+func (rc *RedisCache) SetMulti(values map[string]interface{}, timeout int64) error {
+	if len(values) == 0 {
+		return nil
+	}
+	keys := make([]interface{}, 0, len(values)+1)
+	args := make([]interface{}, 0, 2*len(values)+1)
+	keys = append(keys, rc.key)
+	args = append(args, rc.tagKey(""))
+	for key, val := range values {
+		keys = append(keys, rc.keyTagsKey(key))
+		args = append(args, key, val)
+	}
+	c := rc.p.Get()
+	defer c.Close()
+	_, err := putScript.Do(c, append(append([]interface{}{len(keys)}, keys...), args...)...)
+	return err
+}
+
+// put cache to redis, its tags are dropped.
 // timeout is ignored.
 func (rc *RedisCache) Put(key string, val interface{}, timeout int64) error {
-	_, err := rc.do("HSET", rc.key, key, val)
+	return rc.SetMulti(map[string]interface{}{key: val}, timeout)
+}
+
+// put cache with ttl to redis, reads of a sliding value expire it again with ttl.
+// the value is kept in a key of its own and not in the collection, its tags are dropped.
+func (rc *RedisCache) SetSliding(key string, val interface{}, ttl time.Duration, sliding bool) error {
+	ms := int64(ttl / time.Millisecond)
+	if ms <= 0 {
+		return errors.New("ttl should be at least 1ms")
+	}
+	flag := "0"
+	if sliding {
+		flag = "1"
+	}
+	c := rc.p.Get()
+	defer c.Close()
+	_, err := setScript.Do(c, rc.key, rc.ttlKey(key), rc.slidingKey(key), rc.keyTagsKey(key), key, val, ms, flag, rc.tagKey(""))
 	return err
 }
 
-// delete cache in redis.
+// put cache with tags to redis.
+// values with timeout are kept in keys of their own and expire, others are in the collection like Put.
+// a value set again with Put, SetSliding or PutWithTags replaces its tags.
+func (rc *RedisCache) PutWithTags(key string, val interface{}, timeout int64, tags []string) error {
+	keys := []interface{}{rc.key, rc.ttlKey(key), rc.slidingKey(key), rc.keyTagsKey(key)}
+	for _, tag := range tags {
+		keys = append(keys, rc.tagKey(tag))
+	}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	args := append([]interface{}{len(keys)}, keys...)
+	args = append(args, key, val, timeout*1000, now, rc.tagKey(""))
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	c := rc.p.Get()
+	defer c.Close()
+	_, err := tagScript.Do(c, args...)
+	return err
+}
+
+// delete all caches in redis with tag.
+func (rc *RedisCache) InvalidateTag(tag string) error {
+	c := rc.p.Get()
+	defer c.Close()
+	_, err := invalidateScript.Do(c, rc.key, rc.tagKey(tag), rc.ttlKey(""), rc.slidingKey(""), rc.tagKey(""), rc.keyTagsKey(""))
+	return err
+}
+
+// get remaining ttl of cache in redis, cache.NoExpiry for values in the collection.
+func (rc *RedisCache) TTL(key string) (time.Duration, error) {
+	c := rc.p.Get()
+	defer c.Close()
+	ms, err := redis.Int64(ttlScript.Do(c, rc.key, rc.ttlKey(key), key))
+	if err != nil {
+		return 0, err
+	}
+	switch ms {
+	case -1:
+		return cache.NoExpiry, nil
+	case -2:
+		return 0, errors.New("key not exist")
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// delete cache in redis and drop its tags.
 func (rc *RedisCache) Delete(key string) error {
-	_, err := rc.do("HDEL", rc.key, key)
+	c := rc.p.Get()
+	defer c.Close()
+	_, err := deleteScript.Do(c, rc.key, rc.ttlKey(key), rc.slidingKey(key), rc.keyTagsKey(key), key, rc.tagKey(""))
 	return err
 }
 
@@ -64,28 +324,119 @@ func (rc *RedisCache) IsExist(key string) bool {
 	if err != nil {
 		return false
 	}
+	if !v {
+		v, _ = redis.Bool(rc.do("EXISTS", rc.ttlKey(key)))
+	}
 
 	return v
 }
 
 // increase counter in redis.
 func (rc *RedisCache) Incr(key string) error {
-	_, err := redis.Bool(rc.do("HINCRBY", rc.key, key, 1))
+	_, err := rc.IncrBy(key, 1)
 	return err
 }
 
 // decrease counter in redis.
 func (rc *RedisCache) Decr(key string) error {
-	_, err := redis.Bool(rc.do("HINCRBY", rc.key, key, -1))
+	_, err := rc.IncrBy(key, -1)
 	return err
 }
 
-// clean all cache in redis. delete this redis collection.
+// increase counter in redis by delta and return the new value.
+// counter is created with 0 in the collection if not exist.
+// counters set by SetSliding are increased in their own key and keep their ttl.
+func (rc *RedisCache) IncrBy(key string, delta int64) (int64, error) {
+	c := rc.p.Get()
+	defer c.Close()
+	return redis.Int64(incrScript.Do(c, rc.key, rc.ttlKey(key), rc.slidingKey(key), key, delta))
+}
+
+// clean all cache in redis. delete this redis collection and values with ttl.
 func (rc *RedisCache) ClearAll() error {
-	_, err := rc.do("DEL", rc.key)
-	return err
+	if _, err := rc.do("DEL", rc.key); err != nil {
+		return err
+	}
+	return rc.clearTTLKeys(rc.ttlKey(""), rc.slidingKey(""), rc.tagKey(""), rc.keyTagsKey(""))
+}
+
+// delete redis keys starting with any of starts, e.g. keys of values with ttl and tags.
+// they are found with SCAN MATCH.
+func (rc *RedisCache) clearTTLKeys(starts ...string) error {
+	for _, match := range starts {
+		match = globEscaper.Replace(match) + "*"
+		cursor := "0"
+		for {
+			values, err := redis.Values(rc.do("SCAN", cursor, "MATCH", match, "COUNT", 100))
+			if err != nil {
+				return err
+			}
+			if len(values) != 2 {
+				return errors.New("unexpected SCAN reply")
+			}
+			if cursor, err = redis.String(values[0], nil); err != nil {
+				return err
+			}
+			keys, err := redis.Values(values[1], nil)
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if _, err = rc.do("DEL", keys...); err != nil {
+					return err
+				}
+			}
+			if cursor == "0" {
+				break
+			}
+		}
+	}
+	return nil
 }
 
+// delete caches in redis with key starting with prefix.
+// keys are found with HSCAN MATCH, so the collection isn't blocked like with HKEYS.
+func (rc *RedisCache) ClearPrefix(prefix string) error {
+	// tags of the keys are kept, so the keys are dropped from their tag sets when they are set again
+	if err := rc.clearTTLKeys(rc.ttlKey(prefix), rc.slidingKey(prefix), rc.tagKey(prefix)); err != nil {
+		return err
+	}
+	match := globEscaper.Replace(prefix) + "*"
+	cursor := "0"
+	for {
+		values, err := redis.Values(rc.do("HSCAN", rc.key, cursor, "MATCH", match, "COUNT", 100))
+		if err != nil {
+			return err
+		}
+		if len(values) != 2 {
+			return errors.New("unexpected HSCAN reply")
+		}
+		if cursor, err = redis.String(values[0], nil); err != nil {
+			return err
+		}
+		pairs, err := redis.Strings(values[1], nil)
+		if err != nil {
+			return err
+		}
+		if len(pairs) > 0 {
+			args := make([]interface{}, 0, len(pairs)/2+1)
+			args = append(args, rc.key)
+			for i := 0; i < len(pairs); i += 2 {
+				args = append(args, pairs[i])
+			}
+			if _, err = rc.do("HDEL", args...); err != nil {
+				return err
+			}
+		}
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// escape glob characters of redis MATCH pattern.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
 // start redis cache adapter.
 // config is like {"key":"collection key","conn":"connection info"}
 // the cache item in redis are stored forever,
@@ -0,0 +1,440 @@
+package cache
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beego/redigo/redis"
+
+	"github.com/astaxie/beego/cache"
+)
+
+// mockConn is a redis.Conn keeping hash fields, sets, sorted sets and keys with expire time in memory.
+// scripts are run by go code doing the same, evals records the keys and args they get.
+type mockConn struct {
+	hashes  map[string]map[string][]byte
+	sets    map[string]map[string]bool
+	zsets   map[string]map[string]float64
+	keys    map[string][]byte
+	expires map[string]time.Time
+	evals   [][]interface{}
+}
+
+func newMockConn() *mockConn {
+	return &mockConn{hashes: make(map[string]map[string][]byte), sets: make(map[string]map[string]bool),
+		zsets: make(map[string]map[string]float64), keys: make(map[string][]byte), expires: make(map[string]time.Time)}
+}
+
+// get key if it isn't expired.
+func (c *mockConn) get(key string) ([]byte, bool) {
+	if time.Now().After(c.expires[key]) {
+		delete(c.keys, key)
+		return nil, false
+	}
+	v, ok := c.keys[key]
+	return v, ok
+}
+
+func (c *mockConn) set(key string, v []byte, ms int64) {
+	c.keys[key] = v
+	c.expires[key] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+}
+
+func (c *mockConn) hset(hash, key string, v []byte) {
+	if c.hashes[hash] == nil {
+		c.hashes[hash] = make(map[string][]byte)
+	}
+	c.hashes[hash][key] = v
+}
+
+// untag of untagLua.
+func (c *mockConn) untag(tagPrefix, tagsKey, key string) {
+	for tag := range c.sets[tagsKey] {
+		delete(c.zsets[tagPrefix+tag], key)
+	}
+	delete(c.sets, tagsKey)
+}
+
+// run the scripts of RedisCache, they are told apart by the commands they call.
+func (c *mockConn) eval(src string, keys []string, argv []interface{}) (interface{}, error) {
+	switch {
+	case strings.Contains(src, "ZADD"):
+		key, ms, now := argv[0].(string), argv[2].(int64), argv[3].(int64)
+		c.untag(argv[4].(string), keys[3], key)
+		delete(c.keys, keys[2])
+		expires := math.Inf(1)
+		if ms > 0 {
+			delete(c.hashes[keys[0]], key)
+			c.set(keys[1], argv[1].([]byte), ms)
+			expires = float64(now + ms)
+		} else {
+			delete(c.keys, keys[1])
+			c.hset(keys[0], key, argv[1].([]byte))
+		}
+		for i, tag := range keys[4:] {
+			if c.sets[keys[3]] == nil {
+				c.sets[keys[3]] = make(map[string]bool)
+			}
+			c.sets[keys[3]][argv[5+i].(string)] = true
+			if c.zsets[tag] == nil {
+				c.zsets[tag] = make(map[string]float64)
+			}
+			for k, score := range c.zsets[tag] {
+				if score < float64(now) {
+					delete(c.zsets[tag], k)
+				}
+			}
+			c.zsets[tag][key] = expires
+		}
+		return int64(1), nil
+	case strings.Contains(src, "ZRANGE', KEYS[2], 0"):
+		n := len(c.zsets[keys[1]])
+		for k := range c.zsets[keys[1]] {
+			c.untag(argv[2].(string), argv[3].(string)+k, k)
+			delete(c.hashes[keys[0]], k)
+			delete(c.keys, argv[0].(string)+k)
+			delete(c.keys, argv[1].(string)+k)
+		}
+		delete(c.zsets, keys[1])
+		return int64(n), nil
+	case strings.Contains(src, "'INCRBY'"):
+		v, ok := c.get(keys[1])
+		if !ok {
+			return c.Do("HINCRBY", keys[0], argv[0], argv[1])
+		}
+		n, _ := strconv.ParseInt(string(v), 10, 64)
+		n += argv[1].(int64)
+		ms := int64(c.expires[keys[1]].Sub(time.Now()) / time.Millisecond)
+		if ttl, ok := c.get(keys[2]); ok {
+			ms, _ = strconv.ParseInt(string(ttl), 10, 64)
+			c.set(keys[2], ttl, ms)
+		}
+		c.set(keys[1], []byte(strconv.FormatInt(n, 10)), ms)
+		return n, nil
+	case strings.Contains(src, "PTTL"):
+		if _, ok := c.hashes[keys[0]][argv[0].(string)]; ok {
+			return int64(-1), nil
+		}
+		if _, ok := c.get(keys[1]); !ok {
+			return int64(-2), nil
+		}
+		return int64(c.expires[keys[1]].Sub(time.Now()) / time.Millisecond), nil
+	case strings.Contains(src, "'PX'"):
+		c.untag(argv[4].(string), keys[3], argv[0].(string))
+		delete(c.hashes[keys[0]], argv[0].(string))
+		ms := argv[2].(int64)
+		c.set(keys[1], argv[1].([]byte), ms)
+		if argv[3] == "1" {
+			c.set(keys[2], []byte(strconv.FormatInt(ms, 10)), ms)
+		} else {
+			delete(c.keys, keys[2])
+		}
+		return int64(1), nil
+	case strings.Contains(src, "'HSET'"):
+		for i, tagsKey := range keys[1:] {
+			key := argv[1+2*i].(string)
+			c.untag(argv[0].(string), tagsKey, key)
+			c.hset(keys[0], key, argv[2+2*i].([]byte))
+		}
+		return int64(1), nil
+	case strings.Contains(src, "'HDEL'"):
+		c.untag(argv[1].(string), keys[3], argv[0].(string))
+		delete(c.hashes[keys[0]], argv[0].(string))
+		delete(c.keys, keys[1])
+		delete(c.keys, keys[2])
+		return int64(1), nil
+	case strings.Contains(src, "PEXPIRE"):
+		v, ok := c.get(keys[0])
+		if !ok {
+			return nil, nil
+		}
+		if ttl, ok := c.get(keys[1]); ok {
+			ms, _ := strconv.ParseInt(string(ttl), 10, 64)
+			c.set(keys[0], v, ms)
+			c.set(keys[1], ttl, ms)
+		}
+		return v, nil
+	}
+	return nil, redis.Error("ERR unknown script")
+}
+
+func (c *mockConn) Close() error { return nil }
+
+func (c *mockConn) Err() error { return nil }
+
+func (c *mockConn) Send(cmd string, args ...interface{}) error { return nil }
+
+func (c *mockConn) Flush() error { return nil }
+
+func (c *mockConn) Receive() (interface{}, error) { return nil, nil }
+
+func (c *mockConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	key := args[0].(string)
+	switch cmd {
+	case "HMGET":
+		values := make([]interface{}, 0, len(args)-1)
+		for _, field := range args[1:] {
+			if v, ok := c.hashes[key][field.(string)]; ok {
+				values = append(values, v)
+			} else {
+				values = append(values, nil)
+			}
+		}
+		return values, nil
+	case "HINCRBY":
+		if c.hashes[key] == nil {
+			c.hashes[key] = make(map[string][]byte)
+		}
+		n, _ := strconv.ParseInt(string(c.hashes[key][args[1].(string)]), 10, 64)
+		n += args[2].(int64)
+		c.hashes[key][args[1].(string)] = []byte(strconv.FormatInt(n, 10))
+		return n, nil
+	case "HGET":
+		if v, ok := c.hashes[key][args[1].(string)]; ok {
+			return v, nil
+		}
+		return nil, nil
+	case "HEXISTS":
+		if _, ok := c.hashes[key][args[1].(string)]; ok {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case "HDEL":
+		delete(c.hashes[key], args[1].(string))
+		return int64(1), nil
+	case "EXISTS":
+		if _, ok := c.get(key); ok {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case "DEL":
+		for _, k := range args {
+			delete(c.keys, k.(string))
+			delete(c.zsets, k.(string))
+		}
+		return int64(len(args)), nil
+	case "EVALSHA":
+		return nil, redis.Error("NOSCRIPT No matching script. Please use EVAL.")
+	case "EVAL":
+		n := args[1].(int)
+		keys := make([]string, n)
+		for i := range keys {
+			keys[i] = args[2+i].(string)
+		}
+		c.evals = append(c.evals, args[2:])
+		return c.eval(key, keys, args[2+n:])
+	}
+	return nil, redis.Error("ERR unknown command " + cmd)
+}
+
+func TestRedisJSONStruct(t *testing.T) {
+	conn := newMockConn()
+	rc := NewRedisCache()
+	rc.p = &redis.Pool{Dial: func() (redis.Conn, error) { return conn, nil }}
+
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	if err := cache.PutStruct(rc, "user", user{"astaxie", 30}, time.Minute); err != nil {
+		t.Fatal("PutStruct error", err)
+	}
+	if string(conn.hashes[DefaultKey]["user"]) != `{"name":"astaxie","age":30}` {
+		t.Error("struct should be stored as json", string(conn.hashes[DefaultKey]["user"]))
+	}
+	var u user
+	if ok, err := cache.GetInto(rc, "user", &u); !ok || err != nil {
+		t.Fatal("GetInto err", ok, err)
+	}
+	if u.Name != "astaxie" || u.Age != 30 {
+		t.Error("GetInto should decode the struct", u)
+	}
+	if ok, err := cache.GetInto(rc, "missing", &u); ok || err != nil {
+		t.Error("missing key should not be found without error", ok, err)
+	}
+}
+
+func TestRedisGetMultiEmpty(t *testing.T) {
+	rc := NewRedisCache()
+	rc.p = &redis.Pool{Dial: func() (redis.Conn, error) { return newMockConn(), nil }}
+	if vs, err := rc.GetMulti(nil); err != nil || len(vs) != 0 {
+		t.Error("GetMulti of no keys should return no values without sending HMGET", vs, err)
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	conn := newMockConn()
+	rc := NewRedisCache()
+	rc.p = &redis.Pool{Dial: func() (redis.Conn, error) { return conn, nil }}
+
+	rc.Put("forever", []byte("v"), 60)
+	if ttl, err := cache.TTL(rc, "forever"); err != nil || ttl != cache.NoExpiry {
+		t.Error("values in the collection should never expire", ttl, err)
+	}
+	if _, err := cache.TTL(rc, "missing"); err == nil {
+		t.Error("TTL of missing key should return error")
+	}
+
+	if err := cache.SetSliding(rc, "absolute", []byte("v"), time.Second, false); err != nil {
+		t.Fatal("SetSliding error", err)
+	}
+	if err := cache.SetSliding(rc, "sliding", []byte("v"), time.Second, true); err != nil {
+		t.Fatal("SetSliding error", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	for _, key := range []string{"absolute", "sliding"} {
+		if v := rc.Get(key); string(v.([]byte)) != "v" {
+			t.Error("Get should read value with ttl", key, v)
+		}
+	}
+	if ttl, _ := rc.TTL("absolute"); ttl <= 0 || ttl > 900*time.Millisecond {
+		t.Error("absolute ttl should decrease", ttl)
+	}
+	if ttl, _ := rc.TTL("sliding"); ttl <= 900*time.Millisecond {
+		t.Error("sliding ttl should be reset by Get", ttl)
+	}
+	if !rc.IsExist("sliding") {
+		t.Error("value with ttl should exist")
+	}
+	rc.Delete("sliding")
+	if rc.IsExist("sliding") {
+		t.Error("Delete should delete value with ttl")
+	}
+}
+
+func TestRedisSlidingMulti(t *testing.T) {
+	conn := newMockConn()
+	rc := NewRedisCache()
+	rc.p = &redis.Pool{Dial: func() (redis.Conn, error) { return conn, nil }}
+
+	rc.Put("put", []byte("1"), 60)
+	cache.SetSliding(rc, "sliding", []byte("2"), time.Second, true)
+	cache.SetSliding(rc, "absolute", []byte("3"), time.Second, false)
+	time.Sleep(100 * time.Millisecond)
+	vs, err := rc.GetMulti([]string{"put", "sliding", "absolute", "missing"})
+	if err != nil || len(vs) != 4 {
+		t.Fatal("GetMulti error", vs, err)
+	}
+	for i, want := range []string{"1", "2", "3"} {
+		if v, _ := vs[i].([]byte); string(v) != want {
+			t.Error("GetMulti should read values of Put and SetSliding", i, vs[i])
+		}
+	}
+	if vs[3] != nil {
+		t.Error("missing key should be nil", vs[3])
+	}
+	if ttl, _ := rc.TTL("sliding"); ttl <= 900*time.Millisecond {
+		t.Error("sliding ttl should be reset by GetMulti", ttl)
+	}
+
+	if n, err := rc.IncrBy("sliding", 5); err != nil || n != 7 {
+		t.Error("IncrBy should increase the counter with ttl", n, err)
+	}
+	if n, err := rc.IncrBy("absolute", 1); err != nil || n != 4 {
+		t.Error("IncrBy should increase the counter with ttl", n, err)
+	}
+	if _, ok := conn.hashes[DefaultKey]["sliding"]; ok {
+		t.Error("IncrBy should not start a counter in the collection for values with ttl")
+	}
+	if ttl, _ := rc.TTL("absolute"); ttl <= 0 || ttl > 900*time.Millisecond {
+		t.Error("IncrBy should keep the ttl", ttl)
+	}
+	if v, _ := rc.Get("sliding").([]byte); string(v) != "7" {
+		t.Error("Get should read increased counter", string(v))
+	}
+	if n, err := rc.IncrBy("put", 2); err != nil || n != 3 {
+		t.Error("IncrBy should increase the counter in the collection", n, err)
+	}
+}
+
+func TestRedisTags(t *testing.T) {
+	conn := newMockConn()
+	rc := NewRedisCache()
+	rc.p = &redis.Pool{Dial: func() (redis.Conn, error) { return conn, nil }}
+
+	cache.PutWithTags(rc, "profile", []byte("v"), 0, []string{"user:1"})
+	cache.PutWithTags(rc, "orders", []byte("v"), 60, []string{"user:1", "shop"})
+	cache.PutWithTags(rc, "friends", []byte("v"), 60, []string{"user:1"})
+	cache.PutWithTags(rc, "stock", []byte("v"), 60, []string{"shop"})
+	rc.Put("other", []byte("v"), 60)
+	if ttl, err := rc.TTL("orders"); err != nil || ttl <= 0 {
+		t.Error("tagged value with timeout should expire", ttl, err)
+	}
+
+	if err := cache.InvalidateTag(rc, "user:1"); err != nil {
+		t.Fatal("InvalidateTag error", err)
+	}
+	for _, key := range []string{"profile", "orders", "friends"} {
+		if rc.IsExist(key) {
+			t.Error("InvalidateTag should delete tagged key", key)
+		}
+	}
+	for _, key := range []string{"stock", "other"} {
+		if !rc.IsExist(key) {
+			t.Error("InvalidateTag should keep key", key)
+		}
+	}
+	if _, ok := conn.zsets[rc.tagKey("user:1")]; ok {
+		t.Error("InvalidateTag should delete the tag set")
+	}
+
+	// expired keys are dropped from the tag set by the next put.
+	conn.zsets[rc.tagKey("shop")]["stock"] = 1
+	cache.PutWithTags(rc, "price", []byte("v"), 60, []string{"shop"})
+	if _, ok := conn.zsets[rc.tagKey("shop")]["stock"]; ok {
+		t.Error("expired key should be dropped from the tag set")
+	}
+
+	// values set again replace their tags
+	cache.PutWithTags(rc, "a", []byte("v"), 0, []string{"t1"})
+	cache.PutWithTags(rc, "b", []byte("v"), 0, []string{"t1"})
+	cache.PutWithTags(rc, "c", []byte("v"), 0, []string{"t1"})
+	cache.PutWithTags(rc, "d", []byte("v"), 0, []string{"t1"})
+	rc.Put("a", []byte("v"), 0)
+	cache.SetSliding(rc, "b", []byte("v"), time.Minute, true)
+	cache.PutWithTags(rc, "c", []byte("v"), 0, []string{"t2"})
+	rc.Delete("d")
+	rc.Put("d", []byte("v"), 0)
+	if err := cache.InvalidateTag(rc, "t1"); err != nil {
+		t.Fatal("InvalidateTag error", err)
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if !rc.IsExist(key) {
+			t.Error("value set again without tag should not be invalidated by it", key)
+		}
+	}
+}
+
+func TestRedisTagScriptArgs(t *testing.T) {
+	conn := newMockConn()
+	rc := NewRedisCache()
+	rc.p = &redis.Pool{Dial: func() (redis.Conn, error) { return conn, nil }}
+
+	cache.PutWithTags(rc, "orders", []byte("v"), 60, []string{"user:1", "shop"})
+	cache.InvalidateTag(rc, "shop")
+	if len(conn.evals) != 2 {
+		t.Fatal("PutWithTags and InvalidateTag should run one script each", conn.evals)
+	}
+	tag := conn.evals[0]
+	want := []interface{}{"beecacheRedis", "beecacheRedis:ttl:orders", "beecacheRedis:sliding:orders",
+		"beecacheRedis:keytags:orders", "beecacheRedis:tag:user:1", "beecacheRedis:tag:shop",
+		"orders", []byte("v"), int64(60000), tag[9], "beecacheRedis:tag:", "user:1", "shop"}
+	if !reflect.DeepEqual(tag, want) {
+		t.Error("tag script args error", tag)
+	}
+	if now, ok := tag[9].(int64); !ok || now <= 0 {
+		t.Error("tag script should get now in unix milliseconds", tag[9])
+	}
+	want = []interface{}{"beecacheRedis", "beecacheRedis:tag:shop",
+		"beecacheRedis:ttl:", "beecacheRedis:sliding:", "beecacheRedis:tag:", "beecacheRedis:keytags:"}
+	if !reflect.DeepEqual(conn.evals[1], want) {
+		t.Error("invalidate script args error", conn.evals[1])
+	}
+	if _, ok := conn.zsets[rc.tagKey("user:1")]["orders"]; ok {
+		t.Error("invalidated key should be dropped from its other tag sets")
+	}
+}
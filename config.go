@@ -40,7 +40,7 @@ var (
 	SessionName            string           // the cookie name when saving session id into cookie.
 	SessionGCMaxLifetime   int64            // session gc time for auto cleaning expired session.
 	SessionSavePath        string           // if use mysql/redis/file provider, define save path to connection info.
-	SessionHashFunc        string           // session hash generation func.
+	SessionHashFunc        string           // session id hash func, "sha1" or "md5". default is empty for random ids.
 	SessionHashKey         string           // session hash salt string.
 	SessionCookieLifeTime  int              // the life time of session id in cookie.
 	SessionAutoSetCookie   bool             // auto setcookie
@@ -55,6 +55,7 @@ var (
 	EnableXSRF             bool   // flag of enable xsrf.
 	XSRFExpire             int    // the expiry of xsrf value.
 	CopyRequestBody        bool   // flag of copy raw request body in context.
+	AutoHeadOptions        bool   // flag of answering HEAD by GET and OPTIONS by Allow of routes. default is true.
 	TemplateLeft           string
 	TemplateRight          string
 	BeegoServerName        string // beego server name exported in response header.
@@ -110,7 +111,7 @@ func init() {
 	SessionName = "beegosessionID"
 	SessionGCMaxLifetime = 3600
 	SessionSavePath = ""
-	SessionHashFunc = "sha1"
+	SessionHashFunc = ""
 	SessionHashKey = "beegoserversessionkey"
 	SessionCookieLifeTime = 0 //set cookie default is the brower life
 	SessionAutoSetCookie = true
@@ -121,6 +122,8 @@ func init() {
 
 	EnableGzip = false
 
+	AutoHeadOptions = true
+
 	HttpServerTimeOut = 0
 
 	ErrorsShow = true
@@ -255,6 +258,10 @@ func ParseConfig() (err error) {
 			CopyRequestBody = copyrequestbody
 		}
 
+		if autoheadoptions, err := AppConfig.Bool("AutoHeadOptions"); err == nil {
+			AutoHeadOptions = autoheadoptions
+		}
+
 		if xsrfkey := AppConfig.String("XSRFKEY"); xsrfkey != "" {
 			XSRFKEY = xsrfkey
 		}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"strings"
 )
 
 // ConfigContainer defines how to get and set value from configuration raw data.
@@ -45,3 +48,70 @@ func NewConfig(adapterName, fileaname string) (ConfigContainer, error) {
 	}
 	return adapter.Parse(fileaname)
 }
+
+// ExpandValue expands environment variables in config value.
+// ${NAME} is replaced with the value of env NAME, ${NAME:-default} with default if NAME is empty or unset.
+// $$ is a literal $, so $${NAME} is not expanded.
+// adapters expand string values when parsing the config file.
+func ExpandValue(value string) string {
+	if strings.IndexByte(value, '$') < 0 {
+		return value
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '$' || i+1 == len(value) {
+			buf.WriteByte(c)
+			continue
+		}
+		switch value[i+1] {
+		case '$':
+			buf.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(value[i+2:], '}')
+			if end < 0 {
+				// not closed, keep it as is
+				buf.WriteString(value[i:])
+				return buf.String()
+			}
+			name := value[i+2 : i+2+end]
+			def := ""
+			if n := strings.Index(name, ":-"); n >= 0 {
+				name, def = name[:n], name[n+2:]
+			}
+			if v := os.Getenv(name); v != "" {
+				buf.WriteString(v)
+			} else {
+				buf.WriteString(def)
+			}
+			i += end + 2
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// ExpandValues expands environment variables in all string values of m,
+// including values in nested maps and slices. m is changed in place and returned.
+func ExpandValues(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		m[k] = expandValue(v)
+	}
+	return m
+}
+
+func expandValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return ExpandValue(value)
+	case map[string]interface{}:
+		return ExpandValues(value)
+	case []interface{}:
+		for i, vv := range value {
+			value[i] = expandValue(vv)
+		}
+	}
+	return v
+}
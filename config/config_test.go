@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExpandValue(t *testing.T) {
+	os.Setenv("BEEGO_CONFIG_USER", "astaxie")
+	os.Unsetenv("BEEGO_CONFIG_MISSING")
+	defer os.Unsetenv("BEEGO_CONFIG_USER")
+
+	tests := []struct {
+		value, want string
+	}{
+		{"astaxie", "astaxie"},
+		{"${BEEGO_CONFIG_USER}", "astaxie"},
+		{"user=${BEEGO_CONFIG_USER};", "user=astaxie;"},
+		{"${BEEGO_CONFIG_USER:-root}", "astaxie"},
+		{"${BEEGO_CONFIG_MISSING}", ""},
+		{"${BEEGO_CONFIG_MISSING:-root}", "root"},
+		{"${BEEGO_CONFIG_MISSING:-}", ""},
+		{"$${BEEGO_CONFIG_USER}", "${BEEGO_CONFIG_USER}"},
+		{"price $$5", "price $5"},
+		{"price $5", "price $5"},
+		{"${BEEGO_CONFIG_USER", "${BEEGO_CONFIG_USER"},
+		{"$", "$"},
+	}
+	for _, test := range tests {
+		if v := ExpandValue(test.value); v != test.want {
+			t.Errorf("ExpandValue(%q) = %q, want %q", test.value, v, test.want)
+		}
+	}
+}
+
+func TestExpandValues(t *testing.T) {
+	os.Setenv("BEEGO_CONFIG_USER", "astaxie")
+	defer os.Unsetenv("BEEGO_CONFIG_USER")
+
+	m := ExpandValues(map[string]interface{}{
+		"user":  "${BEEGO_CONFIG_USER}",
+		"port":  8080.0,
+		"db":    map[string]interface{}{"user": "${BEEGO_CONFIG_USER}"},
+		"peers": []interface{}{"${BEEGO_CONFIG_MISSING:-one}", "two"},
+	})
+	if m["user"] != "astaxie" {
+		t.Fatal("user should be expanded", m["user"])
+	}
+	if m["port"] != 8080.0 {
+		t.Fatal("non string values should be kept", m["port"])
+	}
+	if m["db"].(map[string]interface{})["user"] != "astaxie" {
+		t.Fatal("values in nested map should be expanded")
+	}
+	if m["peers"].([]interface{})[0] != "one" {
+		t.Fatal("values in slice should be expanded")
+	}
+}
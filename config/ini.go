@@ -95,7 +95,7 @@ func (ini *IniConfig) Parse(name string) (ConfigContainer, error) {
 
 			key := string(bytes.TrimSpace(keyval[0])) // key name case insensitive
 			key = strings.ToLower(key)
-			cfg.data[section][key] = string(val)
+			cfg.data[section][key] = ExpandValue(string(val))
 			if comment.Len() > 0 {
 				cfg.keycomment[section+"."+key] = comment.String()
 				comment.Reset()
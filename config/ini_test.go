@@ -20,6 +20,10 @@ key1="asta"
 key2 = "xie"
 CaseInsensitive = true
 peers = one;two;three
+[env]
+user = ${BEEGO_CONFIG_USER}
+port = ${BEEGO_CONFIG_MISSING:-3306}
+password = "$${BEEGO_CONFIG_USER}"
 `
 
 func TestIni(t *testing.T) {
@@ -34,6 +38,8 @@ func TestIni(t *testing.T) {
 	}
 	f.Close()
 	defer os.Remove("testini.conf")
+	os.Setenv("BEEGO_CONFIG_USER", "astaxie")
+	defer os.Unsetenv("BEEGO_CONFIG_USER")
 	iniconf, err := NewConfig("ini", "testini.conf")
 	if err != nil {
 		t.Fatal(err)
@@ -85,5 +91,14 @@ func TestIni(t *testing.T) {
 	} else if data[0] != "one" {
 		t.Fatal("get first params error not equat to one")
 	}
+	if iniconf.String("env::user") != "astaxie" {
+		t.Fatal("env::user should be expanded from env")
+	}
+	if port, err := iniconf.Int("env::port"); err != nil || port != 3306 {
+		t.Fatal("env::port should be the default value", port, err)
+	}
+	if iniconf.String("env::password") != "${BEEGO_CONFIG_USER}" {
+		t.Fatal("$$ should be a literal $")
+	}
 
 }
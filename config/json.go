@@ -31,6 +31,7 @@ func (js *JsonConfig) Parse(filename string) (ConfigContainer, error) {
 	if err != nil {
 		return nil, err
 	}
+	ExpandValues(x.data)
 	return x, nil
 }
 
@@ -18,7 +18,7 @@ var jsoncontext = `{
         "port": "port",                 
         "database": "database",
         "username": "username",
-        "password": "password",
+        "password": "${BEEGO_CONFIG_PASSWORD:-password}",
 		"conns":{
 			"maxconnection":12,
 			"autoconnect":true,
@@ -78,6 +78,9 @@ func TestJson(t *testing.T) {
 	if jsonconf.String("database::host") != "host" {
 		t.Fatal("get database::host error")
 	}
+	if jsonconf.String("database::password") != "password" {
+		t.Fatal("get database::password error")
+	}
 	if jsonconf.String("database::conns::connectioninfo") != "info" {
 		t.Fatal("get database::conns::connectioninfo error")
 	}
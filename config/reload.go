@@ -0,0 +1,136 @@
+package config
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// changes within this duration are reloaded once, editors often write a file twice.
+const defaultDebounce = 100 * time.Millisecond
+
+// ReloadConfigContainer is a ConfigContainer which can reload its file.
+// the file is parsed to a new container and swapped when parsing succeeds,
+// so reads never see a half parsed config. values changed by Set are lost after reload.
+type ReloadConfigContainer struct {
+	adapterName string
+	filename    string
+	debounce    time.Duration
+
+	lock      sync.RWMutex
+	container ConfigContainer
+	listeners []func(old, new ConfigContainer)
+
+	reloadLock sync.Mutex // serializes reloads
+	timerLock  sync.Mutex
+	timer      *time.Timer
+}
+
+// NewReloadConfig parses the file with adapter and returns a container which can reload it.
+// call Changed when the file is changed, e.g. by package config/watch.
+func NewReloadConfig(adapterName, filename string) (*ReloadConfigContainer, error) {
+	c, err := NewConfig(adapterName, filename)
+	if err != nil {
+		return nil, err
+	}
+	return &ReloadConfigContainer{
+		adapterName: adapterName,
+		filename:    filename,
+		debounce:    defaultDebounce,
+		container:   c,
+	}, nil
+}
+
+// Filename returns the config file path.
+func (c *ReloadConfigContainer) Filename() string {
+	return c.filename
+}
+
+// OnChange adds a listener called with the old and new container after every reload.
+func (c *ReloadConfigContainer) OnChange(fn func(old, new ConfigContainer)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// Reload parses the file again and swaps the container.
+// if parsing fails the current container is kept and error is returned.
+func (c *ReloadConfigContainer) Reload() error {
+	c.reloadLock.Lock()
+	defer c.reloadLock.Unlock()
+	nc, err := NewConfig(c.adapterName, c.filename)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	old := c.container
+	c.container = nc
+	listeners := c.listeners
+	c.lock.Unlock()
+	for _, fn := range listeners {
+		fn(old, nc)
+	}
+	return nil
+}
+
+// Changed reloads the file after debounce duration,
+// changes in this duration are reloaded once.
+func (c *ReloadConfigContainer) Changed() {
+	c.timerLock.Lock()
+	defer c.timerLock.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.timer = time.AfterFunc(c.debounce, func() {
+		if err := c.Reload(); err != nil {
+			log.Println("config: reload", c.filename, "error:", err)
+		}
+	})
+}
+
+// current container
+func (c *ReloadConfigContainer) current() ConfigContainer {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.container
+}
+
+// Set value in current container, it's lost after reload.
+func (c *ReloadConfigContainer) Set(key, val string) error {
+	return c.current().Set(key, val)
+}
+
+// String returns the string value for a given key.
+func (c *ReloadConfigContainer) String(key string) string {
+	return c.current().String(key)
+}
+
+// Strings returns the []string value for a given key.
+func (c *ReloadConfigContainer) Strings(key string) []string {
+	return c.current().Strings(key)
+}
+
+// Int returns the integer value for a given key.
+func (c *ReloadConfigContainer) Int(key string) (int, error) {
+	return c.current().Int(key)
+}
+
+// Int64 returns the int64 value for a given key.
+func (c *ReloadConfigContainer) Int64(key string) (int64, error) {
+	return c.current().Int64(key)
+}
+
+// Bool returns the boolean value for a given key.
+func (c *ReloadConfigContainer) Bool(key string) (bool, error) {
+	return c.current().Bool(key)
+}
+
+// Float returns the float value for a given key.
+func (c *ReloadConfigContainer) Float(key string) (float64, error) {
+	return c.current().Float(key)
+}
+
+// DIY returns the raw value by a given key.
+func (c *ReloadConfigContainer) DIY(key string) (interface{}, error) {
+	return c.current().DIY(key)
+}
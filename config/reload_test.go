@@ -0,0 +1,58 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReloadConfig(t *testing.T) {
+	if err := ioutil.WriteFile("testreload.conf", []byte("loglevel = info\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("testreload.conf")
+	c, err := NewReloadConfig("ini", "testreload.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.String("loglevel") != "info" {
+		t.Fatal("loglevel should be info")
+	}
+
+	var lock sync.Mutex
+	var changes []string
+	c.OnChange(func(old, new ConfigContainer) {
+		lock.Lock()
+		defer lock.Unlock()
+		changes = append(changes, old.String("loglevel")+"->"+new.String("loglevel"))
+	})
+
+	// editors may truncate the file then write it, both are reloaded once.
+	ioutil.WriteFile("testreload.conf", []byte(""), 0644)
+	c.Changed()
+	ioutil.WriteFile("testreload.conf", []byte("loglevel = debug\n"), 0644)
+	c.Changed()
+	if c.String("loglevel") != "info" {
+		t.Fatal("config should not be reloaded before debounce duration")
+	}
+	time.Sleep(3 * defaultDebounce)
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(changes) != 1 || changes[0] != "info->debug" {
+		t.Fatal("changes should be reloaded once", changes)
+	}
+	if c.String("loglevel") != "debug" {
+		t.Fatal("loglevel should be debug after reload")
+	}
+
+	os.Remove("testreload.conf")
+	if err = c.Reload(); err == nil {
+		t.Fatal("reload should fail without file")
+	}
+	if c.String("loglevel") != "debug" {
+		t.Fatal("config should be kept if reload fails")
+	}
+}
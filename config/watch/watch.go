@@ -0,0 +1,76 @@
+package config
+
+// watch config file changes with fsnotify, it depends on github.com/fsnotify/fsnotify:
+//
+//	go get github.com/fsnotify/fsnotify
+//
+// usage:
+//
+//	import(
+//		"github.com/astaxie/beego/config"
+//		watch "github.com/astaxie/beego/config/watch"
+//	)
+//
+//	c, err := config.NewReloadConfig("ini", "conf/app.conf")
+//	c.OnChange(func(old, new config.ConfigContainer) {
+//		// adjust log level etc.
+//	})
+//	w, err := watch.Watch(c)
+//	defer w.Close()
+
+import (
+	"path/filepath"
+
+	"github.com/astaxie/beego/config"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher reloads a config container when its file changes.
+type Watcher struct {
+	w    *fsnotify.Watcher
+	done chan struct{}
+}
+
+// Watch starts watching the file of c, c.Changed is called for every change.
+// the directory of the file is watched, so files replaced by rename are also noticed.
+func Watch(c *config.ReloadConfigContainer) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	filename := filepath.Clean(c.Filename())
+	if err = fw.Add(filepath.Dir(filename)); err != nil {
+		fw.Close()
+		return nil, err
+	}
+	w := &Watcher{w: fw, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		for {
+			select {
+			case ev, ok := <-fw.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != filename {
+					continue
+				}
+				if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					c.Changed()
+				}
+			case _, ok := <-fw.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return w, nil
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	err := w.w.Close()
+	<-w.done
+	return err
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/astaxie/beego/config"
+)
+
+func TestWatch(t *testing.T) {
+	if err := ioutil.WriteFile("testwatch.conf", []byte("loglevel = info\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("testwatch.conf")
+	c, err := config.NewReloadConfig("ini", "testwatch.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan string, 10)
+	c.OnChange(func(old, new config.ConfigContainer) {
+		changed <- new.String("loglevel")
+	})
+	w, err := Watch(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// rewrite the file like an editor, by a temp file and rename.
+	ioutil.WriteFile("testwatch.conf.tmp", []byte("loglevel = debug\n"), 0644)
+	if err = os.Rename("testwatch.conf.tmp", "testwatch.conf"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-changed:
+		if v != "debug" {
+			t.Fatal("loglevel should be debug after reload", v)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("config should be reloaded after file is rewritten")
+	}
+	if c.String("loglevel") != "debug" {
+		t.Fatal("loglevel should be debug")
+	}
+}
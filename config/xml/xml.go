@@ -8,6 +8,8 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/astaxie/beego/config"
+
 	"github.com/beego/x2j"
 )
 
@@ -18,7 +20,7 @@ type XMLConfig struct {
 }
 
 // Parse returns a ConfigContainer with parsed xml config map.
-func (xmls *XMLConfig) Parse(filename string) (ConfigContainer, error) {
+func (xmls *XMLConfig) Parse(filename string) (config.ConfigContainer, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
@@ -35,7 +37,7 @@ func (xmls *XMLConfig) Parse(filename string) (ConfigContainer, error) {
 	if err != nil {
 		return nil, err
 	}
-	x.data = d["config"].(map[string]interface{})
+	x.data = config.ExpandValues(d["config"].(map[string]interface{}))
 	return x, nil
 }
 
@@ -95,5 +97,5 @@ func (c *XMLConfigContainer) DIY(key string) (v interface{}, err error) {
 }
 
 func init() {
-	Register("xml", &XMLConfig{})
+	config.Register("xml", &XMLConfig{})
 }
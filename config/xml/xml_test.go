@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"testing"
+
+	"github.com/astaxie/beego/config"
 )
 
 //xml parse should incluce in <config></config> tags
@@ -30,7 +32,7 @@ func TestXML(t *testing.T) {
 	}
 	f.Close()
 	defer os.Remove("testxml.conf")
-	xmlconf, err := NewConfig("xml", "testxml.conf")
+	xmlconf, err := config.NewConfig("xml", "testxml.conf")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -67,3 +69,30 @@ func TestXML(t *testing.T) {
 		t.Fatal("get name error")
 	}
 }
+
+func TestXMLEnv(t *testing.T) {
+	os.Setenv("BEEGO_CONFIG_USER", "astaxie")
+	defer os.Unsetenv("BEEGO_CONFIG_USER")
+	f, err := os.Create("testxmlenv.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<config>
+<user>${BEEGO_CONFIG_USER}</user>
+<dbname>${BEEGO_CONFIG_MISSING:-beego}</dbname>
+</config>
+`)
+	f.Close()
+	defer os.Remove("testxmlenv.conf")
+	xmlconf, err := config.NewConfig("xml", "testxmlenv.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xmlconf.String("user") != "astaxie" {
+		t.Fatal("env value should be expanded, got", xmlconf.String("user"))
+	}
+	if xmlconf.String("dbname") != "beego" {
+		t.Fatal("missing env should use default, got", xmlconf.String("dbname"))
+	}
+}
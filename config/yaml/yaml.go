@@ -10,6 +10,8 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/astaxie/beego/config"
+
 	"github.com/beego/goyaml2"
 )
 
@@ -18,7 +20,7 @@ type YAMLConfig struct {
 }
 
 // Parse returns a ConfigContainer with parsed yaml config map.
-func (yaml *YAMLConfig) Parse(filename string) (ConfigContainer, error) {
+func (yaml *YAMLConfig) Parse(filename string) (config.ConfigContainer, error) {
 	y := &YAMLConfigContainer{
 		data: make(map[string]interface{}),
 	}
@@ -26,7 +28,7 @@ func (yaml *YAMLConfig) Parse(filename string) (ConfigContainer, error) {
 	if err != nil {
 		return nil, err
 	}
-	y.data = cnf
+	y.data = config.ExpandValues(cnf)
 	return y, nil
 }
 
@@ -140,5 +142,5 @@ func (c *YAMLConfigContainer) DIY(key string) (v interface{}, err error) {
 }
 
 func init() {
-	Register("yaml", &YAMLConfig{})
+	config.Register("yaml", &YAMLConfig{})
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"testing"
+
+	"github.com/astaxie/beego/config"
 )
 
 var yamlcontext = `
@@ -27,7 +29,7 @@ func TestYaml(t *testing.T) {
 	}
 	f.Close()
 	defer os.Remove("testyaml.conf")
-	yamlconf, err := NewConfig("yaml", "testyaml.conf")
+	yamlconf, err := config.NewConfig("yaml", "testyaml.conf")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -64,3 +66,28 @@ func TestYaml(t *testing.T) {
 		t.Fatal("get name error")
 	}
 }
+
+func TestYamlEnv(t *testing.T) {
+	os.Setenv("BEEGO_CONFIG_USER", "astaxie")
+	defer os.Unsetenv("BEEGO_CONFIG_USER")
+	f, err := os.Create("testyamlenv.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`
+"user": "${BEEGO_CONFIG_USER}"
+"dbname": "${BEEGO_CONFIG_MISSING:-beego}"
+`)
+	f.Close()
+	defer os.Remove("testyamlenv.conf")
+	yamlconf, err := config.NewConfig("yaml", "testyamlenv.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if yamlconf.String("user") != "astaxie" {
+		t.Fatal("env value should be expanded, got", yamlconf.String("user"))
+	}
+	if yamlconf.String("dbname") != "beego" {
+		t.Fatal("missing env should use default, got", yamlconf.String("dbname"))
+	}
+}
@@ -12,4 +12,8 @@ func TestDefaults(t *testing.T) {
 	if FlashSeperator != "BEEGOFLASH" {
 		t.Errorf("FlashName was not set to default.")
 	}
+
+	if SessionHashFunc != "" {
+		t.Errorf("SessionHashFunc should default to random session ids.")
+	}
 }
@@ -0,0 +1,204 @@
+package context
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/astaxie/beego/validation"
+)
+
+// MaxBindSize is the max length of request body decoded by Bind, default is 10MB.
+var MaxBindSize int64 = 10 << 20
+
+// BindError is returned by Bind and BindValid if request body can't be bound.
+// Status is the http status code the request should be answered with,
+// Errors are set if the bound value fails validation.
+type BindError struct {
+	Status int
+	Err    error
+	Errors []*validation.ValidationError
+}
+
+func (e *BindError) Error() string {
+	return "context: bind body: " + e.Err.Error()
+}
+
+// Bind decodes request body into ptr by Content-Type of request.
+// json, xml (and types with +json, +xml suffix) and form-urlencoded bodies are supported,
+// form values are set to fields by `form:"name"` tag or field name.
+// the body is kept in RequestBody, so it can be read again after Bind.
+// a *BindError is returned if content type is unsupported, body is larger than MaxBindSize or malformed.
+func (input *BeegoInput) Bind(ptr interface{}) error {
+	ct := input.Header("Content-Type")
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil && ct != "" {
+		return &BindError{Status: http.StatusUnsupportedMediaType, Err: fmt.Errorf("invalid content type %q", ct)}
+	}
+	switch {
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		body, err := input.bindBody()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(body, ptr); err != nil {
+			return &BindError{Status: http.StatusBadRequest, Err: fmt.Errorf("malformed json: %v", err)}
+		}
+	case mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml"):
+		body, err := input.bindBody()
+		if err != nil {
+			return err
+		}
+		if err := xml.Unmarshal(body, ptr); err != nil {
+			return &BindError{Status: http.StatusBadRequest, Err: fmt.Errorf("malformed xml: %v", err)}
+		}
+	case mt == "application/x-www-form-urlencoded":
+		form, err := input.bindForm()
+		if err != nil {
+			return err
+		}
+		if err := bindForm(form, ptr); err != nil {
+			return &BindError{Status: http.StatusBadRequest, Err: err}
+		}
+	default:
+		return &BindError{Status: http.StatusUnsupportedMediaType, Err: fmt.Errorf("unsupported content type %q", ct)}
+	}
+	return nil
+}
+
+// BindValid binds request body into ptr like Bind, then validates it by the validation package.
+// a *BindError with status 400 and the validation errors is returned if ptr isn't valid.
+func (input *BeegoInput) BindValid(ptr interface{}) error {
+	if err := input.Bind(ptr); err != nil {
+		return err
+	}
+	valid := validation.Validation{}
+	ok, err := valid.Valid(ptr)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		msgs := make([]string, 0, len(valid.Errors))
+		for _, e := range valid.Errors {
+			msgs = append(msgs, e.Key+" "+e.Message)
+		}
+		return &BindError{Status: http.StatusBadRequest,
+			Err:    errors.New("invalid value: " + strings.Join(msgs, ", ")),
+			Errors: valid.Errors}
+	}
+	return nil
+}
+
+// read request body once with MaxBindSize limit, copied body in RequestBody is reused.
+func (input *BeegoInput) bindBody() ([]byte, error) {
+	if input.RequestBody == nil && input.Request.Body != nil {
+		body, err := ioutil.ReadAll(io.LimitReader(input.Request.Body, MaxBindSize+1))
+		input.Request.Body.Close()
+		if err != nil {
+			return nil, &BindError{Status: http.StatusBadRequest, Err: err}
+		}
+		input.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		input.RequestBody = body
+	}
+	if int64(len(input.RequestBody)) > MaxBindSize {
+		return nil, &BindError{Status: http.StatusRequestEntityTooLarge,
+			Err: fmt.Errorf("body exceeds max size %d", MaxBindSize)}
+	}
+	return input.RequestBody, nil
+}
+
+// get form values of body, the form parsed by router is reused.
+func (input *BeegoInput) bindForm() (url.Values, error) {
+	if input.Request.PostForm != nil && input.RequestBody == nil {
+		return input.Request.PostForm, nil
+	}
+	body, err := input.bindBody()
+	if err != nil {
+		return nil, err
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, &BindError{Status: http.StatusBadRequest, Err: fmt.Errorf("malformed form: %v", err)}
+	}
+	return form, nil
+}
+
+// set fields of struct pointer ptr from form values.
+func bindForm(form url.Values, ptr interface{}) error {
+	objV := reflect.ValueOf(ptr)
+	if objV.Kind() != reflect.Ptr || objV.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form needs a struct pointer, got %T", ptr)
+	}
+	objV = objV.Elem()
+	objT := objV.Type()
+
+	for i := 0; i < objT.NumField(); i++ {
+		fieldV := objV.Field(i)
+		if !fieldV.CanSet() {
+			continue
+		}
+		fieldT := objT.Field(i)
+		name := strings.Split(fieldT.Tag.Get("form"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = fieldT.Name
+		}
+		values, ok := form[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if fieldV.Kind() == reflect.Slice && fieldV.Type().Elem().Kind() == reflect.String {
+			fieldV.Set(reflect.ValueOf(append([]string(nil), values...)))
+			continue
+		}
+		if err := setFormField(fieldV, values[0]); err != nil {
+			return fmt.Errorf("form value %s %q is not a valid %s", name, values[0], fieldV.Type())
+		}
+	}
+	return nil
+}
+
+// convert form value to the kind of field, fields of other kinds are skipped.
+func setFormField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(x)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(x)
+	}
+	return nil
+}
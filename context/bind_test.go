@@ -0,0 +1,119 @@
+package context
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type bindUser struct {
+	Name  string   `json:"name" xml:"name" form:"name" valid:"Required"`
+	Age   int      `json:"age" xml:"age" form:"age" valid:"Range(1, 140)"`
+	Admin bool     `json:"admin" xml:"admin" form:"admin"`
+	Tags  []string `json:"tags" xml:"tag" form:"tag"`
+}
+
+func newBindInput(contentType, body string) *BeegoInput {
+	r, _ := http.NewRequest("POST", "/user", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	return NewInput(r)
+}
+
+func checkBindUser(t *testing.T, typ string, u bindUser) {
+	if u.Name != "astaxie" || u.Age != 30 || !u.Admin || len(u.Tags) != 2 || u.Tags[1] != "b" {
+		t.Fatalf("%s: wrong bound value %+v", typ, u)
+	}
+}
+
+func TestBind(t *testing.T) {
+	body := `{"name":"astaxie","age":30,"admin":true,"tags":["a","b"]}`
+	input := newBindInput("application/json; charset=utf-8", body)
+	var u bindUser
+	if err := input.Bind(&u); err != nil {
+		t.Fatal(err)
+	}
+	checkBindUser(t, "json", u)
+	if string(input.RequestBody) != body {
+		t.Fatal("body should be kept in RequestBody")
+	}
+	if b, _ := ioutil.ReadAll(input.Request.Body); string(b) != body {
+		t.Fatal("body should be readable again")
+	}
+
+	u = bindUser{}
+	input = newBindInput("application/xml", `<user><name>astaxie</name><age>30</age><admin>true</admin><tag>a</tag><tag>b</tag></user>`)
+	if err := input.Bind(&u); err != nil {
+		t.Fatal(err)
+	}
+	checkBindUser(t, "xml", u)
+
+	u = bindUser{}
+	input = newBindInput("application/x-www-form-urlencoded", "name=astaxie&age=30&admin=true&tag=a&tag=b")
+	if err := input.Bind(&u); err != nil {
+		t.Fatal(err)
+	}
+	checkBindUser(t, "form", u)
+
+	// form parsed by router
+	u = bindUser{}
+	input = newBindInput("application/x-www-form-urlencoded", "name=astaxie&age=30&admin=true&tag=a&tag=b")
+	input.ParseFormOrMulitForm(1 << 20)
+	if err := input.Bind(&u); err != nil {
+		t.Fatal(err)
+	}
+	checkBindUser(t, "parsed form", u)
+}
+
+func TestBindError(t *testing.T) {
+	cases := []struct {
+		contentType, body string
+		status            int
+	}{
+		{"application/json", `{"name":"astaxie",`, http.StatusBadRequest},
+		{"application/json", `{"age":"thirty"}`, http.StatusBadRequest},
+		{"application/xml", `<user><name>`, http.StatusBadRequest},
+		{"application/x-www-form-urlencoded", "age=thirty", http.StatusBadRequest},
+		{"text/plain", "astaxie", http.StatusUnsupportedMediaType},
+		{"", "astaxie", http.StatusUnsupportedMediaType},
+		{"application/json", `{"name":"` + strings.Repeat("a", 100) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	defer func(size int64) { MaxBindSize = size }(MaxBindSize)
+	MaxBindSize = 64
+	for _, c := range cases {
+		var u bindUser
+		err := newBindInput(c.contentType, c.body).Bind(&u)
+		e, ok := err.(*BindError)
+		if !ok {
+			t.Fatalf("%q %q: should be BindError, got %v", c.contentType, c.body, err)
+		}
+		if e.Status != c.status {
+			t.Fatalf("%q %q: status should be %d, got %d: %v", c.contentType, c.body, c.status, e.Status, e)
+		}
+	}
+
+	var u bindUser
+	err := newBindInput("application/json", `{"name":"astaxie",`).Bind(&u)
+	if !strings.Contains(err.Error(), "malformed json") {
+		t.Fatal("error should describe malformed json", err)
+	}
+}
+
+func TestBindValid(t *testing.T) {
+	var u bindUser
+	if err := newBindInput("application/json", `{"name":"astaxie","age":30}`).BindValid(&u); err != nil {
+		t.Fatal(err)
+	}
+
+	u = bindUser{}
+	err := newBindInput("application/json", `{"age":200}`).BindValid(&u)
+	e, ok := err.(*BindError)
+	if !ok {
+		t.Fatal("should be BindError", err)
+	}
+	if e.Status != http.StatusBadRequest || len(e.Errors) != 2 {
+		t.Fatal("should have 2 validation errors", e, e.Errors)
+	}
+}
@@ -55,12 +55,36 @@ func (ctx *Context) WriteString(content string) {
 	ctx.Output.Body([]byte(content))
 }
 
+// Send file with range and caching support.
+// It's alias of BeegoOutput.ServeFile.
+func (ctx *Context) ServeFile(file string) {
+	ctx.Output.ServeFile(file)
+}
+
+// Start a Server-Sent Events stream.
+// It's alias of BeegoOutput.SSE.
+func (ctx *Context) SSE() (*SSEWriter, error) {
+	return ctx.Output.SSE()
+}
+
 // Get cookie from request by a given key.
 // It's alias of BeegoInput.Cookie.
 func (ctx *Context) GetCookie(key string) string {
 	return ctx.Input.Cookie(key)
 }
 
+// Bind router params to fields of struct pointer.
+// It's alias of BeegoInput.BindParams.
+func (ctx *Context) BindParams(ptr interface{}) error {
+	return ctx.Input.BindParams(ptr)
+}
+
+// Bind request body to ptr by content type.
+// It's alias of BeegoInput.Bind.
+func (ctx *Context) Bind(ptr interface{}) error {
+	return ctx.Input.Bind(ptr)
+}
+
 // Set cookie for response.
 // It's alias of BeegoOutput.Cookie.
 func (ctx *Context) SetCookie(name string, value string, others ...interface{}) {
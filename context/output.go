@@ -12,6 +12,7 @@ import (
 	"io"
 	"mime"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -242,6 +243,36 @@ func (output *BeegoOutput) Download(file string) {
 	http.ServeFile(output.Context.ResponseWriter, output.Context.Request, file)
 }
 
+// ServeFile sends the file by http.ServeContent, so Range, If-Modified-Since and If-None-Match requests
+// are answered with 206 or 304, and Content-Type is detected from extension or content.
+// a weak ETag of size and modification time is set unless the ETag header is set.
+// it responds 404 for missing files and 403 for directories or files which can't be read.
+func (output *BeegoOutput) ServeFile(file string) {
+	rw, r := output.Context.ResponseWriter, output.Context.Request
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			output.Status = http.StatusNotFound
+			http.Error(rw, "404 page not found", http.StatusNotFound)
+		} else {
+			output.Status = http.StatusForbidden
+			http.Error(rw, "403 Forbidden", http.StatusForbidden)
+		}
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		output.Status = http.StatusForbidden
+		http.Error(rw, "403 Forbidden", http.StatusForbidden)
+		return
+	}
+	if rw.Header().Get("ETag") == "" {
+		output.Header("ETag", fmt.Sprintf(`W/"%x-%x"`, fi.Size(), fi.ModTime().UnixNano()))
+	}
+	http.ServeContent(rw, r, fi.Name(), fi.ModTime(), f)
+}
+
 // ContentType sets the content type from ext string.
 // MIME type is given in mime package.
 func (output *BeegoOutput) ContentType(ext string) {
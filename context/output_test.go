@@ -0,0 +1,63 @@
+package context
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func serveFile(file string, header http.Header) *httptest.ResponseRecorder {
+	r, _ := http.NewRequest("GET", "/download", nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	ctx := &Context{Request: r, ResponseWriter: w, Input: NewInput(r), Output: NewOutput()}
+	ctx.Output.Context = ctx
+	ctx.ServeFile(file)
+	return w
+}
+
+func TestServeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "servefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "data.txt")
+	if err := ioutil.WriteFile(file, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serveFile(file, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Fatal("file should be served", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/plain; charset=utf-8" || w.Header().Get("Content-Length") != "10" {
+		t.Fatal("content type and length should be set", w.Header())
+	}
+	etag := w.Header().Get("ETag")
+
+	w = serveFile(file, http.Header{"Range": {"bytes=2-5"}})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "2345" {
+		t.Fatal("range should be served with 206", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Range") != "bytes 2-5/10" {
+		t.Fatal("Content-Range should be set", w.Header().Get("Content-Range"))
+	}
+
+	w = serveFile(file, http.Header{"If-None-Match": {etag}})
+	if etag == "" || w.Code != http.StatusNotModified {
+		t.Fatal("matching etag should be 304", etag, w.Code)
+	}
+
+	if w = serveFile(filepath.Join(dir, "missing.txt"), nil); w.Code != http.StatusNotFound {
+		t.Fatal("missing file should be 404", w.Code)
+	}
+	if w = serveFile(dir, nil); w.Code != http.StatusForbidden {
+		t.Fatal("directory should be 403", w.Code)
+	}
+}
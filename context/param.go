@@ -0,0 +1,119 @@
+package context
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ParamError is returned by BindParams if a router param can't be converted to its field,
+// and by typed getters like QueryIntE if a query or form value can't be converted.
+// it's caused by the request, so the request should be answered with 400 Bad Request.
+type ParamError struct {
+	Param string // router param name, e.g. ":id"
+	Value string
+	Type  string // expected type, e.g. "int" or "uuid"
+	Err   error
+}
+
+func (e *ParamError) Error() string {
+	msg := fmt.Sprintf("context: param %s value %q is not a valid %s", e.Param, e.Value, e.Type)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// BindParams sets fields of struct pointer ptr from router params.
+// field ID is set from :id by default, tag `param:"name"` sets the param name and `param:"-"` skips the field.
+// int, int64, bool and string fields are supported, `param:"id,uuid"` checks the string is an uuid.
+// fields of missing params are not changed. a *ParamError is returned if a param can't be converted.
+//
+//	var p struct {
+//		ID   int
+//		Slug string
+//	}
+//	if err := ctx.Input.BindParams(&p); err != nil {
+//		ctx.Abort(400, err.Error())
+//	}
+func (input *BeegoInput) BindParams(ptr interface{}) error {
+	objV := reflect.ValueOf(ptr)
+	if objV.Kind() != reflect.Ptr || objV.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("context: BindParams needs a struct pointer, got %T", ptr)
+	}
+	objV = objV.Elem()
+	objT := objV.Type()
+
+	for i := 0; i < objT.NumField(); i++ {
+		fieldV := objV.Field(i)
+		if !fieldV.CanSet() {
+			continue
+		}
+		fieldT := objT.Field(i)
+		tags := strings.Split(fieldT.Tag.Get("param"), ",")
+		name := tags[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(fieldT.Name)
+		}
+		if !strings.HasPrefix(name, ":") {
+			name = ":" + name
+		}
+		value, ok := input.Params[name]
+		if !ok {
+			continue
+		}
+		isUUID := len(tags) > 1 && tags[1] == "uuid"
+		if err := setParamField(fieldV, name, value, isUUID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convert router param value to the kind of field.
+func setParamField(field reflect.Value, name, value string, isUUID bool) error {
+	if isUUID {
+		if field.Kind() != reflect.String {
+			return fmt.Errorf("context: uuid param %s needs a string field, got %s", name, field.Type())
+		}
+		if !uuidPattern.MatchString(value) {
+			return &ParamError{Param: name, Value: value, Type: "uuid"}
+		}
+		field.SetString(value)
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		x, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return &ParamError{Param: name, Value: value, Type: field.Kind().String(), Err: numError(err)}
+		}
+		field.SetInt(x)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return &ParamError{Param: name, Value: value, Type: "bool"}
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("context: unsupported type %s of param %s", field.Type(), name)
+	}
+	return nil
+}
+
+// unwrap strconv.NumError, its message repeats the value.
+func numError(err error) error {
+	if e, ok := err.(*strconv.NumError); ok {
+		return e.Err
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package context
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBindParams(t *testing.T) {
+	input := &BeegoInput{Params: map[string]string{
+		":id":     "12",
+		":slug":   "hello-world",
+		":total":  "9000000000",
+		":draft":  "true",
+		":ref":    "0f8fad5b-d9cb-469f-a165-70867728950e",
+		":ignore": "x",
+	}}
+	var p struct {
+		ID     int
+		Slug   string
+		Total  int64
+		Draft  bool
+		Ref    string `param:"ref,uuid"`
+		Ignore string `param:"-"`
+		Page   int
+	}
+	p.Page = 1
+	if err := input.BindParams(&p); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != 12 || p.Slug != "hello-world" || p.Total != 9000000000 || !p.Draft {
+		t.Fatal("params not bound", p)
+	}
+	if p.Ref != "0f8fad5b-d9cb-469f-a165-70867728950e" || p.Ignore != "" {
+		t.Fatal("tagged params not bound", p)
+	}
+	if p.Page != 1 {
+		t.Fatal("missing param should keep field value", p.Page)
+	}
+}
+
+func TestBindParamsError(t *testing.T) {
+	cases := []struct {
+		value, typ, msg string
+		ptr             interface{}
+	}{
+		{"abc", "int", `param :id value "abc" is not a valid int: invalid syntax`, &struct{ ID int }{}},
+		{"99999999999999999999", "int64", "value out of range", &struct{ ID int64 }{}},
+		{"yes", "bool", `param :id value "yes" is not a valid bool`, &struct{ ID bool }{}},
+		{"12", "uuid", `param :id value "12" is not a valid uuid`, &struct {
+			ID string `param:"id,uuid"`
+		}{}},
+	}
+	for _, c := range cases {
+		input := &BeegoInput{Params: map[string]string{":id": c.value}}
+		err := input.BindParams(c.ptr)
+		e, ok := err.(*ParamError)
+		if !ok {
+			t.Fatalf("%s: should be ParamError, got %v", c.typ, err)
+		}
+		if e.Param != ":id" || e.Value != c.value || e.Type != c.typ {
+			t.Fatalf("%s: wrong error %#v", c.typ, e)
+		}
+		if !strings.Contains(e.Error(), c.msg) {
+			t.Fatalf("%s: error %q should contain %q", c.typ, e.Error(), c.msg)
+		}
+	}
+
+	input := &BeegoInput{Params: map[string]string{":id": "1"}}
+	var id int
+	if err := input.BindParams(&id); err == nil {
+		t.Fatal("non struct pointer should fail")
+	}
+	if err := input.BindParams(&struct{ ID float64 }{}); err == nil {
+		t.Fatal("unsupported type should fail")
+	}
+}
@@ -0,0 +1,73 @@
+package context
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// SSEWriter writes Server-Sent Events to the response, every event is flushed to the client.
+type SSEWriter struct {
+	rw      http.ResponseWriter
+	flusher http.Flusher
+	done    <-chan struct{}
+	err     func() error
+}
+
+// SSE starts an event stream response, it sets the text/event-stream headers and sends them.
+// it returns error if the response writer can't flush.
+//
+//	sse, err := ctx.SSE()
+//	for msg := range messages {
+//		if err := sse.Send("message", msg); err != nil {
+//			return // client is gone
+//		}
+//	}
+func (output *BeegoOutput) SSE() (*SSEWriter, error) {
+	rw := output.Context.ResponseWriter
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		return nil, errors.New("context: response writer doesn't support flushing")
+	}
+	output.Header("Content-Type", "text/event-stream")
+	output.Header("Cache-Control", "no-cache")
+	// proxies like nginx buffer responses unless told not to.
+	output.Header("X-Accel-Buffering", "no")
+	output.SetStatus(http.StatusOK)
+	flusher.Flush()
+
+	reqCtx := output.Context.Request.Context()
+	return &SSEWriter{rw: rw, flusher: flusher, done: reqCtx.Done(), err: reqCtx.Err}, nil
+}
+
+// Send writes one event and flushes it. event is the event type, empty for the default message type.
+// every line of data is sent in its own data field, so data may contain newlines.
+// it returns error once the client disconnects, so the handler can stop sending.
+func (sse *SSEWriter) Send(event, data string) error {
+	select {
+	case <-sse.done:
+		return sse.err()
+	default:
+	}
+	if strings.ContainsAny(event, "\r\n") {
+		return errors.New("context: sse event type can't contain newlines")
+	}
+
+	var b bytes.Buffer
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+	data = strings.Replace(data, "\r\n", "\n", -1)
+	data = strings.Replace(data, "\r", "\n", -1)
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	if _, err := sse.rw.Write(b.Bytes()); err != nil {
+		return err
+	}
+	sse.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,64 @@
+package context
+
+import (
+	gocontext "context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSE(t *testing.T) {
+	reqCtx, cancel := gocontext.WithCancel(gocontext.Background())
+	r, _ := http.NewRequest("GET", "/events", nil)
+	r = r.WithContext(reqCtx)
+	w := httptest.NewRecorder()
+	ctx := &Context{Request: r, ResponseWriter: w, Input: NewInput(r), Output: NewOutput()}
+	ctx.Output.Context = ctx
+
+	sse, err := ctx.SSE()
+	if err != nil {
+		t.Fatal("SSE error", err)
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream" || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatal("event stream headers should be set", w.Header())
+	}
+	if !w.Flushed || w.Code != http.StatusOK {
+		t.Fatal("headers should be flushed", w.Code)
+	}
+
+	if err = sse.Send("update", `{"id":1}`); err != nil {
+		t.Fatal("Send error", err)
+	}
+	if w.Body.String() != "event: update\ndata: {\"id\":1}\n\n" {
+		t.Fatalf("wrong event framing %q", w.Body.String())
+	}
+	w.Body.Reset()
+	if err = sse.Send("", "line1\nline2\r\nline3"); err != nil {
+		t.Fatal("Send error", err)
+	}
+	if w.Body.String() != "data: line1\ndata: line2\ndata: line3\n\n" {
+		t.Fatalf("multi-line data should be split into data fields %q", w.Body.String())
+	}
+	if err = sse.Send("bad\nevent", "x"); err == nil {
+		t.Fatal("event with newline should return error")
+	}
+
+	cancel()
+	if err = sse.Send("update", "gone"); err == nil {
+		t.Fatal("Send should return error after client disconnects")
+	}
+}
+
+// response writer without http.Flusher
+type noFlushWriter struct {
+	http.ResponseWriter
+}
+
+func TestSSENoFlusher(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/events", nil)
+	ctx := &Context{Request: r, ResponseWriter: noFlushWriter{httptest.NewRecorder()}, Input: NewInput(r), Output: NewOutput()}
+	ctx.Output.Context = ctx
+	if _, err := ctx.SSE(); err == nil {
+		t.Fatal("SSE should return error without flusher")
+	}
+}
@@ -0,0 +1,141 @@
+package context
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// typed getters of url query and form values.
+// Query* read the url query only, Form* read the request body of POST, PUT and PATCH.
+// they return def if the value is missing or empty, the E variants also return a *ParamError
+// with def if the value can't be converted.
+//
+//	page, err := ctx.Input.QueryIntE("page", 1)
+//	if err != nil {
+//		ctx.Abort(400, err.Error())
+//	}
+
+// get url query values.
+func (input *BeegoInput) queryValues() url.Values {
+	return input.Request.URL.Query()
+}
+
+// get body form values, the form is parsed if it's not yet.
+func (input *BeegoInput) formValues() url.Values {
+	if input.Request.PostForm == nil {
+		input.Request.ParseForm()
+	}
+	return input.Request.PostForm
+}
+
+// QueryStrings returns all values of key in url query, e.g. ?tag=a&tag=b.
+func (input *BeegoInput) QueryStrings(key string) []string {
+	return input.queryValues()[key]
+}
+
+// QueryIntE returns url query value of key as int.
+func (input *BeegoInput) QueryIntE(key string, def int) (int, error) {
+	return intValue(input.queryValues(), key, def)
+}
+
+// QueryInt returns url query value of key as int, def if it's missing or invalid.
+func (input *BeegoInput) QueryInt(key string, def int) int {
+	v, _ := input.QueryIntE(key, def)
+	return v
+}
+
+// QueryBoolE returns url query value of key as bool, it accepts values of strconv.ParseBool.
+func (input *BeegoInput) QueryBoolE(key string, def bool) (bool, error) {
+	return boolValue(input.queryValues(), key, def)
+}
+
+// QueryBool returns url query value of key as bool, def if it's missing or invalid.
+func (input *BeegoInput) QueryBool(key string, def bool) bool {
+	v, _ := input.QueryBoolE(key, def)
+	return v
+}
+
+// QueryFloat64E returns url query value of key as float64.
+func (input *BeegoInput) QueryFloat64E(key string, def float64) (float64, error) {
+	return float64Value(input.queryValues(), key, def)
+}
+
+// QueryFloat64 returns url query value of key as float64, def if it's missing or invalid.
+func (input *BeegoInput) QueryFloat64(key string, def float64) float64 {
+	v, _ := input.QueryFloat64E(key, def)
+	return v
+}
+
+// FormStrings returns all values of key in body form.
+func (input *BeegoInput) FormStrings(key string) []string {
+	return input.formValues()[key]
+}
+
+// FormIntE returns body form value of key as int.
+func (input *BeegoInput) FormIntE(key string, def int) (int, error) {
+	return intValue(input.formValues(), key, def)
+}
+
+// FormInt returns body form value of key as int, def if it's missing or invalid.
+func (input *BeegoInput) FormInt(key string, def int) int {
+	v, _ := input.FormIntE(key, def)
+	return v
+}
+
+// FormBoolE returns body form value of key as bool, it accepts values of strconv.ParseBool.
+func (input *BeegoInput) FormBoolE(key string, def bool) (bool, error) {
+	return boolValue(input.formValues(), key, def)
+}
+
+// FormBool returns body form value of key as bool, def if it's missing or invalid.
+func (input *BeegoInput) FormBool(key string, def bool) bool {
+	v, _ := input.FormBoolE(key, def)
+	return v
+}
+
+// FormFloat64E returns body form value of key as float64.
+func (input *BeegoInput) FormFloat64E(key string, def float64) (float64, error) {
+	return float64Value(input.formValues(), key, def)
+}
+
+// FormFloat64 returns body form value of key as float64, def if it's missing or invalid.
+func (input *BeegoInput) FormFloat64(key string, def float64) float64 {
+	v, _ := input.FormFloat64E(key, def)
+	return v
+}
+
+func intValue(values url.Values, key string, def int) (int, error) {
+	value := values.Get(key)
+	if value == "" {
+		return def, nil
+	}
+	x, err := strconv.ParseInt(value, 10, strconv.IntSize)
+	if err != nil {
+		return def, &ParamError{Param: key, Value: value, Type: "int", Err: numError(err)}
+	}
+	return int(x), nil
+}
+
+func boolValue(values url.Values, key string, def bool) (bool, error) {
+	value := values.Get(key)
+	if value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return def, &ParamError{Param: key, Value: value, Type: "bool"}
+	}
+	return b, nil
+}
+
+func float64Value(values url.Values, key string, def float64) (float64, error) {
+	value := values.Get(key)
+	if value == "" {
+		return def, nil
+	}
+	x, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return def, &ParamError{Param: key, Value: value, Type: "float64", Err: numError(err)}
+	}
+	return x, nil
+}
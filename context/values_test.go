@@ -0,0 +1,118 @@
+package context
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newValuesInput(query, form string) *BeegoInput {
+	r, _ := http.NewRequest("POST", "/?"+query, strings.NewReader(form))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return &BeegoInput{Request: r}
+}
+
+func TestQueryValues(t *testing.T) {
+	input := newValuesInput("page=3&bad=x&on=true&off=0&price=9.5&tag=a&tag=b&empty=", "page=7")
+
+	if v, err := input.QueryIntE("page", 1); v != 3 || err != nil {
+		t.Fatal("valid int should be parsed", v, err)
+	}
+	if v, err := input.QueryIntE("missing", 1); v != 1 || err != nil {
+		t.Fatal("missing int should be default", v, err)
+	}
+	if v, err := input.QueryIntE("empty", 1); v != 1 || err != nil {
+		t.Fatal("empty int should be default", v, err)
+	}
+	if v, err := input.QueryIntE("bad", 1); v != 1 || err == nil {
+		t.Fatal("malformed int should return default and error", v, err)
+	} else if e, ok := err.(*ParamError); !ok || e.Param != "bad" || e.Type != "int" {
+		t.Fatal("malformed int should return ParamError", err)
+	}
+	if input.QueryInt("page", 1) != 3 || input.QueryInt("bad", 1) != 1 {
+		t.Fatal("QueryInt should return value or default")
+	}
+
+	if v, err := input.QueryBoolE("on", false); !v || err != nil {
+		t.Fatal("valid bool should be parsed", v, err)
+	}
+	if v, err := input.QueryBoolE("off", true); v || err != nil {
+		t.Fatal("0 should be false", v, err)
+	}
+	if v, err := input.QueryBoolE("missing", true); !v || err != nil {
+		t.Fatal("missing bool should be default", v, err)
+	}
+	if v, err := input.QueryBoolE("bad", true); !v || err == nil {
+		t.Fatal("malformed bool should return default and error", v, err)
+	}
+	if !input.QueryBool("on", false) || !input.QueryBool("bad", true) {
+		t.Fatal("QueryBool should return value or default")
+	}
+
+	if v, err := input.QueryFloat64E("price", 1); v != 9.5 || err != nil {
+		t.Fatal("valid float should be parsed", v, err)
+	}
+	if v, err := input.QueryFloat64E("missing", 1.5); v != 1.5 || err != nil {
+		t.Fatal("missing float should be default", v, err)
+	}
+	if v, err := input.QueryFloat64E("bad", 1.5); v != 1.5 || err == nil {
+		t.Fatal("malformed float should return default and error", v, err)
+	}
+	if input.QueryFloat64("price", 1) != 9.5 || input.QueryFloat64("bad", 1.5) != 1.5 {
+		t.Fatal("QueryFloat64 should return value or default")
+	}
+
+	if tags := input.QueryStrings("tag"); len(tags) != 2 || tags[0] != "a" || tags[1] != "b" {
+		t.Fatal("repeated values should be returned", tags)
+	}
+	if tags := input.QueryStrings("missing"); len(tags) != 0 {
+		t.Fatal("missing values should be empty", tags)
+	}
+}
+
+func TestFormValues(t *testing.T) {
+	input := newValuesInput("page=3&only=1", "page=7&bad=1e&on=t&price=-0.25&id=1&id=2")
+
+	if v, err := input.FormIntE("page", 1); v != 7 || err != nil {
+		t.Fatal("form int should be read from body", v, err)
+	}
+	if v, err := input.FormIntE("only", 1); v != 1 || err != nil {
+		t.Fatal("query value should be missing in form", v, err)
+	}
+	if v, err := input.FormIntE("bad", 1); v != 1 || err == nil {
+		t.Fatal("malformed int should return default and error", v, err)
+	}
+	if input.FormInt("page", 1) != 7 || input.FormInt("bad", 1) != 1 {
+		t.Fatal("FormInt should return value or default")
+	}
+
+	if v, err := input.FormBoolE("on", false); !v || err != nil {
+		t.Fatal("valid bool should be parsed", v, err)
+	}
+	if v, err := input.FormBoolE("missing", true); !v || err != nil {
+		t.Fatal("missing bool should be default", v, err)
+	}
+	if v, err := input.FormBoolE("bad", true); !v || err == nil {
+		t.Fatal("malformed bool should return default and error", v, err)
+	}
+	if !input.FormBool("on", false) || input.FormBool("bad", false) {
+		t.Fatal("FormBool should return value or default")
+	}
+
+	if v, err := input.FormFloat64E("price", 1); v != -0.25 || err != nil {
+		t.Fatal("valid float should be parsed", v, err)
+	}
+	if v, err := input.FormFloat64E("missing", 2); v != 2 || err != nil {
+		t.Fatal("missing float should be default", v, err)
+	}
+	if v, err := input.FormFloat64E("bad", 2); v != 2 || err == nil {
+		t.Fatal("malformed float should return default and error", v, err)
+	}
+	if input.FormFloat64("price", 1) != -0.25 || input.FormFloat64("bad", 2) != 2 {
+		t.Fatal("FormFloat64 should return value or default")
+	}
+
+	if ids := input.FormStrings("id"); len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Fatal("repeated values should be returned", ids)
+	}
+}
@@ -401,6 +401,11 @@ func (c *Controller) SessionRegenerateID() {
 // DestroySession cleans session data and session cookie.
 func (c *Controller) DestroySession() {
 	c.Ctx.Input.CruSession.Flush()
+	// cookie session must be expired on the store which is released after this request.
+	if cs, ok := c.Ctx.Input.CruSession.(*session.CookieSessionStore); ok {
+		cs.DestroyCookie(c.Ctx.ResponseWriter)
+		return
+	}
 	GlobalSessions.SessionDestroy(c.Ctx.ResponseWriter, c.Ctx.Request)
 }
 
@@ -0,0 +1,64 @@
+package beego
+
+import (
+	"strings"
+)
+
+// RouterGroup adds routers with a shared url prefix and filters.
+// filters of group run after BeforeExec filters and before controller, in the order they are added.
+// if a filter writes response, the rest filters and controller are skipped.
+type RouterGroup struct {
+	handlers *ControllerRegistor
+	prefix   string
+	filters  []FilterFunc
+}
+
+// Group returns a router group with url prefix and filters.
+// usage:
+//
+//	api := handler.Group("/api/v1", auth)
+//	api.Router("/user", &UserController{})       // /api/v1/user, runs auth
+//	admin := api.Group("/admin", checkAdmin)
+//	admin.Router("/user", &AdminUserController{}) // /api/v1/admin/user, runs auth and checkAdmin
+func (p *ControllerRegistor) Group(prefix string, filters ...FilterFunc) *RouterGroup {
+	return &RouterGroup{
+		handlers: p,
+		prefix:   joinRouterPath("", prefix),
+		filters:  filters,
+	}
+}
+
+// Group returns a nested router group,
+// its prefix is appended to prefix of g and its filters run after filters of g.
+func (g *RouterGroup) Group(prefix string, filters ...FilterFunc) *RouterGroup {
+	fs := make([]FilterFunc, 0, len(g.filters)+len(filters))
+	fs = append(fs, g.filters...)
+	fs = append(fs, filters...)
+	return &RouterGroup{
+		handlers: g.handlers,
+		prefix:   joinRouterPath(g.prefix, prefix),
+		filters:  fs,
+	}
+}
+
+// Router adds a url-patterned controller handler with group prefix and filters.
+// arguments are same to App.Router.
+func (g *RouterGroup) Router(pattern string, c ControllerInterface, mappingMethods ...string) *RouterGroup {
+	g.handlers.add(joinRouterPath(g.prefix, pattern), c, g.filters, mappingMethods...)
+	return g
+}
+
+// join url prefix and pattern, "/" pattern means the prefix itself.
+func joinRouterPath(prefix, pattern string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if pattern == "" || pattern == "/" {
+		if prefix == "" {
+			return "/"
+		}
+		return prefix
+	}
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "/" + pattern
+	}
+	return prefix + pattern
+}
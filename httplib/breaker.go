@@ -0,0 +1,110 @@
+package httplib
+
+import (
+	"sync"
+	"time"
+)
+
+// states of a host in CircuitBreaker
+const (
+	CircuitClosed   = iota // requests are sent
+	CircuitOpen            // requests fail with *CircuitOpenError until cooldown ends
+	CircuitHalfOpen        // one probe request is sent, others fail
+)
+
+// CircuitOpenError is returned without sending the request when the circuit of its host is open.
+type CircuitOpenError struct {
+	Host string
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "httplib: circuit breaker is open for " + e.Host
+}
+
+// CircuitBreaker stops sending requests to a host after threshold consecutive failures,
+// connection errors and 5xx responses are failures.
+// after cooldown one probe request is sent, the circuit is closed if it succeeds
+// or opened again for cooldown if it fails.
+// a breaker is shared by requests with SetCircuitBreaker or BeegoHttpClient.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	lock      sync.Mutex
+	hosts     map[string]*circuit
+}
+
+// state of one host
+type circuit struct {
+	state    int
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker returns a breaker opening after threshold failures for cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     make(map[string]*circuit),
+	}
+}
+
+// State returns the state of host, CircuitClosed, CircuitOpen or CircuitHalfOpen.
+func (cb *CircuitBreaker) State(host string) int {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	if c, ok := cb.hosts[host]; ok {
+		if c.state == CircuitOpen && time.Since(c.openedAt) >= cb.cooldown {
+			// the next request is the probe
+			return CircuitHalfOpen
+		}
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// check whether a request to host can be sent.
+// it returns *CircuitOpenError if the circuit is open or a probe is running.
+func (cb *CircuitBreaker) allow(host string) error {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	c, ok := cb.hosts[host]
+	if !ok {
+		return nil
+	}
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < cb.cooldown {
+			return &CircuitOpenError{Host: host}
+		}
+		c.state = CircuitHalfOpen
+	case CircuitHalfOpen:
+		return &CircuitOpenError{Host: host}
+	}
+	return nil
+}
+
+// record result of a request to host.
+func (cb *CircuitBreaker) done(host string, failed bool) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	c, ok := cb.hosts[host]
+	if !failed {
+		if ok {
+			delete(cb.hosts, host)
+		}
+		return
+	}
+	if !ok {
+		c = &circuit{}
+		cb.hosts[host] = c
+	}
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= cb.threshold {
+		c.state = CircuitOpen
+		c.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,165 @@
+package httplib
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var hits int32
+	var failing int32 = 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	host := u.Host
+
+	client := NewClient()
+	client.Breaker = NewCircuitBreaker(2, 50*time.Millisecond)
+	get := func() error {
+		resp, err := client.Get(ts.URL).Response()
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// closed -> open after 2 failures
+	get()
+	if s := client.Breaker.State(host); s != CircuitClosed {
+		t.Fatal("circuit should be closed before threshold", s)
+	}
+	get()
+	if s := client.Breaker.State(host); s != CircuitOpen {
+		t.Fatal("circuit should be open after threshold", s)
+	}
+	err := get()
+	if _, ok := err.(*CircuitOpenError); !ok {
+		t.Fatal("open circuit should fail with CircuitOpenError", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Fatal("open circuit should not send request", n)
+	}
+
+	// open -> half-open after cooldown, failed probe opens it again
+	time.Sleep(60 * time.Millisecond)
+	if s := client.Breaker.State(host); s != CircuitHalfOpen {
+		t.Fatal("circuit should be half-open after cooldown", s)
+	}
+	get()
+	if s := client.Breaker.State(host); s != CircuitOpen {
+		t.Fatal("failed probe should open circuit", s)
+	}
+	if _, ok := get().(*CircuitOpenError); !ok {
+		t.Fatal("circuit should be open for another cooldown")
+	}
+
+	// half-open -> closed after successful probe
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&failing, 0)
+	if err = get(); err != nil {
+		t.Fatal("probe should be sent", err)
+	}
+	if s := client.Breaker.State(host); s != CircuitClosed {
+		t.Fatal("successful probe should close circuit", s)
+	}
+	if n := atomic.LoadInt32(&hits); n != 4 {
+		t.Fatal("requests sent should be 2 failures and 2 probes", n)
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	cb := NewCircuitBreaker(1, 10*time.Millisecond)
+	cb.done("example.com", true)
+	time.Sleep(20 * time.Millisecond)
+	if err := cb.allow("example.com"); err != nil {
+		t.Fatal("probe should be allowed after cooldown", err)
+	}
+	if err := cb.allow("example.com"); err == nil {
+		t.Fatal("only one probe should run in half-open state")
+	}
+	if err := cb.allow("other.com"); err != nil {
+		t.Fatal("circuits should be kept by host", err)
+	}
+}
+
+func TestCircuitBreakerBodyError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+
+	client := NewClient()
+	client.Breaker = NewCircuitBreaker(1, 10*time.Millisecond)
+	client.Breaker.done(u.Host, true)
+	time.Sleep(20 * time.Millisecond)
+
+	_, err := client.Get(ts.URL).AddRequestFilter(func(req *http.Request) error {
+		req.GetBody = func() (io.ReadCloser, error) { return nil, errors.New("open failed") }
+		return nil
+	}).String()
+	if err == nil || err.Error() != "open failed" {
+		t.Fatal("body error should be returned", err)
+	}
+	if s := client.Breaker.State(u.Host); s != CircuitHalfOpen {
+		t.Fatal("body error should not take the half-open probe", s)
+	}
+	if s, err := client.Get(ts.URL).String(); err != nil || s != "ok" {
+		t.Fatal("probe should be sent after body error", s, err)
+	}
+	if s := client.Breaker.State(u.Host); s != CircuitClosed {
+		t.Fatal("successful probe should close circuit", s)
+	}
+}
+
+func TestMaxConnsPerHost(t *testing.T) {
+	var lock sync.Mutex
+	var running, maxRunning int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	client := NewClient()
+	client.Transport = &http.Transport{}
+	// the first request sets up the shared transport
+	if _, err := client.Get(ts.URL).SetMaxConnsPerHost(2).String(); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Get(ts.URL).String()
+		}()
+	}
+	wg.Wait()
+	if maxRunning > 2 {
+		t.Fatal("connections per host should be limited to 2, got", maxRunning)
+	}
+}
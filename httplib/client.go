@@ -0,0 +1,74 @@
+package httplib
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+)
+
+// BeegoHttpClient creates requests sharing a cookie jar,
+// so cookies set by a response, e.g. of login, are sent with later requests to the same host.
+// the jar follows domain, path and expiry rules of cookies.
+// Transport and Breaker are shared by requests if they are set,
+// so connection limit and circuit state apply to all requests of the client.
+// RequestFilters and ResponseFilters run for all requests of the client before their own filters.
+// Tracer and Propagator replace the defaults of SetDefaultTracing if they are set.
+type BeegoHttpClient struct {
+	Jar             http.CookieJar
+	Transport       http.RoundTripper
+	Breaker         *CircuitBreaker
+	RequestFilters  []RequestFilter
+	ResponseFilters []ResponseFilter
+	Tracer          Tracer
+	Propagator      Propagator
+}
+
+// NewClient returns a client with a new in-memory cookie jar.
+func NewClient() *BeegoHttpClient {
+	// cookiejar.New never fails without options
+	jar, _ := cookiejar.New(nil)
+	return &BeegoHttpClient{Jar: jar}
+}
+
+// Get returns *BeegoHttpRequest with GET method and the client jar.
+func (c *BeegoHttpClient) Get(url string) *BeegoHttpRequest {
+	return c.newRequest(url, "GET")
+}
+
+// Post returns *BeegoHttpRequest with POST method and the client jar.
+func (c *BeegoHttpClient) Post(url string) *BeegoHttpRequest {
+	return c.newRequest(url, "POST")
+}
+
+// Put returns *BeegoHttpRequest with PUT method and the client jar.
+func (c *BeegoHttpClient) Put(url string) *BeegoHttpRequest {
+	return c.newRequest(url, "PUT")
+}
+
+// Delete returns *BeegoHttpRequest with DELETE method and the client jar.
+func (c *BeegoHttpClient) Delete(url string) *BeegoHttpRequest {
+	return c.newRequest(url, "DELETE")
+}
+
+// Head returns *BeegoHttpRequest with HEAD method and the client jar.
+func (c *BeegoHttpClient) Head(url string) *BeegoHttpRequest {
+	return c.newRequest(url, "HEAD")
+}
+
+func (c *BeegoHttpClient) newRequest(url, method string) *BeegoHttpRequest {
+	b := newBeegoRequest(url, method).SetCookieJar(c.Jar)
+	if c.Transport != nil {
+		b.SetTransport(c.Transport)
+	}
+	if c.Breaker != nil {
+		b.SetCircuitBreaker(c.Breaker)
+	}
+	if c.Tracer != nil {
+		b.SetTracer(c.Tracer)
+	}
+	if c.Propagator != nil {
+		b.SetPropagator(c.Propagator)
+	}
+	b.AddRequestFilter(c.RequestFilters...)
+	b.AddResponseFilter(c.ResponseFilters...)
+	return b
+}
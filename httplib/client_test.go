@@ -0,0 +1,64 @@
+package httplib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientCookieJar(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.FormValue("user") != "astaxie" {
+			http.Error(w, "bad login", http.StatusBadRequest)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "astaxie-session", Path: "/"})
+		http.SetCookie(w, &http.Cookie{Name: "admin", Value: "1", Path: "/admin"})
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/private", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("sid"); err != nil || c.Value != "astaxie-session" {
+			http.Error(w, "login required", http.StatusUnauthorized)
+			return
+		}
+		if _, err := r.Cookie("admin"); err == nil {
+			http.Error(w, "admin cookie should not be sent out of its path", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("private"))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := Get(ts.URL + "/private").Response()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatal("request without login should be rejected", resp.StatusCode)
+	}
+
+	client := NewClient()
+	if s, err := client.Post(ts.URL+"/login").Param("user", "astaxie").String(); err != nil || s != "ok" {
+		t.Fatal("login failed", s, err)
+	}
+	s, err := client.Get(ts.URL + "/private").String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "private" {
+		t.Fatal("cookies of login should be sent by client", s)
+	}
+
+	// requests out of the client don't share the jar
+	resp, err = Get(ts.URL + "/private").Response()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatal("jar should only be used by client requests", resp.StatusCode)
+	}
+}
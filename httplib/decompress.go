@@ -0,0 +1,80 @@
+package httplib
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// replace body of gzip or deflate response with decoding reader, like transport does for gzip.
+// responses those are decompressed by transport already have no Content-Encoding.
+func decompressBody(resp *http.Response) {
+	if resp.Body == nil || resp.Uncompressed {
+		return
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding != "gzip" && encoding != "deflate" {
+		return
+	}
+	resp.Body = &decompressReader{body: resp.Body, encoding: encoding}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// decompressReader decodes body on first read, so empty bodies don't fail.
+type decompressReader struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.Reader
+	err      error
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		d.r, d.err = d.newReader()
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
+
+// deflate should be zlib format, but some servers send raw deflate data.
+func (d *decompressReader) newReader() (io.Reader, error) {
+	if d.encoding == "gzip" {
+		zr, err := gzip.NewReader(d.body)
+		if err != nil {
+			// io.EOF of empty body
+			return nil, err
+		}
+		return zr, nil
+	}
+	br := bufio.NewReader(d.body)
+	head, _ := br.Peek(2)
+	if len(head) == 0 {
+		// empty body
+		return br, nil
+	}
+	// zlib header: compression method 8 and check bits
+	if len(head) == 2 && head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr, nil
+	}
+	return flate.NewReader(br), nil
+}
+
+func (d *decompressReader) Close() error {
+	if c, ok := d.r.(io.Closer); ok {
+		c.Close()
+	}
+	return d.body.Close()
+}
@@ -0,0 +1,127 @@
+package httplib
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const decompressText = "hello beego, hello beego, hello beego"
+
+func compressServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		var zw io.WriteCloser
+		encoding := r.URL.Query().Get("encoding")
+		switch encoding {
+		case "gzip":
+			zw = gzip.NewWriter(&buf)
+		case "deflate":
+			zw = zlib.NewWriter(&buf)
+		case "rawdeflate":
+			zw, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+			encoding = "deflate"
+		default:
+			w.Header().Set("X-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+			w.Write([]byte(decompressText))
+			return
+		}
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			t.Error("Accept-Encoding should be sent, got", r.Header.Get("Accept-Encoding"))
+		}
+		zw.Write([]byte(decompressText))
+		zw.Close()
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(buf.Bytes())
+	}))
+}
+
+func TestDecompression(t *testing.T) {
+	ts := compressServer(t)
+	defer ts.Close()
+
+	for _, encoding := range []string{"gzip", "deflate", "rawdeflate", "identity"} {
+		s, err := Get(ts.URL + "?encoding=" + encoding).String()
+		if err != nil {
+			t.Fatal(encoding, err)
+		}
+		if s != decompressText {
+			t.Fatalf("%s response should be decoded, got %q", encoding, s)
+		}
+	}
+
+	body, err := Get(ts.URL + "?encoding=gzip").Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(body)
+	body.Close()
+	if string(data) != decompressText {
+		t.Fatalf("stream should be decoded, got %q", data)
+	}
+
+	resp, err := Get(ts.URL + "?encoding=gzip").Response()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "" || !resp.Uncompressed {
+		t.Fatal("decoded response should have no Content-Encoding")
+	}
+
+	// header set by caller is kept
+	resp, err = Get(ts.URL).Header("Accept-Encoding", "br").Response()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Accept-Encoding") != "br" {
+		t.Fatal("Accept-Encoding of caller should be sent, got", resp.Header.Get("X-Accept-Encoding"))
+	}
+}
+
+func TestDisableDecompression(t *testing.T) {
+	ts := compressServer(t)
+	defer ts.Close()
+
+	data, err := Get(ts.URL+"?encoding=gzip").Header("Accept-Encoding", "gzip").SetEnableDecompression(false).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("body should be kept compressed", err)
+	}
+	if b, _ := ioutil.ReadAll(zr); string(b) != decompressText {
+		t.Fatalf("compressed body should have the text, got %q", b)
+	}
+
+	resp, err := Get(ts.URL).SetEnableDecompression(false).Response()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Accept-Encoding") != "" {
+		t.Fatal("Accept-Encoding should not be sent, got", resp.Header.Get("X-Accept-Encoding"))
+	}
+}
+
+func TestDecompressEmptyBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	data, err := Get(ts.URL).Bytes()
+	if err != nil || len(data) != 0 {
+		t.Fatal("empty gzip response should have empty body", data, err)
+	}
+}
@@ -2,6 +2,7 @@ package httplib
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"encoding/xml"
@@ -12,6 +13,7 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -20,49 +22,55 @@ var defaultUserAgent = "beegoServer"
 
 // Get returns *BeegoHttpRequest with GET method.
 func Get(url string) *BeegoHttpRequest {
-	var req http.Request
-	req.Method = "GET"
-	req.Header = http.Header{}
-	req.Header.Set("User-Agent", defaultUserAgent)
-	return &BeegoHttpRequest{url, &req, map[string]string{}, false, 60 * time.Second, 60 * time.Second, nil, nil, nil}
+	return newBeegoRequest(url, "GET")
 }
 
 // Post returns *BeegoHttpRequest with POST method.
 func Post(url string) *BeegoHttpRequest {
-	var req http.Request
-	req.Method = "POST"
-	req.Header = http.Header{}
-	req.Header.Set("User-Agent", defaultUserAgent)
-	return &BeegoHttpRequest{url, &req, map[string]string{}, false, 60 * time.Second, 60 * time.Second, nil, nil, nil}
+	return newBeegoRequest(url, "POST")
 }
 
 // Put returns *BeegoHttpRequest with PUT method.
 func Put(url string) *BeegoHttpRequest {
-	var req http.Request
-	req.Method = "PUT"
-	req.Header = http.Header{}
-	req.Header.Set("User-Agent", defaultUserAgent)
-	return &BeegoHttpRequest{url, &req, map[string]string{}, false, 60 * time.Second, 60 * time.Second, nil, nil, nil}
+	return newBeegoRequest(url, "PUT")
 }
 
 // Delete returns *BeegoHttpRequest DELETE GET method.
 func Delete(url string) *BeegoHttpRequest {
-	var req http.Request
-	req.Method = "DELETE"
-	req.Header = http.Header{}
-	req.Header.Set("User-Agent", defaultUserAgent)
-	return &BeegoHttpRequest{url, &req, map[string]string{}, false, 60 * time.Second, 60 * time.Second, nil, nil, nil}
+	return newBeegoRequest(url, "DELETE")
 }
 
 // Head returns *BeegoHttpRequest with HEAD method.
 func Head(url string) *BeegoHttpRequest {
+	return newBeegoRequest(url, "HEAD")
+}
+
+func newBeegoRequest(url, method string) *BeegoHttpRequest {
 	var req http.Request
-	req.Method = "HEAD"
+	req.Method = method
 	req.Header = http.Header{}
 	req.Header.Set("User-Agent", defaultUserAgent)
-	return &BeegoHttpRequest{url, &req, map[string]string{}, false, 60 * time.Second, 60 * time.Second, nil, nil, nil}
+	return &BeegoHttpRequest{
+		url:              url,
+		req:              &req,
+		params:           map[string]string{},
+		files:            map[string]string{},
+		connectTimeout:   60 * time.Second,
+		readWriteTimeout: 60 * time.Second,
+		decompress:       true,
+		tracer:           defaultTracer,
+		propagator:       defaultPropagator,
+	}
 }
 
+// RequestFilter can change the request before it's sent, e.g. add auth headers.
+// an error aborts the request.
+type RequestFilter func(req *http.Request) error
+
+// ResponseFilter checks the response before it's returned, e.g. logs timing or rejects 4xx status.
+// an error closes the response and is returned instead.
+type ResponseFilter func(resp *http.Response) error
+
 // BeegoHttpRequest provides more useful methods for requesting one url than http.Request.
 type BeegoHttpRequest struct {
 	url              string
@@ -71,9 +79,24 @@ type BeegoHttpRequest struct {
 	showdebug        bool
 	connectTimeout   time.Duration
 	readWriteTimeout time.Duration
+	deadline         time.Duration
 	tlsClientConfig  *tls.Config
 	proxy            func(*http.Request) (*url.URL, error)
 	transport        http.RoundTripper
+	retries          int
+	retryDelay       time.Duration
+	retryBackoff     bool
+	retryAnyMethod   bool
+	jar              http.CookieJar
+	files            map[string]string // form field => file path
+	uploadProgress   func(sent, total int64)
+	maxConnsPerHost  int
+	breaker          *CircuitBreaker
+	decompress       bool
+	reqFilters       []RequestFilter
+	respFilters      []ResponseFilter
+	tracer           Tracer
+	propagator       Propagator
 }
 
 // Debug sets show debug or not when executing request.
@@ -83,18 +106,96 @@ func (b *BeegoHttpRequest) Debug(isdebug bool) *BeegoHttpRequest {
 }
 
 // SetTimeout sets connect time out and read-write time out for BeegoRequest.
+// the read-write time out is a deadline of the connection from the time it's dialed,
+// it's only applied to connections dialed by the transport of the request.
 func (b *BeegoHttpRequest) SetTimeout(connectTimeout, readWriteTimeout time.Duration) *BeegoHttpRequest {
 	b.connectTimeout = connectTimeout
 	b.readWriteTimeout = readWriteTimeout
 	return b
 }
 
+// SetDeadline sets the total time of the request, from connecting to reading the last byte of the body.
+// it covers retries and their delays and applies to reused connections and any transport.
+// if SetTimeout is also set, whichever expires first aborts the request.
+// the deadline ends when the body is closed, so the body of Stream and Response must be closed.
+func (b *BeegoHttpRequest) SetDeadline(total time.Duration) *BeegoHttpRequest {
+	b.deadline = total
+	return b
+}
+
 // SetTLSClientConfig sets tls connection configurations if visiting https url.
 func (b *BeegoHttpRequest) SetTLSClientConfig(config *tls.Config) *BeegoHttpRequest {
 	b.tlsClientConfig = config
 	return b
 }
 
+// Retries sets how many times the request is retried after connection errors or 5xx responses.
+// only GET and HEAD requests are retried, unless RetryAnyMethod is set.
+func (b *BeegoHttpRequest) Retries(count int) *BeegoHttpRequest {
+	b.retries = count
+	return b
+}
+
+// RetryDelay sets the wait time before retrying.
+// if the response has Retry-After header, it's used instead.
+func (b *BeegoHttpRequest) RetryDelay(d time.Duration) *BeegoHttpRequest {
+	b.retryDelay = d
+	return b
+}
+
+// RetryBackoff doubles the retry delay after each retry.
+func (b *BeegoHttpRequest) RetryBackoff(backoff bool) *BeegoHttpRequest {
+	b.retryBackoff = backoff
+	return b
+}
+
+// RetryAnyMethod allows retrying requests those are not idempotent, e.g. POST.
+// the body is sent again with every retry.
+func (b *BeegoHttpRequest) RetryAnyMethod(allow bool) *BeegoHttpRequest {
+	b.retryAnyMethod = allow
+	return b
+}
+
+// SetEnableDecompression sets whether gzip and deflate responses are decompressed, default is true.
+// if it's on, Accept-Encoding is sent unless it's set by Header, and the body of a response
+// with gzip or deflate Content-Encoding is decoded for Bytes, String, Stream, ToFile and Response.
+// if it's off, the body is returned as sent by the server.
+func (b *BeegoHttpRequest) SetEnableDecompression(enable bool) *BeegoHttpRequest {
+	b.decompress = enable
+	return b
+}
+
+// WithContext sets the context of request, it cancels the request when it's done
+// and its trace context is propagated by the propagator.
+func (b *BeegoHttpRequest) WithContext(ctx context.Context) *BeegoHttpRequest {
+	b.req = b.req.WithContext(ctx)
+	return b
+}
+
+// SetTracer sets the tracer starting a client span around the round-trip, nil turns it off.
+func (b *BeegoHttpRequest) SetTracer(tracer Tracer) *BeegoHttpRequest {
+	b.tracer = tracer
+	return b
+}
+
+// SetPropagator sets the propagator writing trace headers to the request, nil turns it off.
+func (b *BeegoHttpRequest) SetPropagator(propagator Propagator) *BeegoHttpRequest {
+	b.propagator = propagator
+	return b
+}
+
+// AddRequestFilter adds filters run in order before the request is sent, retries don't run them again.
+func (b *BeegoHttpRequest) AddRequestFilter(filters ...RequestFilter) *BeegoHttpRequest {
+	b.reqFilters = append(b.reqFilters, filters...)
+	return b
+}
+
+// AddResponseFilter adds filters run in order on the response after retries and decompression.
+func (b *BeegoHttpRequest) AddResponseFilter(filters ...ResponseFilter) *BeegoHttpRequest {
+	b.respFilters = append(b.respFilters, filters...)
+	return b
+}
+
 // Header add header item string in request.
 func (b *BeegoHttpRequest) Header(key, value string) *BeegoHttpRequest {
 	b.req.Header.Set(key, value)
@@ -107,6 +208,27 @@ func (b *BeegoHttpRequest) SetCookie(cookie *http.Cookie) *BeegoHttpRequest {
 	return b
 }
 
+// SetCookieJar sets the cookie jar of request.
+// cookies in the jar are sent with the request and cookies in the response are saved to it.
+func (b *BeegoHttpRequest) SetCookieJar(jar http.CookieJar) *BeegoHttpRequest {
+	b.jar = jar
+	return b
+}
+
+// SetMaxConnsPerHost limits connections of the transport to one host, requests wait for a free one.
+// the limit is shared by requests using the same transport, e.g. set by SetTransport or BeegoHttpClient.
+func (b *BeegoHttpRequest) SetMaxConnsPerHost(n int) *BeegoHttpRequest {
+	b.maxConnsPerHost = n
+	return b
+}
+
+// SetCircuitBreaker sets the breaker checked before sending the request.
+// if the circuit of the host is open, the request fails with *CircuitOpenError.
+func (b *BeegoHttpRequest) SetCircuitBreaker(cb *CircuitBreaker) *BeegoHttpRequest {
+	b.breaker = cb
+	return b
+}
+
 // Set transport to
 func (b *BeegoHttpRequest) SetTransport(transport http.RoundTripper) *BeegoHttpRequest {
 	b.transport = transport
@@ -132,18 +254,37 @@ func (b *BeegoHttpRequest) Param(key, value string) *BeegoHttpRequest {
 	return b
 }
 
+// PostFile adds a file to upload in multipart form with field name.
+// the file is streamed from disk when the request is sent, params are sent as form fields with it.
+func (b *BeegoHttpRequest) PostFile(formname, filename string) *BeegoHttpRequest {
+	b.files[formname] = filename
+	return b
+}
+
+// SetUploadProgress sets the function called when the request body is sent,
+// sent is bytes sent and total is the body size, -1 if unknown.
+func (b *BeegoHttpRequest) SetUploadProgress(progress func(sent, total int64)) *BeegoHttpRequest {
+	b.uploadProgress = progress
+	return b
+}
+
 // Body adds request raw body.
 // it supports string and []byte.
 func (b *BeegoHttpRequest) Body(data interface{}) *BeegoHttpRequest {
+	var buf []byte
 	switch t := data.(type) {
 	case string:
-		bf := bytes.NewBufferString(t)
-		b.req.Body = ioutil.NopCloser(bf)
-		b.req.ContentLength = int64(len(t))
+		buf = []byte(t)
 	case []byte:
-		bf := bytes.NewBuffer(t)
-		b.req.Body = ioutil.NopCloser(bf)
-		b.req.ContentLength = int64(len(t))
+		buf = t
+	default:
+		return b
+	}
+	b.req.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	b.req.ContentLength = int64(len(buf))
+	// GetBody rewinds the body for retries and redirects.
+	b.req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf)), nil
 	}
 	return b
 }
@@ -168,10 +309,17 @@ func (b *BeegoHttpRequest) getResponse() (*http.Response, error) {
 		} else {
 			b.url = b.url + "?" + paramBody
 		}
+	} else if len(b.files) > 0 && b.req.Body == nil {
+		if err := b.multipartBody(); err != nil {
+			return nil, err
+		}
 	} else if b.req.Method == "POST" && b.req.Body == nil && len(paramBody) > 0 {
 		b.Header("Content-Type", "application/x-www-form-urlencoded")
 		b.Body(paramBody)
 	}
+	if b.uploadProgress != nil && (b.req.Body != nil || b.req.GetBody != nil) {
+		b.withUploadProgress()
+	}
 
 	url, err := url.Parse(b.url)
 	if url.Scheme == "" {
@@ -183,6 +331,15 @@ func (b *BeegoHttpRequest) getResponse() (*http.Response, error) {
 	}
 
 	b.req.URL = url
+	if b.decompress && b.req.Header.Get("Accept-Encoding") == "" {
+		// transport decompresses only gzip and only if it sets the header itself.
+		b.req.Header.Set("Accept-Encoding", "gzip, deflate")
+	}
+	for _, filter := range b.reqFilters {
+		if err := filter(b.req); err != nil {
+			return nil, err
+		}
+	}
 	if b.showdebug {
 		dump, err := httputil.DumpRequest(b.req, true)
 		if err != nil {
@@ -199,33 +356,162 @@ func (b *BeegoHttpRequest) getResponse() (*http.Response, error) {
 			TLSClientConfig: b.tlsClientConfig,
 			Proxy:           b.proxy,
 			Dial:            TimeoutDialer(b.connectTimeout, b.readWriteTimeout),
+			MaxConnsPerHost: b.maxConnsPerHost,
+			// keep compressed body if decompression is off
+			DisableCompression: !b.decompress,
 		}
 	} else {
 		// if b.transport is *http.Transport then set the settings.
+		// empty settings are not written, transports may be shared by requests.
 		if t, ok := trans.(*http.Transport); ok {
-			if t.TLSClientConfig == nil {
+			if t.TLSClientConfig == nil && b.tlsClientConfig != nil {
 				t.TLSClientConfig = b.tlsClientConfig
 			}
-			if t.Proxy == nil {
+			if t.Proxy == nil && b.proxy != nil {
 				t.Proxy = b.proxy
 			}
 			if t.Dial == nil {
 				t.Dial = TimeoutDialer(b.connectTimeout, b.readWriteTimeout)
 			}
+			if t.MaxConnsPerHost == 0 && b.maxConnsPerHost > 0 {
+				t.MaxConnsPerHost = b.maxConnsPerHost
+			}
 		}
 	}
 
 	client := &http.Client{
 		Transport: trans,
+		Jar:       b.jar,
+	}
+
+	req, cancel := b.withDeadline()
+	var end func(*http.Response, error)
+	if b.tracer != nil || b.propagator != nil {
+		req, end = b.withTrace(req)
 	}
 
-	resp, err := client.Do(b.req)
+	var resp *http.Response
+	for i := 0; ; i++ {
+		// multipart body is only created by GetBody.
+		// it's created before the breaker admits the request, so a failure doesn't hold a half-open probe.
+		if (i > 0 || req.Body == nil) && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				break
+			}
+		}
+		if b.breaker != nil {
+			if err = b.breaker.allow(url.Host); err != nil {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				break
+			}
+		}
+		resp, err = client.Do(req)
+		if b.breaker != nil {
+			b.breaker.done(url.Host, err != nil || resp.StatusCode >= 500)
+		}
+		if i >= b.retries || !b.retryable() || err == nil && resp.StatusCode < 500 {
+			break
+		}
+		delay := b.retryWait(i, resp)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if err = sleepCtx(req.Context(), delay); err != nil {
+			break
+		}
+	}
+	if end != nil {
+		if err != nil {
+			end(nil, err)
+		} else {
+			end(resp, nil)
+		}
+	}
 	if err != nil {
+		cancel()
 		return nil, err
 	}
+	if b.deadline > 0 {
+		resp.Body = &cancelBody{resp.Body, cancel}
+	}
+	if b.decompress && b.req.Method != "HEAD" {
+		decompressBody(resp)
+	}
+	for _, filter := range b.respFilters {
+		if err := filter(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
 	return resp, nil
 }
 
+// get the request to send with the deadline and the function ending it.
+func (b *BeegoHttpRequest) withDeadline() (*http.Request, context.CancelFunc) {
+	if b.deadline <= 0 {
+		return b.req, func() {}
+	}
+	ctx, cancel := context.WithTimeout(b.req.Context(), b.deadline)
+	return b.req.WithContext(ctx), cancel
+}
+
+// wait for d, it returns error if ctx is done before.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// body of response ending the deadline of its request when it's closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// check whether the request can be sent again.
+func (b *BeegoHttpRequest) retryable() bool {
+	if b.retryAnyMethod {
+		return b.req.Body == nil || b.req.GetBody != nil
+	}
+	return b.req.Method == "GET" || b.req.Method == "HEAD"
+}
+
+// get the wait time before the retry after i-th attempt.
+// Retry-After of the response in seconds or http date is preferred.
+func (b *BeegoHttpRequest) retryWait(i int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			if secs, err := strconv.Atoi(ra); err == nil && secs >= 0 {
+				return time.Duration(secs) * time.Second
+			}
+			if t, err := http.ParseTime(ra); err == nil {
+				if d := t.Sub(time.Now()); d > 0 {
+					return d
+				}
+				return 0
+			}
+		}
+	}
+	d := b.retryDelay
+	if b.retryBackoff {
+		d <<= uint(i)
+	}
+	return d
+}
+
 // String returns the body string in response.
 // it calls Response inner.
 func (b *BeegoHttpRequest) String() (string, error) {
@@ -255,6 +541,28 @@ func (b *BeegoHttpRequest) Bytes() ([]byte, error) {
 	return data, nil
 }
 
+// Stream returns the body of response without reading it into memory.
+// the caller must close it.
+// if debug is on, the response is dumped, so the whole body is read into memory before returning.
+func (b *BeegoHttpRequest) Stream() (io.ReadCloser, error) {
+	resp, err := b.getResponse()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Body == nil {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if b.showdebug {
+		dump, err := httputil.DumpResponse(resp, true)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		println(string(dump))
+	}
+	return resp.Body, nil
+}
+
 // ToFile saves the body data in response to one file.
 // it calls Response inner.
 func (b *BeegoHttpRequest) ToFile(filename string) error {
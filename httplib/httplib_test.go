@@ -1,8 +1,19 @@
 package httplib
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestGetUrl(t *testing.T) {
@@ -30,3 +41,277 @@ func TestGetUrl(t *testing.T) {
 		t.Fatal("has no info")
 	}
 }
+
+// flakyServer fails the first n requests with 503.
+func flakyServer(n int, hits *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		if *hits <= n {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte("ok" + string(body)))
+	}))
+}
+
+func TestRetries(t *testing.T) {
+	hits := 0
+	ts := flakyServer(2, &hits)
+	defer ts.Close()
+
+	str, err := Get(ts.URL).Retries(2).RetryDelay(time.Millisecond).RetryBackoff(true).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str != "ok" || hits != 3 {
+		t.Fatal("get should succeed on third attempt", str, hits)
+	}
+
+	hits = 0
+	resp, err := Post(ts.URL).Body("data").Retries(2).Response()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || hits != 1 {
+		t.Fatal("post should not be retried by default", resp.StatusCode, hits)
+	}
+
+	hits = 0
+	str, err = Post(ts.URL).Body("data").Retries(2).RetryAnyMethod(true).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str != "okdata" || hits != 3 {
+		t.Fatal("post body should be sent again with retries", str, hits)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	b := Get("http://beego.me/").RetryDelay(time.Second).RetryBackoff(true)
+	resp := &http.Response{Header: http.Header{}}
+	if d := b.retryWait(2, resp); d != 4*time.Second {
+		t.Fatal("delay should be doubled with backoff", d)
+	}
+	resp.Header.Set("Retry-After", "7")
+	if d := b.retryWait(2, resp); d != 7*time.Second {
+		t.Fatal("Retry-After should be honored", d)
+	}
+}
+
+func TestStream(t *testing.T) {
+	const size = 64 << 20
+	chunk := bytes.Repeat([]byte("beego"), 1<<12)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for n := 0; n < size; n += len(chunk) {
+			if size-n < len(chunk) {
+				chunk = chunk[:size-n]
+			}
+			w.Write(chunk)
+		}
+	}))
+	defer ts.Close()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	body, err := Get(ts.URL).Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := io.Copy(ioutil.Discard, body)
+	body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if n != size {
+		t.Fatal("stream should read the whole body", n)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > size/8 {
+		t.Fatal("stream should not buffer the body, allocated", alloc)
+	}
+}
+
+func TestPostFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "beego-upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	data := bytes.Repeat([]byte("0123456789abcdef"), 4<<20/16)
+	f.Write(data)
+	f.Close()
+
+	var received []byte
+	var contentLength, bodySize int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		body, _ := ioutil.ReadAll(r.Body)
+		bodySize = int64(len(body))
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		file, header, err := r.FormFile("upload")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		received, _ = ioutil.ReadAll(file)
+		w.Write([]byte(header.Filename + ":" + r.FormValue("name")))
+	}))
+	defer ts.Close()
+
+	var sent, total int64
+	s, err := Post(ts.URL).Param("name", "astaxie").PostFile("upload", f.Name()).
+		SetUploadProgress(func(s, t int64) { sent, total = s, t }).String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != filepath.Base(f.Name())+":astaxie" {
+		t.Fatal("file name and params should be sent in form", s)
+	}
+	if !bytes.Equal(received, data) {
+		t.Fatal("received file should be same to uploaded file", len(received), len(data))
+	}
+	if contentLength != bodySize {
+		t.Fatal("Content-Length should be the body size", contentLength, bodySize)
+	}
+	if sent != total || total != bodySize {
+		t.Fatal("progress should report the whole body", sent, total, bodySize)
+	}
+
+	if _, err = Post(ts.URL).PostFile("upload", f.Name()+".missing").String(); err == nil {
+		t.Fatal("missing file should fail before sending")
+	}
+
+	// the body is not opened if the request fails before sending
+	_, err = Post(ts.URL).PostFile("upload", f.Name()).AddRequestFilter(func(req *http.Request) error {
+		if req.Body != nil {
+			t.Error("multipart body should be opened right before sending")
+		}
+		return errors.New("no token")
+	}).String()
+	if err == nil {
+		t.Fatal("request filter error should abort the upload")
+	}
+}
+
+func TestFilters(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-Order")))
+	}))
+	defer ts.Close()
+
+	rejectClientError := func(resp *http.Response) error {
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return fmt.Errorf("client error %d", resp.StatusCode)
+		}
+		return nil
+	}
+	client := NewClient()
+	client.RequestFilters = []RequestFilter{func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("X-Order", "client")
+		return nil
+	}}
+	client.ResponseFilters = []ResponseFilter{rejectClientError}
+
+	s, err := client.Get(ts.URL).AddRequestFilter(func(req *http.Request) error {
+		req.Header.Set("X-Order", req.Header.Get("X-Order")+",request")
+		return nil
+	}).String()
+	if err != nil || s != "client,request" {
+		t.Fatal("filters should inject headers in order", s, err)
+	}
+
+	if _, err = Get(ts.URL).AddResponseFilter(rejectClientError).String(); err == nil || err.Error() != "client error 401" {
+		t.Fatal("response filter should reject 4xx", err)
+	}
+
+	hits = 0
+	_, err = client.Get(ts.URL).AddRequestFilter(func(req *http.Request) error {
+		return errors.New("no token")
+	}).String()
+	if err == nil || hits != 0 {
+		t.Fatal("request filter error should abort the request", err, hits)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	stall := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("beego"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-stall:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(stall)
+
+	start := time.Now()
+	_, err := Get(ts.URL).SetTimeout(time.Second, time.Minute).SetDeadline(200 * time.Millisecond).Bytes()
+	if err == nil {
+		t.Fatal("deadline should abort a stalled body")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatal("deadline fired too late", d)
+	}
+
+	resp, err := Get(ts.URL).SetDeadline(200 * time.Millisecond).Response()
+	if err != nil {
+		t.Fatal("headers should be read before the deadline", err)
+	}
+	defer resp.Body.Close()
+	buf := make([]byte, 5)
+	if _, err = io.ReadFull(resp.Body, buf); err != nil || string(buf) != "beego" {
+		t.Fatal("body should be read until the stall", string(buf), err)
+	}
+	if _, err = resp.Body.Read(buf); err == nil {
+		t.Fatal("deadline should abort reading the stalled body")
+	}
+}
+
+func TestTracing(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("traceparent") + " " + r.Header.Get("tracestate")))
+	}))
+	defer ts.Close()
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := WithTraceContext(context.Background(), parent, "beego=1")
+	s, err := Get(ts.URL).WithContext(ctx).SetPropagator(TraceContextPropagator).String()
+	if err != nil || s != parent+" beego=1" {
+		t.Fatal("trace headers should be injected", s, err)
+	}
+	if s, _ = Get(ts.URL).WithContext(ctx).String(); s != " " {
+		t.Fatal("trace headers should not be sent without propagator", s)
+	}
+
+	// the tracer starts a span, its context is propagated
+	const child = "00-4bf92f3577b34da6a3ce929d0e0e4736-b7ad6b7169203331-01"
+	var ended []int
+	tracer := func(ctx context.Context, req *http.Request) (context.Context, func(*http.Response, error)) {
+		return WithTraceContext(ctx, child, ""), func(resp *http.Response, err error) {
+			if err == nil {
+				ended = append(ended, resp.StatusCode)
+			}
+		}
+	}
+	SetDefaultTracing(tracer, TraceContextPropagator)
+	defer SetDefaultTracing(nil, nil)
+	s, err = Get(ts.URL).WithContext(ctx).String()
+	if err != nil || s != child+" " {
+		t.Fatal("trace headers of the span should be injected", s, err)
+	}
+	if len(ended) != 1 || ended[0] != http.StatusOK {
+		t.Fatal("span should be ended once with the response", ended)
+	}
+}
@@ -0,0 +1,74 @@
+package httplib
+
+import (
+	"context"
+	"net/http"
+)
+
+// Propagator writes the trace context of ctx to headers of the outgoing request, e.g. traceparent and tracestate.
+// with opentelemetry it's
+//
+//	func(ctx context.Context, h http.Header) {
+//		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+//	}
+type Propagator func(ctx context.Context, header http.Header)
+
+// Tracer starts a client span of the request in the context of the request.
+// the returned context is passed to the propagator, end is called once
+// with the response or error of the round-trip, after retries.
+type Tracer func(ctx context.Context, req *http.Request) (spanCtx context.Context, end func(resp *http.Response, err error))
+
+var (
+	defaultTracer     Tracer
+	defaultPropagator Propagator
+)
+
+// SetDefaultTracing sets the tracer and propagator of requests created after it, nil turns them off.
+// without them requests aren't traced.
+func SetDefaultTracing(tracer Tracer, propagator Propagator) {
+	defaultTracer = tracer
+	defaultPropagator = propagator
+}
+
+type traceContextKey struct{}
+
+// w3c trace context headers of WithTraceContext.
+type traceContext struct {
+	traceparent, tracestate string
+}
+
+// WithTraceContext returns a copy of ctx carrying w3c trace headers, e.g. of the incoming request,
+// TraceContextPropagator forwards them.
+//
+//	ctx := httplib.WithTraceContext(r.Context(), r.Header.Get("traceparent"), r.Header.Get("tracestate"))
+//	httplib.Get(url).WithContext(ctx).SetPropagator(httplib.TraceContextPropagator).String()
+func WithTraceContext(ctx context.Context, traceparent, tracestate string) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceContext{traceparent, tracestate})
+}
+
+// TraceContextPropagator writes traceparent and tracestate headers saved in ctx by WithTraceContext.
+func TraceContextPropagator(ctx context.Context, header http.Header) {
+	tc, ok := ctx.Value(traceContextKey{}).(traceContext)
+	if !ok || tc.traceparent == "" {
+		return
+	}
+	header.Set("traceparent", tc.traceparent)
+	if tc.tracestate != "" {
+		header.Set("tracestate", tc.tracestate)
+	}
+}
+
+// start span of the tracer and write the trace headers of the request context.
+// end is nil if there is no tracer.
+func (b *BeegoHttpRequest) withTrace(req *http.Request) (*http.Request, func(*http.Response, error)) {
+	var end func(*http.Response, error)
+	ctx := req.Context()
+	if b.tracer != nil {
+		ctx, end = b.tracer(ctx, req)
+		req = req.WithContext(ctx)
+	}
+	if b.propagator != nil {
+		b.propagator(ctx, req.Header)
+	}
+	return req, end
+}
@@ -0,0 +1,121 @@
+package httplib
+
+import (
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+)
+
+// set request body to multipart form of params and files.
+// the body is written by a goroutine through a pipe, so files are not read into memory.
+// only GetBody is set, the body is opened by GetBody right before sending,
+// so nothing is left open if the request fails before it.
+// Content-Length is counted from file sizes before sending.
+func (b *BeegoHttpRequest) multipartBody() error {
+	var fileSize int64
+	for _, name := range b.files {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		fileSize += fi.Size()
+	}
+	// write the form without file contents to count the size of the rest
+	counter := &countWriter{}
+	mw := multipart.NewWriter(counter)
+	if err := b.writeMultipart(mw, false); err != nil {
+		return err
+	}
+	boundary := mw.Boundary()
+
+	b.req.GetBody = func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		mw := multipart.NewWriter(pw)
+		mw.SetBoundary(boundary)
+		go func() {
+			pw.CloseWithError(b.writeMultipart(mw, true))
+		}()
+		return pr, nil
+	}
+	b.req.ContentLength = counter.n + fileSize
+	b.Header("Content-Type", mw.FormDataContentType())
+	return nil
+}
+
+// write params and files to multipart writer, file contents are skipped if withContent is false.
+func (b *BeegoHttpRequest) writeMultipart(mw *multipart.Writer, withContent bool) error {
+	for k, v := range b.params {
+		if err := mw.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+	for formname, filename := range b.files {
+		fw, err := mw.CreateFormFile(formname, filepath.Base(filename))
+		if err != nil {
+			return err
+		}
+		if withContent {
+			f, err := os.Open(filename)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(fw, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return mw.Close()
+}
+
+// wrap request body to report upload progress.
+func (b *BeegoHttpRequest) withUploadProgress() {
+	total := b.req.ContentLength
+	if total == 0 {
+		total = -1
+	}
+	wrap := func(body io.ReadCloser) io.ReadCloser {
+		return &progressReader{ReadCloser: body, total: total, progress: b.uploadProgress}
+	}
+	if b.req.Body != nil {
+		b.req.Body = wrap(b.req.Body)
+	}
+	if getBody := b.req.GetBody; getBody != nil {
+		b.req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return wrap(body), nil
+		}
+	}
+}
+
+// progressReader calls progress with bytes read after every read.
+type progressReader struct {
+	io.ReadCloser
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		r.progress(r.sent, r.total)
+	}
+	return n, err
+}
+
+// countWriter counts bytes written.
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
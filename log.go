@@ -22,11 +22,23 @@ func SetLevel(l int) {
 	BeeLogger.SetLevel(l)
 }
 
+// SetLoggerLevel sets the min log level of one logger adapter,
+// e.g. console at LevelDebug and file at LevelError.
+func SetLoggerLevel(adaptername string, l int) error {
+	return BeeLogger.SetLoggerLevel(adaptername, l)
+}
+
 func SetLogFuncCall(b bool) {
 	BeeLogger.EnableFuncCallDepth(b)
 	BeeLogger.SetLogFuncCallDepth(3)
 }
 
+// SetLogCallDepth sets the count of own wrappers around the log functions,
+// so the logged file and line point at their caller.
+func SetLogCallDepth(n int) {
+	BeeLogger.SetCallDepth(n)
+}
+
 // logger references the used application logger.
 var BeeLogger *logs.BeeLogger
 
@@ -2,6 +2,7 @@ package logs
 
 import (
 	"encoding/json"
+	"io"
 	"log"
 	"os"
 	"runtime"
@@ -28,20 +29,24 @@ var colors = []Brush{
 
 // ConsoleWriter implements LoggerInterface and writes messages to terminal.
 type ConsoleWriter struct {
-	lg    *log.Logger
-	Level int `json:"level"`
+	lg     *log.Logger
+	out    io.Writer
+	Level  int    `json:"level"`
+	Format string `json:"format"`
 }
 
 // create ConsoleWriter returning as LoggerInterface.
 func NewConsole() LoggerInterface {
 	cw := new(ConsoleWriter)
-	cw.lg = log.New(os.Stdout, "", log.Ldate|log.Ltime)
+	cw.out = os.Stdout
+	cw.lg = log.New(cw.out, "", log.Ldate|log.Ltime)
 	cw.Level = LevelTrace
 	return cw
 }
 
 // init console logger.
-// jsonconfig like '{"level":LevelTrace}'.
+// jsonconfig like '{"level":LevelTrace,"format":"json"}'.
+// format is "text" by default, "json" writes every record as one json object per line.
 func (c *ConsoleWriter) Init(jsonconfig string) error {
 	if len(jsonconfig) == 0 {
 		return nil
@@ -66,6 +71,22 @@ func (c *ConsoleWriter) WriteMsg(msg string, level int) error {
 	return nil
 }
 
+// write record in console, as json if format is json.
+func (c *ConsoleWriter) WriteRecord(r *Record) error {
+	if c.Format != "json" {
+		return c.WriteMsg(r.Text(), r.Level)
+	}
+	if r.Level < c.Level {
+		return nil
+	}
+	b, err := r.JSON()
+	if err != nil {
+		return err
+	}
+	_, err = c.out.Write(append(b, '\n'))
+	return err
+}
+
 // implementing method. empty.
 func (c *ConsoleWriter) Destroy() {
 
@@ -1,7 +1,11 @@
 package logs
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestConsole(t *testing.T) {
@@ -30,3 +34,35 @@ func BenchmarkConsole(b *testing.B) {
 		log.Trace("trace")
 	}
 }
+
+func TestConsoleJSON(t *testing.T) {
+	log := NewLogger(100)
+	log.EnableFuncCallDepth(true)
+	log.SetLogger("console", `{"format":"json"}`)
+	buf := new(bytes.Buffer)
+	log.outputs["console"].(*ConsoleWriter).out = buf
+	log.Info("info %d", 1)
+	log.Critical("critical")
+	time.Sleep(100 * time.Millisecond)
+	log.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("should write one json object per record", buf.String())
+	}
+	for i, level := range []string{"info", "critical"} {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &m); err != nil {
+			t.Fatal("invalid json", lines[i], err)
+		}
+		if m["level"] != level {
+			t.Fatal("wrong level name", m["level"], level)
+		}
+		if m["file"] != "console_test.go" || m["line"] == nil || m["time"] == nil {
+			t.Fatal("json should have time, file and line", lines[i])
+		}
+	}
+	if !strings.Contains(lines[0], `"msg":"info 1"`) {
+		t.Fatal("json should have msg", lines[0])
+	}
+}
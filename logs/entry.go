@@ -0,0 +1,66 @@
+package logs
+
+import (
+	"fmt"
+)
+
+// Entry is a log entry with fields, they are written with every message of it.
+// fields are written as json keys with json format, or as k=v in text.
+// Entry is not changed after creating, so it can be used in goroutines.
+type Entry struct {
+	logger *BeeLogger
+	fields map[string]interface{}
+}
+
+// WithFields returns an entry logging messages with fields.
+func (bl *BeeLogger) WithFields(fields map[string]interface{}) *Entry {
+	return &Entry{logger: bl, fields: mergeFields(nil, fields)}
+}
+
+// WithFields returns a new entry with fields added to fields of e.
+// if a key is set in both, value in fields is used.
+func (e *Entry) WithFields(fields map[string]interface{}) *Entry {
+	return &Entry{logger: e.logger, fields: mergeFields(e.fields, fields)}
+}
+
+// copy fields of a and b into a new map, b is prior.
+func mergeFields(a, b map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+// log trace level message with fields.
+func (e *Entry) Trace(format string, v ...interface{}) {
+	e.logger.writerMsg(LevelTrace, fmt.Sprintf(format, v...), e.fields)
+}
+
+// log debug level message with fields.
+func (e *Entry) Debug(format string, v ...interface{}) {
+	e.logger.writerMsg(LevelDebug, fmt.Sprintf(format, v...), e.fields)
+}
+
+// log info level message with fields.
+func (e *Entry) Info(format string, v ...interface{}) {
+	e.logger.writerMsg(LevelInfo, fmt.Sprintf(format, v...), e.fields)
+}
+
+// log warn level message with fields.
+func (e *Entry) Warn(format string, v ...interface{}) {
+	e.logger.writerMsg(LevelWarn, fmt.Sprintf(format, v...), e.fields)
+}
+
+// log error level message with fields.
+func (e *Entry) Error(format string, v ...interface{}) {
+	e.logger.writerMsg(LevelError, fmt.Sprintf(format, v...), e.fields)
+}
+
+// log critical level message with fields.
+func (e *Entry) Critical(format string, v ...interface{}) {
+	e.logger.writerMsg(LevelCritical, fmt.Sprintf(format, v...), e.fields)
+}
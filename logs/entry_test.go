@@ -0,0 +1,49 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntryFields(t *testing.T) {
+	log := NewLogger(100)
+	log.SetLogger("console", `{"format":"json"}`)
+	buf := new(bytes.Buffer)
+	log.outputs["console"].(*ConsoleWriter).out = buf
+
+	entry := log.WithFields(map[string]interface{}{"user": 1, "action": "login"})
+	entry.WithFields(map[string]interface{}{"user": 2, "level": "fake"}).Info("login")
+	entry.Info("again")
+	time.Sleep(100 * time.Millisecond)
+	log.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatal("should write one json object per record", buf.String())
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &m); err != nil {
+		t.Fatal("invalid json", lines[0], err)
+	}
+	if m["user"] != float64(2) || m["action"] != "login" {
+		t.Fatal("later fields should replace earlier ones", lines[0])
+	}
+	if m["level"] != "info" {
+		t.Fatal("fields should not replace level", lines[0])
+	}
+	m = nil
+	json.Unmarshal([]byte(lines[1]), &m)
+	if m["user"] != float64(1) {
+		t.Fatal("parent entry should not be changed", lines[1])
+	}
+}
+
+func TestEntryText(t *testing.T) {
+	r := &Record{Level: LevelInfo, Msg: "login", Fields: map[string]interface{}{"user": 1, "action": "login"}}
+	if s := r.Text(); s != "[I] login action=login user=1" {
+		t.Fatal("fields should be appended as k=v", s)
+	}
+}
@@ -1,13 +1,16 @@
 package logs
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -30,14 +33,28 @@ type FileLogWriter struct {
 
 	// Rotate daily
 	Daily          bool  `json:"daily"`
-	Maxdays        int64 `json:"maxdays`
+	Maxdays        int64 `json:"maxdays"`
 	daily_opendate int
 
 	Rotate bool `json:"rotate"`
 
+	// Keep at most Maxfiles rotated files, 0 means no limit
+	Maxfiles int `json:"maxfiles"`
+
+	// Gzip rotated files
+	Compress bool `json:"compress"`
+
+	rotateDate string // date and number of the last rotated file
+	rotateNum  int
+
+	cleanLock sync.Mutex     // Only one cleanup of rotated files at a time
+	cleanWg   sync.WaitGroup // Running cleanups
+
 	startLock sync.Mutex // Only one log can write to the file
 
 	Level int `json:"level"`
+
+	Format string `json:"format"`
 }
 
 // an *os.File writer with locker.
@@ -87,8 +104,15 @@ func NewFileWriter() LoggerInterface {
 //	"maxsize":1<<30,
 //	"daily":true,
 //	"maxdays":15,
-//	"rotate":true
+//	"rotate":true,
+//	"maxfiles":10,
+//	"compress":true,
+//	"format":"json"
 //	}
+// it rotates when any of maxlines, maxsize or the daily boundary is hit.
+// rotated files older than maxdays or beyond the newest maxfiles are deleted,
+// they are gzipped to xx.log.2013-01-01.001.gz if compress is true.
+// format is "text" by default, "json" writes every record as one json object per line.
 func (w *FileLogWriter) Init(jsonconfig string) error {
 	err := json.Unmarshal([]byte(jsonconfig), w)
 	if err != nil {
@@ -141,6 +165,24 @@ func (w *FileLogWriter) WriteMsg(msg string, level int) error {
 	return nil
 }
 
+// write record into file, as json if format is json.
+func (w *FileLogWriter) WriteRecord(r *Record) error {
+	if w.Format != "json" {
+		return w.WriteMsg(r.Text(), r.Level)
+	}
+	if r.Level < w.Level {
+		return nil
+	}
+	b, err := r.JSON()
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	w.docheck(len(b))
+	_, err = w.mw.Write(b)
+	return err
+}
+
 func (w *FileLogWriter) createLogFile() (*os.File, error) {
 	// Open the log file
 	fd, err := os.OpenFile(w.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
@@ -172,13 +214,22 @@ func (w *FileLogWriter) initFd() error {
 func (w *FileLogWriter) DoRotate() error {
 	_, err := os.Lstat(w.Filename)
 	if err == nil { // file exists
-		// Find the next available number
+		// Find the next available number,
+		// after the last one of today so numbers of deleted files aren't reused.
+		date := time.Now().Format("2006-01-02")
 		num := 1
+		if date == w.rotateDate {
+			num = w.rotateNum + 1
+		}
 		fname := ""
 		for ; err == nil && num <= 999; num++ {
-			fname = w.Filename + fmt.Sprintf(".%s.%03d", time.Now().Format("2006-01-02"), num)
-			_, err = os.Lstat(fname)
+			fname = w.Filename + fmt.Sprintf(".%s.%03d", date, num)
+			if _, err = os.Lstat(fname); err != nil {
+				// the number is taken by the compressed file too
+				_, err = os.Lstat(fname + gzipSuffix)
+			}
 		}
+		w.rotateDate, w.rotateNum = date, num-1
 		// return error if the last file checked still existed
 		if err == nil {
 			return fmt.Errorf("Rotate: Cannot find free log number to rename %s\n", w.Filename)
@@ -204,26 +255,119 @@ func (w *FileLogWriter) DoRotate() error {
 			return fmt.Errorf("Rotate StartLogger: %s\n", err)
 		}
 
-		go w.deleteOldLog()
+		w.cleanWg.Add(1)
+		go w.cleanRotated()
 	}
 
 	return nil
 }
 
-func (w *FileLogWriter) deleteOldLog() {
-	dir := filepath.Dir(w.Filename)
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() && info.ModTime().Unix() < (time.Now().Unix()-60*60*24*w.Maxdays) {
-			if strings.HasPrefix(filepath.Base(path), filepath.Base(w.Filename)) {
-				os.Remove(path)
+// suffix of compressed rotated files.
+const gzipSuffix = ".gz"
+
+// compress rotated files and delete old ones, the logger isn't blocked meanwhile.
+func (w *FileLogWriter) cleanRotated() {
+	defer w.cleanWg.Done()
+	w.cleanLock.Lock()
+	defer w.cleanLock.Unlock()
+	if w.Compress {
+		for _, name := range w.rotatedFiles() {
+			if strings.HasSuffix(name, gzipSuffix) {
+				continue
+			}
+			if err := gzipFile(name); err != nil {
+				fmt.Fprintf(os.Stderr, "FileLogWriter(%q): compress %s\n", w.Filename, err)
 			}
 		}
+	}
+	w.deleteOldLog()
+}
+
+// get paths of rotated files.
+func (w *FileLogWriter) rotatedFiles() []string {
+	dir := filepath.Dir(w.Filename)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
 		return nil
+	}
+	prefix := filepath.Base(w.Filename) + "."
+	var names []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasPrefix(info.Name(), prefix) {
+			names = append(names, filepath.Join(dir, info.Name()))
+		}
+	}
+	return names
+}
+
+// delete rotated files older than Maxdays and beyond the newest Maxfiles.
+func (w *FileLogWriter) deleteOldLog() {
+	type rotatedFile struct {
+		name    string
+		modTime time.Time
+	}
+	var rotated []rotatedFile
+	for _, name := range w.rotatedFiles() {
+		info, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		if w.Maxdays > 0 && info.ModTime().Unix() < (time.Now().Unix()-60*60*24*w.Maxdays) {
+			os.Remove(name)
+			continue
+		}
+		rotated = append(rotated, rotatedFile{name, info.ModTime()})
+	}
+	if w.Maxfiles <= 0 || len(rotated) <= w.Maxfiles {
+		return
+	}
+	// oldest first, names of the same time are ordered by date and number
+	sort.Slice(rotated, func(i, j int) bool {
+		if !rotated[i].modTime.Equal(rotated[j].modTime) {
+			return rotated[i].modTime.Before(rotated[j].modTime)
+		}
+		return rotated[i].name < rotated[j].name
 	})
+	for _, f := range rotated[:len(rotated)-w.Maxfiles] {
+		os.Remove(f.name)
+	}
+}
+
+// gzip file to file.gz and remove it, file.gz keeps the modification time.
+func gzipFile(fname string) error {
+	src, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(fname+gzipSuffix, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0660)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(fname + gzipSuffix)
+		return err
+	}
+	src.Close()
+	os.Chtimes(fname+gzipSuffix, info.ModTime(), info.ModTime())
+	return os.Remove(fname)
 }
 
 // destroy file logger, close file writer.
+// rotated files being compressed are finished first.
 func (w *FileLogWriter) Destroy() {
+	w.cleanWg.Wait()
 	w.mw.fd.Close()
 }
 
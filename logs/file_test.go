@@ -2,8 +2,14 @@ package logs
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -108,3 +114,90 @@ func BenchmarkFile(b *testing.B) {
 	}
 	os.Remove("test4.log")
 }
+
+func TestFileJSON(t *testing.T) {
+	log := NewLogger(10000)
+	log.SetLogger("file", `{"filename":"test_json.log","format":"json"}`)
+	log.Warn("warning")
+	log.Error("error")
+	time.Sleep(100 * time.Millisecond)
+	log.Close()
+	defer os.Remove("test_json.log")
+
+	f, err := os.Open("test_json.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b := bufio.NewReader(f)
+	levels := []string{"warn", "error"}
+	linenum := 0
+	for {
+		line, _, err := b.ReadLine()
+		if err != nil {
+			break
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(line, &m); err != nil {
+			t.Fatal("invalid json", string(line), err)
+		}
+		if linenum >= len(levels) || m["level"] != levels[linenum] {
+			t.Fatal("wrong level name", string(line))
+		}
+		linenum++
+	}
+	if linenum != 2 {
+		t.Fatal(linenum, "not line 2")
+	}
+}
+
+func TestFileRotateCompress(t *testing.T) {
+	w := NewFileWriter().(*FileLogWriter)
+	if err := w.Init(`{"filename":"test_gzip.log","maxsize":200,"daily":false,"maxfiles":2,"compress":true}`); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		names, _ := filepath.Glob("test_gzip.log*")
+		for _, name := range names {
+			os.Remove(name)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				w.WriteMsg("rotate by size message", LevelInfo)
+			}
+		}()
+	}
+	wg.Wait()
+	w.Destroy()
+
+	rotated, _ := filepath.Glob("test_gzip.log.*")
+	if len(rotated) != 2 {
+		t.Fatal("should keep 2 rotated files", rotated)
+	}
+	for _, name := range rotated {
+		if filepath.Ext(name) != ".gz" {
+			t.Fatal("rotated file should be gzipped", name)
+		}
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) < 2 || b[0] != 0x1f || b[1] != 0x8b {
+			t.Fatal("wrong gzip header", name)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(zr)
+		if err != nil || !bytes.Contains(content, []byte("rotate by size message")) {
+			t.Fatal("wrong gzip content", name, err)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"path"
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -45,27 +47,30 @@ func Register(name string, log loggerType) {
 // BeeLogger is default logger in beego application.
 // it can contain several providers and log message into all providers.
 type BeeLogger struct {
+	dropped             int64 // first field, 64 bit aligned for atomic
 	lock                sync.Mutex
 	level               int
 	enableFuncCallDepth bool
 	loggerFuncCallDepth int
-	msg                 chan *logMsg
+	callDepth           int // frames of wrappers skipped above the caller
+	msg                 chan *Record
 	outputs             map[string]LoggerInterface
-}
-
-type logMsg struct {
-	level int
-	msg   string
+	levels              map[string]int // min level of adapters
+	drop                bool
+	flushing            chan chan struct{}
 }
 
 // NewLogger returns a new BeeLogger.
 // channellen means the number of messages in chan.
-// if the buffering chan is full, logger adapters write to file or other way.
+// messages are written to adapters by a goroutine reading the chan,
+// logging blocks if the chan is full unless the overflow policy is drop.
 func NewLogger(channellen int64) *BeeLogger {
 	bl := new(BeeLogger)
 	bl.loggerFuncCallDepth = 2
-	bl.msg = make(chan *logMsg, channellen)
+	bl.msg = make(chan *Record, channellen)
+	bl.flushing = make(chan chan struct{})
 	bl.outputs = make(map[string]LoggerInterface)
+	bl.levels = make(map[string]int)
 	//bl.SetLogger("console", "") // default output to console
 	go bl.startLogger()
 	return bl
@@ -97,44 +102,107 @@ func (bl *BeeLogger) DelLogger(adaptername string) error {
 	if lg, ok := bl.outputs[adaptername]; ok {
 		lg.Destroy()
 		delete(bl.outputs, adaptername)
+		delete(bl.levels, adaptername)
 		return nil
 	} else {
 		return fmt.Errorf("logs: unknown adaptername %q (forgotten Register?)", adaptername)
 	}
 }
 
-func (bl *BeeLogger) writerMsg(loglevel int, msg string) error {
+func (bl *BeeLogger) writerMsg(loglevel int, msg string, fields map[string]interface{}) error {
 	if bl.level > loglevel {
 		return nil
 	}
-	lm := new(logMsg)
-	lm.level = loglevel
+	lm := new(Record)
+	lm.Time = time.Now()
+	lm.Level = loglevel
+	lm.Msg = msg
+	lm.Fields = fields
 	if bl.enableFuncCallDepth {
-		_, file, line, ok := runtime.Caller(bl.loggerFuncCallDepth)
+		_, file, line, ok := runtime.Caller(bl.loggerFuncCallDepth + bl.callDepth)
 		if ok {
-			_, filename := path.Split(file)
-			lm.msg = fmt.Sprintf("[%s:%d] %s", filename, line, msg)
-		} else {
-			lm.msg = msg
+			_, lm.File = path.Split(file)
+			lm.Line = line
 		}
-	} else {
-		lm.msg = msg
+	}
+	if bl.drop {
+		select {
+		case bl.msg <- lm:
+		default:
+			atomic.AddInt64(&bl.dropped, 1)
+		}
+		return nil
 	}
 	bl.msg <- lm
 	return nil
 }
 
+// write record to all adapters, those with higher level than record are skipped.
+func (bl *BeeLogger) writeRecord(r *Record) {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	for name, l := range bl.outputs {
+		if r.Level < bl.levels[name] {
+			continue
+		}
+		if rw, ok := l.(RecordWriter); ok {
+			rw.WriteRecord(r)
+		} else {
+			l.WriteMsg(r.Text(), r.Level)
+		}
+	}
+}
+
 // set log message level.
 // if message level (such as LevelTrace) is less than logger level (such as LevelWarn), ignore message.
+// it applies to all adapters, SetLoggerLevel filters messages of one adapter further.
 func (bl *BeeLogger) SetLevel(l int) {
 	bl.level = l
 }
 
+// set min message level of the logger adapter, e.g. console at LevelDebug, file at LevelInfo and smtp at LevelError.
+// one message is written to every adapter with level not greater than it.
+func (bl *BeeLogger) SetLoggerLevel(adaptername string, l int) error {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	if _, ok := bl.outputs[adaptername]; !ok {
+		return fmt.Errorf("logs: unknown adaptername %q (forgotten SetLogger?)", adaptername)
+	}
+	bl.levels[adaptername] = l
+	return nil
+}
+
+// set what logging does if the chan is full, "block" waits for adapters (default),
+// "drop" discards the message and counts it in Dropped, so slow adapters don't slow down requests.
+func (bl *BeeLogger) SetOverflowPolicy(policy string) error {
+	switch policy {
+	case "block":
+		bl.drop = false
+	case "drop":
+		bl.drop = true
+	default:
+		return fmt.Errorf("logs: unknown overflow policy %q", policy)
+	}
+	return nil
+}
+
+// get count of messages dropped because the chan was full.
+func (bl *BeeLogger) Dropped() int64 {
+	return atomic.LoadInt64(&bl.dropped)
+}
+
 // set log funcCallDepth
 func (bl *BeeLogger) SetLogFuncCallDepth(d int) {
 	bl.loggerFuncCallDepth = d
 }
 
+// set count of wrapper functions between the caller and the log method,
+// so file and line of records point at the caller of the wrappers instead of them.
+// e.g. 1 for a helper calling Info, it's added to the func call depth.
+func (bl *BeeLogger) SetCallDepth(n int) {
+	bl.callDepth = n
+}
+
 // enable log funcCallDepth
 func (bl *BeeLogger) EnableFuncCallDepth(b bool) {
 	bl.enableFuncCallDepth = b
@@ -146,51 +214,64 @@ func (bl *BeeLogger) startLogger() {
 	for {
 		select {
 		case bm := <-bl.msg:
-			for _, l := range bl.outputs {
-				l.WriteMsg(bm.msg, bm.level)
+			bl.writeRecord(bm)
+		case done := <-bl.flushing:
+			for len(bl.msg) > 0 {
+				bl.writeRecord(<-bl.msg)
 			}
+			close(done)
 		}
 	}
 }
 
+// wait until messages in chan are written by the logger goroutine.
+func (bl *BeeLogger) drain() {
+	done := make(chan struct{})
+	bl.flushing <- done
+	<-done
+}
+
 // log trace level message.
 func (bl *BeeLogger) Trace(format string, v ...interface{}) {
-	msg := fmt.Sprintf("[T] "+format, v...)
-	bl.writerMsg(LevelTrace, msg)
+	msg := fmt.Sprintf(format, v...)
+	bl.writerMsg(LevelTrace, msg, nil)
 }
 
 // log debug level message.
 func (bl *BeeLogger) Debug(format string, v ...interface{}) {
-	msg := fmt.Sprintf("[D] "+format, v...)
-	bl.writerMsg(LevelDebug, msg)
+	msg := fmt.Sprintf(format, v...)
+	bl.writerMsg(LevelDebug, msg, nil)
 }
 
 // log info level message.
 func (bl *BeeLogger) Info(format string, v ...interface{}) {
-	msg := fmt.Sprintf("[I] "+format, v...)
-	bl.writerMsg(LevelInfo, msg)
+	msg := fmt.Sprintf(format, v...)
+	bl.writerMsg(LevelInfo, msg, nil)
 }
 
 // log warn level message.
 func (bl *BeeLogger) Warn(format string, v ...interface{}) {
-	msg := fmt.Sprintf("[W] "+format, v...)
-	bl.writerMsg(LevelWarn, msg)
+	msg := fmt.Sprintf(format, v...)
+	bl.writerMsg(LevelWarn, msg, nil)
 }
 
 // log error level message.
 func (bl *BeeLogger) Error(format string, v ...interface{}) {
-	msg := fmt.Sprintf("[E] "+format, v...)
-	bl.writerMsg(LevelError, msg)
+	msg := fmt.Sprintf(format, v...)
+	bl.writerMsg(LevelError, msg, nil)
 }
 
 // log critical level message.
 func (bl *BeeLogger) Critical(format string, v ...interface{}) {
-	msg := fmt.Sprintf("[C] "+format, v...)
-	bl.writerMsg(LevelCritical, msg)
+	msg := fmt.Sprintf(format, v...)
+	bl.writerMsg(LevelCritical, msg, nil)
 }
 
-// flush all chan data.
+// flush all chan data, messages logged before are written to adapters in order.
 func (bl *BeeLogger) Flush() {
+	bl.drain()
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
 	for _, l := range bl.outputs {
 		l.Flush()
 	}
@@ -198,16 +279,9 @@ func (bl *BeeLogger) Flush() {
 
 // close logger, flush all chan data and destroy all adapters in BeeLogger.
 func (bl *BeeLogger) Close() {
-	for {
-		if len(bl.msg) > 0 {
-			bm := <-bl.msg
-			for _, l := range bl.outputs {
-				l.WriteMsg(bm.msg, bm.level)
-			}
-		} else {
-			break
-		}
-	}
+	bl.drain()
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
 	for _, l := range bl.outputs {
 		l.Flush()
 		l.Destroy()
@@ -0,0 +1,165 @@
+package logs
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// levelWriter records messages written to it.
+type levelWriter struct {
+	lock sync.Mutex
+	msgs []string
+}
+
+func (w *levelWriter) Init(config string) error { return nil }
+
+func (w *levelWriter) WriteMsg(msg string, level int) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.msgs = append(w.msgs, msg)
+	return nil
+}
+
+func (w *levelWriter) Destroy() {}
+
+func (w *levelWriter) Flush() {}
+
+var (
+	debugWriter = new(levelWriter)
+	errorWriter = new(levelWriter)
+)
+
+func init() {
+	Register("test_debug", func() LoggerInterface { return debugWriter })
+	Register("test_error", func() LoggerInterface { return errorWriter })
+}
+
+func TestSetLoggerLevel(t *testing.T) {
+	debugWriter.msgs, errorWriter.msgs = nil, nil
+	log := NewLogger(100)
+	log.SetLogger("test_debug", "")
+	log.SetLogger("test_error", "")
+	if err := log.SetLoggerLevel("test_debug", LevelDebug); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.SetLoggerLevel("test_error", LevelError); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.SetLoggerLevel("console", LevelError); err == nil {
+		t.Fatal("SetLoggerLevel should fail for adapter not set")
+	}
+	log.Trace("trace")
+	log.Debug("debug")
+	log.Error("error")
+	log.Close()
+
+	if len(debugWriter.msgs) != 2 || debugWriter.msgs[0] != "[D] debug" || debugWriter.msgs[1] != "[E] error" {
+		t.Fatal("debug adapter should get debug and error messages", debugWriter.msgs)
+	}
+	if len(errorWriter.msgs) != 1 || errorWriter.msgs[0] != "[E] error" {
+		t.Fatal("error adapter should get error message only", errorWriter.msgs)
+	}
+}
+
+// stalledWriter blocks in WriteMsg until release is closed.
+type stalledWriter struct {
+	levelWriter
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (w *stalledWriter) WriteMsg(msg string, level int) error {
+	select {
+	case w.entered <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return w.levelWriter.WriteMsg(msg, level)
+}
+
+var stalled = &stalledWriter{}
+
+func init() {
+	Register("test_stalled", func() LoggerInterface { return stalled })
+}
+
+func TestOverflowBlock(t *testing.T) {
+	debugWriter.msgs = nil
+	log := NewLogger(1)
+	log.SetLogger("test_debug", "")
+	for i := 0; i < 100; i++ {
+		log.Info("%d", i)
+	}
+	log.Flush()
+	if len(debugWriter.msgs) != 100 {
+		t.Fatal("flush should write all messages, got", len(debugWriter.msgs))
+	}
+	for i, msg := range debugWriter.msgs {
+		if msg != fmt.Sprintf("[I] %d", i) {
+			t.Fatal("messages should be written in order", i, msg)
+		}
+	}
+	if log.Dropped() != 0 {
+		t.Fatal("block policy should not drop messages")
+	}
+	if err := log.SetOverflowPolicy("discard"); err == nil {
+		t.Fatal("unknown policy should return error")
+	}
+}
+
+func TestOverflowDrop(t *testing.T) {
+	stalled.msgs = nil
+	stalled.entered, stalled.release = make(chan struct{}, 1), make(chan struct{})
+	log := NewLogger(2)
+	log.SetLogger("test_stalled", "")
+	if err := log.SetOverflowPolicy("drop"); err != nil {
+		t.Fatal(err)
+	}
+	log.Info("first")
+	<-stalled.entered
+	// first is being written, 2 fit in chan, the rest is dropped.
+	for i := 0; i < 9; i++ {
+		log.Info("%d", i)
+	}
+	if log.Dropped() != 7 {
+		t.Fatal("7 messages should be dropped, got", log.Dropped())
+	}
+	close(stalled.release)
+	log.Close()
+	if len(stalled.msgs) != 3 || stalled.msgs[0] != "[I] first" || stalled.msgs[2] != "[I] 1" {
+		t.Fatal("close should write buffered messages", stalled.msgs)
+	}
+}
+
+func logVia(log *BeeLogger, msg string) {
+	logViaHelper(log, msg)
+}
+
+func logViaHelper(log *BeeLogger, msg string) {
+	log.Info("%s", msg)
+}
+
+func TestSetCallDepth(t *testing.T) {
+	debugWriter.msgs = nil
+	log := NewLogger(100)
+	log.SetLogger("test_debug", "")
+	log.EnableFuncCallDepth(true)
+	_, _, line, _ := runtime.Caller(0)
+	logVia(log, "unadjusted")
+	log.SetCallDepth(2)
+	logVia(log, "wrapped")
+	log.Close()
+
+	if len(debugWriter.msgs) != 2 {
+		t.Fatal("2 messages should be written", debugWriter.msgs)
+	}
+	if msg := debugWriter.msgs[0]; msg == "[log_test.go:"+strconv.Itoa(line+1)+"] [I] unadjusted" {
+		t.Fatal("message without call depth should point at the wrapper", msg)
+	}
+	if want := "[log_test.go:" + strconv.Itoa(line+3) + "] [I] wrapped"; debugWriter.msgs[1] != want {
+		t.Fatal("wrapped message should point at the caller of the wrappers", debugWriter.msgs[1], want)
+	}
+}
@@ -0,0 +1,72 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// level names in json output.
+var levelNames = []string{"trace", "debug", "info", "warn", "error", "critical"}
+
+// level prefixes in text output.
+var levelPrefixes = []string{"[T] ", "[D] ", "[I] ", "[W] ", "[E] ", "[C] "}
+
+// Record is one log message with its context.
+// File and Line are only set if func call depth is enabled.
+type Record struct {
+	Time   time.Time
+	Level  int
+	Msg    string
+	File   string
+	Line   int
+	Fields map[string]interface{}
+}
+
+// RecordWriter is implemented by adapters those write structured records, e.g. as json.
+// BeeLogger calls WriteRecord instead of WriteMsg for them.
+type RecordWriter interface {
+	WriteRecord(r *Record) error
+}
+
+// Text returns the record as text line, e.g. "[log.go:95] [I] message user=1".
+// fields are appended as k=v sorted by key.
+func (r *Record) Text() string {
+	msg := levelPrefixes[r.Level] + r.Msg
+	if r.File != "" {
+		msg = fmt.Sprintf("[%s:%d] %s", r.File, r.Line, msg)
+	}
+	if len(r.Fields) > 0 {
+		keys := make([]string, 0, len(r.Fields))
+		for k := range r.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			msg += fmt.Sprintf(" %s=%v", k, r.Fields[k])
+		}
+	}
+	return msg
+}
+
+// JSON returns the record as json object,
+// e.g. {"time":"2014-01-02T15:04:05+08:00","level":"info","msg":"message","file":"log.go","line":95}.
+// fields are added as keys, but they can't replace the keys above.
+func (r *Record) JSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(r.Fields)+5)
+	for k, v := range r.Fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		m[k] = v
+	}
+	m["time"] = r.Time.Format(time.RFC3339)
+	m["level"] = levelNames[r.Level]
+	m["msg"] = r.Msg
+	if r.File != "" {
+		m["file"] = r.File
+		m["line"] = r.Line
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,185 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// display width of mysql integer types, e.g. int(11).
+var mysqlIntWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|integer|bigint)\(\d+\)`)
+
+// live schema of the tables of registered models.
+type dbSchema struct {
+	tables  map[string]bool
+	columns map[string]map[string][3]string // table => column => name, type, is nullable
+	indexes map[string]bool                 // table.index of existing model indexes
+}
+
+// read tables, columns and the indexes of registered models from database.
+func loadDbSchema(al *alias, indexes map[string][]dbIndex) (*dbSchema, error) {
+	db := al.DB
+	tables, err := al.DbBaser.GetTables(db)
+	if err != nil {
+		return nil, err
+	}
+	schema := &dbSchema{
+		tables:  tables,
+		columns: make(map[string]map[string][3]string),
+		indexes: make(map[string]bool),
+	}
+	for _, mi := range modelCache.allOrdered() {
+		if !tables[mi.table] {
+			continue
+		}
+		columns, err := al.DbBaser.GetColumns(db, mi.table)
+		if err != nil {
+			return nil, err
+		}
+		schema.columns[mi.table] = columns
+		for _, idx := range indexes[mi.table] {
+			if al.DbBaser.IndexExists(db, idx.Table, idx.Name) {
+				schema.indexes[idx.Table+"."+idx.Name] = true
+			}
+		}
+	}
+	return schema, nil
+}
+
+// GenerateMigration returns the ddl migrating the database of alias name to the registered models.
+// tables missing in database are created, missing columns are added,
+// columns with another type or nullability are altered (mysql and postgres only) and missing indexes are created.
+// columns not in models are only dropped if drop is true.
+// the ddl isn't executed, review it before running it, e.g. by Raw(query).Exec().
+func GenerateMigration(name string, drop ...bool) ([]string, error) {
+	BootStrap()
+
+	al, ok := dataBaseCache.get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown DataBase alias name %s", name)
+	}
+	if len(modelCache.cache) == 0 {
+		return nil, errors.New("no Model found, need register your model")
+	}
+	_, indexes := getDbCreateSql(al)
+	schema, err := loadDbSchema(al, indexes)
+	if err != nil {
+		return nil, err
+	}
+	return getMigrationSql(al, schema, len(drop) > 0 && drop[0]), nil
+}
+
+// diff registered models against schema, return the ddl to reconcile them.
+func getMigrationSql(al *alias, schema *dbSchema, drop bool) (sqls []string) {
+	Q := al.DbBaser.TableQuote()
+	creates, indexes := getDbCreateSql(al)
+
+	for i, mi := range modelCache.allOrdered() {
+		if !schema.tables[mi.table] {
+			sqls = append(sqls, creates[i])
+			for _, idx := range indexes[mi.table] {
+				sqls = append(sqls, idx.Sql)
+			}
+			continue
+		}
+
+		columns := schema.columns[mi.table]
+		for _, fi := range mi.fields.fieldsDB {
+			column, ok := columns[fi.column]
+			if !ok {
+				sqls = append(sqls, getColumnAddQuery(al, fi))
+				continue
+			}
+			if fi.pk || fi.auto {
+				continue
+			}
+			sqls = append(sqls, getColumnAlterSql(al, fi, column[1], strings.EqualFold(column[2], "YES"))...)
+		}
+
+		for _, idx := range indexes[mi.table] {
+			if !schema.indexes[idx.Table+"."+idx.Name] {
+				sqls = append(sqls, idx.Sql)
+			}
+		}
+
+		if drop {
+			names := make([]string, 0, len(columns))
+			for name := range columns {
+				if _, ok := mi.fields.columns[name]; !ok {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s%s%s DROP COLUMN %s%s%s", Q, mi.table, Q, Q, name, Q))
+			}
+		}
+	}
+	return
+}
+
+// create sqls altering the column of fi if its type or nullability in database differ.
+func getColumnAlterSql(al *alias, fi *fieldInfo, typ string, null bool) (sqls []string) {
+	Q := al.DbBaser.TableQuote()
+	col := getColumnTyp(al, fi)
+	sameTyp := normalizeColumnTyp(al.Driver, col) == normalizeColumnTyp(al.Driver, typ)
+	alter := fmt.Sprintf("ALTER TABLE %s%s%s ", Q, fi.mi.table, Q)
+
+	switch al.Driver {
+	case DR_MySQL:
+		if sameTyp && null == fi.null {
+			return
+		}
+		if fi.null == false {
+			col += " " + "NOT NULL"
+		}
+		sqls = append(sqls, fmt.Sprintf("%sMODIFY COLUMN %s%s%s %s", alter, Q, fi.column, Q, col))
+	case DR_Postgres:
+		alter += fmt.Sprintf("ALTER COLUMN %s%s%s ", Q, fi.column, Q)
+		if !sameTyp {
+			// check constraints of the type are kept by the existing column.
+			if i := strings.Index(col, " CHECK("); i != -1 {
+				col = col[:i]
+			}
+			sqls = append(sqls, alter+"TYPE "+col)
+		}
+		if null != fi.null {
+			if fi.null {
+				sqls = append(sqls, alter+"DROP NOT NULL")
+			} else {
+				sqls = append(sqls, alter+"SET NOT NULL")
+			}
+		}
+	}
+	return
+}
+
+// reduce column type to the form database reports, e.g. mysql integer and int(11) are both int.
+func normalizeColumnTyp(driver DriverType, typ string) string {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if i := strings.Index(typ, " check("); i != -1 {
+		typ = typ[:i]
+	}
+	typ = strings.Replace(typ, ", ", ",", -1)
+
+	switch driver {
+	case DR_MySQL:
+		if typ == "bool" || typ == "boolean" || typ == "tinyint(1)" {
+			return "tinyint(1)"
+		}
+		typ = mysqlIntWidth.ReplaceAllString(typ, "$1")
+		typ = strings.Replace(typ, "integer", "int", 1)
+		typ = strings.Replace(typ, "double precision", "double", 1)
+		typ = strings.Replace(typ, "numeric", "decimal", 1)
+	case DR_Postgres:
+		if typ == "bool" {
+			return "boolean"
+		}
+		if strings.HasPrefix(typ, "varchar") {
+			typ = "character varying" + typ[len("varchar"):]
+		}
+	}
+	return typ
+}
@@ -73,6 +73,12 @@ checkColumn:
 		} else {
 			col = fmt.Sprintf(s, fi.digits, fi.decimals)
 		}
+	case TypeJSONField:
+		if fi.jsonb {
+			col = T["jsonb"]
+		} else {
+			col = T["json"]
+		}
 	case RelForeignKey, RelOneToOne:
 		fieldType = fi.relModelInfo.fields.pk.fieldType
 		goto checkColumn
@@ -90,6 +96,10 @@ func getColumnAddQuery(al *alias, fi *fieldInfo) string {
 		typ += " " + "NOT NULL"
 	}
 
+	if strings.Index(typ, "%COL%") != -1 {
+		typ = strings.Replace(typ, "%COL%", fi.column, -1)
+	}
+
 	return fmt.Sprintf("ALTER TABLE %s%s%s ADD COLUMN %s%s%s %s", Q, fi.mi.table, Q, Q, fi.column, Q, typ)
 }
 
@@ -2,6 +2,7 @@ package orm
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -137,9 +138,19 @@ func (d *dbBase) collectFieldValue(mi *modelInfo, fi *fieldInfo, ind reflect.Val
 			case TypeDateField, TypeDateTimeField:
 				value = field.Interface()
 				if t, ok := value.(time.Time); ok {
+					if fi.softDelete && t.IsZero() {
+						value = nil
+						break
+					}
 					d.ins.TimeToDB(&t, tz)
 					value = t
 				}
+			case TypeJSONField:
+				v, err := getJSONValue(fi, field.Interface())
+				if err != nil {
+					return nil, err
+				}
+				value = v
 			default:
 				switch {
 				case fi.fieldType&IsPostiveIntegerField > 0:
@@ -187,6 +198,22 @@ func (d *dbBase) collectFieldValue(mi *modelInfo, fi *fieldInfo, ind reflect.Val
 					field.Set(reflect.ValueOf(tnow.In(DefaultTimeLoc)))
 				}
 			}
+		default:
+			// integer auto_now fields are unix timestamp in seconds.
+			if fi.fieldType&IsIntegerField > 0 && (fi.auto_now || fi.auto_now_add && insert) {
+				if insert && value != nil && ToStr(value) != "0" {
+					break
+				}
+				tnow := time.Now().Unix()
+				if fi.fieldType&IsPostiveIntegerField > 0 {
+					value = uint64(tnow)
+				} else {
+					value = tnow
+				}
+				if _, err := d.setFieldValue(fi, value, field); err != nil {
+					return nil, err
+				}
+			}
 		}
 	}
 	return value, nil
@@ -322,6 +349,7 @@ func (d *dbBase) InsertMulti(q dbQuerier, mi *modelInfo, sind reflect.Value, bul
 	// typ := reflect.Indirect(mi.addrField).Type()
 
 	length := sind.Len()
+	start := 0
 
 	for i := 1; i <= length; i++ {
 
@@ -355,41 +383,85 @@ func (d *dbBase) InsertMulti(q dbQuerier, mi *modelInfo, sind reflect.Value, bul
 		}
 
 		if i > 1 && i%bulk == 0 || length == i {
-			num, err := d.InsertValue(q, mi, true, names, values[:nums])
+			num, ids, err := d.InsertRows(q, mi, names, values[:nums], i-start)
 			if err != nil {
 				return cnt, err
 			}
+			// set auto pk values back to the inserted structs, if database reports them.
+			if len(ids) == i-start {
+				for j, id := range ids {
+					ind := reflect.Indirect(sind.Index(start + j))
+					if ind.CanSet() {
+						setAutoPk(mi, ind, id)
+					}
+				}
+			}
 			cnt += num
 			nums = 0
+			start = i
 		}
 	}
 
 	return cnt, nil
 }
 
-// execute insert sql with given struct and given values.
-// insert the given values, not the field values in struct.
-func (d *dbBase) InsertValue(q dbQuerier, mi *modelInfo, isMulti bool, names []string, values []interface{}) (int64, error) {
-	Q := d.ins.TableQuote()
+// execute one multi-row insert sql with given values of rows.
+// it also returns pk values of inserted rows if pk is auto,
+// they are read by RETURNING if supported, or computed from LastInsertId.
+// ids is nil if database doesn't report LastInsertId.
+func (d *dbBase) InsertRows(q dbQuerier, mi *modelInfo, names []string, values []interface{}, rows int) (int64, []int64, error) {
+	query := d.insertQuery(mi, names, rows)
 
-	marks := make([]string, len(names))
-	for i, _ := range marks {
-		marks[i] = "?"
+	if mi.fields.pk.auto && d.ins.HasReturningID(mi, &query) {
+		rs, err := q.Query(query, values...)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer rs.Close()
+		ids := make([]int64, 0, rows)
+		for rs.Next() {
+			var id int64
+			if err := rs.Scan(&id); err != nil {
+				return 0, nil, err
+			}
+			ids = append(ids, id)
+		}
+		return int64(len(ids)), ids, rs.Err()
 	}
 
-	sep := fmt.Sprintf("%s, %s", Q, Q)
-	qmarks := strings.Join(marks, ", ")
-	columns := strings.Join(names, sep)
+	res, err := q.Exec(query, values...)
+	if err != nil {
+		return 0, nil, err
+	}
+	cnt, err := res.RowsAffected()
+	if err != nil || !mi.fields.pk.auto {
+		return cnt, nil, err
+	}
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return cnt, nil, nil
+	}
+	return cnt, d.ins.MultiInsertIds(lastId, rows), nil
+}
 
-	multi := len(values) / len(names)
+// get pk values of rows inserted by one sql from its LastInsertId, which is the id of first row.
+func (d *dbBase) MultiInsertIds(lastId int64, rows int) []int64 {
+	ids := make([]int64, rows)
+	for i := range ids {
+		ids[i] = lastId + int64(i)
+	}
+	return ids
+}
 
+// execute insert sql with given struct and given values.
+// insert the given values, not the field values in struct.
+func (d *dbBase) InsertValue(q dbQuerier, mi *modelInfo, isMulti bool, names []string, values []interface{}) (int64, error) {
+	multi := 1
 	if isMulti {
-		qmarks = strings.Repeat(qmarks+"), (", multi-1) + qmarks
+		multi = len(values) / len(names)
 	}
 
-	query := fmt.Sprintf("INSERT INTO %s%s%s (%s%s%s) VALUES (%s)", Q, mi.table, Q, Q, columns, Q, qmarks)
-
-	d.ins.ReplaceMarks(&query)
+	query := d.insertQuery(mi, names, multi)
 
 	if isMulti || !d.ins.HasReturningID(mi, &query) {
 		if res, err := q.Exec(query, values...); err == nil {
@@ -408,6 +480,29 @@ func (d *dbBase) InsertValue(q dbQuerier, mi *modelInfo, isMulti bool, names []s
 	}
 }
 
+// generate insert sql of given columns with rows of values,
+// marks are already replaced for database.
+func (d *dbBase) insertQuery(mi *modelInfo, names []string, rows int) string {
+	Q := d.ins.TableQuote()
+
+	marks := make([]string, len(names))
+	for i, _ := range marks {
+		marks[i] = "?"
+	}
+
+	sep := fmt.Sprintf("%s, %s", Q, Q)
+	qmarks := strings.Join(marks, ", ")
+	columns := strings.Join(names, sep)
+
+	qmarks = strings.Repeat(qmarks+"), (", rows-1) + qmarks
+
+	query := fmt.Sprintf("INSERT INTO %s%s%s (%s%s%s) VALUES (%s)", Q, mi.table, Q, Q, columns, Q, qmarks)
+
+	d.ins.ReplaceMarks(&query)
+
+	return query
+}
+
 // execute update sql dbQuerier with given struct reflect.Value.
 func (d *dbBase) Update(q dbQuerier, mi *modelInfo, ind reflect.Value, tz *time.Location, cols []string) (int64, error) {
 	pkName, pkValue, ok := getExistPk(mi, ind)
@@ -422,6 +517,7 @@ func (d *dbBase) Update(q dbQuerier, mi *modelInfo, ind reflect.Value, tz *time.
 		cols = mi.fields.dbcols
 		setNames = make([]string, 0, len(mi.fields.dbcols)-1)
 	} else {
+		cols = appendAutoNowCols(mi, cols)
 		setNames = make([]string, 0, len(cols))
 	}
 
@@ -501,6 +597,13 @@ func (d *dbBase) UpdateBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Con
 		if fi, ok := mi.fields.GetByAny(col); ok == false || fi.dbcol == false {
 			panic(fmt.Errorf("wrong field/column name `%s`", col))
 		} else {
+			if fi.fieldType == TypeJSONField && fi.isFielder == false {
+				v, err := getJSONValue(fi, val)
+				if err != nil {
+					return 0, err
+				}
+				val = v
+			}
 			columns = append(columns, fi.column)
 			values = append(values, val)
 		}
@@ -533,21 +636,9 @@ func (d *dbBase) UpdateBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Con
 
 	for i, v := range columns {
 		col := fmt.Sprintf("%s%s%s%s", T, Q, v, Q)
-		if c, ok := values[i].(colValue); ok {
-			switch c.opt {
-			case Col_Add:
-				cols = append(cols, col+" = "+col+" + ?")
-			case Col_Minus:
-				cols = append(cols, col+" = "+col+" - ?")
-			case Col_Multiply:
-				cols = append(cols, col+" = "+col+" * ?")
-			case Col_Except:
-				cols = append(cols, col+" = "+col+" / ?")
-			}
-			values[i] = c.value
-		} else {
-			cols = append(cols, col+" = ?")
-		}
+		var set string
+		set, values[i] = getColumnSetSql(col, values[i])
+		cols = append(cols, set)
 	}
 
 	sets := strings.Join(cols, ", ") + " "
@@ -906,6 +997,33 @@ func (d *dbBase) Count(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition
 	return
 }
 
+// excute select of one matched row and return whether it's found.
+// it stops at the first row, unlike counting all of them.
+func (d *dbBase) Exists(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition, tz *time.Location) (bool, error) {
+	tables := newDbTables(mi, d.ins)
+	tables.parseRelated(qs.related, qs.relDepth)
+
+	where, args := tables.getCondSql(cond, false, tz)
+	join := tables.getJoinSql()
+	limit := tables.getLimitSql(mi, 0, 1)
+
+	Q := d.ins.TableQuote()
+
+	query := fmt.Sprintf("SELECT 1 FROM %s%s%s T0 %s%s%s", Q, mi.table, Q, join, where, limit)
+
+	d.ins.ReplaceMarks(&query)
+
+	rs, err := q.Query(query, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rs.Close()
+	if rs.Next() {
+		return true, nil
+	}
+	return false, rs.Err()
+}
+
 // generate sql with replacing operator string placeholders and replaced values.
 func (d *dbBase) GenerateOperatorSql(mi *modelInfo, fi *fieldInfo, operator string, args []interface{}, tz *time.Location) (string, []interface{}) {
 	sql := ""
@@ -1035,7 +1153,7 @@ setValue:
 			}
 			value = b
 		}
-	case fieldType == TypeCharField || fieldType == TypeTextField:
+	case fieldType == TypeCharField || fieldType == TypeTextField || fieldType == TypeJSONField:
 		if str == nil {
 			value = ToStr(val)
 		} else {
@@ -1195,6 +1313,18 @@ setValue:
 			}
 			field.Set(reflect.ValueOf(value))
 		}
+	case fieldType == TypeJSONField:
+		if isNative {
+			if value == nil {
+				field.Set(reflect.Zero(field.Type()))
+			} else {
+				v := reflect.New(field.Type())
+				if err := json.Unmarshal([]byte(value.(string)), v.Interface()); err != nil {
+					return nil, fmt.Errorf("json value `%v` unmarshal to `%s` failed, err: %s", value, fi.fullName, err)
+				}
+				field.Set(v.Elem())
+			}
+		}
 	case fieldType&IsIntegerField > 0:
 		if fieldType&IsPostiveIntegerField > 0 {
 			if isNative {
@@ -1525,6 +1655,30 @@ func (d *dbBase) GetColumns(db dbQuerier, table string) (map[string][3]string, e
 	return columns, nil
 }
 
+// get set expression of quoted col and its arg for UPDATE, ColValue is computed from col.
+func getColumnSetSql(col string, value interface{}) (string, interface{}) {
+	c, ok := value.(colValue)
+	if !ok {
+		return col + " = ?", value
+	}
+	var op string
+	switch c.opt {
+	case Col_Add:
+		op = "+"
+	case Col_Minus:
+		op = "-"
+	case Col_Multiply:
+		op = "*"
+	case Col_Except:
+		op = "/"
+	case Col_BitAnd:
+		op = "&"
+	case Col_BitOr:
+		op = "|"
+	}
+	return col + " = " + col + " " + op + " ?", c.value
+}
+
 // not implement.
 func (d *dbBase) OperatorSql(operator string) string {
 	panic(ErrNotImplement)
@@ -1544,3 +1698,8 @@ func (d *dbBase) ShowColumnsQuery(table string) string {
 func (d *dbBase) IndexExists(dbQuerier, string, string) bool {
 	panic(ErrNotImplement)
 }
+
+// advisory locks are not supported, queries are empty.
+func (d *dbBase) AdvisoryLockQueries() (string, string) {
+	return "", ""
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -83,16 +84,28 @@ func (ac *_dbCache) getDefault() (al *alias) {
 }
 
 type alias struct {
-	Name         string
-	Driver       DriverType
-	DriverName   string
-	DataSource   string
-	MaxIdleConns int
-	MaxOpenConns int
-	DB           *sql.DB
-	DbBaser      dbBaser
-	TZ           *time.Location
-	Engine       string
+	Name            string
+	Driver          DriverType
+	DriverName      string
+	DataSource      string
+	MaxIdleConns    int
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration // 0 is forever
+	DB              *sql.DB
+	DbBaser         dbBaser
+	TZ              *time.Location
+	Engine          string
+	Replicas        []*sql.DB // read only replicas of DB, used by QuerySeter reads
+	replicaNext     uint32
+}
+
+// get next replica round-robin, nil if alias has no replica.
+func (al *alias) replica() *sql.DB {
+	if len(al.Replicas) == 0 {
+		return nil
+	}
+	n := atomic.AddUint32(&al.replicaNext, 1)
+	return al.Replicas[int(n-1)%len(al.Replicas)]
 }
 
 func detectTZ(al *alias) {
@@ -180,6 +193,8 @@ func AddAliasWthDB(aliasName, driverName string, db *sql.DB) error {
 }
 
 // Setting the database connect params. Use the database driver self dataSource args.
+// params are max idle conns, max open conns and conn max lifetime in seconds,
+// they can be changed later by SetMaxIdleConns, SetMaxOpenConns and SetConnMaxLifetime.
 func RegisterDataBase(aliasName, driverName, dataSource string, params ...int) error {
 	var (
 		err error
@@ -208,6 +223,49 @@ func RegisterDataBase(aliasName, driverName, dataSource string, params ...int) e
 			SetMaxIdleConns(al.Name, v)
 		case 1:
 			SetMaxOpenConns(al.Name, v)
+		case 2:
+			SetConnMaxLifetime(al.Name, time.Duration(v)*time.Second)
+		}
+	}
+
+end:
+	if err != nil {
+		if db != nil {
+			db.Close()
+		}
+		DebugLog.Println(err.Error())
+	}
+
+	return err
+}
+
+// Register a read only replica for registered database alias aliasName, the master.
+// QuerySeter reads (All, One, Count, Values...) are sent to replicas round-robin,
+// inserts, updates, deletes and transactions always use the master.
+// params are max idle conns and max open conns of the replica like RegisterDataBase.
+func RegisterReplica(aliasName, driverName, dataSource string, params ...int) error {
+	var (
+		err error
+		db  *sql.DB
+	)
+
+	db, err = sql.Open(driverName, dataSource)
+	if err != nil {
+		err = fmt.Errorf("register replica of db `%s`, %s", aliasName, err.Error())
+		goto end
+	}
+
+	err = AddReplicaWthDB(aliasName, driverName, db)
+	if err != nil {
+		goto end
+	}
+
+	for i, v := range params {
+		switch i {
+		case 0:
+			db.SetMaxIdleConns(v)
+		case 1:
+			db.SetMaxOpenConns(v)
 		}
 	}
 
@@ -222,6 +280,27 @@ end:
 	return err
 }
 
+// Add *sql.DB as read only replica of registered database alias aliasName.
+// driverName must be of the same driver type as the master.
+func AddReplicaWthDB(aliasName, driverName string, db *sql.DB) error {
+	al, ok := dataBaseCache.get(aliasName)
+	if !ok {
+		return fmt.Errorf("DataBase alias name `%s` not registered", aliasName)
+	}
+	if dr, ok := drivers[driverName]; !ok || dr != al.Driver {
+		return fmt.Errorf("replica driver name `%s` doesn't match db `%s`", driverName, aliasName)
+	}
+	if err := db.Ping(); err != nil {
+		return fmt.Errorf("register replica Ping `%s`, %s", aliasName, err.Error())
+	}
+	dataBaseCache.mux.Lock()
+	defer dataBaseCache.mux.Unlock()
+	replicas := make([]*sql.DB, len(al.Replicas), len(al.Replicas)+1)
+	copy(replicas, al.Replicas)
+	al.Replicas = append(replicas, db)
+	return nil
+}
+
 // Register a database driver use specify driver name, this can be definition the driver is which database type.
 func RegisterDriver(driverName string, typ DriverType) error {
 	if t, ok := drivers[driverName]; ok == false {
@@ -244,14 +323,16 @@ func SetDataBaseTZ(aliasName string, tz *time.Location) error {
 	return nil
 }
 
-// Change the max idle conns for *sql.DB, use specify database alias name
+// Change the max idle conns for *sql.DB, use specify database alias name.
+// it takes effect immediately, idle conns exceeding it are closed.
 func SetMaxIdleConns(aliasName string, maxIdleConns int) {
 	al := getDbAlias(aliasName)
 	al.MaxIdleConns = maxIdleConns
 	al.DB.SetMaxIdleConns(maxIdleConns)
 }
 
-// Change the max open conns for *sql.DB, use specify database alias name
+// Change the max open conns for *sql.DB, use specify database alias name.
+// it takes effect immediately, queries wait for a free conn once it's reached, 0 is unlimited.
 func SetMaxOpenConns(aliasName string, maxOpenConns int) {
 	al := getDbAlias(aliasName)
 	al.MaxOpenConns = maxOpenConns
@@ -261,6 +342,16 @@ func SetMaxOpenConns(aliasName string, maxOpenConns int) {
 	}
 }
 
+// Change the max lifetime of conns for *sql.DB, use specify database alias name.
+// it takes effect immediately, expired conns are closed before reuse, 0 is forever.
+// set it below the connection timeout of the database server or proxy, e.g. mysql wait_timeout.
+// replicas registered by RegisterReplica are not changed.
+func SetConnMaxLifetime(aliasName string, d time.Duration) {
+	al := getDbAlias(aliasName)
+	al.ConnMaxLifetime = d
+	al.DB.SetConnMaxLifetime(d)
+}
+
 // Get *sql.DB from registered database by db alias name.
 // Use "default" as alias name if you not set.
 func GetDB(aliasNames ...string) (*sql.DB, error) {
@@ -41,6 +41,8 @@ var mysqlTypes = map[string]string{
 	"uint64":          "bigint unsigned",
 	"float64":         "double precision",
 	"float64-decimal": "numeric(%d, %d)",
+	"json":            "json",
+	"jsonb":           "json",
 }
 
 // mysql dbBaser implementation.
@@ -80,6 +82,20 @@ func (d *dbBaseMysql) IndexExists(db dbQuerier, table string, name string) bool
 	return cnt > 0
 }
 
+// queries trying to get and releasing named lock, names are up to 64 characters.
+func (d *dbBaseMysql) AdvisoryLockQueries() (string, string) {
+	return "SELECT GET_LOCK(?, 0)", "SELECT RELEASE_LOCK(?)"
+}
+
+// get pk values of rows inserted by one sql, mysql reports the id of first row.
+// they are only consecutive with some settings, so they are nil unless MysqlConsecutiveIds is on.
+func (d *dbBaseMysql) MultiInsertIds(lastId int64, rows int) []int64 {
+	if !MysqlConsecutiveIds {
+		return nil
+	}
+	return d.dbBase.MultiInsertIds(lastId, rows)
+}
+
 // create new mysql dbBaser.
 func newdbBaseMysql() dbBaser {
 	b := new(dbBaseMysql)
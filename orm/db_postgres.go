@@ -3,6 +3,7 @@ package orm
 import (
 	"fmt"
 	"strconv"
+	"time"
 )
 
 // postgresql operators.
@@ -40,6 +41,8 @@ var postgresTypes = map[string]string{
 	"uint64":          `bigint CHECK("%COL%" >= 0)`,
 	"float64":         "double precision",
 	"float64-decimal": "numeric(%d, %d)",
+	"json":            "json",
+	"jsonb":           "jsonb",
 }
 
 // postgresql dbBaser.
@@ -54,8 +57,27 @@ func (d *dbBasePostgres) OperatorSql(operator string) string {
 	return postgresOperators[operator]
 }
 
+// generate sql of operator, contains of jsonb field is the @> containment operator.
+// e.g. Filter("data__contains", map[string]interface{}{"tag": "go"}) is `data @> '{"tag":"go"}'`.
+func (d *dbBasePostgres) GenerateOperatorSql(mi *modelInfo, fi *fieldInfo, operator string, args []interface{}, tz *time.Location) (string, []interface{}) {
+	if isJSONBContains(fi, operator) {
+		if len(args) != 1 {
+			panic(fmt.Errorf("operator `%s` need 1 args not %d", operator, len(args)))
+		}
+		v, err := getJSONValue(fi, args[0])
+		if err != nil {
+			panic(err)
+		}
+		return "@> ?::jsonb", []interface{}{v}
+	}
+	return d.dbBase.GenerateOperatorSql(mi, fi, operator, args, tz)
+}
+
 // generate functioned sql string, such as contains(text).
 func (d *dbBasePostgres) GenerateOperatorLeftCol(fi *fieldInfo, operator string, leftCol *string) {
+	if isJSONBContains(fi, operator) {
+		return
+	}
 	switch operator {
 	case "contains", "startswith", "endswith":
 		*leftCol = fmt.Sprintf("%s::text", *leftCol)
@@ -64,6 +86,11 @@ func (d *dbBasePostgres) GenerateOperatorLeftCol(fi *fieldInfo, operator string,
 	}
 }
 
+// check operator is contains of jsonb field.
+func isJSONBContains(fi *fieldInfo, operator string) bool {
+	return operator == "contains" && fi.fieldType == TypeJSONField && fi.jsonb
+}
+
 // postgresql unsupports updating joined record.
 func (d *dbBasePostgres) SupportUpdateJoin() bool {
 	return false
@@ -123,8 +150,11 @@ func (d *dbBasePostgres) ShowTablesQuery() string {
 }
 
 // show table columns sql for postgresql.
+// types have their length or precision, e.g. character varying(255), so they can be diffed by GenerateMigration.
 func (d *dbBasePostgres) ShowColumnsQuery(table string) string {
-	return fmt.Sprintf("SELECT column_name, data_type, is_nullable FROM information_schema.columns where table_schema NOT IN ('pg_catalog', 'information_schema') and table_name = '%s'", table)
+	return fmt.Sprintf("SELECT column_name, CASE WHEN character_maximum_length IS NOT NULL THEN data_type || '(' || character_maximum_length || ')' "+
+		"WHEN data_type = 'numeric' AND numeric_precision IS NOT NULL THEN data_type || '(' || numeric_precision || ',' || numeric_scale || ')' "+
+		"ELSE data_type END, is_nullable FROM information_schema.columns where table_schema NOT IN ('pg_catalog', 'information_schema') and table_name = '%s'", table)
 }
 
 // get column types of postgresql.
@@ -141,6 +171,11 @@ func (d *dbBasePostgres) IndexExists(db dbQuerier, table string, name string) bo
 	return cnt > 0
 }
 
+// queries trying to get and releasing advisory lock of the key hash.
+func (d *dbBasePostgres) AdvisoryLockQueries() (string, string) {
+	return "SELECT pg_try_advisory_lock(hashtext(?))", "SELECT pg_advisory_unlock(hashtext(?))"
+}
+
 // create new postgresql dbBaser.
 func newdbBasePostgres() dbBaser {
 	b := new(dbBasePostgres)
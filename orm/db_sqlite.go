@@ -40,6 +40,8 @@ var sqliteTypes = map[string]string{
 	"uint64":          "bigint unsigned",
 	"float64":         "real",
 	"float64-decimal": "decimal",
+	"json":            "text",
+	"jsonb":           "text",
 }
 
 // sqlite dbBaser.
@@ -72,6 +74,16 @@ func (d *dbBaseSqlite) MaxLimit() uint64 {
 	return 9223372036854775807
 }
 
+// get pk values of rows inserted by one sql.
+// sqlite reports the id of last row.
+func (d *dbBaseSqlite) MultiInsertIds(lastId int64, rows int) []int64 {
+	ids := make([]int64, rows)
+	for i := range ids {
+		ids[i] = lastId - int64(rows-1-i)
+	}
+	return ids
+}
+
 // get column types in sqlite.
 func (d *dbBaseSqlite) DbTypes() map[string]string {
 	return sqliteTypes
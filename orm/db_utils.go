@@ -1,6 +1,7 @@
 package orm
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"time"
@@ -39,6 +40,75 @@ func getExistPk(mi *modelInfo, ind reflect.Value) (column string, value interfac
 	return
 }
 
+// set auto pk field value.
+func setAutoPk(mi *modelInfo, ind reflect.Value, id int64) {
+	if mi.fields.pk.auto {
+		if mi.fields.pk.fieldType&IsPostiveIntegerField > 0 {
+			ind.Field(mi.fields.pk.fieldIndex).SetUint(uint64(id))
+		} else {
+			ind.Field(mi.fields.pk.fieldIndex).SetInt(id)
+		}
+	}
+}
+
+// get pk value of model as int64.
+func getPkInt(mi *modelInfo, ind reflect.Value) int64 {
+	f := ind.Field(mi.fields.pk.fieldIndex)
+	switch f.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(f.Uint())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.Int()
+	}
+	return 0
+}
+
+// append auto_now fields missing in update columns, they are set on every update.
+func appendAutoNowCols(mi *modelInfo, cols []string) []string {
+	var autoNow []string
+outFor:
+	for _, fi := range mi.fields.fieldsDB {
+		if fi.auto_now == false {
+			continue
+		}
+		for _, col := range cols {
+			if f, ok := mi.fields.GetByAny(col); ok && f == fi {
+				continue outFor
+			}
+		}
+		autoNow = append(autoNow, fi.column)
+	}
+	if len(autoNow) == 0 {
+		return cols
+	}
+	return append(append(make([]string, 0, len(cols)+len(autoNow)), cols...), autoNow...)
+}
+
+// get json text of json field value, nil pointer, map or slice is NULL.
+// strings and []byte are taken as json text already.
+func getJSONValue(fi *fieldInfo, val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return nil, fmt.Errorf("field `%s` marshal json failed, %s", fi.fullName, err)
+	}
+	return string(b), nil
+}
+
 // get fields description as flatted string.
 func getFlatParams(fi *fieldInfo, args []interface{}, tz *time.Location) (params []interface{}) {
 
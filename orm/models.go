@@ -27,6 +27,7 @@ var (
 		"auto":         1,
 		"auto_now":     1,
 		"auto_now_add": 1,
+		"soft_delete":  1,
 		"size":         2,
 		"column":       2,
 		"default":      2,
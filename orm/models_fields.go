@@ -44,6 +44,9 @@ const (
 	// float64
 	TypeDecimalField
 
+	// struct, map or slice saved as json
+	TypeJSONField
+
 	RelForeignKey
 	RelOneToOne
 	RelManyToMany
@@ -54,7 +57,7 @@ const (
 const (
 	IsIntegerField        = ^-TypePositiveBigIntegerField >> 4 << 5
 	IsPostiveIntegerField = ^-TypePositiveBigIntegerField >> 8 << 9
-	IsRelField            = ^-RelReverseMany >> 14 << 15
+	IsRelField            = ^-RelReverseMany >> 15 << 16
 	IsFieldType           = ^-RelReverseMany<<1 + 1
 )
 
@@ -20,6 +20,7 @@ type fields struct {
 	fieldsReverse []*fieldInfo
 	fieldsDB      []*fieldInfo
 	rels          []*fieldInfo
+	softDelete    *fieldInfo
 	orders        []string
 	dbcols        []string
 }
@@ -48,6 +49,9 @@ func (f *fields) Add(fi *fieldInfo) (added bool) {
 	if fi.reverse {
 		f.fieldsReverse = append(f.fieldsReverse, fi)
 	}
+	if fi.softDelete {
+		f.softDelete = fi
+	}
 	return true
 }
 
@@ -106,6 +110,8 @@ type fieldInfo struct {
 	size                int
 	auto_now            bool
 	auto_now_add        bool
+	softDelete          bool
+	jsonb               bool
 	rel                 bool
 	reverse             bool
 	reverseField        string
@@ -215,6 +221,13 @@ checkType:
 			}
 		}
 
+		// any value encoding/json can marshal, saved as json text.
+		if tv := tags["type"]; tv == "json" || tv == "jsonb" {
+			fieldType = TypeJSONField
+			fi.jsonb = tv == "jsonb"
+			break checkType
+		}
+
 		fieldType, err = getFieldType(addrField)
 		if err != nil {
 			goto end
@@ -325,7 +338,7 @@ checkType:
 		} else {
 			fi.size = 255
 		}
-	case TypeTextField:
+	case TypeTextField, TypeJSONField:
 		fi.index = false
 		fi.unique = false
 	case TypeDateField, TypeDateTimeField:
@@ -334,6 +347,11 @@ checkType:
 		} else if attrs["auto_now_add"] {
 			fi.auto_now_add = true
 		}
+		// soft deleted rows have the deletion time, others are NULL.
+		if attrs["soft_delete"] {
+			fi.softDelete = true
+			fi.null = true
+		}
 	case TypeFloatField:
 	case TypeDecimalField:
 		d1 := digits
@@ -349,10 +367,34 @@ checkType:
 	default:
 		switch {
 		case fieldType&IsIntegerField > 0:
+			// unix timestamp columns, custom Fielder may not accept int64 value.
+			if fi.isFielder == false && (attrs["auto_now"] || attrs["auto_now_add"]) {
+				switch addrField.Elem().Kind() {
+				case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+				default:
+					err = fmt.Errorf("auto_now/auto_now_add integer field only support int, int32, int64, uint, uint32, uint64 but found `%s`", addrField.Elem().Kind())
+					goto end
+				}
+				if attrs["auto_now"] {
+					fi.auto_now = true
+				} else if attrs["auto_now_add"] {
+					fi.auto_now_add = true
+				}
+			}
 		case fieldType&IsRelField > 0:
 		}
 	}
 
+	if (attrs["auto_now"] || attrs["auto_now_add"]) && fi.auto_now == false && fi.auto_now_add == false {
+		err = fmt.Errorf("auto_now/auto_now_add only support date, datetime and integer fields")
+		goto end
+	}
+
+	if attrs["soft_delete"] && fi.softDelete == false {
+		err = fmt.Errorf("soft_delete only support date and datetime fields")
+		goto end
+	}
+
 	if fieldType&IsIntegerField == 0 {
 		if fi.auto {
 			err = fmt.Errorf("non-integer type cannot set auto")
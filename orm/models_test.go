@@ -298,6 +298,39 @@ func NewComment() *Comment {
 	return obj
 }
 
+type Article struct {
+	Id        int
+	Title     string    `orm:"size(60)"`
+	DeletedAt time.Time `orm:"soft_delete"`
+}
+
+type Stamp struct {
+	Id        int
+	Name      string    `orm:"size(30)"`
+	Created   time.Time `orm:"auto_now_add"`
+	Updated   time.Time `orm:"auto_now"`
+	CreatedAt int64     `orm:"auto_now_add"`
+	UpdatedAt int64     `orm:"auto_now"`
+}
+
+type DocumentAuthor struct {
+	Name  string
+	Email string
+}
+
+type DocumentData struct {
+	Title  string
+	Tags   []string
+	Author DocumentAuthor
+}
+
+type Document struct {
+	Id   int
+	Data DocumentData      `orm:"type(jsonb)"`
+	Meta map[string]string `orm:"type(json);null"`
+	Prev *DocumentData     `orm:"type(json);null"`
+}
+
 var DBARGS = struct {
 	Driver string
 	Source string
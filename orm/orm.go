@@ -1,6 +1,7 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -27,15 +28,23 @@ var (
 	ErrStmtClosed    = errors.New("<QuerySeter> stmt already closed")
 	ErrArgs          = errors.New("<Ormer> args error may be empty")
 	ErrNotImplement  = errors.New("have not implement")
+
+	// set auto pk values computed from LastInsertId to structs of InsertMulti on mysql.
+	// mysql only gives consecutive ids to rows of one insert if innodb_autoinc_lock_mode is 0 or 1
+	// (2 is the default since mysql 8) and auto_increment_increment is 1, turn it on only then.
+	// postgres and sqlite always set them.
+	MysqlConsecutiveIds = false
 )
 
 type Params map[string]interface{}
 type ParamsList []interface{}
 
 type orm struct {
-	alias *alias
-	db    dbQuerier
-	isTx  bool
+	alias   *alias
+	db      dbQuerier
+	isTx    bool
+	ctx     context.Context
+	capture func(query string, args []interface{})
 }
 
 var _ Ormer = new(orm)
@@ -74,7 +83,10 @@ func (o *orm) Read(md interface{}, cols ...string) error {
 	return nil
 }
 
-// Try to read a row from the database, or insert one if it doesn't exist
+// Try to read a row from the database, or insert one if it doesn't exist.
+// cols should be unique together, if a concurrent call inserts the row first
+// the insert fails on the unique index and the row is read again, so only one caller creates it.
+// in a postgres transaction the failed insert aborts the transaction, its error is returned.
 func (o *orm) ReadOrCreate(md interface{}, col1 string, cols ...string) (bool, int64, error) {
 	cols = append([]string{col1}, cols...)
 	mi, ind := o.getMiInd(md, true)
@@ -82,10 +94,17 @@ func (o *orm) ReadOrCreate(md interface{}, col1 string, cols ...string) (bool, i
 	if err == ErrNoRows {
 		// Create
 		id, err := o.Insert(md)
-		return (err == nil), id, err
+		if err == nil {
+			return true, id, nil
+		}
+		// lost the race, read the row created by another caller.
+		if o.alias.DbBaser.Read(o.db, mi, ind, o.alias.TZ, cols) == nil {
+			return false, getPkInt(mi, ind), nil
+		}
+		return false, id, err
 	}
 
-	return false, ind.Field(mi.fields.pk.fieldIndex).Int(), err
+	return false, getPkInt(mi, ind), err
 }
 
 // insert model data to database
@@ -103,13 +122,7 @@ func (o *orm) Insert(md interface{}) (int64, error) {
 
 // set auto pk field
 func (o *orm) setPk(mi *modelInfo, ind reflect.Value, id int64) {
-	if mi.fields.pk.auto {
-		if mi.fields.pk.fieldType&IsPostiveIntegerField > 0 {
-			ind.Field(mi.fields.pk.fieldIndex).SetUint(uint64(id))
-		} else {
-			ind.Field(mi.fields.pk.fieldIndex).SetInt(id)
-		}
-	}
+	setAutoPk(mi, ind, id)
 }
 
 // insert some models to database
@@ -129,7 +142,7 @@ func (o *orm) InsertMulti(bulk int, mds interface{}) (int64, error) {
 
 	if bulk <= 1 {
 		for i := 0; i < sind.Len(); i++ {
-			ind := sind.Index(i)
+			ind := reflect.Indirect(sind.Index(i))
 			mi, _ := o.getMiInd(ind.Interface(), false)
 			id, err := o.alias.DbBaser.Insert(o.db, mi, ind, o.alias.TZ)
 			if err != nil {
@@ -159,8 +172,13 @@ func (o *orm) Update(md interface{}, cols ...string) (int64, error) {
 }
 
 // delete model in database
+// if model has soft_delete field, it's set to now instead of deleting the row.
 func (o *orm) Delete(md interface{}) (int64, error) {
 	mi, ind := o.getMiInd(md, true)
+	if fi := mi.fields.softDelete; fi != nil {
+		ind.Field(fi.fieldIndex).Set(reflect.ValueOf(time.Now().In(DefaultTimeLoc)))
+		return o.alias.DbBaser.Update(o.db, mi, ind, o.alias.TZ, []string{fi.name})
+	}
 	num, err := o.alias.DbBaser.Delete(o.db, mi, ind, o.alias.TZ)
 	if err != nil {
 		return num, err
@@ -369,17 +387,27 @@ func (o *orm) Using(name string) error {
 	}
 	if al, ok := dataBaseCache.get(name); ok {
 		o.alias = al
-		if Debug {
-			o.db = newDbQueryLog(al, al.DB)
-		} else {
-			o.db = al.DB
-		}
+		o.db = o.wrapDB(al.DB)
 	} else {
 		return fmt.Errorf("<Ormer.Using> unknown db alias name `%s`", name)
 	}
 	return nil
 }
 
+// wrap db of alias with the query log, capture and context of o.
+func (o *orm) wrapDB(db dbQuerier) dbQuerier {
+	if Debug {
+		db = newDbQueryLog(o.alias, db)
+	}
+	if o.capture != nil {
+		db = newDbQueryCapture(o.capture, db)
+	}
+	if o.ctx != nil {
+		db = newDbQueryCtx(o.ctx, db)
+	}
+	return db
+}
+
 // begin transaction
 func (o *orm) Begin() error {
 	if o.isTx {
@@ -391,8 +419,8 @@ func (o *orm) Begin() error {
 		return err
 	}
 	o.isTx = true
-	if Debug {
-		o.db.(*dbQueryLog).SetDB(tx)
+	if w, ok := o.db.(dbWrapper); ok {
+		w.SetDB(tx)
 	} else {
 		o.db = tx
 	}
@@ -429,6 +457,76 @@ func (o *orm) Rollback() error {
 	return err
 }
 
+// return a copy of ormer using ctx for all queries,
+// queries in flight are aborted when ctx is done.
+// in transaction, the copy shares the transaction with o.
+// the copy gets its own wrappers, so its Begin doesn't move queries of o into the transaction.
+func (o *orm) WithContext(ctx context.Context) Ormer {
+	n := *o
+	n.ctx = ctx
+	n.db = n.wrapDB(rawDB(o.db))
+	return &n
+}
+
+// get the *sql.DB or *sql.Tx under the log, capture and context wrappers of db.
+func rawDB(db dbQuerier) dbQuerier {
+	for {
+		switch d := db.(type) {
+		case *dbQueryCtx:
+			db = d.db
+		case *dbQueryCapture:
+			db = d.db
+		case *dbQueryLog:
+			db = d.db
+		default:
+			return db
+		}
+	}
+}
+
+// return a copy of ormer passing every query and its args to fn before running it,
+// e.g. to check the sql of QuerySeter in tests. queries are still logged if Debug is on.
+// in transaction, the copy shares the transaction with o. like WithContext, the copy gets its own wrappers.
+func (o *orm) Capture(fn func(query string, args []interface{})) Ormer {
+	n := *o
+	n.capture = fn
+	n.db = n.wrapDB(rawDB(o.db))
+	return &n
+}
+
+// try to get the advisory lock of key, mysql and postgres only.
+// acquired is false if another connection holds it, then unlock is nil.
+// advisory locks belong to a connection, so one is taken from the pool until unlock is called.
+// the database releases the lock if the connection is lost.
+func (o *orm) AdvisoryLock(key string) (unlock func() error, acquired bool, err error) {
+	lockQuery, unlockQuery := o.alias.DbBaser.AdvisoryLockQueries()
+	if lockQuery == "" || o.alias.DB == nil {
+		return nil, false, ErrNotImplement
+	}
+	o.alias.DbBaser.ReplaceMarks(&lockQuery)
+	o.alias.DbBaser.ReplaceMarks(&unlockQuery)
+
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	conn, err := o.alias.DB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var ok sql.NullBool
+	if err = conn.QueryRowContext(ctx, lockQuery, key).Scan(&ok); err != nil || !ok.Bool {
+		conn.Close()
+		return nil, false, err
+	}
+	unlock = func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), unlockQuery, key)
+		return err
+	}
+	return unlock, true, nil
+}
+
 // return a raw query seter for raw sql string.
 func (o *orm) Raw(query string, args ...interface{}) RawSeter {
 	return newRawSet(o, query, args)
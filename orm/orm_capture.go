@@ -0,0 +1,90 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// database querier passing every query and its args to fn before running it.
+// prepared statements are passed with nil args when they are prepared.
+type dbQueryCapture struct {
+	fn func(query string, args []interface{})
+	db dbQuerier
+}
+
+var _ dbQuerier = new(dbQueryCapture)
+var _ txer = new(dbQueryCapture)
+var _ txEnder = new(dbQueryCapture)
+var _ dbQuerierCtx = new(dbQueryCapture)
+var _ txerCtx = new(dbQueryCapture)
+
+func (d *dbQueryCapture) Prepare(query string) (*sql.Stmt, error) {
+	d.fn(query, nil)
+	return d.db.Prepare(query)
+}
+
+func (d *dbQueryCapture) Exec(query string, args ...interface{}) (sql.Result, error) {
+	d.fn(query, args)
+	return d.db.Exec(query, args...)
+}
+
+func (d *dbQueryCapture) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	d.fn(query, args)
+	return d.db.Query(query, args...)
+}
+
+func (d *dbQueryCapture) QueryRow(query string, args ...interface{}) *sql.Row {
+	d.fn(query, args)
+	return d.db.QueryRow(query, args...)
+}
+
+func (d *dbQueryCapture) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	d.fn(query, nil)
+	return d.db.(dbQuerierCtx).PrepareContext(ctx, query)
+}
+
+func (d *dbQueryCapture) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	d.fn(query, args)
+	return d.db.(dbQuerierCtx).ExecContext(ctx, query, args...)
+}
+
+func (d *dbQueryCapture) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	d.fn(query, args)
+	return d.db.(dbQuerierCtx).QueryContext(ctx, query, args...)
+}
+
+func (d *dbQueryCapture) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	d.fn(query, args)
+	return d.db.(dbQuerierCtx).QueryRowContext(ctx, query, args...)
+}
+
+func (d *dbQueryCapture) Begin() (*sql.Tx, error) {
+	return d.db.(txer).Begin()
+}
+
+func (d *dbQueryCapture) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return d.db.(txerCtx).BeginTx(ctx, opts)
+}
+
+func (d *dbQueryCapture) Commit() error {
+	return d.db.(txEnder).Commit()
+}
+
+func (d *dbQueryCapture) Rollback() error {
+	return d.db.(txEnder).Rollback()
+}
+
+func (d *dbQueryCapture) SetDB(db dbQuerier) {
+	if w, ok := d.db.(dbWrapper); ok {
+		w.SetDB(db)
+	} else {
+		d.db = db
+	}
+}
+
+func newDbQueryCapture(fn func(query string, args []interface{}), db dbQuerier) dbQuerier {
+	d := new(dbQueryCapture)
+	d.fn = fn
+	d.db = db
+	return d
+}
@@ -0,0 +1,75 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// db querier with context, *sql.DB and *sql.Tx implement it.
+type dbQuerierCtx interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// transaction beginner with context
+type txerCtx interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// database querier bound to a context.
+// queries are sent with ExecContext, QueryContext, etc.,
+// so they are aborted when ctx is done.
+type dbQueryCtx struct {
+	ctx context.Context
+	db  dbQuerier
+}
+
+var _ dbQuerier = new(dbQueryCtx)
+var _ txer = new(dbQueryCtx)
+var _ txEnder = new(dbQueryCtx)
+
+func (d *dbQueryCtx) Prepare(query string) (*sql.Stmt, error) {
+	return d.db.(dbQuerierCtx).PrepareContext(d.ctx, query)
+}
+
+func (d *dbQueryCtx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.db.(dbQuerierCtx).ExecContext(d.ctx, query, args...)
+}
+
+func (d *dbQueryCtx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.(dbQuerierCtx).QueryContext(d.ctx, query, args...)
+}
+
+func (d *dbQueryCtx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.db.(dbQuerierCtx).QueryRowContext(d.ctx, query, args...)
+}
+
+// begin transaction with context, it's rolled back if ctx is done before commit.
+func (d *dbQueryCtx) Begin() (*sql.Tx, error) {
+	return d.db.(txerCtx).BeginTx(d.ctx, nil)
+}
+
+func (d *dbQueryCtx) Commit() error {
+	return d.db.(txEnder).Commit()
+}
+
+func (d *dbQueryCtx) Rollback() error {
+	return d.db.(txEnder).Rollback()
+}
+
+func (d *dbQueryCtx) SetDB(db dbQuerier) {
+	if w, ok := d.db.(dbWrapper); ok {
+		w.SetDB(db)
+	} else {
+		d.db = db
+	}
+}
+
+func newDbQueryCtx(ctx context.Context, db dbQuerier) dbQuerier {
+	d := new(dbQueryCtx)
+	d.ctx = ctx
+	d.db = db
+	return d
+}
@@ -0,0 +1,149 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// returned by queryCatcher instead of running the query.
+var errQueryCaught = errors.New("query caught")
+
+// dbQuerier catching the query and args of a read instead of running it.
+type queryCatcher struct {
+	query string
+	args  []interface{}
+}
+
+func (d *queryCatcher) Prepare(query string) (*sql.Stmt, error) {
+	return nil, errQueryCaught
+}
+
+func (d *queryCatcher) Exec(query string, args ...interface{}) (sql.Result, error) {
+	d.query, d.args = query, args
+	return nil, errQueryCaught
+}
+
+func (d *queryCatcher) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	d.query, d.args = query, args
+	return nil, errQueryCaught
+}
+
+// not used by ReadBatch, *sql.Row can't carry an error.
+func (d *queryCatcher) QueryRow(query string, args ...interface{}) *sql.Row {
+	panic(fmt.Errorf("<queryCatcher.QueryRow> is not supported"))
+}
+
+// dbQuerier running the reads of ReadBatch on a prepared statement with args.
+type stmtReader struct {
+	stmt stmtQuerier
+	args []interface{}
+}
+
+func (d *stmtReader) Prepare(query string) (*sql.Stmt, error) {
+	return nil, ErrNotImplement
+}
+
+func (d *stmtReader) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.stmt.Exec(d.args...)
+}
+
+func (d *stmtReader) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.stmt.Query(d.args...)
+}
+
+func (d *stmtReader) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.stmt.QueryRow(d.args...)
+}
+
+// a prepared select queryer struct
+type queryPrepare struct {
+	qs     *querySet
+	cols   []string
+	args   []interface{} // filter values of QuerySeter
+	stmt   stmtQuerier
+	lock   sync.RWMutex
+	closed bool
+}
+
+var _ QueryPreparer = new(queryPrepare)
+
+// get args to run the statement with, the filter values if args is empty.
+func (o *queryPrepare) getArgs(args []interface{}) ([]interface{}, error) {
+	if len(args) == 0 {
+		return o.args, nil
+	}
+	args = getFlatParams(nil, args, o.qs.orm.alias.TZ)
+	if len(args) != len(o.args) {
+		return nil, fmt.Errorf("<QueryPreparer> need %d args but found %d", len(o.args), len(args))
+	}
+	return args, nil
+}
+
+// run the select with args without reading rows.
+func (o *queryPrepare) Exec(args ...interface{}) (sql.Result, error) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	if o.closed {
+		return nil, ErrStmtClosed
+	}
+	args, err := o.getArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return o.stmt.Exec(args...)
+}
+
+// run the select with args and map rows to container like QuerySeter.All.
+func (o *queryPrepare) Query(container interface{}, args ...interface{}) (int64, error) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	if o.closed {
+		return 0, ErrStmtClosed
+	}
+	args, err := o.getArgs(args)
+	if err != nil {
+		return 0, err
+	}
+	qs := o.qs
+	return qs.orm.alias.DbBaser.ReadBatch(&stmtReader{o.stmt, args}, qs, qs.mi, qs.getCond(), container, qs.orm.alias.TZ, o.cols)
+}
+
+// close prepared select statement, it waits for running Exec and Query.
+func (o *queryPrepare) Close() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.closed {
+		return ErrStmtClosed
+	}
+	o.closed = true
+	return o.stmt.Close()
+}
+
+// create new prepared select queryer.
+func newQueryPrepare(qs *querySet, cols []string) (QueryPreparer, error) {
+	o := new(queryPrepare)
+	o.qs = qs
+	o.cols = cols
+
+	catcher := new(queryCatcher)
+	container := reflect.New(qs.mi.addrField.Elem().Type()).Interface()
+	if _, err := qs.orm.alias.DbBaser.ReadBatch(catcher, qs, qs.mi, qs.getCond(), container, qs.orm.alias.TZ, cols); err != errQueryCaught {
+		return nil, err
+	}
+	o.args = catcher.args
+
+	db := qs.readDB()
+	st, err := db.Prepare(catcher.query)
+	if err != nil {
+		return nil, err
+	}
+	if Debug {
+		o.stmt = newStmtQueryLog(qs.orm.alias, st, catcher.query)
+	} else {
+		o.stmt = st
+	}
+	return o, nil
+}
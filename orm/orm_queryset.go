@@ -2,6 +2,7 @@ package orm
 
 import (
 	"fmt"
+	"time"
 )
 
 type colValue struct {
@@ -16,15 +17,28 @@ const (
 	Col_Minus
 	Col_Multiply
 	Col_Except
+	Col_BitAnd
+	Col_BitOr
+)
+
+// operators of ColValue named without underscore.
+const (
+	ColAdd      = Col_Add
+	ColMinus    = Col_Minus
+	ColMultiply = Col_Multiply
+	ColDivide   = Col_Except
+	ColBitAnd   = Col_BitAnd
+	ColBitOr    = Col_BitOr
 )
 
 // ColValue do the field raw changes. e.g Nums = Nums + 10. usage:
 // 	Params{
 // 		"Nums": ColValue(Col_Add, 10),
 // 	}
+// the value is computed by database in the UPDATE, so concurrent updates are not lost.
 func ColValue(opt operator, value interface{}) interface{} {
 	switch opt {
-	case Col_Add, Col_Minus, Col_Multiply, Col_Except:
+	case Col_Add, Col_Minus, Col_Multiply, Col_Except, Col_BitAnd, Col_BitOr:
 	default:
 		panic(fmt.Errorf("orm.ColValue wrong operator"))
 	}
@@ -48,6 +62,8 @@ type querySet struct {
 	offset   int64
 	orders   []string
 	orm      *orm
+	deleted  bool
+	master   bool
 }
 
 var _ QuerySeter = new(querySet)
@@ -126,27 +142,102 @@ func (o querySet) SetCond(cond *Condition) QuerySeter {
 	return &o
 }
 
+// query soft deleted rows too.
+func (o querySet) IncludeDeleted() QuerySeter {
+	o.deleted = true
+	return &o
+}
+
+// send reads of QuerySeter to master even if the database has replicas,
+// e.g. to read rows just written before they are replicated.
+func (o querySet) UsingMaster() QuerySeter {
+	o.master = true
+	return &o
+}
+
+// get db querier for reads.
+// it's a replica round-robin unless UsingMaster is called or ormer is in transaction.
+func (o *querySet) readDB() dbQuerier {
+	if o.master || o.orm.isTx {
+		return o.orm.db
+	}
+	r := o.orm.alias.replica()
+	if r == nil {
+		return o.orm.db
+	}
+	return o.orm.wrapDB(r)
+}
+
+// get condition to query with.
+// soft deleted rows are filtered out unless IncludeDeleted is called.
+func (o *querySet) getCond() *Condition {
+	fi := o.mi.fields.softDelete
+	if fi == nil || o.deleted {
+		return o.cond
+	}
+	return o.softDeleteCond(true)
+}
+
+// get condition of rows not yet or already soft deleted.
+// the condition of querySet is grouped, so its OR expressions don't mix with the soft delete one.
+func (o *querySet) softDeleteCond(isNull bool) *Condition {
+	cond := NewCondition().And(o.mi.fields.softDelete.name+ExprSep+"isnull", isNull)
+	if o.cond != nil && o.cond.IsEmpty() == false {
+		cond = cond.AndCond(o.cond)
+	}
+	return cond
+}
+
 // return QuerySeter execution result number
 func (o *querySet) Count() (int64, error) {
-	return o.orm.alias.DbBaser.Count(o.orm.db, o, o.mi, o.cond, o.orm.alias.TZ)
+	return o.orm.alias.DbBaser.Count(o.readDB(), o, o.mi, o.getCond(), o.orm.alias.TZ)
 }
 
 // check result empty or not after QuerySeter executed
 func (o *querySet) Exist() bool {
-	cnt, _ := o.orm.alias.DbBaser.Count(o.orm.db, o, o.mi, o.cond, o.orm.alias.TZ)
-	return cnt > 0
+	exist, _ := o.Exists()
+	return exist
+}
+
+// check any row is matched by selecting one row instead of counting all of them.
+func (o *querySet) Exists() (bool, error) {
+	return o.orm.alias.DbBaser.Exists(o.readDB(), o, o.mi, o.getCond(), o.orm.alias.TZ)
 }
 
 // execute update with parameters
 func (o *querySet) Update(values Params) (int64, error) {
-	return o.orm.alias.DbBaser.UpdateBatch(o.orm.db, o, o.mi, o.cond, values, o.orm.alias.TZ)
+	return o.orm.alias.DbBaser.UpdateBatch(o.orm.db, o, o.mi, o.getCond(), values, o.orm.alias.TZ)
 }
 
-// execute delete
+// execute delete.
+// if model has soft_delete field, it's set to now instead of deleting the rows.
 func (o *querySet) Delete() (int64, error) {
+	fi := o.mi.fields.softDelete
+	if fi == nil {
+		return o.ForceDelete()
+	}
+	if o.cond == nil || o.cond.IsEmpty() {
+		panic(fmt.Errorf("delete operation cannot execute without condition"))
+	}
+	now := time.Now()
+	o.orm.alias.DbBaser.TimeToDB(&now, o.orm.alias.TZ)
+	return o.orm.alias.DbBaser.UpdateBatch(o.orm.db, o, o.mi, o.getCond(), Params{fi.name: now}, o.orm.alias.TZ)
+}
+
+// execute delete, rows are removed even if model has soft_delete field.
+func (o *querySet) ForceDelete() (int64, error) {
 	return o.orm.alias.DbBaser.DeleteBatch(o.orm.db, o, o.mi, o.cond, o.orm.alias.TZ)
 }
 
+// restore soft deleted rows.
+func (o *querySet) Restore() (int64, error) {
+	fi := o.mi.fields.softDelete
+	if fi == nil {
+		panic(fmt.Errorf("<QuerySeter.Restore> model `%s` has no soft_delete field", o.mi.fullName))
+	}
+	return o.orm.alias.DbBaser.UpdateBatch(o.orm.db, o, o.mi, o.softDeleteCond(false), Params{fi.name: nil}, o.orm.alias.TZ)
+}
+
 // return a insert queryer.
 // it can be used in times.
 // example:
@@ -156,16 +247,28 @@ func (o *querySet) PrepareInsert() (Inserter, error) {
 	return newInsertSet(o.orm, o.mi)
 }
 
+// return a prepared select of QuerySeter, it can be used in times.
+// args of its Query and Exec replace the filter values in order, the filter values are used if args are empty.
+// cols means the columns when querying.
+// example:
+// 	p, err := qs.Filter("user_name", "").Prepare()
+// 	p.Query(&users, "slene")
+// 	p.Query(&users, "astaxie")
+// 	p.Close()
+func (o *querySet) Prepare(cols ...string) (QueryPreparer, error) {
+	return newQueryPrepare(o, cols)
+}
+
 // query all data and map to containers.
 // cols means the columns when querying.
 func (o *querySet) All(container interface{}, cols ...string) (int64, error) {
-	return o.orm.alias.DbBaser.ReadBatch(o.orm.db, o, o.mi, o.cond, container, o.orm.alias.TZ, cols)
+	return o.orm.alias.DbBaser.ReadBatch(o.readDB(), o, o.mi, o.getCond(), container, o.orm.alias.TZ, cols)
 }
 
 // query one row data and map to containers.
 // cols means the columns when querying.
 func (o *querySet) One(container interface{}, cols ...string) error {
-	num, err := o.orm.alias.DbBaser.ReadBatch(o.orm.db, o, o.mi, o.cond, container, o.orm.alias.TZ, cols)
+	num, err := o.orm.alias.DbBaser.ReadBatch(o.readDB(), o, o.mi, o.getCond(), container, o.orm.alias.TZ, cols)
 	if err != nil {
 		return err
 	}
@@ -182,19 +285,19 @@ func (o *querySet) One(container interface{}, cols ...string) error {
 // expres means condition expression.
 // it converts data to []map[column]value.
 func (o *querySet) Values(results *[]Params, exprs ...string) (int64, error) {
-	return o.orm.alias.DbBaser.ReadValues(o.orm.db, o, o.mi, o.cond, exprs, results, o.orm.alias.TZ)
+	return o.orm.alias.DbBaser.ReadValues(o.readDB(), o, o.mi, o.getCond(), exprs, results, o.orm.alias.TZ)
 }
 
 // query all data and map to [][]interface
 // it converts data to [][column_index]value
 func (o *querySet) ValuesList(results *[]ParamsList, exprs ...string) (int64, error) {
-	return o.orm.alias.DbBaser.ReadValues(o.orm.db, o, o.mi, o.cond, exprs, results, o.orm.alias.TZ)
+	return o.orm.alias.DbBaser.ReadValues(o.readDB(), o, o.mi, o.getCond(), exprs, results, o.orm.alias.TZ)
 }
 
 // query all data and map to []interface.
 // it's designed for one row record set, auto change to []value, not [][column]value.
 func (o *querySet) ValuesFlat(result *ParamsList, expr string) (int64, error) {
-	return o.orm.alias.DbBaser.ReadValues(o.orm.db, o, o.mi, o.cond, []string{expr}, result, o.orm.alias.TZ)
+	return o.orm.alias.DbBaser.ReadValues(o.readDB(), o, o.mi, o.getCond(), []string{expr}, result, o.orm.alias.TZ)
 }
 
 // query all rows into map[string]interface with specify key and value column name.
@@ -209,7 +312,7 @@ func (o *querySet) ValuesFlat(result *ParamsList, expr string) (int64, error) {
 // }
 func (o *querySet) RowsToMap(result *Params, keyCol, valueCol string) (int64, error) {
 	panic(ErrNotImplement)
-	return o.orm.alias.DbBaser.RowsTo(o.orm.db, o, o.mi, o.cond, result, keyCol, valueCol, o.orm.alias.TZ)
+	return o.orm.alias.DbBaser.RowsTo(o.orm.db, o, o.mi, o.getCond(), result, keyCol, valueCol, o.orm.alias.TZ)
 }
 
 // query all rows into struct with specify key and value column name.
@@ -224,7 +327,7 @@ func (o *querySet) RowsToMap(result *Params, keyCol, valueCol string) (int64, er
 // }
 func (o *querySet) RowsToStruct(ptrStruct interface{}, keyCol, valueCol string) (int64, error) {
 	panic(ErrNotImplement)
-	return o.orm.alias.DbBaser.RowsTo(o.orm.db, o, o.mi, o.cond, ptrStruct, keyCol, valueCol, o.orm.alias.TZ)
+	return o.orm.alias.DbBaser.RowsTo(o.orm.db, o, o.mi, o.getCond(), ptrStruct, keyCol, valueCol, o.orm.alias.TZ)
 }
 
 // create new QuerySeter.
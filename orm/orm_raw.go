@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -168,6 +170,89 @@ func (o *rawSet) setFieldValue(ind reflect.Value, value interface{}) {
 	}
 }
 
+// set values of columns to struct ind.
+// columns are matched to fields of model mi, or to column tags and snake names if ind isn't a model.
+// columns aliased like author.name are set to struct field author, so joins can fill nested structs.
+func (o *rawSet) setStructValues(ind reflect.Value, mi *modelInfo, columns []string, values map[string]interface{}) {
+	var nested []string
+	for _, col := range columns {
+		if strings.Contains(col, ".") {
+			nested = append(nested, col)
+			continue
+		}
+		if mi != nil {
+			if fi := mi.fields.GetByColumn(col); fi != nil {
+				value := reflect.ValueOf(values[col]).Elem().Interface()
+				o.setFieldValue(ind.FieldByIndex([]int{fi.fieldIndex}), value)
+			}
+		}
+	}
+	if mi == nil {
+		for i := 0; i < ind.NumField(); i++ {
+			f := ind.Field(i)
+			fe := ind.Type().Field(i)
+
+			var attrs map[string]bool
+			var tags map[string]string
+			parseStructTag(fe.Tag.Get("orm"), &attrs, &tags)
+			var col string
+			if col = tags["column"]; len(col) == 0 {
+				col = snakeString(fe.Name)
+			}
+			if v, ok := values[col]; ok {
+				value := reflect.ValueOf(v).Elem().Interface()
+				o.setFieldValue(f, value)
+			}
+		}
+	}
+	if len(nested) == 0 {
+		return
+	}
+
+	// group aliased columns by struct field
+	prefixes := make([]string, 0)
+	subColumns := make(map[string][]string)
+	subValues := make(map[string]map[string]interface{})
+	for _, col := range nested {
+		i := strings.Index(col, ".")
+		prefix, sub := col[:i], col[i+1:]
+		if _, ok := subColumns[prefix]; !ok {
+			prefixes = append(prefixes, prefix)
+			subValues[prefix] = make(map[string]interface{})
+		}
+		subColumns[prefix] = append(subColumns[prefix], sub)
+		subValues[prefix][sub] = values[col]
+	}
+	for _, prefix := range prefixes {
+		for i := 0; i < ind.NumField(); i++ {
+			fe := ind.Type().Field(i)
+			var attrs map[string]bool
+			var tags map[string]string
+			parseStructTag(fe.Tag.Get("orm"), &attrs, &tags)
+			if prefix != snakeString(fe.Name) && prefix != tags["column"] {
+				continue
+			}
+			f := ind.Field(i)
+			typ := fe.Type
+			if typ.Kind() == reflect.Ptr {
+				typ = typ.Elem()
+			}
+			if !f.CanSet() || typ.Kind() != reflect.Struct || typ.String() == "time.Time" {
+				break
+			}
+			if f.Kind() == reflect.Ptr {
+				if f.IsNil() {
+					f.Set(reflect.New(typ))
+				}
+				f = f.Elem()
+			}
+			sub, _ := modelCache.getByFN(getFullName(typ))
+			o.setStructValues(f, sub, subColumns[prefix], subValues[prefix])
+			break
+		}
+	}
+}
+
 // set field value in loop for slice container
 func (o *rawSet) loopSetRefs(refs []interface{}, sInds []reflect.Value, nIndsPtr *[]reflect.Value, eTyps []reflect.Type, init bool) {
 	nInds := *nIndsPtr
@@ -323,31 +408,7 @@ func (o *rawSet) QueryRow(containers ...interface{}) error {
 				ind = ind.Elem()
 			}
 
-			if sMi != nil {
-				for _, col := range columns {
-					if fi := sMi.fields.GetByColumn(col); fi != nil {
-						value := reflect.ValueOf(columnsMp[col]).Elem().Interface()
-						o.setFieldValue(ind.FieldByIndex([]int{fi.fieldIndex}), value)
-					}
-				}
-			} else {
-				for i := 0; i < ind.NumField(); i++ {
-					f := ind.Field(i)
-					fe := ind.Type().Field(i)
-
-					var attrs map[string]bool
-					var tags map[string]string
-					parseStructTag(fe.Tag.Get("orm"), &attrs, &tags)
-					var col string
-					if col = tags["column"]; len(col) == 0 {
-						col = snakeString(fe.Name)
-					}
-					if v, ok := columnsMp[col]; ok {
-						value := reflect.ValueOf(v).Elem().Interface()
-						o.setFieldValue(f, value)
-					}
-				}
-			}
+			o.setStructValues(ind, sMi, columns, columnsMp)
 
 		} else {
 			if err := rows.Scan(refs...); err != nil {
@@ -460,31 +521,7 @@ func (o *rawSet) QueryRows(containers ...interface{}) (int64, error) {
 				ind = ind.Elem()
 			}
 
-			if sMi != nil {
-				for _, col := range columns {
-					if fi := sMi.fields.GetByColumn(col); fi != nil {
-						value := reflect.ValueOf(columnsMp[col]).Elem().Interface()
-						o.setFieldValue(ind.FieldByIndex([]int{fi.fieldIndex}), value)
-					}
-				}
-			} else {
-				for i := 0; i < ind.NumField(); i++ {
-					f := ind.Field(i)
-					fe := ind.Type().Field(i)
-
-					var attrs map[string]bool
-					var tags map[string]string
-					parseStructTag(fe.Tag.Get("orm"), &attrs, &tags)
-					var col string
-					if col = tags["column"]; len(col) == 0 {
-						col = snakeString(fe.Name)
-					}
-					if v, ok := columnsMp[col]; ok {
-						value := reflect.ValueOf(v).Elem().Interface()
-						o.setFieldValue(f, value)
-					}
-				}
-			}
+			o.setStructValues(ind, sMi, columns, columnsMp)
 
 			if eTyps[0].Kind() == reflect.Ptr {
 				ind = ind.Addr()
@@ -775,6 +812,156 @@ func (o *rawSet) ValuesFlat(container *ParamsList, cols ...string) (int64, error
 	return o.readValues(container, cols)
 }
 
+// query data to []map[string]interface with typed values.
+// unlike Values, whose values are strings, value types are picked by column types of driver:
+// NULL is nil, integers are int64, floats are float64, bools are bool, dates and times are time.Time,
+// binary columns are []byte and other columns are string. decimals are string to keep precision.
+func (o *rawSet) RowsToMaps() ([]Params, error) {
+	query := o.query
+	o.orm.alias.DbBaser.ReplaceMarks(&query)
+
+	args := getFlatParams(nil, o.args, o.orm.alias.TZ)
+
+	rs, err := o.orm.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+
+	types, err := rs.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	kinds := make([]int, len(types))
+	for i, ct := range types {
+		kinds[i] = getColumnKind(ct)
+	}
+
+	var maps []Params
+	refs := make([]interface{}, len(types))
+	for rs.Next() {
+		values := make([]interface{}, len(types))
+		for i := range refs {
+			refs[i] = &values[i]
+		}
+		if err := rs.Scan(refs...); err != nil {
+			return nil, err
+		}
+		params := make(Params, len(types))
+		for i, ct := range types {
+			params[ct.Name()] = convertColumnValue(kinds[i], values[i], o.orm.alias.TZ)
+		}
+		maps = append(maps, params)
+	}
+	return maps, rs.Err()
+}
+
+// go kinds of scanned column values
+const (
+	colString = iota
+	colInt
+	colFloat
+	colBool
+	colTime
+	colBytes
+)
+
+// get kind of column by database type name, or by scan type if driver doesn't report the name.
+func getColumnKind(ct *sql.ColumnType) int {
+	// e.g. "VARCHAR(30)", "UNSIGNED BIGINT", "TIMESTAMP WITH TIME ZONE"
+	name := strings.ToUpper(ct.DatabaseTypeName())
+	if i := strings.IndexAny(name, "( "); i > 0 {
+		name = name[:i]
+	}
+	if name != "" {
+		switch name {
+		case "INT", "INTEGER", "TINYINT", "SMALLINT", "MEDIUMINT", "BIGINT", "INT2", "INT4", "INT8",
+			"SERIAL", "BIGSERIAL", "UNSIGNED":
+			return colInt
+		case "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8":
+			return colFloat
+		case "BOOL", "BOOLEAN":
+			return colBool
+		case "DATE", "DATETIME", "TIMESTAMP", "TIMESTAMPTZ":
+			return colTime
+		case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "BYTEA":
+			return colBytes
+		}
+		return colString
+	}
+	typ := ct.ScanType()
+	if typ == nil {
+		return colString
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return colInt
+	case reflect.Float32, reflect.Float64:
+		return colFloat
+	case reflect.Bool:
+		return colBool
+	}
+	switch typ {
+	case reflect.TypeOf(sql.NullInt64{}):
+		return colInt
+	case reflect.TypeOf(sql.NullFloat64{}):
+		return colFloat
+	case reflect.TypeOf(sql.NullBool{}):
+		return colBool
+	case reflect.TypeOf(time.Time{}), reflect.TypeOf(sql.NullTime{}):
+		return colTime
+	}
+	return colString
+}
+
+// convert scanned driver value to the go type of column kind.
+// text values those can't be converted are returned as string.
+func convertColumnValue(kind int, value interface{}, tz *time.Location) interface{} {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if kind == colBytes {
+			return v
+		}
+		s = string(v)
+	case string:
+		s = v
+	case time.Time:
+		return v.In(tz)
+	case int64:
+		if kind == colBool {
+			return v != 0
+		}
+		return v
+	default:
+		return v
+	}
+	switch kind {
+	case colInt:
+		if x, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return x
+		}
+	case colFloat:
+		if x, err := strconv.ParseFloat(s, 64); err == nil {
+			return x
+		}
+	case colBool:
+		if x, err := strconv.ParseBool(s); err == nil {
+			return x
+		}
+	case colTime:
+		for _, layout := range []string{format_DateTime, format_Date, time.RFC3339Nano} {
+			if t, err := time.ParseInLocation(layout, s, tz); err == nil {
+				return t
+			}
+		}
+	}
+	return s
+}
+
 // query all rows into map[string]interface with specify key and value column name.
 // keyCol = "name", valueCol = "value"
 // table data
@@ -2,6 +2,7 @@ package orm
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	"io/ioutil"
@@ -10,6 +11,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -157,6 +159,9 @@ func TestSyncDb(t *testing.T) {
 	RegisterModel(new(Comment))
 	RegisterModel(new(UserBig))
 	RegisterModel(new(PostTags))
+	RegisterModel(new(Article))
+	RegisterModel(new(Document))
+	RegisterModel(new(Stamp))
 
 	err := RunSyncdb("default", true, false)
 	throwFail(t, err)
@@ -173,6 +178,9 @@ func TestRegisterModels(t *testing.T) {
 	RegisterModel(new(Comment))
 	RegisterModel(new(UserBig))
 	RegisterModel(new(PostTags))
+	RegisterModel(new(Article))
+	RegisterModel(new(Document))
+	RegisterModel(new(Stamp))
 
 	BootStrap()
 
@@ -194,6 +202,20 @@ func TestModelSyntax(t *testing.T) {
 	}
 }
 
+func TestAutoNowIntegerKinds(t *testing.T) {
+	type stamps struct {
+		Unix   int64  `orm:"auto_now"`
+		Unix32 uint32 `orm:"auto_now_add"`
+		Unix8  int8   `orm:"auto_now"`
+	}
+	ind := reflect.ValueOf(&stamps{}).Elem()
+	mi := &modelInfo{fullName: "stamps"}
+	for i, ok := range []bool{true, true, false} {
+		_, err := newFieldInfo(mi, ind.Field(i), ind.Type().Field(i))
+		throwFail(t, AssertIs(err == nil, ok))
+	}
+}
+
 var Data_Values = map[string]interface{}{
 	"Boolean":  true,
 	"Char":     "char",
@@ -1331,6 +1353,98 @@ func TestPrepareInsert(t *testing.T) {
 	throwFail(t, AssertIs(err, ErrStmtClosed))
 }
 
+func TestPrepare(t *testing.T) {
+	qs := dORM.QueryTable("user").Filter("user_name", "slene").OrderBy("Id")
+	p, err := qs.Prepare()
+	throwFailNow(t, err)
+
+	var users []*User
+	num, err := p.Query(&users)
+	throwFail(t, err)
+	throwFailNow(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(users[0].UserName, "slene"))
+
+	for _, name := range []string{"slene", "astaxie", "nobody", "nothing"} {
+		var prepared, adhoc []User
+		num, err := p.Query(&prepared, name)
+		throwFail(t, err)
+		n, err := dORM.QueryTable("user").Filter("user_name", name).OrderBy("Id").All(&adhoc)
+		throwFail(t, err)
+		throwFail(t, AssertIs(num, n))
+		throwFailNow(t, AssertIs(len(prepared), len(adhoc)))
+		for i := range prepared {
+			throwFail(t, AssertIs(prepared[i].Id, adhoc[i].Id))
+			throwFail(t, AssertIs(prepared[i].Email, adhoc[i].Email))
+		}
+	}
+
+	var user User
+	num, err = p.Query(&user, "astaxie")
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(user.UserName, "astaxie"))
+
+	_, err = p.Query(&users, "slene", "astaxie")
+	throwFail(t, AssertIs(err != nil, true))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var users []*User
+			for j := 0; j < 10; j++ {
+				num, err := p.Query(&users, "nobody")
+				throwFail(t, err)
+				throwFail(t, AssertIs(num, 1))
+			}
+		}()
+	}
+	wg.Wait()
+
+	p2, err := dORM.QueryTable("user").Filter("user_name__in", "", "").Prepare("UserName")
+	throwFailNow(t, err)
+	num, err = p2.Query(&users, []string{"slene", "astaxie"})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 2))
+	throwFail(t, AssertIs(users[0].Id, 0))
+	throwFail(t, p2.Close())
+
+	throwFail(t, p.Close())
+	_, err = p.Query(&users)
+	throwFail(t, AssertIs(err, ErrStmtClosed))
+	throwFail(t, AssertIs(p.Close(), ErrStmtClosed))
+}
+
+func benchmarkPrepare(b *testing.B, prepared bool) {
+	p, err := dORM.QueryTable("user").Filter("user_name", "").Prepare()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer p.Close()
+	var user User
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 10000; j++ {
+			if prepared {
+				_, err = p.Query(&user, "slene")
+			} else {
+				_, err = dORM.QueryTable("user").Filter("user_name", "slene").All(&user)
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkQueryPrepared(b *testing.B) {
+	benchmarkPrepare(b, true)
+}
+
+func BenchmarkQueryAdhoc(b *testing.B) {
+	benchmarkPrepare(b, false)
+}
+
 func TestRawExec(t *testing.T) {
 	Q := dDbBaser.TableQuote()
 
@@ -1493,6 +1607,47 @@ func TestQueryRows(t *testing.T) {
 	throwFailNow(t, AssertIs(usernames[2], "nobody"))
 }
 
+func TestRawNestedStructs(t *testing.T) {
+	Q := dDbBaser.TableQuote()
+
+	type postReport struct {
+		Id      int
+		Title   string
+		Author  User
+		Profile *Profile
+	}
+	query := fmt.Sprintf("SELECT T0.%sid%s, T0.%stitle%s, T1.%sid%s AS %sauthor.id%s, T1.%suser_name%s AS %sauthor.user_name%s, T2.%sage%s AS %sprofile.age%s "+
+		"FROM %spost%s T0 INNER JOIN %suser%s T1 ON T1.%sid%s = T0.%suser_id%s INNER JOIN %suser_profile%s T2 ON T2.%sid%s = T1.%sprofile_id%s WHERE T0.%stitle%s = ?",
+		Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q)
+
+	var report postReport
+	err := dORM.Raw(query, "Examples").QueryRow(&report)
+	throwFailNow(t, err)
+	throwFail(t, AssertIs(report.Title, "Examples"))
+	throwFail(t, AssertIs(report.Author.UserName, "astaxie"))
+	throwFail(t, AssertIs(report.Author.Id > 0, true))
+	throwFailNow(t, AssertIs(report.Profile == nil, false))
+	throwFail(t, AssertIs(report.Profile.Age, 30))
+
+	var reports []*postReport
+	num, err := dORM.Raw(query, "Introduction").QueryRows(&reports)
+	throwFailNow(t, err)
+	throwFailNow(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(reports[0].Author.UserName, "slene"))
+	throwFail(t, AssertIs(reports[0].Profile.Age, 28))
+
+	// registered model, the relation field is filled
+	query = fmt.Sprintf("SELECT T0.%sid%s, T0.%stitle%s, T1.%suser_name%s AS %suser.user_name%s FROM %spost%s T0 INNER JOIN %suser%s T1 ON T1.%sid%s = T0.%suser_id%s ORDER BY T0.%sid%s",
+		Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q)
+	var posts []Post
+	num, err = dORM.Raw(query).QueryRows(&posts)
+	throwFailNow(t, err)
+	throwFailNow(t, AssertIs(num, 4))
+	throwFailNow(t, AssertIs(posts[0].User == nil, false))
+	throwFail(t, AssertIs(posts[0].User.UserName, "slene"))
+	throwFail(t, AssertIs(posts[1].User.UserName, "astaxie"))
+}
+
 func TestRawValues(t *testing.T) {
 	Q := dDbBaser.TableQuote()
 
@@ -1525,6 +1680,34 @@ func TestRawValues(t *testing.T) {
 	}
 }
 
+func TestRawRowsToMaps(t *testing.T) {
+	Q := dDbBaser.TableQuote()
+
+	query := fmt.Sprintf("SELECT T0.%suser_name%s, T0.%sis_staff%s, T0.%screated%s, T1.%sage%s, T1.%smoney%s "+
+		"FROM %suser%s T0 LEFT OUTER JOIN %suser_profile%s T1 ON T1.%sid%s = T0.%sprofile_id%s ORDER BY T0.%sid%s ASC",
+		Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q, Q)
+	maps, err := dORM.Raw(query).RowsToMaps()
+	throwFailNow(t, err)
+	throwFailNow(t, AssertIs(len(maps), 3))
+
+	throwFail(t, AssertIs(maps[0]["user_name"], "slene"))
+	throwFail(t, AssertIs(maps[0]["age"], int64(28)))
+	throwFail(t, AssertIs(maps[0]["money"], 1234.12))
+	_, ok := maps[0]["created"].(time.Time)
+	throwFail(t, AssertIs(ok, true))
+	if IsPostgres {
+		throwFail(t, AssertIs(maps[0]["is_staff"], false))
+	}
+
+	throwFail(t, AssertIs(maps[1]["user_name"], "astaxie"))
+	throwFail(t, AssertIs(maps[1]["age"], int64(30)))
+
+	// nobody has no profile
+	throwFail(t, AssertIs(maps[2]["user_name"], "nobody"))
+	throwFail(t, AssertIs(maps[2]["age"], nil))
+	throwFail(t, AssertIs(maps[2]["money"], nil))
+}
+
 func TestRawPrepare(t *testing.T) {
 	switch {
 	case IsMysql || IsSqlite:
@@ -1637,6 +1820,54 @@ func TestUpdate(t *testing.T) {
 	err = dORM.Read(&user, "UserName")
 	throwFail(t, err)
 	throwFail(t, AssertIs(user.Nums, 30))
+
+	num, err = qs.Filter("user_name", "slene").Update(Params{
+		"Nums": ColValue(ColBitOr, 5),
+	})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+
+	num, err = qs.Filter("user_name", "slene").Update(Params{
+		"Nums": ColValue(ColBitAnd, 12),
+	})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+
+	err = dORM.Read(&user, "UserName")
+	throwFail(t, err)
+	throwFail(t, AssertIs(user.Nums, 12)) // (30 | 5) & 12
+
+	// increments are done by database, none of them are lost.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_, err := qs.Filter("user_name", "slene").Update(Params{
+					"Nums": ColValue(ColAdd, 1),
+				})
+				throwFail(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	err = dORM.Read(&user, "UserName")
+	throwFail(t, err)
+	throwFail(t, AssertIs(user.Nums, 112))
+
+	for _, d := range []DriverType{DR_MySQL, DR_Postgres} {
+		Q := dbBasers[d].TableQuote()
+		col := Q + "nums" + Q
+		for opt, op := range map[operator]string{ColAdd: "+", ColMinus: "-", ColMultiply: "*", ColDivide: "/", ColBitAnd: "&", ColBitOr: "|"} {
+			set, arg := getColumnSetSql(col, ColValue(opt, 2))
+			throwFail(t, AssertIs(set, col+" = "+col+" "+op+" ?"))
+			throwFail(t, AssertIs(arg, int64(2)))
+		}
+	}
+	set, arg := getColumnSetSql("`nums`", 3)
+	throwFail(t, AssertIs(set, "`nums` = ?"))
+	throwFail(t, AssertIs(arg, 3))
 }
 
 func TestDelete(t *testing.T) {
@@ -1766,4 +1997,584 @@ func TestReadOrCreate(t *testing.T) {
 	throwFail(t, AssertIs(nu.IsActive, u.IsActive))
 
 	dORM.Delete(u)
+
+	// only one of concurrent calls creates the row, the others read it.
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	creates := 0
+	pks := make(map[int64]bool)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ru := &User{UserName: "racer", Email: "racer@gmail.com"}
+			created, pk, err := dORM.ReadOrCreate(ru, "UserName")
+			throwFail(t, err)
+			lock.Lock()
+			defer lock.Unlock()
+			if created {
+				creates++
+			}
+			pks[pk] = true
+		}()
+	}
+	wg.Wait()
+	throwFail(t, AssertIs(creates, 1))
+	throwFail(t, AssertIs(len(pks), 1))
+	num, err := dORM.QueryTable("user").Filter("user_name", "racer").Delete()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+}
+
+func TestExists(t *testing.T) {
+	qs := dORM.QueryTable("user")
+	exist, err := qs.Filter("user_name", "slene").Exists()
+	throwFail(t, err)
+	throwFail(t, AssertIs(exist, true))
+
+	exist, err = qs.Filter("user_name", "nothing").Exists()
+	throwFail(t, err)
+	throwFail(t, AssertIs(exist, false))
+
+	exist, err = qs.Filter("profile__age", 30).Exists()
+	throwFail(t, err)
+	throwFail(t, AssertIs(exist, true))
+	throwFail(t, AssertIs(qs.Filter("profile__age", 30).Exist(), true))
+}
+
+func TestInsertMulti(t *testing.T) {
+	if IsMysql {
+		// ids of mysql depend on innodb_autoinc_lock_mode, they are only set if it's turned on.
+		tags := []*Tag{{Name: "bulk"}, {Name: "bulk"}}
+		num, err := dORM.InsertMulti(2, tags)
+		throwFailNow(t, err)
+		throwFail(t, AssertIs(num, 2))
+		throwFail(t, AssertIs(tags[0].Id, 0))
+		_, err = dORM.QueryTable("tag").Filter("name", "bulk").Delete()
+		throwFail(t, err)
+
+		MysqlConsecutiveIds = true
+		defer func() { MysqlConsecutiveIds = false }()
+	}
+	tags := make([]*Tag, 1000)
+	for i := range tags {
+		tags[i] = &Tag{Name: fmt.Sprintf("bulk%d", i)}
+	}
+	num, err := dORM.InsertMulti(100, tags)
+	throwFailNow(t, err)
+	throwFail(t, AssertIs(num, 1000))
+
+	ids := make(map[int]bool, len(tags))
+	for _, tag := range tags {
+		throwFailNow(t, AssertIs(tag.Id > 0, true))
+		ids[tag.Id] = true
+	}
+	throwFail(t, AssertIs(len(ids), 1000))
+
+	tag := &Tag{Id: tags[512].Id}
+	err = dORM.Read(tag)
+	throwFail(t, err)
+	throwFail(t, AssertIs(tag.Name, "bulk512"))
+
+	num, err = dORM.QueryTable("tag").Filter("name__startswith", "bulk").Delete()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1000))
+}
+
+func benchmarkInsertMulti(b *testing.B, bulk int) {
+	tags := make([]*Tag, 1000)
+	for i := 0; i < b.N; i++ {
+		for j := range tags {
+			tags[j] = &Tag{Name: fmt.Sprintf("bench%d", j)}
+		}
+		if _, err := dORM.InsertMulti(bulk, tags); err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		dORM.QueryTable("tag").Filter("name__startswith", "bench").Delete()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkInsertMultiLoop(b *testing.B) {
+	benchmarkInsertMulti(b, 1)
+}
+
+func BenchmarkInsertMultiBatch(b *testing.B) {
+	benchmarkInsertMulti(b, 100)
+}
+
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	o := dORM.WithContext(ctx)
+
+	var users []*User
+	_, err := o.QueryTable("user").All(&users)
+	throwFail(t, AssertIs(err, context.Canceled))
+	_, err = o.QueryTable("user").Count()
+	throwFail(t, AssertIs(err, context.Canceled))
+	_, err = o.QueryTable("user").Filter("user_name", "nobody").Update(Params{"nums": 1})
+	throwFail(t, AssertIs(err, context.Canceled))
+
+	// old methods still work without context
+	_, err = dORM.QueryTable("user").Count()
+	throwFail(t, err)
+
+	var sleep string
+	switch {
+	case IsMysql:
+		sleep = "SELECT SLEEP(3)"
+	case IsPostgres:
+		sleep = "SELECT pg_sleep(3)"
+	default:
+		// sqlite has no sleep function
+		return
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = dORM.WithContext(ctx).Raw(sleep).Exec()
+	throwFail(t, AssertIs(err != nil, true))
+	throwFail(t, AssertIs(time.Since(start) < 2*time.Second, true))
+}
+
+func TestWithContextBegin(t *testing.T) {
+	debug, debugLog := Debug, DebugLog
+	Debug, DebugLog = true, NewLog(ioutil.Discard)
+	defer func() { Debug, DebugLog = debug, debugLog }()
+
+	al := getDbAlias("default")
+	o := NewOrm()
+	n := o.WithContext(context.Background())
+	throwFailNow(t, n.Begin())
+	// the query log of o is not moved into the transaction of the copy
+	throwFail(t, AssertIs(rawDB(o.(*orm).db) == dbQuerier(al.DB), true))
+	throwFail(t, AssertIs(rawDB(n.(*orm).db) != dbQuerier(al.DB), true))
+	_, err := o.QueryTable("user").Count()
+	throwFail(t, err)
+	throwFail(t, n.Rollback())
+	throwFail(t, AssertIs(rawDB(o.(*orm).db) == dbQuerier(al.DB), true))
+	throwFail(t, AssertIs(rawDB(n.(*orm).db) == dbQuerier(al.DB), true))
+
+	// copy in transaction shares it
+	throwFailNow(t, o.Begin())
+	n = o.WithContext(context.Background())
+	throwFail(t, AssertIs(rawDB(n.(*orm).db) == rawDB(o.(*orm).db), true))
+	throwFail(t, o.Rollback())
+}
+
+func TestCapture(t *testing.T) {
+	var queries []string
+	var args [][]interface{}
+	o := dORM.Capture(func(query string, a []interface{}) {
+		queries = append(queries, query)
+		args = append(args, a)
+	})
+
+	num, err := o.QueryTable("user").Filter("user_name", "slene").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFailNow(t, AssertIs(len(queries), 1))
+	Q := dDbBaser.TableQuote()
+	prefix := fmt.Sprintf("SELECT COUNT(*) FROM %suser%s T0 WHERE T0.%suser_name%s ", Q, Q, Q, Q)
+	throwFail(t, AssertIs(strings.HasPrefix(queries[0], prefix), true))
+	throwFailNow(t, AssertIs(len(args[0]), 1))
+	throwFail(t, AssertIs(args[0][0], "slene"))
+
+	// ormer is not changed
+	_, err = dORM.QueryTable("user").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(len(queries), 1))
+
+	// queries in transaction are captured
+	o = NewOrm().Capture(func(query string, a []interface{}) {
+		queries = append(queries, query)
+	})
+	throwFailNow(t, o.Begin())
+	_, err = o.QueryTable("user").Filter("user_name", "slene").Update(Params{"nums": 1})
+	throwFail(t, err)
+	throwFail(t, o.Rollback())
+	throwFail(t, AssertIs(len(queries), 2))
+	throwFail(t, AssertIs(strings.HasPrefix(queries[1], "UPDATE "), true))
+
+	// transaction of a copy doesn't change the capture ormer and its parent
+	debug, debugLog := Debug, DebugLog
+	Debug, DebugLog = true, NewLog(ioutil.Discard)
+	defer func() { Debug, DebugLog = debug, debugLog }()
+	al := getDbAlias("default")
+	parent := NewOrm()
+	o = parent.Capture(func(query string, a []interface{}) {
+		queries = append(queries, query)
+	})
+	n := o.WithContext(context.Background())
+	throwFailNow(t, n.Begin())
+	throwFail(t, AssertIs(rawDB(parent.(*orm).db) == dbQuerier(al.DB), true))
+	throwFail(t, AssertIs(rawDB(o.(*orm).db) == dbQuerier(al.DB), true))
+	throwFail(t, n.Rollback())
+	throwFailNow(t, o.Begin())
+	throwFail(t, AssertIs(rawDB(parent.(*orm).db) == dbQuerier(al.DB), true))
+	_, err = o.QueryTable("user").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(len(queries), 3))
+	throwFail(t, o.Rollback())
+}
+
+func TestAdvisoryLock(t *testing.T) {
+	if IsSqlite {
+		_, _, err := dORM.AdvisoryLock("job")
+		throwFail(t, AssertIs(err, ErrNotImplement))
+		return
+	}
+	unlock, acquired, err := dORM.AdvisoryLock("job")
+	throwFailNow(t, err)
+	throwFailNow(t, AssertIs(acquired, true))
+
+	// held by the connection of the first lock
+	again, acquired, err := NewOrm().AdvisoryLock("job")
+	throwFail(t, err)
+	throwFail(t, AssertIs(acquired, false))
+	throwFail(t, AssertIs(again == nil, true))
+
+	other, acquired, err := NewOrm().AdvisoryLock("other job")
+	throwFail(t, err)
+	throwFailNow(t, AssertIs(acquired, true))
+	throwFail(t, other())
+
+	throwFail(t, unlock())
+	again, acquired, err = NewOrm().AdvisoryLock("job")
+	throwFail(t, err)
+	throwFailNow(t, AssertIs(acquired, true))
+	throwFail(t, again())
+}
+
+func TestSoftDelete(t *testing.T) {
+	a1 := &Article{Title: "soft1"}
+	a2 := &Article{Title: "soft2"}
+	_, err := dORM.Insert(a1)
+	throwFailNow(t, err)
+	_, err = dORM.Insert(a2)
+	throwFailNow(t, err)
+
+	qs := dORM.QueryTable("article")
+	num, err := dORM.Delete(a1)
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(a1.DeletedAt.IsZero(), false))
+
+	// filtered reads
+	num, err = qs.Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	var articles []*Article
+	num, err = qs.All(&articles)
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(articles[0].Title, "soft2"))
+	throwFail(t, AssertIs(articles[0].DeletedAt.IsZero(), true))
+	err = qs.Filter("title", "soft1").One(new(Article))
+	throwFail(t, AssertIs(err, ErrNoRows))
+	num, err = qs.IncludeDeleted().Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 2))
+
+	num, err = qs.Filter("title", "soft2").Delete()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	num, err = qs.Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 0))
+
+	// restore
+	num, err = qs.Filter("title", "soft1").Restore()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	article := new(Article)
+	err = qs.Filter("title", "soft1").One(article)
+	throwFail(t, err)
+	throwFail(t, AssertIs(article.DeletedAt.IsZero(), true))
+
+	// force delete
+	num, err = qs.Filter("title__in", "soft1", "soft2").ForceDelete()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 2))
+	num, err = qs.IncludeDeleted().Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 0))
+}
+
+func TestReplica(t *testing.T) {
+	// db querier of QuerySeter reads without debug log
+	readOf := func(qs QuerySeter) dbQuerier {
+		db := qs.(*querySet).readDB()
+		if l, ok := db.(*dbQueryLog); ok {
+			return l.db
+		}
+		return db
+	}
+
+	al := getDbAlias("default")
+	throwFailNow(t, RegisterReplica("default", DBARGS.Driver, DBARGS.Source))
+	throwFailNow(t, RegisterReplica("default", DBARGS.Driver, DBARGS.Source))
+	defer func() {
+		for _, db := range al.Replicas {
+			db.Close()
+		}
+		al.Replicas = nil
+	}()
+	throwFail(t, AssertIs(len(al.Replicas), 2))
+	throwFail(t, AssertIs(RegisterReplica("unknown", DBARGS.Driver, DBARGS.Source) != nil, true))
+
+	o := NewOrm()
+	qs := o.QueryTable("user")
+	first := readOf(qs)
+	second := readOf(qs)
+	throwFail(t, AssertIs(first != second, true))
+	throwFail(t, AssertIs(first == al.Replicas[0] || first == al.Replicas[1], true))
+	throwFail(t, AssertIs(second == al.Replicas[0] || second == al.Replicas[1], true))
+	throwFail(t, AssertIs(readOf(qs), first))
+
+	// reads after write
+	throwFail(t, AssertIs(readOf(qs.UsingMaster()), o.(*orm).db))
+	throwFail(t, AssertIs(readOf(qs.UsingMaster().Filter("user_name", "slene")), o.(*orm).db))
+
+	// transaction always uses master
+	throwFailNow(t, o.Begin())
+	throwFail(t, AssertIs(readOf(o.QueryTable("user")), o.(*orm).db))
+	throwFail(t, o.Rollback())
+
+	if IsSqlite {
+		// every connection of sqlite memory database is a new database
+		return
+	}
+	num, err := qs.Count()
+	throwFail(t, err)
+	masterNum, err := qs.UsingMaster().Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, masterNum))
+}
+
+func TestJSONField(t *testing.T) {
+	data := DocumentData{
+		Title:  "orm",
+		Tags:   []string{"golang", "beego"},
+		Author: DocumentAuthor{Name: "slene", Email: "vslene@gmail.com"},
+	}
+	doc := &Document{Data: data, Meta: map[string]string{"lang": "go"}}
+	id, err := dORM.Insert(doc)
+	throwFailNow(t, err)
+	throwFail(t, AssertIs(id > 0, true))
+
+	d := &Document{Id: doc.Id}
+	throwFailNow(t, dORM.Read(d))
+	throwFail(t, AssertIs(reflect.DeepEqual(d.Data, data), true))
+	throwFail(t, AssertIs(d.Meta["lang"], "go"))
+	// NULL is read as nil
+	throwFail(t, AssertIs(d.Prev == nil, true))
+
+	var maps []Params
+	num, err := dORM.QueryTable("document").Filter("id", doc.Id).Values(&maps, "Prev")
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(maps[0]["Prev"] == nil, true))
+
+	d.Prev = &data
+	d.Data.Tags = nil
+	d.Meta = nil
+	_, err = dORM.Update(d)
+	throwFailNow(t, err)
+	d = &Document{Id: doc.Id}
+	throwFailNow(t, dORM.Read(d))
+	throwFail(t, AssertIs(d.Prev != nil && reflect.DeepEqual(*d.Prev, data), true))
+	throwFail(t, AssertIs(d.Data.Tags == nil, true))
+	throwFail(t, AssertIs(d.Meta == nil, true))
+
+	num, err = dORM.QueryTable("document").Filter("id", doc.Id).Update(Params{"meta": map[string]string{"lang": "zh"}})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFailNow(t, dORM.Read(d))
+	throwFail(t, AssertIs(d.Meta["lang"], "zh"))
+
+	if IsPostgres {
+		num, err = dORM.QueryTable("document").Filter("data__contains", map[string]interface{}{
+			"Author": map[string]string{"Name": "slene"},
+		}).Count()
+		throwFail(t, err)
+		throwFail(t, AssertIs(num, 1))
+		num, err = dORM.QueryTable("document").Filter("data__contains", map[string]string{"Title": "nobody"}).Count()
+		throwFail(t, err)
+		throwFail(t, AssertIs(num, 0))
+	}
+}
+
+func TestAutoNow(t *testing.T) {
+	start := time.Now().Unix()
+
+	// zero values are set on insert
+	s := &Stamp{Name: "new"}
+	_, err := dORM.Insert(s)
+	throwFailNow(t, err)
+	throwFail(t, AssertIs(s.Created.Unix() >= start, true))
+	throwFail(t, AssertIs(s.Updated.Unix() >= start, true))
+	throwFail(t, AssertIs(s.CreatedAt >= start, true))
+	throwFail(t, AssertIs(s.UpdatedAt >= start, true))
+
+	// created stays fixed across updates while updated changes
+	past := time.Now().Add(-time.Hour)
+	s = &Stamp{Name: "old", Created: past, Updated: past, CreatedAt: past.Unix(), UpdatedAt: past.Unix()}
+	_, err = dORM.Insert(s)
+	throwFailNow(t, err)
+	s = &Stamp{Id: s.Id}
+	throwFailNow(t, dORM.Read(s))
+	throwFail(t, AssertIs(s.Created.Unix(), past.Unix()))
+	throwFail(t, AssertIs(s.UpdatedAt, past.Unix()))
+
+	s.Name = "updated"
+	_, err = dORM.Update(s)
+	throwFailNow(t, err)
+	throwFail(t, AssertIs(s.Updated.Unix() >= start, true))
+	throwFail(t, AssertIs(s.UpdatedAt >= start, true))
+	s = &Stamp{Id: s.Id}
+	throwFailNow(t, dORM.Read(s))
+	throwFail(t, AssertIs(s.Created.Unix(), past.Unix()))
+	throwFail(t, AssertIs(s.CreatedAt, past.Unix()))
+	throwFail(t, AssertIs(s.Updated.Unix() >= start, true))
+	throwFail(t, AssertIs(s.UpdatedAt >= start, true))
+
+	// auto_now fields are updated with specified columns too
+	_, err = dORM.QueryTable("stamp").Filter("id", s.Id).Update(Params{"updated_at": past.Unix()})
+	throwFailNow(t, err)
+	s.Name = "name only"
+	_, err = dORM.Update(s, "Name")
+	throwFailNow(t, err)
+	s = &Stamp{Id: s.Id}
+	throwFailNow(t, dORM.Read(s))
+	throwFail(t, AssertIs(s.Name, "name only"))
+	throwFail(t, AssertIs(s.UpdatedAt >= start, true))
+	throwFail(t, AssertIs(s.CreatedAt, past.Unix()))
+
+	stamps := []*Stamp{{Name: "multi1"}, {Name: "multi2"}}
+	_, err = dORM.InsertMulti(2, stamps)
+	throwFailNow(t, err)
+	for _, s := range stamps {
+		throwFail(t, AssertIs(s.Created.Unix() >= start, true))
+		throwFail(t, AssertIs(s.UpdatedAt >= start, true))
+	}
+	var rows []*Stamp
+	num, err := dORM.QueryTable("stamp").Filter("name__startswith", "multi").All(&rows)
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 2))
+	for _, s := range rows {
+		throwFail(t, AssertIs(s.Created.Unix() >= start, true))
+		throwFail(t, AssertIs(s.CreatedAt >= start, true))
+	}
+}
+
+func TestConnPool(t *testing.T) {
+	throwFailNow(t, RegisterDataBase("pool", DBARGS.Driver, DBARGS.Source, 5, 10, 60))
+	al := getDbAlias("pool")
+	defer al.DB.Close()
+	throwFail(t, AssertIs(al.MaxIdleConns, 5))
+	throwFail(t, AssertIs(al.MaxOpenConns, 10))
+	throwFail(t, AssertIs(al.ConnMaxLifetime, time.Minute))
+	throwFail(t, AssertIs(al.DB.Stats().MaxOpenConnections, 10))
+
+	SetMaxOpenConns("pool", 3)
+	throwFail(t, AssertIs(al.DB.Stats().MaxOpenConnections, 3))
+
+	// the conn of ping is kept idle, then closed once idle conns are limited to 0
+	throwFailNow(t, al.DB.Ping())
+	throwFail(t, AssertIs(al.DB.Stats().Idle, 1))
+	SetMaxIdleConns("pool", 0)
+	throwFail(t, AssertIs(al.DB.Stats().Idle, 0))
+	throwFail(t, AssertIs(al.DB.Stats().MaxIdleClosed, int64(1)))
+
+	SetConnMaxLifetime("pool", time.Second)
+	throwFail(t, AssertIs(al.ConnMaxLifetime, time.Second))
+}
+
+// schema of registered models as they're created by syncdb.
+func mockDbSchema(al *alias) *dbSchema {
+	schema := &dbSchema{
+		tables:  make(map[string]bool),
+		columns: make(map[string]map[string][3]string),
+		indexes: make(map[string]bool),
+	}
+	_, indexes := getDbCreateSql(al)
+	for _, mi := range modelCache.allOrdered() {
+		schema.tables[mi.table] = true
+		columns := make(map[string][3]string)
+		for _, fi := range mi.fields.fieldsDB {
+			null := "NO"
+			if fi.null {
+				null = "YES"
+			}
+			columns[fi.column] = [3]string{fi.column, getColumnTyp(al, fi), null}
+		}
+		schema.columns[mi.table] = columns
+		for _, idx := range indexes[mi.table] {
+			schema.indexes[idx.Table+"."+idx.Name] = true
+		}
+	}
+	return schema
+}
+
+func TestGenerateMigration(t *testing.T) {
+	cases := []struct {
+		al     *alias
+		sqls   []string
+		dropTo string
+	}{
+		{&alias{Name: "mysql", Driver: DR_MySQL, DbBaser: dbBasers[DR_MySQL], Engine: "INNODB"}, []string{
+			"ALTER TABLE `user` MODIFY COLUMN `email` varchar(100) NOT NULL",
+			"ALTER TABLE `user` ADD COLUMN `nums` integer NOT NULL",
+			"CREATE INDEX `user_id_user_name` ON `user` (`id`, `user_name`);",
+		}, "ALTER TABLE `user` DROP COLUMN `legacy`"},
+		{&alias{Name: "postgres", Driver: DR_Postgres, DbBaser: dbBasers[DR_Postgres]}, []string{
+			`ALTER TABLE "user" ALTER COLUMN "email" TYPE varchar(100)`,
+			`ALTER TABLE "user" ADD COLUMN "nums" integer NOT NULL`,
+			`CREATE INDEX "user_id_user_name" ON "user" ("id", "user_name");`,
+		}, `ALTER TABLE "user" DROP COLUMN "legacy"`},
+	}
+	for _, c := range cases {
+		schema := mockDbSchema(c.al)
+		throwFail(t, AssertIs(len(getMigrationSql(c.al, schema, true)), 0))
+
+		columns := schema.columns["user"]
+		delete(columns, "nums")
+		columns["email"] = [3]string{"email", "varchar(50)", "NO"}
+		columns["legacy"] = [3]string{"legacy", "text", "YES"}
+		delete(schema.indexes, "user.user_id_user_name")
+
+		sqls := getMigrationSql(c.al, schema, false)
+		throwFailNow(t, AssertIs(len(sqls), len(c.sqls)))
+		for i, sql := range c.sqls {
+			throwFail(t, AssertIs(sqls[i], sql))
+		}
+
+		sqls = getMigrationSql(c.al, schema, true)
+		throwFailNow(t, AssertIs(len(sqls), len(c.sqls)+1))
+		throwFail(t, AssertIs(sqls[len(c.sqls)], c.dropTo))
+	}
+
+	// types as information_schema reports them
+	for typ, col := range map[string]string{
+		"int(11)":             "integer",
+		"bigint(20) unsigned": "bigint unsigned",
+		"tinyint(1)":          "bool",
+		"tinyint(4)":          "tinyint",
+		"decimal(8,4)":        "numeric(8, 4)",
+		"double":              "double precision",
+	} {
+		throwFail(t, AssertIs(normalizeColumnTyp(DR_MySQL, typ), normalizeColumnTyp(DR_MySQL, col)))
+	}
+	for typ, col := range map[string]string{
+		"character varying(100)": "varchar(100)",
+		"boolean":                "bool",
+		"smallint":               `smallint CHECK("status" >= -127 AND "status" <= 128)`,
+		"numeric(8,4)":           "numeric(8, 4)",
+	} {
+		throwFail(t, AssertIs(normalizeColumnTyp(DR_Postgres, typ), normalizeColumnTyp(DR_Postgres, col)))
+	}
+	throwFail(t, AssertIs(normalizeColumnTyp(DR_MySQL, "tinyint(1)") == normalizeColumnTyp(DR_MySQL, "tinyint"), false))
 }
@@ -1,6 +1,7 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"reflect"
 	"time"
@@ -35,6 +36,9 @@ type Ormer interface {
 	Begin() error
 	Commit() error
 	Rollback() error
+	WithContext(context.Context) Ormer
+	Capture(func(query string, args []interface{})) Ormer
+	AdvisoryLock(string) (func() error, bool, error)
 	Raw(string, ...interface{}) RawSeter
 	Driver() Driver
 	GetDB() dbQuerier
@@ -46,6 +50,14 @@ type Inserter interface {
 	Close() error
 }
 
+// prepared select statement of QuerySeter.
+// it's safe for concurrent use, Close waits for running Exec and Query.
+type QueryPreparer interface {
+	Exec(...interface{}) (sql.Result, error)
+	Query(interface{}, ...interface{}) (int64, error)
+	Close() error
+}
+
 // query seter
 type QuerySeter interface {
 	Filter(string, ...interface{}) QuerySeter
@@ -57,9 +69,15 @@ type QuerySeter interface {
 	RelatedSel(...interface{}) QuerySeter
 	Count() (int64, error)
 	Exist() bool
+	Exists() (bool, error)
 	Update(Params) (int64, error)
 	Delete() (int64, error)
+	ForceDelete() (int64, error)
+	Restore() (int64, error)
+	IncludeDeleted() QuerySeter
+	UsingMaster() QuerySeter
 	PrepareInsert() (Inserter, error)
+	Prepare(...string) (QueryPreparer, error)
 	All(interface{}, ...string) (int64, error)
 	One(interface{}, ...string) error
 	Values(*[]Params, ...string) (int64, error)
@@ -93,6 +111,7 @@ type RawSeter interface {
 	Values(*[]Params, ...string) (int64, error)
 	ValuesList(*[]ParamsList, ...string) (int64, error)
 	ValuesFlat(*ParamsList, ...string) (int64, error)
+	RowsToMaps() ([]Params, error)
 	RowsToMap(*Params, string, string) (int64, error)
 	RowsToStruct(interface{}, string, string) (int64, error)
 	Prepare() (RawPreparer, error)
@@ -133,12 +152,20 @@ type txEnder interface {
 	Rollback() error
 }
 
+// db querier wrapping another one, e.g. the query log.
+// Begin replaces the wrapped querier by the transaction.
+type dbWrapper interface {
+	SetDB(dbQuerier)
+}
+
 // base database struct
 type dbBaser interface {
 	Read(dbQuerier, *modelInfo, reflect.Value, *time.Location, []string) error
 	Insert(dbQuerier, *modelInfo, reflect.Value, *time.Location) (int64, error)
 	InsertMulti(dbQuerier, *modelInfo, reflect.Value, int, *time.Location) (int64, error)
 	InsertValue(dbQuerier, *modelInfo, bool, []string, []interface{}) (int64, error)
+	InsertRows(dbQuerier, *modelInfo, []string, []interface{}, int) (int64, []int64, error)
+	MultiInsertIds(int64, int) []int64
 	InsertStmt(stmtQuerier, *modelInfo, reflect.Value, *time.Location) (int64, error)
 	Update(dbQuerier, *modelInfo, reflect.Value, *time.Location, []string) (int64, error)
 	Delete(dbQuerier, *modelInfo, reflect.Value, *time.Location) (int64, error)
@@ -147,6 +174,7 @@ type dbBaser interface {
 	UpdateBatch(dbQuerier, *querySet, *modelInfo, *Condition, Params, *time.Location) (int64, error)
 	DeleteBatch(dbQuerier, *querySet, *modelInfo, *Condition, *time.Location) (int64, error)
 	Count(dbQuerier, *querySet, *modelInfo, *Condition, *time.Location) (int64, error)
+	Exists(dbQuerier, *querySet, *modelInfo, *Condition, *time.Location) (bool, error)
 	OperatorSql(string) string
 	GenerateOperatorSql(*modelInfo, *fieldInfo, string, []interface{}, *time.Location) (string, []interface{})
 	GenerateOperatorLeftCol(*fieldInfo, string, *string)
@@ -165,5 +193,6 @@ type dbBaser interface {
 	ShowTablesQuery() string
 	ShowColumnsQuery(string) string
 	IndexExists(dbQuerier, string, string) bool
+	AdvisoryLockQueries() (string, string)
 	collectFieldValue(*modelInfo, *fieldInfo, reflect.Value, bool, *time.Location) (interface{}, error)
 }
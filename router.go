@@ -54,6 +54,7 @@ type controllerInfo struct {
 	controllerType reflect.Type
 	methods        map[string]string
 	hasMethod      bool
+	filters        []FilterFunc // filters of router group, run before controller
 }
 
 // ControllerRegistor containers registered router rules, controller handlers and filters.
@@ -86,6 +87,11 @@ func NewControllerRegistor() *ControllerRegistor {
 //	Add("/api",&RestController{},"get,post:ApiFunc")
 //	Add("/simple",&SimpleController{},"get:GetFunc;post:PostFunc")
 func (p *ControllerRegistor) Add(pattern string, c ControllerInterface, mappingMethods ...string) {
+	p.add(pattern, c, nil, mappingMethods...)
+}
+
+// add controller handler with filters running before it.
+func (p *ControllerRegistor) add(pattern string, c ControllerInterface, filters []FilterFunc, mappingMethods ...string) {
 	parts := strings.Split(pattern, "/")
 
 	j := 0
@@ -200,6 +206,7 @@ func (p *ControllerRegistor) Add(pattern string, c ControllerInterface, mappingM
 		route.pattern = pattern
 		route.controllerType = t
 		route.methods = methods
+		route.filters = filters
 		if len(methods) > 0 {
 			route.hasMethod = true
 		}
@@ -222,6 +229,7 @@ func (p *ControllerRegistor) Add(pattern string, c ControllerInterface, mappingM
 		route.params = params
 		route.pattern = pattern
 		route.methods = methods
+		route.filters = filters
 		if len(methods) > 0 {
 			route.hasMethod = true
 		}
@@ -492,9 +500,13 @@ func (p *ControllerRegistor) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 	var runrouter reflect.Type
 	var findrouter bool
 	var runMethod string
+	var runFilters []FilterFunc
 	params := make(map[string]string)
+	allow := make(map[string]bool) // methods of routes matching the path but not the method
 
 	w := &responseWriter{writer: rw}
+	// the body of HEAD answered by GET is discarded.
+	w.discardBody = AutoHeadOptions && r.Method == "HEAD"
 	w.Header().Set("Server", BeegoServerName)
 
 	// init context
@@ -534,7 +546,9 @@ func (p *ControllerRegistor) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 	if SessionOn {
 		context.Input.CruSession = GlobalSessions.SessionStart(w, r)
 		defer func() {
-			context.Input.CruSession.SessionRelease(w)
+			if err := GlobalSessions.SessionReleaseRequest(w, r, context.Input.CruSession); err != nil {
+				Error("session release error:", err)
+			}
 		}()
 	}
 
@@ -576,9 +590,11 @@ func (p *ControllerRegistor) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 				runMethod = p.getRunMethod(r.Method, context, route)
 				if runMethod != "" {
 					runrouter = route.controllerType
+					runFilters = route.filters
 					findrouter = true
 					break
 				}
+				route.allowMethods(allow)
 			}
 			// pattern /admin   url /admin 200  /admin/ 200
 			// pattern /admin/  url /admin 301  /admin/ 200
@@ -590,9 +606,11 @@ func (p *ControllerRegistor) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 				runMethod = p.getRunMethod(r.Method, context, route)
 				if runMethod != "" {
 					runrouter = route.controllerType
+					runFilters = route.filters
 					findrouter = true
 					break
 				}
+				route.allowMethods(allow)
 			}
 		}
 	}
@@ -628,13 +646,22 @@ func (p *ControllerRegistor) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 			runMethod = p.getRunMethod(r.Method, context, route)
 			if runMethod != "" {
 				runrouter = route.controllerType
+				runFilters = route.filters
 				context.Input.Params = params
 				findrouter = true
 				break
 			}
+			route.allowMethods(allow)
 		}
 	}
 
+	// answer OPTIONS of routes without options mapping by the methods of the path.
+	if !findrouter && AutoHeadOptions && r.Method == "OPTIONS" && len(allow) > 0 {
+		w.Header().Set("Allow", allowHeader(allow))
+		w.WriteHeader(http.StatusOK)
+		goto Admin
+	}
+
 	if !findrouter && p.enableAuto {
 		// deal with url with diffirent ext
 		// /controller/simple
@@ -698,6 +725,14 @@ func (p *ControllerRegistor) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 			goto Admin
 		}
 
+		//execute filters of router group
+		for _, filter := range runFilters {
+			filter(context)
+			if w.started {
+				goto Admin
+			}
+		}
+
 		//Invoke the request handler
 		vc := reflect.New(runrouter)
 		execController, ok := vc.Interface().(ControllerInterface)
@@ -812,20 +847,92 @@ func (p *ControllerRegistor) getRunMethod(method string, context *beecontext.Con
 			return m
 		} else if m, ok = router.methods["*"]; ok {
 			return m
+		} else if m, ok = router.methods["get"]; ok && method == "head" && AutoHeadOptions {
+			return m
 		} else {
 			return ""
 		}
 	} else {
+		// controllers without Head run Get, controllers without Options are answered by their methods.
+		if AutoHeadOptions && method == "head" && !declaresMethod(router.controllerType, "Head") {
+			return "Get"
+		}
+		if AutoHeadOptions && method == "options" && !declaresMethod(router.controllerType, "Options") {
+			return ""
+		}
 		return strings.Title(method)
 	}
 }
 
+// add methods mapped by route to allow, HEAD is added with GET if AutoHeadOptions is on.
+// routes without method mappings add the methods their controller has.
+func (route *controllerInfo) allowMethods(allow map[string]bool) {
+	methods := route.methods
+	if !route.hasMethod {
+		methods = make(map[string]string)
+		for _, m := range HTTPMETHOD {
+			if declaresMethod(route.controllerType, strings.Title(m)) {
+				methods[m] = strings.Title(m)
+			}
+		}
+	}
+	for m := range methods {
+		allow[m] = true
+		if m == "get" && AutoHeadOptions {
+			allow["head"] = true
+		}
+	}
+	allow["options"] = true
+}
+
+var baseControllerType = reflect.TypeOf(Controller{})
+
+// check whether controller type t or a struct embedded in it declares method name.
+// methods of the embedded Controller only answer 405, they don't count.
+// promoted methods are autogenerated wrappers, so their file tells them from declared ones.
+func declaresMethod(t reflect.Type, name string) bool {
+	if t == baseControllerType {
+		return false
+	}
+	for _, typ := range []reflect.Type{t, reflect.PtrTo(t)} {
+		if m, ok := typ.MethodByName(name); ok {
+			pc := m.Func.Pointer()
+			if file, _ := runtime.FuncForPC(pc).FileLine(pc); file != "<autogenerated>" {
+				return true
+			}
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct && declaresMethod(ft, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// get Allow header of methods in allow, in the order of HTTPMETHOD.
+func allowHeader(allow map[string]bool) string {
+	methods := make([]string, 0, len(allow))
+	for _, m := range HTTPMETHOD {
+		if allow[m] {
+			methods = append(methods, strings.ToUpper(m))
+		}
+	}
+	return strings.Join(methods, ", ")
+}
+
 //responseWriter is a wrapper for the http.ResponseWriter
 //started set to true if response was written to then don't execute other handler
 type responseWriter struct {
-	writer  http.ResponseWriter
-	started bool
-	status  int
+	writer      http.ResponseWriter
+	started     bool
+	status      int
+	discardBody bool // response of HEAD request
 }
 
 // Header returns the header map that will be sent by WriteHeader.
@@ -838,6 +945,9 @@ func (w *responseWriter) Header() http.Header {
 // started means the response has sent out.
 func (w *responseWriter) Write(p []byte) (int, error) {
 	w.started = true
+	if w.discardBody {
+		return len(p), nil
+	}
 	return w.writer.Write(p)
 }
 
@@ -849,6 +959,13 @@ func (w *responseWriter) WriteHeader(code int) {
 	w.writer.WriteHeader(code)
 }
 
+// Flush sends buffered data to the client if the webserver supports flushing.
+func (w *responseWriter) Flush() {
+	if f, ok := w.writer.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // hijacker for http
 func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	hj, ok := w.writer.(http.Hijacker)
@@ -3,7 +3,10 @@ package beego
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/astaxie/beego/context"
 )
 
 type TestController struct {
@@ -210,3 +213,166 @@ func TestAutoPrefix(t *testing.T) {
 		t.Errorf("TestAutoPrefix can't run")
 	}
 }
+
+func TestGroupPrefix(t *testing.T) {
+	handler := NewControllerRegistor()
+	api := handler.Group("/api")
+	api.Router("/list", &TestController{}, "*:List")
+	v1 := api.Group("v1/")
+	v1.Router("/list", &TestController{}, "*:List")
+	v1.Router("/:id([0-9]+)", &TestController{}, "*:List")
+	v1.Router("/", &TestController{}, "*:List")
+
+	for _, url := range []string{"/api/list", "/api/v1/list", "/api/v1/12", "/api/v1"} {
+		r, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Body.String() != "i am list" {
+			t.Errorf("%s should be routed by group, got %d %s", url, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGroupFilters(t *testing.T) {
+	var called []string
+	filter := func(name string) FilterFunc {
+		return func(ctx *context.Context) {
+			called = append(called, name)
+		}
+	}
+	handler := NewControllerRegistor()
+	handler.InsertFilter("*", BeforeExec, filter("global"))
+	api := handler.Group("/api", filter("api1"), filter("api2"))
+	api.Router("/list", &TestController{}, "*:List")
+	admin := api.Group("/admin", filter("admin"))
+	admin.Router("/list", &TestController{}, "*:List")
+	api.Group("/deny", func(ctx *context.Context) {
+		ctx.Output.SetStatus(403)
+		ctx.Output.Body([]byte("denied"))
+	}).Router("/list", &TestController{}, "*:List")
+	handler.Add("/list", &TestController{}, "*:List")
+
+	tests := []struct {
+		url    string
+		called string
+	}{
+		{"/api/admin/list", "global,api1,api2,admin"},
+		{"/api/list", "global,api1,api2"},
+		{"/list", "global"},
+	}
+	for _, test := range tests {
+		called = nil
+		r, _ := http.NewRequest("GET", test.url, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Body.String() != "i am list" {
+			t.Errorf("%s should run controller, got %s", test.url, w.Body.String())
+		}
+		if s := strings.Join(called, ","); s != test.called {
+			t.Errorf("%s should run filters %s, got %s", test.url, test.called, s)
+		}
+	}
+
+	called = nil
+	r, _ := http.NewRequest("GET", "/api/deny/list", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != 403 || w.Body.String() != "denied" {
+		t.Errorf("controller should be skipped if group filter writes response, got %d %s", w.Code, w.Body.String())
+	}
+	if s := strings.Join(called, ","); s != "global,api1,api2" {
+		t.Errorf("parent group filters should run before nested ones, got %s", s)
+	}
+}
+
+func TestAutoHeadOptions(t *testing.T) {
+	handler := NewControllerRegistor()
+	handler.Add("/user", &TestController{}, "get:Get")
+	handler.Add("/user/:id", &TestController{}, "get:Get;post:List")
+
+	r, _ := http.NewRequest("HEAD", "/user", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("HEAD of GET route should answer without body, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Server") != BeegoServerName {
+		t.Error("HEAD should send the headers of GET")
+	}
+
+	r, _ = http.NewRequest("OPTIONS", "/user", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Allow") != "GET, OPTIONS, HEAD" {
+		t.Errorf("OPTIONS should answer with Allow of the route, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	r, _ = http.NewRequest("OPTIONS", "/user/1", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("Allow") != "GET, POST, OPTIONS, HEAD" {
+		t.Errorf("OPTIONS should answer with Allow of the regexp route, got %q", w.Header().Get("Allow"))
+	}
+
+	AutoHeadOptions = false
+	defer func() { AutoHeadOptions = true }()
+	for _, method := range []string{"HEAD", "OPTIONS"} {
+		r, _ = http.NewRequest(method, "/user", nil)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s should not be answered with AutoHeadOptions off, got %d", method, w.Code)
+		}
+	}
+}
+
+// OptionsController embeds TestController and answers OPTIONS itself.
+type OptionsController struct {
+	TestController
+}
+
+func (this *OptionsController) Options() {
+	this.Ctx.Output.Body([]byte("options"))
+}
+
+func TestAutoHeadOptionsPlainController(t *testing.T) {
+	handler := NewControllerRegistor()
+	handler.Add("/plain", &TestController{})
+	handler.Add("/plain/:id", &OptionsController{})
+
+	r, _ := http.NewRequest("HEAD", "/plain", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("HEAD of plain controller should run Get without body, got %d %q", w.Code, w.Body.String())
+	}
+
+	r, _ = http.NewRequest("OPTIONS", "/plain", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Allow") != "GET, OPTIONS, HEAD" {
+		t.Errorf("OPTIONS should answer with methods of the controller, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	r, _ = http.NewRequest("OPTIONS", "/plain/1", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Body.String() != "options" {
+		t.Errorf("Options of the controller should be run, got %q", w.Body.String())
+	}
+	r, _ = http.NewRequest("HEAD", "/plain/1", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("Get of the embedded controller should be run for HEAD, got %d", w.Code)
+	}
+
+	AutoHeadOptions = false
+	defer func() { AutoHeadOptions = true }()
+	r, _ = http.NewRequest("OPTIONS", "/plain", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("OPTIONS should run Options of Controller with AutoHeadOptions off, got %d", w.Code)
+	}
+}
@@ -0,0 +1,331 @@
+package session
+
+// bolt session support depends on go.etcd.io/bbolt:
+//
+//	go get go.etcd.io/bbolt
+//
+// all sessions are saved in one file, bucket "session" is keyed by sid.
+// every value is 8 bytes big endian expiry unix time followed by encoded session values.
+//
+// it will be activated with these settings in app.conf:
+//
+//	SessionOn = true
+//	SessionProvider = bolt
+//	SessionSavePath = {"path":"./tmp/session.db","maxlifetime":3600}
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/astaxie/beego/session"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltpder   = &BoltProvider{}
+	bucketName = []byte("session")
+)
+
+// bolt session store
+type BoltSessionStore struct {
+	p        *BoltProvider
+	sid      string
+	lock     sync.RWMutex
+	values   map[interface{}]interface{}
+	dirty    bool
+	accessed time.Time // time of SessionRead
+}
+
+// set value in bolt session
+func (bs *BoltSessionStore) Set(key, value interface{}) error {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+	bs.dirty = true
+	bs.values[key] = value
+	return nil
+}
+
+// get value in bolt session
+func (bs *BoltSessionStore) Get(key interface{}) interface{} {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+	if v, ok := bs.values[key]; ok {
+		return v
+	}
+	return nil
+}
+
+// delete value in bolt session
+func (bs *BoltSessionStore) Delete(key interface{}) error {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+	bs.dirty = true
+	delete(bs.values, key)
+	return nil
+}
+
+// clear all values in bolt session
+func (bs *BoltSessionStore) Flush() error {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+	bs.dirty = true
+	bs.values = session.FlushValues(bs.values)
+	return nil
+}
+
+// check whether bolt session values changed since read
+func (bs *BoltSessionStore) IsDirty() bool {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+	return bs.dirty
+}
+
+// get keys of bolt session
+func (bs *BoltSessionStore) Keys() []interface{} {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+	keys := make([]interface{}, 0, len(bs.values))
+	for k := range bs.values {
+		if session.IsReservedKey(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// get count of values in bolt session
+func (bs *BoltSessionStore) Len() int {
+	return len(bs.Keys())
+}
+
+// get creation time of bolt session, zero if unknown
+func (bs *BoltSessionStore) CreatedAt() time.Time {
+	bs.lock.RLock()
+	defer bs.lock.RUnlock()
+	return session.CreatedTime(bs.values)
+}
+
+// get the time bolt session was read
+func (bs *BoltSessionStore) LastAccessedAt() time.Time {
+	return bs.accessed
+}
+
+// get bolt session id
+func (bs *BoltSessionStore) SessionID() string {
+	return bs.sid
+}
+
+// save session values to bolt file and reset expiry.
+func (bs *BoltSessionStore) SessionRelease(w http.ResponseWriter) error {
+	bs.lock.Lock()
+	defer bs.lock.Unlock()
+	bs.dirty = false
+	b, err := session.GetSerializer().Encode(bs.values)
+	if err != nil {
+		return err
+	}
+	return bs.p.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put([]byte(bs.sid), bs.p.encodeValue(b))
+	})
+}
+
+type boltConfig struct {
+	Path        string `json:"path"`
+	Maxlifetime int64  `json:"maxlifetime"`
+}
+
+// bolt session provider
+type BoltProvider struct {
+	maxlifetime int64
+	path        string
+	db          *bolt.DB
+}
+
+// init bolt session.
+// savePath is json config with db file path and optional maxlifetime,
+// e.g. {"path":"./tmp/session.db","maxlifetime":3600}
+func (bp *BoltProvider) SessionInit(maxlifetime int64, savePath string) error {
+	cf := &boltConfig{}
+	if err := json.Unmarshal([]byte(savePath), cf); err != nil {
+		return err
+	}
+	if cf.Path == "" {
+		return errors.New("session: bolt path is empty")
+	}
+	bp.maxlifetime = maxlifetime
+	if cf.Maxlifetime > 0 {
+		bp.maxlifetime = cf.Maxlifetime
+	}
+	bp.path = cf.Path
+	db, err := bolt.Open(bp.path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return err
+	}
+	bp.db = db
+	return nil
+}
+
+// read bolt session by sid.
+// a new empty session is returned if sid is not saved or expired.
+func (bp *BoltProvider) SessionRead(sid string) (session.SessionStore, error) {
+	kv, err := bp.readValues(sid)
+	if err != nil {
+		return nil, err
+	}
+	return &BoltSessionStore{p: bp, sid: sid, values: session.InitCreated(kv), accessed: session.Now()}, nil
+}
+
+// check bolt session exist by sid.
+// sessions not removed by gc yet are treated as expired.
+func (bp *BoltProvider) SessionExist(sid string) bool {
+	exist := false
+	bp.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketName).Get([]byte(sid))
+		exist = v != nil && !expired(v)
+		return nil
+	})
+	return exist
+}
+
+// generate new sid for bolt session.
+// values of old sid are moved to new sid.
+func (bp *BoltProvider) SessionRegenerate(oldsid, sid string) (session.SessionStore, error) {
+	err := bp.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+		v := b.Get([]byte(oldsid))
+		if v == nil || expired(v) {
+			return nil
+		}
+		// v is only valid in the transaction, copy the data before putting.
+		data := append([]byte{}, v[8:]...)
+		if err := b.Put([]byte(sid), bp.encodeValue(data)); err != nil {
+			return err
+		}
+		return b.Delete([]byte(oldsid))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bp.SessionRead(sid)
+}
+
+// delete bolt session by id
+func (bp *BoltProvider) SessionDestroy(sid string) error {
+	return bp.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete([]byte(sid))
+	})
+}
+
+// reset expiry of bolt session to maxlifetime from now, expired sessions aren't renewed.
+func (bp *BoltProvider) SessionUpdate(sid string) error {
+	return bp.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+		v := b.Get([]byte(sid))
+		if v == nil || expired(v) {
+			return nil
+		}
+		data := append([]byte{}, v[8:]...)
+		return b.Put([]byte(sid), bp.encodeValue(data))
+	})
+}
+
+// delete expired bolt sessions.
+// expired keys are collected in a read transaction,
+// so the write lock of the db is only held while deleting.
+func (bp *BoltProvider) SessionGC() {
+	var keys [][]byte
+	bp.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).ForEach(func(k, v []byte) error {
+			if expired(v) {
+				keys = append(keys, append([]byte{}, k...))
+			}
+			return nil
+		})
+	})
+	if len(keys) == 0 {
+		return
+	}
+	bp.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+		for _, k := range keys {
+			// the session may be saved again after the read transaction.
+			if v := b.Get(k); v != nil && expired(v) {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// bolt session supports all capabilities.
+func (bp *BoltProvider) Capabilities() session.ProviderCapabilities {
+	return session.CapAll
+}
+
+// count active bolt sessions
+func (bp *BoltProvider) SessionAll() int {
+	n := 0
+	bp.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).ForEach(func(k, v []byte) error {
+			if !expired(v) {
+				n++
+			}
+			return nil
+		})
+	})
+	return n
+}
+
+func (bp *BoltProvider) readValues(sid string) (map[interface{}]interface{}, error) {
+	var data []byte
+	err := bp.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketName).Get([]byte(sid))
+		if v != nil && !expired(v) {
+			data = append([]byte{}, v[8:]...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return make(map[interface{}]interface{}), nil
+	}
+	return session.GetSerializer().Decode(data)
+}
+
+// prefix data with expiry time in maxlifetime from now
+func (bp *BoltProvider) encodeValue(data []byte) []byte {
+	v := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(v, uint64(session.Now().Unix()+bp.maxlifetime))
+	copy(v[8:], data)
+	return v
+}
+
+// check expiry time of a saved value
+func expired(v []byte) bool {
+	if len(v) < 8 {
+		return true
+	}
+	return int64(binary.BigEndian.Uint64(v)) < session.Now().Unix()
+}
+
+func init() {
+	session.Register("bolt", boltpder)
+}
@@ -0,0 +1,43 @@
+package session
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock tells the time to session expiry, e.g. cookie timestamps, file mtimes and GC of memory sessions.
+type Clock interface {
+	Now() time.Time
+}
+
+// time of the system clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// current Clock, it's boxed since atomic.Value needs one concrete type.
+type clockBox struct {
+	Clock
+}
+
+var clock atomic.Value
+
+// SetClock sets the clock of session expiry, nil restores the system clock.
+// the clock is shared by all managers and providers, it's meant to fake time in tests.
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	clock.Store(clockBox{c})
+}
+
+// Now returns the time of the session clock, providers use it for every expiry check.
+func Now() time.Time {
+	return clock.Load().(clockBox).Now()
+}
+
+func init() {
+	SetClock(nil)
+}
@@ -1,9 +1,11 @@
 package session
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/couchbaselabs/go-couchbase"
 
@@ -18,6 +20,7 @@ type CouchbaseSessionStore struct {
 	lock        sync.RWMutex
 	values      map[interface{}]interface{}
 	maxlifetime int64
+	accessed    time.Time // time of SessionRead
 }
 
 type CouchbaseProvider struct {
@@ -56,29 +59,55 @@ func (cs *CouchbaseSessionStore) Delete(key interface{}) error {
 func (cs *CouchbaseSessionStore) Flush() error {
 	cs.lock.Lock()
 	defer cs.lock.Unlock()
-	cs.values = make(map[interface{}]interface{})
+	cs.values = session.FlushValues(cs.values)
 	return nil
 }
 
+func (cs *CouchbaseSessionStore) Keys() []interface{} {
+	cs.lock.RLock()
+	defer cs.lock.RUnlock()
+	keys := make([]interface{}, 0, len(cs.values))
+	for k := range cs.values {
+		if session.IsReservedKey(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (cs *CouchbaseSessionStore) Len() int {
+	return len(cs.Keys())
+}
+
+func (cs *CouchbaseSessionStore) CreatedAt() time.Time {
+	cs.lock.RLock()
+	defer cs.lock.RUnlock()
+	return session.CreatedTime(cs.values)
+}
+
+func (cs *CouchbaseSessionStore) LastAccessedAt() time.Time {
+	return cs.accessed
+}
+
 func (cs *CouchbaseSessionStore) SessionID() string {
 	return cs.sid
 }
 
-func (cs *CouchbaseSessionStore) SessionRelease(w http.ResponseWriter) {
+func (cs *CouchbaseSessionStore) SessionRelease(w http.ResponseWriter) error {
 	defer cs.b.Close()
 
 	// if rs.values is empty, return directly
 	if len(cs.values) < 1 {
-		cs.b.Delete(cs.sid)
-		return
+		return cs.b.Delete(cs.sid)
 	}
 
-	bo, err := session.EncodeGob(cs.values)
+	bo, err := session.GetSerializer().Encode(cs.values)
 	if err != nil {
-		return
+		return err
 	}
 
-	cs.b.Set(cs.sid, int(cs.maxlifetime), bo)
+	return cs.b.Set(cs.sid, int(cs.maxlifetime), bo)
 }
 
 func (cp *CouchbaseProvider) getBucket() *couchbase.Bucket {
@@ -130,13 +159,13 @@ func (cp *CouchbaseProvider) SessionRead(sid string) (session.SessionStore, erro
 	if doc == nil {
 		kv = make(map[interface{}]interface{})
 	} else {
-		kv, err = session.DecodeGob(doc)
+		kv, err = session.GetSerializer().Decode(doc)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	cs := &CouchbaseSessionStore{b: cp.b, sid: sid, values: kv, maxlifetime: cp.maxlifetime}
+	cs := &CouchbaseSessionStore{b: cp.b, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), maxlifetime: cp.maxlifetime}
 	return cs, nil
 }
 
@@ -175,13 +204,13 @@ func (cp *CouchbaseProvider) SessionRegenerate(oldsid, sid string) (session.Sess
 	if doc == nil {
 		kv = make(map[interface{}]interface{})
 	} else {
-		kv, err = session.DecodeGob(doc)
+		kv, err = session.GetSerializer().Decode(doc)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	cs := &CouchbaseSessionStore{b: cp.b, sid: sid, values: kv, maxlifetime: cp.maxlifetime}
+	cs := &CouchbaseSessionStore{b: cp.b, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), maxlifetime: cp.maxlifetime}
 	return cs, nil
 }
 
@@ -193,10 +222,25 @@ func (cp *CouchbaseProvider) SessionDestroy(sid string) error {
 	return nil
 }
 
+// check couchbase connection by getting the bucket
+func (cp *CouchbaseProvider) Ping() error {
+	b := cp.getBucket()
+	if b == nil {
+		return errors.New("session: can not connect to couchbase bucket")
+	}
+	b.Close()
+	return nil
+}
+
 func (cp *CouchbaseProvider) SessionGC() {
 	return
 }
 
+// couchbase expires sessions by itself, they can not be counted.
+func (cp *CouchbaseProvider) Capabilities() session.ProviderCapabilities {
+	return session.CapRegenerate | session.CapDestroy | session.CapGC
+}
+
 func (cp *CouchbaseProvider) SessionAll() int {
 	return 0
 }
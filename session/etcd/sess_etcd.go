@@ -0,0 +1,383 @@
+package session
+
+// etcd session support depends on go.etcd.io/etcd/client/v3:
+//
+//	go get go.etcd.io/etcd/client/v3
+//
+// every session is saved under key /beego/session/<sid> with a lease of maxlifetime,
+// so etcd removes expired sessions itself. saves keep the lease of the session alive
+// instead of granting a new one.
+//
+// it will be activated with these settings in app.conf:
+//
+//	SessionOn = true
+//	SessionProvider = etcd
+//	SessionSavePath = {"endpoints":["127.0.0.1:2379"],"dialTimeout":5}
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/astaxie/beego/session"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// key prefix of etcd sessions
+const keyPrefix = "/beego/session/"
+
+var etcdpder = &EtcdProvider{}
+
+// etcdClient is the part of etcd client used by the provider, it's replaced in tests.
+type etcdClient interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error)
+	Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error)
+	Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error)
+	KeepAliveOnce(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error)
+	Close() error
+}
+
+// etcd session store
+type EtcdSessionStore struct {
+	p        *EtcdProvider
+	sid      string
+	lock     sync.RWMutex
+	values   map[interface{}]interface{}
+	dirty    bool
+	accessed time.Time        // time of SessionRead
+	lease    clientv3.LeaseID // lease of the key when it was read or released, 0 if it's missing
+}
+
+// set value in etcd session
+func (es *EtcdSessionStore) Set(key, value interface{}) error {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	es.dirty = true
+	es.values[key] = value
+	return nil
+}
+
+// get value in etcd session
+func (es *EtcdSessionStore) Get(key interface{}) interface{} {
+	es.lock.RLock()
+	defer es.lock.RUnlock()
+	if v, ok := es.values[key]; ok {
+		return v
+	}
+	return nil
+}
+
+// delete value in etcd session
+func (es *EtcdSessionStore) Delete(key interface{}) error {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	es.dirty = true
+	delete(es.values, key)
+	return nil
+}
+
+// clear all values in etcd session
+func (es *EtcdSessionStore) Flush() error {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	es.dirty = true
+	es.values = session.FlushValues(es.values)
+	return nil
+}
+
+// check whether etcd session values changed since read
+func (es *EtcdSessionStore) IsDirty() bool {
+	es.lock.RLock()
+	defer es.lock.RUnlock()
+	return es.dirty
+}
+
+// get keys of etcd session
+func (es *EtcdSessionStore) Keys() []interface{} {
+	es.lock.RLock()
+	defer es.lock.RUnlock()
+	keys := make([]interface{}, 0, len(es.values))
+	for k := range es.values {
+		if session.IsReservedKey(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// get count of values in etcd session
+func (es *EtcdSessionStore) Len() int {
+	return len(es.Keys())
+}
+
+// get creation time of etcd session, zero if unknown
+func (es *EtcdSessionStore) CreatedAt() time.Time {
+	es.lock.RLock()
+	defer es.lock.RUnlock()
+	return session.CreatedTime(es.values)
+}
+
+// get the time etcd session was read
+func (es *EtcdSessionStore) LastAccessedAt() time.Time {
+	return es.accessed
+}
+
+// get etcd session id
+func (es *EtcdSessionStore) SessionID() string {
+	return es.sid
+}
+
+// save session values to etcd, the lease of the session is kept alive for another maxlifetime.
+func (es *EtcdSessionStore) SessionRelease(w http.ResponseWriter) error {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	es.dirty = false
+	b, err := session.GetSerializer().Encode(es.values)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := es.p.context(context.Background())
+	defer cancel()
+	lease, err := es.p.put(ctx, es.sid, b, es.lease)
+	if err != nil {
+		return err
+	}
+	es.lease = lease
+	return nil
+}
+
+// json config of etcd session provider
+type etcdConfig struct {
+	Endpoints      []string `json:"endpoints"`
+	DialTimeout    int      `json:"dialTimeout"`
+	RequestTimeout int      `json:"requestTimeout"`
+	Username       string   `json:"username"`
+	Password       string   `json:"password"`
+	TLSCert        string   `json:"tlsCert"`
+	TLSKey         string   `json:"tlsKey"`
+	TLSCACert      string   `json:"tlsCACert"`
+	TLSSkipVerify  bool     `json:"tlsSkipVerify"`
+}
+
+// etcd session provider
+type EtcdProvider struct {
+	maxlifetime int64
+	timeout     time.Duration
+	client      etcdClient
+}
+
+// init etcd session.
+// savePath is json config, e.g.
+// {"endpoints":["127.0.0.1:2379"],"dialTimeout":5,"requestTimeout":5,"tlsCACert":"/path/to/ca.pem"}
+// timeouts are seconds and 5 by default. TLS is used if any of tlsCert, tlsCACert or tlsSkipVerify is set.
+func (ep *EtcdProvider) SessionInit(maxlifetime int64, savePath string) error {
+	cf := &etcdConfig{}
+	if err := json.Unmarshal([]byte(savePath), cf); err != nil {
+		return err
+	}
+	if len(cf.Endpoints) == 0 {
+		return errors.New("session: etcd endpoints are empty")
+	}
+	if cf.DialTimeout <= 0 {
+		cf.DialTimeout = 5
+	}
+	if cf.RequestTimeout <= 0 {
+		cf.RequestTimeout = 5
+	}
+	tlsConfig, err := parseTLS(cf)
+	if err != nil {
+		return err
+	}
+	c, err := clientv3.New(clientv3.Config{
+		Endpoints:   cf.Endpoints,
+		DialTimeout: time.Duration(cf.DialTimeout) * time.Second,
+		Username:    cf.Username,
+		Password:    cf.Password,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return err
+	}
+	ep.maxlifetime = maxlifetime
+	ep.timeout = time.Duration(cf.RequestTimeout) * time.Second
+	ep.client = c
+	return nil
+}
+
+// build tls config from the certificate files in config.
+func parseTLS(cf *etcdConfig) (*tls.Config, error) {
+	if cf.TLSCert == "" && cf.TLSCACert == "" && !cf.TLSSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: cf.TLSSkipVerify}
+	if cf.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(cf.TLSCert, cf.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if cf.TLSCACert != "" {
+		pem, err := ioutil.ReadFile(cf.TLSCACert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("session: no certificate found in " + cf.TLSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// read etcd session by sid
+func (ep *EtcdProvider) SessionRead(sid string) (session.SessionStore, error) {
+	return ep.SessionReadContext(context.Background(), sid)
+}
+
+// read etcd session by sid, the request is canceled when ctx is done.
+func (ep *EtcdProvider) SessionReadContext(ctx context.Context, sid string) (session.SessionStore, error) {
+	ctx, cancel := ep.context(ctx)
+	defer cancel()
+	kv, lease, err := ep.readValues(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	return &EtcdSessionStore{p: ep, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), lease: lease}, nil
+}
+
+// check etcd session exist by sid
+func (ep *EtcdProvider) SessionExist(sid string) bool {
+	ctx, cancel := ep.context(context.Background())
+	defer cancel()
+	resp, err := ep.client.Get(ctx, keyPrefix+sid, clientv3.WithCountOnly())
+	return err == nil && resp.Count > 0
+}
+
+// generate new sid for etcd session.
+// values of old sid are moved to new sid.
+func (ep *EtcdProvider) SessionRegenerate(oldsid, sid string) (session.SessionStore, error) {
+	ctx, cancel := ep.context(context.Background())
+	defer cancel()
+	resp, err := ep.client.Get(ctx, keyPrefix+oldsid)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) > 0 {
+		// the new key takes the lease of the old one
+		if _, err = ep.put(ctx, sid, resp.Kvs[0].Value, clientv3.LeaseID(resp.Kvs[0].Lease)); err != nil {
+			return nil, err
+		}
+		ep.client.Delete(ctx, keyPrefix+oldsid)
+	}
+	kv, lease, err := ep.readValues(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	return &EtcdSessionStore{p: ep, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), lease: lease}, nil
+}
+
+// delete etcd session by id
+func (ep *EtcdProvider) SessionDestroy(sid string) error {
+	ctx, cancel := ep.context(context.Background())
+	defer cancel()
+	_, err := ep.client.Delete(ctx, keyPrefix+sid)
+	return err
+}
+
+// refresh the lease of the session to maxlifetime
+func (ep *EtcdProvider) SessionUpdate(sid string) error {
+	ctx, cancel := ep.context(context.Background())
+	defer cancel()
+	resp, err := ep.client.Get(ctx, keyPrefix+sid)
+	if err != nil || len(resp.Kvs) == 0 {
+		return err
+	}
+	_, err = ep.put(ctx, sid, resp.Kvs[0].Value, clientv3.LeaseID(resp.Kvs[0].Lease))
+	return err
+}
+
+// check etcd connection
+func (ep *EtcdProvider) Ping() error {
+	ctx, cancel := ep.context(context.Background())
+	defer cancel()
+	_, err := ep.client.Get(ctx, keyPrefix, clientv3.WithCountOnly())
+	return err
+}
+
+// Implement method, no used.
+// expired sessions are removed with their lease.
+func (ep *EtcdProvider) SessionGC() {
+	return
+}
+
+// etcd session supports all capabilities, expired sessions are removed with their lease.
+func (ep *EtcdProvider) Capabilities() session.ProviderCapabilities {
+	return session.CapAll
+}
+
+// count active etcd sessions
+func (ep *EtcdProvider) SessionAll() int {
+	ctx, cancel := ep.context(context.Background())
+	defer cancel()
+	resp, err := ep.client.Get(ctx, keyPrefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return 0
+	}
+	return int(resp.Count)
+}
+
+// derive the context of one etcd request with request timeout
+func (ep *EtcdProvider) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, ep.timeout)
+}
+
+// put session data with lease, it's kept alive for another maxlifetime.
+// a new lease is granted if lease is 0 or expired, so every session key has one lease.
+// it returns the lease of the key.
+func (ep *EtcdProvider) put(ctx context.Context, sid string, data []byte, lease clientv3.LeaseID) (clientv3.LeaseID, error) {
+	if lease != 0 {
+		if _, err := ep.client.KeepAliveOnce(ctx, lease); err != nil {
+			lease = 0
+		}
+	}
+	if lease == 0 {
+		resp, err := ep.client.Grant(ctx, ep.maxlifetime)
+		if err != nil {
+			return 0, err
+		}
+		lease = resp.ID
+	}
+	if _, err := ep.client.Put(ctx, keyPrefix+sid, string(data), clientv3.WithLease(lease)); err != nil {
+		return 0, err
+	}
+	return lease, nil
+}
+
+// read values of sid and the lease of its key.
+func (ep *EtcdProvider) readValues(ctx context.Context, sid string) (map[interface{}]interface{}, clientv3.LeaseID, error) {
+	resp, err := ep.client.Get(ctx, keyPrefix+sid)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(resp.Kvs) == 0 || len(resp.Kvs[0].Value) == 0 {
+		return make(map[interface{}]interface{}), 0, nil
+	}
+	lease := clientv3.LeaseID(resp.Kvs[0].Lease)
+	kv, err := session.GetSerializer().Decode(resp.Kvs[0].Value)
+	return kv, lease, err
+}
+
+func init() {
+	session.Register("etcd", etcdpder)
+}
@@ -0,0 +1,169 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// mockClient is an in memory etcd kv with leases, leases only expire by expire.
+type mockClient struct {
+	lock   sync.Mutex
+	values map[string]string
+	leases map[string]clientv3.LeaseID // lease of keys
+	alive  map[clientv3.LeaseID]bool
+	nextID clientv3.LeaseID
+	grants int
+}
+
+func newMockClient() *mockClient {
+	return &mockClient{values: make(map[string]string), leases: make(map[string]clientv3.LeaseID),
+		alive: make(map[clientv3.LeaseID]bool)}
+}
+
+// get lease of put options, 0 if there is no lease.
+func (m *mockClient) leaseOf(key, val string, opts []clientv3.OpOption) clientv3.LeaseID {
+	op := clientv3.OpPut(key, val, opts...)
+	for id := m.nextID; id > 0; id-- {
+		if reflect.DeepEqual(op, clientv3.OpPut(key, val, clientv3.WithLease(id))) {
+			return id
+		}
+	}
+	return 0
+}
+
+// expire lease and remove its keys.
+func (m *mockClient) expire(id clientv3.LeaseID) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.alive, id)
+	for key, lease := range m.leases {
+		if lease == id {
+			delete(m.values, key)
+			delete(m.leases, key)
+		}
+	}
+}
+
+func (m *mockClient) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	resp := &clientv3.GetResponse{}
+	if v, ok := m.values[key]; ok {
+		resp.Kvs = []*mvccpb.KeyValue{{Key: []byte(key), Value: []byte(v), Lease: int64(m.leases[key])}}
+		resp.Count = 1
+	}
+	return resp, ctx.Err()
+}
+
+func (m *mockClient) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	lease := m.leaseOf(key, val, opts)
+	if lease != 0 && !m.alive[lease] {
+		return nil, errors.New("etcdserver: requested lease not found")
+	}
+	m.values[key] = val
+	m.leases[key] = lease
+	return &clientv3.PutResponse{}, nil
+}
+
+func (m *mockClient) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.values, key)
+	delete(m.leases, key)
+	return &clientv3.DeleteResponse{}, nil
+}
+
+func (m *mockClient) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.nextID++
+	m.grants++
+	m.alive[m.nextID] = true
+	return &clientv3.LeaseGrantResponse{ID: m.nextID, TTL: ttl}, nil
+}
+
+func (m *mockClient) KeepAliveOnce(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if !m.alive[id] {
+		return nil, errors.New("etcdserver: requested lease not found")
+	}
+	return &clientv3.LeaseKeepAliveResponse{ID: id}, nil
+}
+
+func (m *mockClient) Close() error { return nil }
+
+func TestEtcd(t *testing.T) {
+	m := newMockClient()
+	ep := &EtcdProvider{maxlifetime: 3600, timeout: time.Second, client: m}
+
+	sess, err := ep.SessionRead("sid1")
+	if err != nil {
+		t.Fatal("SessionRead error", err)
+	}
+	sess.Set("username", "astaxie")
+	if err = sess.SessionRelease(nil); err != nil {
+		t.Fatal("SessionRelease error", err)
+	}
+	if _, ok := m.values[keyPrefix+"sid1"]; !ok {
+		t.Fatal("session should be put under", keyPrefix+"sid1")
+	}
+	if m.leases[keyPrefix+"sid1"] == 0 {
+		t.Fatal("session should be put with lease")
+	}
+	if !ep.SessionExist("sid1") {
+		t.Fatal("session should exist")
+	}
+
+	// later saves keep the lease alive instead of granting new ones
+	sess, _ = ep.SessionRead("sid1")
+	sess.Set("username", "astaxie")
+	if err = sess.SessionRelease(nil); err != nil {
+		t.Fatal("SessionRelease error", err)
+	}
+	if err = ep.SessionUpdate("sid1"); err != nil {
+		t.Fatal("SessionUpdate error", err)
+	}
+	if m.grants != 1 {
+		t.Fatal("saves of one session should share its lease, grants", m.grants)
+	}
+	// a new lease is granted if the lease expired after read
+	sess, _ = ep.SessionRead("sid1")
+	m.expire(m.leases[keyPrefix+"sid1"])
+	if err = sess.SessionRelease(nil); err != nil {
+		t.Fatal("SessionRelease error", err)
+	}
+	if m.grants != 2 || sess.Get("username") != "astaxie" || !ep.SessionExist("sid1") {
+		t.Fatal("expired lease should be replaced, grants", m.grants)
+	}
+
+	sess, err = ep.SessionRegenerate("sid1", "sid2")
+	if err != nil {
+		t.Fatal("SessionRegenerate error", err)
+	}
+	if sess.Get("username") != "astaxie" {
+		t.Fatal("values should be moved to new sid")
+	}
+	if ep.SessionExist("sid1") {
+		t.Fatal("old sid should be deleted")
+	}
+	if m.grants != 2 {
+		t.Fatal("new sid should take the lease of old sid, grants", m.grants)
+	}
+
+	if err = ep.SessionDestroy("sid2"); err != nil {
+		t.Fatal("SessionDestroy error", err)
+	}
+	if ep.SessionExist("sid2") {
+		t.Fatal("session should be destroyed")
+	}
+}
@@ -0,0 +1,253 @@
+package session
+
+// memcache session support depends on github.com/bradfitz/gomemcache:
+//
+//	go get github.com/bradfitz/gomemcache/memcache
+//
+// it will be activated with these settings in app.conf:
+//
+//	SessionOn = true
+//	SessionProvider = memcache
+//	SessionSavePath = {"servers":["127.0.0.1:11211","127.0.0.1:11212"],"maxlifetime":3600}
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/astaxie/beego/session"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+var mempder = &MemcacheProvider{}
+
+// memcache session store
+type MemcacheSessionStore struct {
+	c           *memcache.Client
+	sid         string
+	lock        sync.RWMutex
+	values      map[interface{}]interface{}
+	maxlifetime int64
+	dirty       bool
+	accessed    time.Time // time of SessionRead
+}
+
+// set value in memcache session
+func (rs *MemcacheSessionStore) Set(key, value interface{}) error {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.dirty = true
+	rs.values[key] = value
+	return nil
+}
+
+// get value in memcache session
+func (rs *MemcacheSessionStore) Get(key interface{}) interface{} {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	if v, ok := rs.values[key]; ok {
+		return v
+	}
+	return nil
+}
+
+// delete value in memcache session
+func (rs *MemcacheSessionStore) Delete(key interface{}) error {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.dirty = true
+	delete(rs.values, key)
+	return nil
+}
+
+// clear all values in memcache session
+func (rs *MemcacheSessionStore) Flush() error {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.dirty = true
+	rs.values = session.FlushValues(rs.values)
+	return nil
+}
+
+// check whether memcache session values changed since read
+func (rs *MemcacheSessionStore) IsDirty() bool {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	return rs.dirty
+}
+
+// get keys of memcache session
+func (rs *MemcacheSessionStore) Keys() []interface{} {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	keys := make([]interface{}, 0, len(rs.values))
+	for k := range rs.values {
+		if session.IsReservedKey(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// get count of values in memcache session
+func (rs *MemcacheSessionStore) Len() int {
+	return len(rs.Keys())
+}
+
+// get creation time of memcache session, zero if unknown
+func (rs *MemcacheSessionStore) CreatedAt() time.Time {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	return session.CreatedTime(rs.values)
+}
+
+// get the time memcache session was read
+func (rs *MemcacheSessionStore) LastAccessedAt() time.Time {
+	return rs.accessed
+}
+
+// get memcache session id
+func (rs *MemcacheSessionStore) SessionID() string {
+	return rs.sid
+}
+
+// save session values to memcache.
+// the item expires in maxlifetime seconds.
+func (rs *MemcacheSessionStore) SessionRelease(w http.ResponseWriter) error {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	rs.dirty = false
+	b, err := session.GetSerializer().Encode(rs.values)
+	if err != nil {
+		return err
+	}
+	return rs.c.Set(&memcache.Item{Key: rs.sid, Value: b, Expiration: int32(rs.maxlifetime)})
+}
+
+type memcacheConfig struct {
+	Servers     []string `json:"servers"`
+	Maxlifetime int64    `json:"maxlifetime"`
+}
+
+// memcache session provider
+type MemcacheProvider struct {
+	maxlifetime int64
+	servers     []string
+	client      *memcache.Client
+}
+
+// init memcache session.
+// savePath is json config with memcache server list and optional maxlifetime,
+// e.g. {"servers":["127.0.0.1:11211"],"maxlifetime":3600}
+func (rp *MemcacheProvider) SessionInit(maxlifetime int64, savePath string) error {
+	cf := &memcacheConfig{}
+	if err := json.Unmarshal([]byte(savePath), cf); err != nil {
+		return err
+	}
+	if len(cf.Servers) == 0 {
+		return errors.New("session: memcache servers are empty")
+	}
+	rp.maxlifetime = maxlifetime
+	if cf.Maxlifetime > 0 {
+		rp.maxlifetime = cf.Maxlifetime
+	}
+	rp.servers = cf.Servers
+	rp.client = memcache.New(rp.servers...)
+	return nil
+}
+
+// read memcache session by sid.
+// a new empty session is returned if sid is not in memcache.
+func (rp *MemcacheProvider) SessionRead(sid string) (session.SessionStore, error) {
+	kv, err := rp.readValues(sid)
+	if err != nil {
+		return nil, err
+	}
+	rs := &MemcacheSessionStore{c: rp.client, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), maxlifetime: rp.maxlifetime}
+	return rs, nil
+}
+
+// read memcache session by sid, it returns when ctx is done.
+func (rp *MemcacheProvider) SessionReadContext(ctx context.Context, sid string) (session.SessionStore, error) {
+	return session.ReadContext(ctx, func() (session.SessionStore, error) {
+		return rp.SessionRead(sid)
+	})
+}
+
+// check memcache session exist by sid
+func (rp *MemcacheProvider) SessionExist(sid string) bool {
+	if _, err := rp.client.Get(sid); err != nil {
+		return false
+	}
+	return true
+}
+
+// generate new sid for memcache session.
+// values of old sid are moved to new sid.
+func (rp *MemcacheProvider) SessionRegenerate(oldsid, sid string) (session.SessionStore, error) {
+	item, err := rp.client.Get(oldsid)
+	if err == nil {
+		err = rp.client.Set(&memcache.Item{Key: sid, Value: item.Value, Expiration: int32(rp.maxlifetime)})
+		if err != nil {
+			return nil, err
+		}
+		rp.client.Delete(oldsid)
+	} else if err != memcache.ErrCacheMiss {
+		return nil, err
+	}
+	return rp.SessionRead(sid)
+}
+
+// delete memcache session by id
+func (rp *MemcacheProvider) SessionDestroy(sid string) error {
+	if err := rp.client.Delete(sid); err != nil && err != memcache.ErrCacheMiss {
+		return err
+	}
+	return nil
+}
+
+// check memcache connection.
+// a cache miss means the server is reachable.
+func (rp *MemcacheProvider) Ping() error {
+	if _, err := rp.client.Get("__beego_session_ping__"); err != nil && err != memcache.ErrCacheMiss {
+		return err
+	}
+	return nil
+}
+
+// Implement method, no used.
+// memcache expires sessions by itself.
+func (rp *MemcacheProvider) SessionGC() {
+	return
+}
+
+// memcache expires sessions by itself, they can not be counted.
+func (rp *MemcacheProvider) Capabilities() session.ProviderCapabilities {
+	return session.CapRegenerate | session.CapDestroy | session.CapGC
+}
+
+// Implement method, return 0.
+// memcache can not count keys.
+func (rp *MemcacheProvider) SessionAll() int {
+	return 0
+}
+
+func (rp *MemcacheProvider) readValues(sid string) (map[interface{}]interface{}, error) {
+	item, err := rp.client.Get(sid)
+	if err == memcache.ErrCacheMiss || (err == nil && len(item.Value) == 0) {
+		return make(map[interface{}]interface{}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session.GetSerializer().Decode(item.Value)
+}
+
+func init() {
+	session.Register("memcache", mempder)
+}
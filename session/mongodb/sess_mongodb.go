@@ -0,0 +1,312 @@
+package session
+
+// mongodb session support depends on gopkg.in/mgo.v2:
+//
+//	go get gopkg.in/mgo.v2
+//
+// every session is saved as document {_id: sid, data: <encoded values>, expireAt: <time>}.
+// a TTL index on expireAt makes mongodb remove expired sessions itself.
+//
+// it will be activated with these settings in app.conf:
+//
+//	SessionOn = true
+//	SessionProvider = mongodb
+//	SessionSavePath = {"url":"mongodb://127.0.0.1:27017","db":"beego","collection":"session"}
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/astaxie/beego/session"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var mongopder = &MongoProvider{}
+
+type mongoDoc struct {
+	Id       string    `bson:"_id"`
+	Data     []byte    `bson:"data"`
+	ExpireAt time.Time `bson:"expireAt"`
+}
+
+// mongodb session store
+type MongoSessionStore struct {
+	p           *MongoProvider
+	sid         string
+	lock        sync.RWMutex
+	values      map[interface{}]interface{}
+	maxlifetime int64
+	dirty       bool
+	accessed    time.Time // time of SessionRead
+}
+
+// set value in mongodb session
+func (ms *MongoSessionStore) Set(key, value interface{}) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.dirty = true
+	ms.values[key] = value
+	return nil
+}
+
+// get value in mongodb session
+func (ms *MongoSessionStore) Get(key interface{}) interface{} {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+	if v, ok := ms.values[key]; ok {
+		return v
+	}
+	return nil
+}
+
+// delete value in mongodb session
+func (ms *MongoSessionStore) Delete(key interface{}) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.dirty = true
+	delete(ms.values, key)
+	return nil
+}
+
+// clear all values in mongodb session
+func (ms *MongoSessionStore) Flush() error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.dirty = true
+	ms.values = session.FlushValues(ms.values)
+	return nil
+}
+
+// check whether mongodb session values changed since read
+func (ms *MongoSessionStore) IsDirty() bool {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+	return ms.dirty
+}
+
+// get keys of mongodb session
+func (ms *MongoSessionStore) Keys() []interface{} {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+	keys := make([]interface{}, 0, len(ms.values))
+	for k := range ms.values {
+		if session.IsReservedKey(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// get count of values in mongodb session
+func (ms *MongoSessionStore) Len() int {
+	return len(ms.Keys())
+}
+
+// get creation time of mongodb session, zero if unknown
+func (ms *MongoSessionStore) CreatedAt() time.Time {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+	return session.CreatedTime(ms.values)
+}
+
+// get the time mongodb session was read
+func (ms *MongoSessionStore) LastAccessedAt() time.Time {
+	return ms.accessed
+}
+
+// get mongodb session id
+func (ms *MongoSessionStore) SessionID() string {
+	return ms.sid
+}
+
+// save session values to mongodb and reset expireAt.
+func (ms *MongoSessionStore) SessionRelease(w http.ResponseWriter) error {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.dirty = false
+	b, err := session.GetSerializer().Encode(ms.values)
+	if err != nil {
+		return err
+	}
+	s, c := ms.p.collection()
+	defer s.Close()
+	_, err = c.UpsertId(ms.sid, bson.M{"$set": bson.M{"data": b, "expireAt": ms.p.expireAt()}})
+	return err
+}
+
+type mongoConfig struct {
+	Url        string `json:"url"`
+	Db         string `json:"db"`
+	Collection string `json:"collection"`
+}
+
+// mongodb session provider
+type MongoProvider struct {
+	maxlifetime int64
+	config      *mongoConfig
+	session     *mgo.Session
+}
+
+// init mongodb session.
+// savePath is json config with connection url, database and collection name,
+// e.g. {"url":"mongodb://127.0.0.1:27017","db":"beego","collection":"session"}
+// collection is "session" by default.
+func (mp *MongoProvider) SessionInit(maxlifetime int64, savePath string) error {
+	mp.maxlifetime = maxlifetime
+	mp.config = &mongoConfig{}
+	if err := json.Unmarshal([]byte(savePath), mp.config); err != nil {
+		return err
+	}
+	if mp.config.Url == "" {
+		return errors.New("session: mongodb url is empty")
+	}
+	if mp.config.Collection == "" {
+		mp.config.Collection = "session"
+	}
+	s, err := mgo.Dial(mp.config.Url)
+	if err != nil {
+		return err
+	}
+	mp.session = s
+	c := s.DB(mp.config.Db).C(mp.config.Collection)
+	// mongodb removes the document shortly after expireAt.
+	return c.EnsureIndex(mgo.Index{Key: []string{"expireAt"}, ExpireAfter: time.Second})
+}
+
+// read mongodb session by sid
+func (mp *MongoProvider) SessionRead(sid string) (session.SessionStore, error) {
+	s, c := mp.collection()
+	defer s.Close()
+
+	kv, err := mp.readValues(c, sid)
+	if err != nil {
+		return nil, err
+	}
+	ms := &MongoSessionStore{p: mp, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), maxlifetime: mp.maxlifetime}
+	return ms, nil
+}
+
+// read mongodb session by sid, it returns when ctx is done.
+func (mp *MongoProvider) SessionReadContext(ctx context.Context, sid string) (session.SessionStore, error) {
+	return session.ReadContext(ctx, func() (session.SessionStore, error) {
+		return mp.SessionRead(sid)
+	})
+}
+
+// check mongodb session exist by sid.
+// documents not removed by TTL monitor yet are treated as expired.
+func (mp *MongoProvider) SessionExist(sid string) bool {
+	s, c := mp.collection()
+	defer s.Close()
+
+	n, err := c.Find(bson.M{"_id": sid, "expireAt": bson.M{"$gt": session.Now()}}).Count()
+	return err == nil && n > 0
+}
+
+// generate new sid for mongodb session.
+// values of old sid are moved to new sid.
+func (mp *MongoProvider) SessionRegenerate(oldsid, sid string) (session.SessionStore, error) {
+	s, c := mp.collection()
+	defer s.Close()
+
+	doc := &mongoDoc{}
+	err := c.FindId(oldsid).One(doc)
+	if err == nil {
+		err = c.Insert(&mongoDoc{Id: sid, Data: doc.Data, ExpireAt: mp.expireAt()})
+		if err != nil {
+			return nil, err
+		}
+		c.RemoveId(oldsid)
+	} else if err != mgo.ErrNotFound {
+		return nil, err
+	}
+
+	kv, err := mp.readValues(c, sid)
+	if err != nil {
+		return nil, err
+	}
+	ms := &MongoSessionStore{p: mp, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), maxlifetime: mp.maxlifetime}
+	return ms, nil
+}
+
+// delete mongodb session by id
+func (mp *MongoProvider) SessionDestroy(sid string) error {
+	s, c := mp.collection()
+	defer s.Close()
+
+	if err := c.RemoveId(sid); err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// reset expireAt of mongodb session to maxlifetime from now
+func (mp *MongoProvider) SessionUpdate(sid string) error {
+	s, c := mp.collection()
+	defer s.Close()
+
+	return c.UpdateId(sid, bson.M{"$set": bson.M{"expireAt": mp.expireAt()}})
+}
+
+// check mongodb connection
+func (mp *MongoProvider) Ping() error {
+	s := mp.session.Copy()
+	defer s.Close()
+	return s.Ping()
+}
+
+// Implement method, no used.
+// expired sessions are removed by the TTL index.
+func (mp *MongoProvider) SessionGC() {
+	return
+}
+
+// mongodb session supports all capabilities, expired sessions are removed by the TTL index.
+func (mp *MongoProvider) Capabilities() session.ProviderCapabilities {
+	return session.CapAll
+}
+
+// count active mongodb sessions
+func (mp *MongoProvider) SessionAll() int {
+	s, c := mp.collection()
+	defer s.Close()
+
+	n, err := c.Find(bson.M{"expireAt": bson.M{"$gt": session.Now()}}).Count()
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// copy a mgo session for one operation, the caller must close it.
+func (mp *MongoProvider) collection() (*mgo.Session, *mgo.Collection) {
+	s := mp.session.Copy()
+	return s, s.DB(mp.config.Db).C(mp.config.Collection)
+}
+
+func (mp *MongoProvider) expireAt() time.Time {
+	return session.Now().Add(time.Duration(mp.maxlifetime) * time.Second)
+}
+
+func (mp *MongoProvider) readValues(c *mgo.Collection, sid string) (map[interface{}]interface{}, error) {
+	doc := &mongoDoc{}
+	err := c.Find(bson.M{"_id": sid, "expireAt": bson.M{"$gt": session.Now()}}).One(doc)
+	if err == mgo.ErrNotFound || (err == nil && len(doc.Data) == 0) {
+		return make(map[interface{}]interface{}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session.GetSerializer().Decode(doc.Data)
+}
+
+func init() {
+	session.Register("mongodb", mongopder)
+}
@@ -5,12 +5,20 @@ package session
 //	`session_key` char(64) NOT NULL,
 //	session_data` blob,
 //	`session_expiry` int(11) unsigned NOT NULL,
+//	`session_version` int(11) unsigned NOT NULL DEFAULT 0,
 //	PRIMARY KEY (`session_key`)
 //	) ENGINE=MyISAM DEFAULT CHARSET=utf8;
+//
+// session_version is increased by every save, a save fails with session.ErrConflict
+// if another request saved the session after it was read. tables of earlier versions need it:
+//	ALTER TABLE `session` ADD `session_version` int(11) unsigned NOT NULL DEFAULT 0;
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -23,10 +31,12 @@ var mysqlpder = &MysqlProvider{}
 
 // mysql session store
 type MysqlSessionStore struct {
-	c      *sql.DB
-	sid    string
-	lock   sync.RWMutex
-	values map[interface{}]interface{}
+	c        *sql.DB
+	sid      string
+	lock     sync.RWMutex
+	values   map[interface{}]interface{}
+	accessed time.Time // time of SessionRead
+	version  int64     // session_version read
 }
 
 // set value in mysql session.
@@ -62,26 +72,72 @@ func (st *MysqlSessionStore) Delete(key interface{}) error {
 func (st *MysqlSessionStore) Flush() error {
 	st.lock.Lock()
 	defer st.lock.Unlock()
-	st.values = make(map[interface{}]interface{})
+	st.values = session.FlushValues(st.values)
 	return nil
 }
 
+// get keys of mysql session
+func (st *MysqlSessionStore) Keys() []interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	keys := make([]interface{}, 0, len(st.values))
+	for k := range st.values {
+		if session.IsReservedKey(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// get count of values in mysql session
+func (st *MysqlSessionStore) Len() int {
+	return len(st.Keys())
+}
+
+// get creation time of mysql session, zero if unknown
+func (st *MysqlSessionStore) CreatedAt() time.Time {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return session.CreatedTime(st.values)
+}
+
+// get the time mysql session was read
+func (st *MysqlSessionStore) LastAccessedAt() time.Time {
+	return st.accessed
+}
+
 // get session id of this mysql session store
 func (st *MysqlSessionStore) SessionID() string {
 	return st.sid
 }
 
+// get session_version of mysql session when it was read
+func (st *MysqlSessionStore) Version() string {
+	return strconv.FormatInt(st.version, 10)
+}
+
 // save mysql session values to database.
 // must call this method to save values to database.
-func (st *MysqlSessionStore) SessionRelease(w http.ResponseWriter) {
+// values are only saved if session_version wasn't changed since read, otherwise session.ErrConflict is returned.
+func (st *MysqlSessionStore) SessionRelease(w http.ResponseWriter) error {
 	defer st.c.Close()
-	b, err := session.EncodeGob(st.values)
+	b, err := session.GetSerializer().Encode(st.values)
 	if err != nil {
-		return
+		return err
 	}
-	st.c.Exec("UPDATE session set `session_data`=?, `session_expiry`=? where session_key=?",
-		b, time.Now().Unix(), st.sid)
-
+	res, err := st.c.Exec("UPDATE session set `session_data`=?, `session_expiry`=?, `session_version`=`session_version`+1 where session_key=? and `session_version`=?",
+		b, session.Now().Unix(), st.sid, st.version)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return session.ErrConflict
+	}
+	st.version++
+	return nil
 }
 
 // mysql session provider
@@ -109,24 +165,33 @@ func (mp *MysqlProvider) SessionInit(maxlifetime int64, savePath string) error {
 
 // get mysql session by sid
 func (mp *MysqlProvider) SessionRead(sid string) (session.SessionStore, error) {
+	return mp.SessionReadContext(context.Background(), sid)
+}
+
+// get mysql session by sid, queries are canceled when ctx is done.
+func (mp *MysqlProvider) SessionReadContext(ctx context.Context, sid string) (session.SessionStore, error) {
 	c := mp.connectInit()
-	row := c.QueryRow("select session_data from session where session_key=?", sid)
+	row := c.QueryRowContext(ctx, "select session_data, session_version from session where session_key=?", sid)
 	var sessiondata []byte
-	err := row.Scan(&sessiondata)
+	var version int64
+	err := row.Scan(&sessiondata, &version)
 	if err == sql.ErrNoRows {
-		c.Exec("insert into session(`session_key`,`session_data`,`session_expiry`) values(?,?,?)",
-			sid, "", time.Now().Unix())
+		c.ExecContext(ctx, "insert into session(`session_key`,`session_data`,`session_expiry`) values(?,?,?)",
+			sid, "", session.Now().Unix())
+	} else if err != nil && ctx.Err() != nil {
+		c.Close()
+		return nil, ctx.Err()
 	}
 	var kv map[interface{}]interface{}
 	if len(sessiondata) == 0 {
 		kv = make(map[interface{}]interface{})
 	} else {
-		kv, err = session.DecodeGob(sessiondata)
+		kv, err = session.GetSerializer().Decode(sessiondata)
 		if err != nil {
 			return nil, err
 		}
 	}
-	rs := &MysqlSessionStore{c: c, sid: sid, values: kv}
+	rs := &MysqlSessionStore{c: c, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), version: version}
 	return rs, nil
 }
 
@@ -147,23 +212,24 @@ func (mp *MysqlProvider) SessionExist(sid string) bool {
 // generate new sid for mysql session
 func (mp *MysqlProvider) SessionRegenerate(oldsid, sid string) (session.SessionStore, error) {
 	c := mp.connectInit()
-	row := c.QueryRow("select session_data from session where session_key=?", oldsid)
+	row := c.QueryRow("select session_data, session_version from session where session_key=?", oldsid)
 	var sessiondata []byte
-	err := row.Scan(&sessiondata)
+	var version int64
+	err := row.Scan(&sessiondata, &version)
 	if err == sql.ErrNoRows {
-		c.Exec("insert into session(`session_key`,`session_data`,`session_expiry`) values(?,?,?)", oldsid, "", time.Now().Unix())
+		c.Exec("insert into session(`session_key`,`session_data`,`session_expiry`) values(?,?,?)", oldsid, "", session.Now().Unix())
 	}
 	c.Exec("update session set `session_key`=? where session_key=?", sid, oldsid)
 	var kv map[interface{}]interface{}
 	if len(sessiondata) == 0 {
 		kv = make(map[interface{}]interface{})
 	} else {
-		kv, err = session.DecodeGob(sessiondata)
+		kv, err = session.GetSerializer().Decode(sessiondata)
 		if err != nil {
 			return nil, err
 		}
 	}
-	rs := &MysqlSessionStore{c: c, sid: sid, values: kv}
+	rs := &MysqlSessionStore{c: c, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), version: version}
 	return rs, nil
 }
 
@@ -175,14 +241,37 @@ func (mp *MysqlProvider) SessionDestroy(sid string) error {
 	return nil
 }
 
+// update expiry of mysql session to now
+func (mp *MysqlProvider) SessionUpdate(sid string) error {
+	c := mp.connectInit()
+	defer c.Close()
+	_, err := c.Exec("UPDATE session set `session_expiry`=? where session_key=?", session.Now().Unix(), sid)
+	return err
+}
+
+// check mysql connection
+func (mp *MysqlProvider) Ping() error {
+	c := mp.connectInit()
+	if c == nil {
+		return errors.New("session: can not open mysql")
+	}
+	defer c.Close()
+	return c.Ping()
+}
+
 // delete expired values in mysql session
 func (mp *MysqlProvider) SessionGC() {
 	c := mp.connectInit()
-	c.Exec("DELETE from session where session_expiry < ?", time.Now().Unix()-mp.maxlifetime)
+	c.Exec("DELETE from session where session_expiry < ?", session.Now().Unix()-mp.maxlifetime)
 	c.Close()
 	return
 }
 
+// mysql session supports all capabilities.
+func (mp *MysqlProvider) Capabilities() session.ProviderCapabilities {
+	return session.CapAll
+}
+
 // count values in mysql session
 func (mp *MysqlProvider) SessionAll() int {
 	c := mp.connectInit()
@@ -13,12 +13,29 @@ go install github.com/lib/pq
 needs this table in your database:
 
 CREATE TABLE session (
-session_key	char(64) NOT NULL,
+session_key	text NOT NULL,
 session_data	bytea,
-session_expiry	timestamp NOT NULL,
+session_expiry	timestamptz NOT NULL,
+session_version	bigint NOT NULL DEFAULT 0,
 CONSTRAINT session_key PRIMARY KEY(session_key)
 );
 
+session_expiry is the time the session expires, saving it extends it by maxlifetime.
+tables created for earlier versions saved the last access time in a timestamp column,
+migrate them with:
+
+ALTER TABLE session ALTER COLUMN session_key TYPE text;
+ALTER TABLE session ALTER COLUMN session_expiry TYPE timestamptz;
+UPDATE session SET session_expiry = session_expiry + interval '3600 seconds';
+
+use your maxlifetime (gclifetime) instead of 3600, without the UPDATE existing sessions
+are read as expired and removed by the next gc.
+
+session_version is increased by every save, a save fails with session.ErrConflict
+if another request saved the session after it was read. add it to earlier tables with:
+
+ALTER TABLE session ADD COLUMN session_version bigint NOT NULL DEFAULT 0;
+
 
 will be activated with these settings in app.conf:
 
@@ -30,8 +47,11 @@ SessionName = session
 */
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -44,10 +64,13 @@ var postgresqlpder = &PostgresqlProvider{}
 
 // postgresql session store
 type PostgresqlSessionStore struct {
-	c      *sql.DB
-	sid    string
-	lock   sync.RWMutex
-	values map[interface{}]interface{}
+	c           *sql.DB
+	sid         string
+	lock        sync.RWMutex
+	values      map[interface{}]interface{}
+	maxlifetime int64
+	accessed    time.Time // time of SessionRead
+	version     int64     // session_version read, -1 if the row didn't exist
 }
 
 // set value in postgresql session.
@@ -83,26 +106,81 @@ func (st *PostgresqlSessionStore) Delete(key interface{}) error {
 func (st *PostgresqlSessionStore) Flush() error {
 	st.lock.Lock()
 	defer st.lock.Unlock()
-	st.values = make(map[interface{}]interface{})
+	st.values = session.FlushValues(st.values)
 	return nil
 }
 
+// get keys of postgresql session
+func (st *PostgresqlSessionStore) Keys() []interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	keys := make([]interface{}, 0, len(st.values))
+	for k := range st.values {
+		if session.IsReservedKey(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// get count of values in postgresql session
+func (st *PostgresqlSessionStore) Len() int {
+	return len(st.Keys())
+}
+
+// get creation time of postgresql session, zero if unknown
+func (st *PostgresqlSessionStore) CreatedAt() time.Time {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return session.CreatedTime(st.values)
+}
+
+// get the time postgresql session was read
+func (st *PostgresqlSessionStore) LastAccessedAt() time.Time {
+	return st.accessed
+}
+
 // get session id of this postgresql session store
 func (st *PostgresqlSessionStore) SessionID() string {
 	return st.sid
 }
 
+// get session_version of postgresql session when it was read, empty for a new session
+func (st *PostgresqlSessionStore) Version() string {
+	if st.version < 0 {
+		return ""
+	}
+	return strconv.FormatInt(st.version, 10)
+}
+
 // save postgresql session values to database.
 // must call this method to save values to database.
-func (st *PostgresqlSessionStore) SessionRelease(w http.ResponseWriter) {
+// values are only saved if session_version wasn't changed since read, otherwise session.ErrConflict is returned.
+// a new session is inserted unless another request saved it first, an expired row is replaced.
+func (st *PostgresqlSessionStore) SessionRelease(w http.ResponseWriter) error {
 	defer st.c.Close()
-	b, err := session.EncodeGob(st.values)
+	b, err := session.GetSerializer().Encode(st.values)
 	if err != nil {
-		return
+		return err
 	}
-	st.c.Exec("UPDATE session set session_data=$1, session_expiry=$2 where session_key=$3",
-		b, time.Now().Format(time.RFC3339), st.sid)
-
+	var row *sql.Row
+	if st.version < 0 {
+		row = st.c.QueryRow(`INSERT INTO session(session_key,session_data,session_expiry,session_version) VALUES($1,$2,$3,1)
+			ON CONFLICT (session_key) DO UPDATE SET session_data=EXCLUDED.session_data, session_expiry=EXCLUDED.session_expiry,
+			session_version=session.session_version+1 WHERE session.session_expiry <= now()
+			RETURNING session_version`,
+			st.sid, b, expiry(st.maxlifetime))
+	} else {
+		row = st.c.QueryRow(`UPDATE session SET session_data=$2, session_expiry=$3, session_version=session_version+1
+			WHERE session_key=$1 AND session_version=$4 RETURNING session_version`,
+			st.sid, b, expiry(st.maxlifetime), st.version)
+	}
+	err = row.Scan(&st.version)
+	if err == sql.ErrNoRows {
+		return session.ErrConflict
+	}
+	return err
 }
 
 // postgresql session provider
@@ -128,20 +206,23 @@ func (mp *PostgresqlProvider) SessionInit(maxlifetime int64, savePath string) er
 	return nil
 }
 
-// get postgresql session by sid
+// get postgresql session by sid.
+// the row is saved by SessionRelease.
 func (mp *PostgresqlProvider) SessionRead(sid string) (session.SessionStore, error) {
+	return mp.SessionReadContext(context.Background(), sid)
+}
+
+// get postgresql session by sid, the query is canceled when ctx is done.
+func (mp *PostgresqlProvider) SessionReadContext(ctx context.Context, sid string) (session.SessionStore, error) {
 	c := mp.connectInit()
-	row := c.QueryRow("select session_data from session where session_key=$1", sid)
+	row := c.QueryRowContext(ctx, "select session_data, session_version from session where session_key=$1 and session_expiry > now()", sid)
 	var sessiondata []byte
-	err := row.Scan(&sessiondata)
+	var version int64
+	err := row.Scan(&sessiondata, &version)
 	if err == sql.ErrNoRows {
-		_, err = c.Exec("insert into session(session_key,session_data,session_expiry) values($1,$2,$3)",
-			sid, "", time.Now().Format(time.RFC3339))
-
-		if err != nil {
-			return nil, err
-		}
+		version = -1
 	} else if err != nil {
+		c.Close()
 		return nil, err
 	}
 
@@ -149,12 +230,12 @@ func (mp *PostgresqlProvider) SessionRead(sid string) (session.SessionStore, err
 	if len(sessiondata) == 0 {
 		kv = make(map[interface{}]interface{})
 	} else {
-		kv, err = session.DecodeGob(sessiondata)
+		kv, err = session.GetSerializer().Decode(sessiondata)
 		if err != nil {
 			return nil, err
 		}
 	}
-	rs := &PostgresqlSessionStore{c: c, sid: sid, values: kv}
+	rs := &PostgresqlSessionStore{c: c, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), maxlifetime: mp.maxlifetime, version: version}
 	return rs, nil
 }
 
@@ -162,7 +243,7 @@ func (mp *PostgresqlProvider) SessionRead(sid string) (session.SessionStore, err
 func (mp *PostgresqlProvider) SessionExist(sid string) bool {
 	c := mp.connectInit()
 	defer c.Close()
-	row := c.QueryRow("select session_data from session where session_key=$1", sid)
+	row := c.QueryRow("select session_data from session where session_key=$1 and session_expiry > now()", sid)
 	var sessiondata []byte
 	err := row.Scan(&sessiondata)
 
@@ -176,24 +257,23 @@ func (mp *PostgresqlProvider) SessionExist(sid string) bool {
 // generate new sid for postgresql session
 func (mp *PostgresqlProvider) SessionRegenerate(oldsid, sid string) (session.SessionStore, error) {
 	c := mp.connectInit()
-	row := c.QueryRow("select session_data from session where session_key=$1", oldsid)
+	row := c.QueryRow("select session_data, session_version from session where session_key=$1 and session_expiry > now()", oldsid)
 	var sessiondata []byte
-	err := row.Scan(&sessiondata)
-	if err == sql.ErrNoRows {
-		c.Exec("insert into session(session_key,session_data,session_expiry) values($1,$2,$3)",
-			oldsid, "", time.Now().Format(time.RFC3339))
+	var version int64 = -1
+	err := row.Scan(&sessiondata, &version)
+	if err == nil {
+		c.Exec("update session set session_key=$1 where session_key=$2", sid, oldsid)
 	}
-	c.Exec("update session set session_key=$1 where session_key=$2", sid, oldsid)
 	var kv map[interface{}]interface{}
 	if len(sessiondata) == 0 {
 		kv = make(map[interface{}]interface{})
 	} else {
-		kv, err = session.DecodeGob(sessiondata)
+		kv, err = session.GetSerializer().Decode(sessiondata)
 		if err != nil {
 			return nil, err
 		}
 	}
-	rs := &PostgresqlSessionStore{c: c, sid: sid, values: kv}
+	rs := &PostgresqlSessionStore{c: c, sid: sid, values: session.InitCreated(kv), accessed: session.Now(), maxlifetime: mp.maxlifetime, version: version}
 	return rs, nil
 }
 
@@ -205,26 +285,54 @@ func (mp *PostgresqlProvider) SessionDestroy(sid string) error {
 	return nil
 }
 
+// update expiry of postgresql session to maxlifetime from now
+func (mp *PostgresqlProvider) SessionUpdate(sid string) error {
+	c := mp.connectInit()
+	defer c.Close()
+	_, err := c.Exec("UPDATE session set session_expiry=$1 where session_key=$2", expiry(mp.maxlifetime), sid)
+	return err
+}
+
+// check postgresql connection
+func (mp *PostgresqlProvider) Ping() error {
+	c := mp.connectInit()
+	if c == nil {
+		return errors.New("session: can not open postgresql")
+	}
+	defer c.Close()
+	return c.Ping()
+}
+
 // delete expired values in postgresql session
 func (mp *PostgresqlProvider) SessionGC() {
 	c := mp.connectInit()
-	c.Exec("DELETE from session where EXTRACT(EPOCH FROM (current_timestamp - session_expiry)) > $1", mp.maxlifetime)
+	c.Exec("DELETE from session where session_expiry < now()")
 	c.Close()
 	return
 }
 
+// postgresql session supports all capabilities.
+func (mp *PostgresqlProvider) Capabilities() session.ProviderCapabilities {
+	return session.CapAll
+}
+
 // count values in postgresql session
 func (mp *PostgresqlProvider) SessionAll() int {
 	c := mp.connectInit()
 	defer c.Close()
 	var total int
-	err := c.QueryRow("SELECT count(*) as num from session").Scan(&total)
+	err := c.QueryRow("SELECT count(*) as num from session where session_expiry > now()").Scan(&total)
 	if err != nil {
 		return 0
 	}
 	return total
 }
 
+// expiry time of a session saved now
+func expiry(maxlifetime int64) time.Time {
+	return session.Now().Add(time.Duration(maxlifetime) * time.Second)
+}
+
 func init() {
 	session.Register("postgresql", postgresqlpder)
 }
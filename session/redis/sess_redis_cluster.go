@@ -0,0 +1,235 @@
+package session
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/redigo/redis"
+)
+
+// number of hash slots in redis cluster.
+const clusterSlots = 16384
+
+// max MOVED or ASK redirections followed by one command.
+const maxRedirects = 5
+
+var errClusterPipeline = errors.New("session: redis cluster connection doesn't support pipelining")
+
+// source of redis connections, a pool or a cluster.
+type connPool interface {
+	Get() redis.Conn
+}
+
+// slots from start to end served by the master at addr.
+type slotRange struct {
+	start, end int
+	addr       string
+}
+
+// redis cluster, commands are sent to the master serving the slot of their first argument as key.
+// the slot map is read by CLUSTER SLOTS and refreshed after MOVED replies.
+type redisCluster struct {
+	rp          *RedisProvider
+	addrs       []string // seed nodes
+	idleTimeout time.Duration
+	lock        sync.RWMutex
+	ranges      []slotRange // sorted by start
+	pools       map[string]*redis.Pool
+}
+
+func newRedisCluster(rp *RedisProvider, addrs []string, idleTimeout time.Duration) *redisCluster {
+	return &redisCluster{rp: rp, addrs: addrs, idleTimeout: idleTimeout, pools: make(map[string]*redis.Pool)}
+}
+
+// Get returns a connection routing every command by its key.
+func (rc *redisCluster) Get() redis.Conn {
+	return &clusterConn{rc}
+}
+
+// get connection pool of node addr.
+func (rc *redisCluster) pool(addr string) *redis.Pool {
+	rc.lock.RLock()
+	p, ok := rc.pools[addr]
+	rc.lock.RUnlock()
+	if ok {
+		return p
+	}
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if p, ok = rc.pools[addr]; !ok {
+		p = redis.NewPool(func() (redis.Conn, error) { return rc.rp.dialAddr(addr) }, rc.rp.poolsize)
+		p.IdleTimeout = rc.idleTimeout
+		rc.pools[addr] = p
+	}
+	return p
+}
+
+// read slot map from the first seed or known master answering CLUSTER SLOTS.
+func (rc *redisCluster) refresh() error {
+	rc.lock.RLock()
+	addrs := append([]string{}, rc.addrs...)
+	for _, r := range rc.ranges {
+		addrs = append(addrs, r.addr)
+	}
+	rc.lock.RUnlock()
+
+	var lastErr error
+	for _, addr := range addrs {
+		ranges, err := rc.clusterSlots(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		rc.lock.Lock()
+		rc.ranges = ranges
+		rc.lock.Unlock()
+		return nil
+	}
+	return lastErr
+}
+
+// query slot ranges and their masters from node addr.
+func (rc *redisCluster) clusterSlots(addr string) ([]slotRange, error) {
+	c := rc.pool(addr).Get()
+	defer c.Close()
+
+	res, err := redis.Values(c.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, err
+	}
+	ranges := make([]slotRange, 0, len(res))
+	for _, r := range res {
+		fields, err := redis.Values(r, nil)
+		if err != nil || len(fields) < 3 {
+			return nil, errors.New("session: invalid redis CLUSTER SLOTS reply")
+		}
+		master, err := redis.Values(fields[2], nil)
+		if err != nil || len(master) < 2 {
+			return nil, errors.New("session: invalid redis CLUSTER SLOTS reply")
+		}
+		start, _ := redis.Int(fields[0], nil)
+		end, _ := redis.Int(fields[1], nil)
+		host, _ := redis.String(master[0], nil)
+		port, _ := redis.Int(master[1], nil)
+		if host == "" {
+			// the node doesn't know its own ip.
+			host, _, _ = net.SplitHostPort(addr)
+		}
+		ranges = append(ranges, slotRange{start, end, net.JoinHostPort(host, strconv.Itoa(port))})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+	return ranges, nil
+}
+
+// get address of the master serving slot, the first seed node if it's unknown.
+func (rc *redisCluster) addr(slot int) string {
+	rc.lock.RLock()
+	defer rc.lock.RUnlock()
+	i := sort.Search(len(rc.ranges), func(i int) bool { return rc.ranges[i].end >= slot })
+	if i < len(rc.ranges) && rc.ranges[i].start <= slot {
+		return rc.ranges[i].addr
+	}
+	return rc.addrs[0]
+}
+
+// send command to node addr, ASKING is sent before it if ask is true.
+func (rc *redisCluster) do(addr string, ask bool, cmd string, args ...interface{}) (interface{}, error) {
+	c := rc.pool(addr).Get()
+	defer c.Close()
+
+	if ask {
+		if _, err := c.Do("ASKING"); err != nil {
+			return nil, err
+		}
+	}
+	return c.Do(cmd, args...)
+}
+
+// connection of redis cluster.
+// commands with more than one key must have them in one slot, pipelining isn't supported.
+type clusterConn struct {
+	rc *redisCluster
+}
+
+func (c *clusterConn) Close() error { return nil }
+
+func (c *clusterConn) Err() error { return nil }
+
+func (c *clusterConn) Send(cmd string, args ...interface{}) error { return errClusterPipeline }
+
+func (c *clusterConn) Flush() error { return errClusterPipeline }
+
+func (c *clusterConn) Receive() (interface{}, error) { return nil, errClusterPipeline }
+
+// send command to the master of its key and follow MOVED and ASK redirections.
+// the key is the first argument, or the first key of scripts.
+// commands without key, e.g. PING, are sent to the master of slot 0.
+func (c *clusterConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd == "" {
+		return nil, nil
+	}
+	slot, keyArg := 0, 0
+	if cmd == "EVAL" || cmd == "EVALSHA" {
+		// script, key count, keys...
+		keyArg = 2
+	}
+	if len(args) > keyArg {
+		if key, ok := args[keyArg].(string); ok {
+			slot = keySlot(key)
+		}
+	}
+	addr, ask := c.rc.addr(slot), false
+	for i := 0; ; i++ {
+		reply, err := c.rc.do(addr, ask, cmd, args...)
+		rerr, ok := err.(redis.Error)
+		if !ok || i >= maxRedirects {
+			return reply, err
+		}
+		// MOVED <slot> <addr> or ASK <slot> <addr>
+		parts := strings.Fields(string(rerr))
+		if len(parts) != 3 {
+			return reply, err
+		}
+		switch parts[0] {
+		case "MOVED":
+			c.rc.refresh()
+			ask = false
+		case "ASK":
+			ask = true
+		default:
+			return reply, err
+		}
+		addr = parts[2]
+	}
+}
+
+// get hash slot of key, only the part in {} is hashed if key has a hash tag.
+func keySlot(key string) int {
+	if s := strings.IndexByte(key, '{'); s != -1 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+1+e]
+		}
+	}
+	return int(crc16(key)) % clusterSlots
+}
+
+// crc16 CCITT XMODEM used by redis cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,136 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var chainpder = &ChainProvider{}
+
+type chainConfig struct {
+	Primary         string `json:"primary"`
+	PrimaryConfig   string `json:"primaryConfig"`
+	Secondary       string `json:"secondary"`
+	SecondaryConfig string `json:"secondaryConfig"`
+}
+
+// Chain session provider.
+// it's used to migrate sessions to a new provider without logging users out.
+// sessions are read from primary, or from secondary if they're not in primary yet.
+// sessions read from secondary are written to primary when released.
+type ChainProvider struct {
+	primary   Provider
+	secondary Provider
+}
+
+// Init chain session provider with maxlifetime and config json, e.g.
+// {"primary":"redis","primaryConfig":"127.0.0.1:6379","secondary":"file","secondaryConfig":"./tmp"}
+// both providers must be registered and are initialized with their own config.
+func (pder *ChainProvider) SessionInit(maxlifetime int64, config string) error {
+	cf := &chainConfig{}
+	if err := json.Unmarshal([]byte(config), cf); err != nil {
+		return err
+	}
+	if cf.Primary == "chain" || cf.Secondary == "chain" {
+		return errors.New("session: chain provider can not chain itself")
+	}
+	var ok bool
+	if pder.primary, ok = provides[cf.Primary]; !ok {
+		return fmt.Errorf("session: unknown primary provide %q (forgotten import?)", cf.Primary)
+	}
+	if pder.secondary, ok = provides[cf.Secondary]; !ok {
+		return fmt.Errorf("session: unknown secondary provide %q (forgotten import?)", cf.Secondary)
+	}
+	if err := pder.primary.SessionInit(maxlifetime, cf.PrimaryConfig); err != nil {
+		return err
+	}
+	return pder.secondary.SessionInit(maxlifetime, cf.SecondaryConfig)
+}
+
+// Read session from primary.
+// if sid is only in secondary, its values are copied to a primary session store.
+func (pder *ChainProvider) SessionRead(sid string) (SessionStore, error) {
+	if pder.primary.SessionExist(sid) || !pder.secondary.SessionExist(sid) {
+		return pder.primary.SessionRead(sid)
+	}
+	return pder.migrate(sid, sid)
+}
+
+// Check session exist in primary or secondary.
+func (pder *ChainProvider) SessionExist(sid string) bool {
+	return pder.primary.SessionExist(sid) || pder.secondary.SessionExist(sid)
+}
+
+// Generate new sid in primary.
+// if old sid is only in secondary, its values are moved to primary.
+func (pder *ChainProvider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
+	if pder.primary.SessionExist(oldsid) || !pder.secondary.SessionExist(oldsid) {
+		return pder.primary.SessionRegenerate(oldsid, sid)
+	}
+	st, err := pder.migrate(oldsid, sid)
+	if err != nil {
+		return nil, err
+	}
+	pder.secondary.SessionDestroy(oldsid)
+	return st, nil
+}
+
+// Destroy session in both providers.
+func (pder *ChainProvider) SessionDestroy(sid string) error {
+	err := pder.primary.SessionDestroy(sid)
+	if err2 := pder.secondary.SessionDestroy(sid); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Extend session lifetime in primary.
+func (pder *ChainProvider) SessionUpdate(sid string) error {
+	if su, ok := pder.primary.(sessionUpdater); ok {
+		return su.SessionUpdate(sid)
+	}
+	return nil
+}
+
+// Clean expired sessions in both providers.
+func (pder *ChainProvider) SessionGC() {
+	pder.primary.SessionGC()
+	pder.secondary.SessionGC()
+}
+
+// capabilities supported by both providers.
+func (pder *ChainProvider) Capabilities() ProviderCapabilities {
+	return Capabilities(pder.primary) & Capabilities(pder.secondary)
+}
+
+// Get active session count of both providers.
+// sessions being migrated are counted twice.
+func (pder *ChainProvider) SessionAll() int {
+	return pder.primary.SessionAll() + pder.secondary.SessionAll()
+}
+
+// copy values of oldsid in secondary to a primary session store of sid.
+func (pder *ChainProvider) migrate(oldsid, sid string) (SessionStore, error) {
+	old, err := pder.secondary.SessionRead(oldsid)
+	if err != nil {
+		return nil, err
+	}
+	// secondary only needs to release its resources, e.g. the open file.
+	defer old.SessionRelease(nil)
+
+	st, err := pder.primary.SessionRead(sid)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[interface{}]interface{})
+	copyValues(values, old)
+	for k, v := range values {
+		st.Set(k, v)
+	}
+	return st, nil
+}
+
+func init() {
+	Register("chain", chainpder)
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestChainMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "beego-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a session saved by the old file provider
+	filepder.SessionInit(3600, dir)
+	old, _ := filepder.SessionRead("chainsid1")
+	old.Set("username", "astaxie")
+	old.SessionRelease(nil)
+
+	config := `{"cookieName":"gosessionid","gclifetime":3600,"ProviderConfig":"{\"primary\":\"memory\",\"secondary\":\"file\",\"secondaryConfig\":\"` + dir + `\"}"}`
+	globalSessions, err := NewManager("chain", config)
+	if err != nil {
+		t.Fatal("init chain session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "gosessionid", Value: "chainsid1"})
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	if sess.Get("username") != "astaxie" {
+		t.Fatal("session should be read through from secondary")
+	}
+	if err = globalSessions.SessionRelease(w, sess); err != nil {
+		t.Fatal("release error", err)
+	}
+	if !mempder.SessionExist("chainsid1") {
+		t.Fatal("session should be migrated to primary")
+	}
+	st, _ := mempder.SessionRead("chainsid1")
+	if st.Get("username") != "astaxie" {
+		t.Fatal("primary should have the migrated values")
+	}
+
+	if err = chainpder.SessionDestroy("chainsid1"); err != nil {
+		t.Fatal("destroy error", err)
+	}
+	if mempder.SessionExist("chainsid1") || filepder.SessionExist("chainsid1") {
+		t.Fatal("destroy should remove session from both providers")
+	}
+}
+
+func TestChainRegenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "beego-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = chainpder.SessionInit(3600, `{"primary":"memory","secondary":"file","secondaryConfig":"`+dir+`"}`); err != nil {
+		t.Fatal("init chain session err", err)
+	}
+	old, _ := filepder.SessionRead("chainsid2")
+	old.Set("username", "astaxie")
+	old.SessionRelease(nil)
+
+	st, err := chainpder.SessionRegenerate("chainsid2", "chainsid3")
+	if err != nil {
+		t.Fatal("regenerate error", err)
+	}
+	if st.Get("username") != "astaxie" {
+		t.Fatal("values should be moved to new sid")
+	}
+	if filepder.SessionExist("chainsid2") {
+		t.Fatal("old sid should be removed from secondary")
+	}
+	if err = chainpder.SessionInit(3600, `{"primary":"memory","secondary":"unknown"}`); err == nil {
+		t.Fatal("unknown secondary provider should return error")
+	}
+}
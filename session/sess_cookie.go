@@ -3,19 +3,33 @@ package session
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
+	"time"
 )
 
 var cookiepder = &CookieProvider{}
 
+// browsers drop cookies larger than 4096 bytes including name and attributes.
+const defaultMaxCookieSize = 4000
+
 // Cookie SessionStore
 type CookieSessionStore struct {
-	sid    string
-	values map[interface{}]interface{} // session data
-	lock   sync.RWMutex
+	sid       string
+	values    map[interface{}]interface{} // session data
+	lock      sync.RWMutex
+	destroyed bool      // cookie has been expired, don't write it again
+	released  bool      // cookie has been written in this request
+	dirty     bool      // values changed since read
+	accessed  time.Time // time of SessionRead
 }
 
 // Set value to cookie session.
@@ -23,6 +37,7 @@ type CookieSessionStore struct {
 func (st *CookieSessionStore) Set(key, value interface{}) error {
 	st.lock.Lock()
 	defer st.lock.Unlock()
+	st.dirty = true
 	st.values[key] = value
 	return nil
 }
@@ -43,6 +58,7 @@ func (st *CookieSessionStore) Get(key interface{}) interface{} {
 func (st *CookieSessionStore) Delete(key interface{}) error {
 	st.lock.Lock()
 	defer st.lock.Unlock()
+	st.dirty = true
 	delete(st.values, key)
 	return nil
 }
@@ -51,41 +67,143 @@ func (st *CookieSessionStore) Delete(key interface{}) error {
 func (st *CookieSessionStore) Flush() error {
 	st.lock.Lock()
 	defer st.lock.Unlock()
-	st.values = make(map[interface{}]interface{})
+	st.dirty = true
+	st.values = FlushValues(st.values)
 	return nil
 }
 
+// Check whether cookie session values changed since read
+func (st *CookieSessionStore) IsDirty() bool {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.dirty
+}
+
+// get keys of cookie session
+func (st *CookieSessionStore) Keys() []interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	keys := make([]interface{}, 0, len(st.values))
+	for k := range st.values {
+		if IsReservedKey(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// get count of values in cookie session
+func (st *CookieSessionStore) Len() int {
+	return len(st.Keys())
+}
+
+// get creation time of cookie session, zero if unknown
+func (st *CookieSessionStore) CreatedAt() time.Time {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return CreatedTime(st.values)
+}
+
+// get the time cookie session was read
+func (st *CookieSessionStore) LastAccessedAt() time.Time {
+	return st.accessed
+}
+
 // Return id of this cookie session
 func (st *CookieSessionStore) SessionID() string {
 	return st.sid
 }
 
 // Write cookie session to http response cookie
-func (st *CookieSessionStore) SessionRelease(w http.ResponseWriter) {
-	str, err := encodeCookie(cookiepder.block,
-		cookiepder.config.SecurityKey,
-		cookiepder.config.SecurityName,
-		st.values)
+// it returns error and writes nothing if the encoded cookie exceeds maxCookieSize.
+// only the first call after Reset writes the cookie, later calls are no-ops.
+func (st *CookieSessionStore) SessionRelease(w http.ResponseWriter) error {
+	return st.sessionReleaseSecure(w, cookiepder.config.Secure)
+}
+
+// write cookie session with given Secure attribute, the manager detects it from request.
+func (st *CookieSessionStore) sessionReleaseSecure(w http.ResponseWriter, secure bool) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	if st.destroyed || st.released {
+		return nil
+	}
+	str, err := cookiepder.codec.Encode(st.values)
 	if err != nil {
-		return
+		return err
+	}
+	str = cookiepder.encodeValue(str)
+	if len(str) > cookiepder.config.MaxCookieSize {
+		return fmt.Errorf("session: cookie session size %d exceeds maxCookieSize %d", len(str), cookiepder.config.MaxCookieSize)
 	}
 	cookie := &http.Cookie{Name: cookiepder.config.CookieName,
-		Value:    url.QueryEscape(str),
-		Path:     "/",
+		Value:    str,
+		Path:     cookiepder.config.CookiePath,
+		Domain:   cookiepder.config.CookieDomain,
+		HttpOnly: true,
+		Secure:   secure,
+		MaxAge:   cookiepder.config.Maxage,
+		SameSite: cookiepder.sameSite}
+	// browsers reject SameSite=None cookies without Secure.
+	if cookie.SameSite == http.SameSiteNoneMode {
+		cookie.Secure = true
+	}
+	http.SetCookie(w, cookie)
+	st.released = true
+	st.dirty = false
+	return nil
+}
+
+// Reset the released and destroyed state, SessionRelease writes the cookie again.
+// manager calls it when a request starts with this store.
+func (st *CookieSessionStore) Reset() {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.released = false
+	st.destroyed = false
+}
+
+// Expire cookie session in http response cookie.
+// the browser drops the session cookie and later SessionRelease writes nothing.
+func (st *CookieSessionStore) DestroyCookie(w http.ResponseWriter) {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.values = make(map[interface{}]interface{})
+	st.destroyed = true
+	cookie := &http.Cookie{Name: cookiepder.config.CookieName,
+		Value:    "",
+		Path:     cookiepder.config.CookiePath,
+		Domain:   cookiepder.config.CookieDomain,
 		HttpOnly: true,
 		Secure:   cookiepder.config.Secure,
-		MaxAge:   cookiepder.config.Maxage}
+		Expires:  time.Unix(1, 0),
+		MaxAge:   -1,
+		SameSite: cookiepder.sameSite}
+	if cookie.SameSite == http.SameSiteNoneMode {
+		cookie.Secure = true
+	}
 	http.SetCookie(w, cookie)
-	return
 }
 
 type cookieConfig struct {
-	SecurityKey  string `json:"securityKey"`
-	BlockKey     string `json:"blockKey"`
-	SecurityName string `json:"securityName"`
-	CookieName   string `json:"cookieName"`
-	Secure       bool   `json:"secure"`
-	Maxage       int    `json:"maxage"`
+	SecurityKey   string   `json:"securityKey"`
+	SecurityKeys  []string `json:"securityKeys"`
+	BlockKey      string   `json:"blockKey"`
+	RawBlockKey   *bool    `json:"rawBlockKey"`
+	SecurityName  string   `json:"securityName"`
+	CookieName    string   `json:"cookieName"`
+	Secure        bool     `json:"secure"`
+	Maxage        int      `json:"maxage"`
+	SameSite      string   `json:"sameSite"`
+	Mode          string   `json:"mode"`
+	MaxCookieSize int      `json:"maxCookieSize"`
+	CookieDomain  string   `json:"cookieDomain"`
+	CookiePath    string   `json:"cookiePath"`
+	Compress      bool     `json:"compress"`
+	EncryptData   *bool    `json:"encryptData"`
+	Encoding      string   `json:"cookieEncoding"`
+	StrictKeys    bool     `json:"strictKeys"`
 }
 
 // Cookie session provider
@@ -93,47 +211,199 @@ type CookieProvider struct {
 	maxlifetime int64
 	config      *cookieConfig
 	block       cipher.Block
+	crypt       cookieCipher
+	hashKeys    []string
+	sameSite    http.SameSite
+	codec       CookieCodec
+	customCodec CookieCodec // set by SetCookieCodec
+	generated   GeneratedCookieKeys
+	logf        func(format string, v ...interface{}) // set by SetCookieLogger
+}
+
+// GeneratedCookieKeys are the keys generated by the cookie provider because they aren't configured,
+// empty if they are. pin them in the config to keep sessions across restarts and instances.
+type GeneratedCookieKeys struct {
+	BlockKey     string
+	SecurityName string
+}
+
+// CookieGeneratedKeys returns the keys generated by the last init of the cookie provider.
+func CookieGeneratedKeys() GeneratedCookieKeys {
+	return cookiepder.generated
+}
+
+// SetCookieLogger sets the logger of cookie provider warnings, e.g. about generated keys.
+// nil restores the log package. call it before the session manager is created.
+func SetCookieLogger(logf func(format string, v ...interface{})) {
+	cookiepder.logf = logf
+}
+
+// SetCookieCodec sets the codec of cookie session values, nil restores the beego format.
+// call it before the session manager is created.
+// securityKey, blockKey, mode and compress config are only used by the beego format.
+func SetCookieCodec(codec CookieCodec) {
+	cookiepder.customCodec = codec
 }
 
 // Init cookie session provider with max lifetime and config json.
 // maxlifetime is ignored.
 // json config:
 // 	securityKey - hash string
+// 	securityKeys - hash strings, the first one signs cookie and all of them verify it,
+// 	so the key can be rotated without invalidating live sessions. it overrides securityKey.
 // 	blockKey - gob encode hash string. it's saved as aes crypto.
+// 	a key of 16, 24 or 32 bytes is the aes key, a passphrase of other length is hashed by SHA-256.
+// 	rawBlockKey - true requires blockKey to be an aes key, false hashes every blockKey.
 // 	securityName - recognized name in encoded cookie string
 // 	cookieName - cookie name
 // 	maxage - cookie max life time.
+// 	sameSite - SameSite attribute of cookie, lax, strict or none.
+// 	mode - encryption mode, ctr (default) or gcm for authenticated encryption.
+// 	maxCookieSize - max length of encoded cookie value, default is 4000.
+// 	cookieDomain - cookie domain, default is none.
+// 	cookiePath - cookie path, default is /.
+// 	compress - compress session data before encryption, for large sessions.
+// 	encryptData - encrypt session data, default is true. if false data is only signed with securityKey.
+// 	cookieEncoding - encoding of cookie value, base64 (default) is url-safe base64 which proxies don't escape again,
+// 	query is url query escaping of earlier versions, e.g. for cookies read by another framework. both are read.
+// 	strictKeys - return error if blockKey or securityName is missing instead of generating a random one.
+// random keys change on restart and differ between instances, so sessions are lost, a warning is logged for them.
 func (pder *CookieProvider) SessionInit(maxlifetime int64, config string) error {
 	pder.config = &cookieConfig{}
 	err := json.Unmarshal([]byte(config), pder.config)
 	if err != nil {
 		return err
 	}
-	if pder.config.BlockKey == "" {
-		pder.config.BlockKey = string(generateRandomKey(16))
+	if err = pder.generateKeys(); err != nil {
+		return err
 	}
-	if pder.config.SecurityName == "" {
-		pder.config.SecurityName = string(generateRandomKey(20))
+	pder.hashKeys = pder.config.SecurityKeys
+	if len(pder.hashKeys) == 0 {
+		pder.hashKeys = []string{pder.config.SecurityKey}
+	}
+	if pder.config.CookiePath == "" {
+		pder.config.CookiePath = "/"
+	}
+	if pder.config.MaxCookieSize <= 0 {
+		pder.config.MaxCookieSize = defaultMaxCookieSize
+	}
+	key, err := cookieBlockKey(pder.config.BlockKey, pder.config.RawBlockKey)
+	if err != nil {
+		return err
+	}
+	pder.block, err = aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	if pder.config.EncryptData != nil && !*pder.config.EncryptData {
+		// data is readable by the client, securityKey still protects it from tampering.
+		pder.crypt = plainCipher{}
+	} else if pder.crypt, err = newCookieCipher(pder.block, pder.config.Mode); err != nil {
+		return err
 	}
-	pder.block, err = aes.NewCipher([]byte(pder.config.BlockKey))
+	pder.sameSite, err = parseSameSite(pder.config.SameSite)
 	if err != nil {
 		return err
 	}
+	switch pder.config.Encoding {
+	case "":
+		pder.config.Encoding = "base64"
+	case "base64", "query":
+	default:
+		return fmt.Errorf("session: unknown cookieEncoding %q", pder.config.Encoding)
+	}
 	pder.maxlifetime = maxlifetime
+	pder.codec = pder.customCodec
+	if pder.codec == nil {
+		pder.codec = &beegoCodec{crypt: pder.crypt,
+			hashKeys:    pder.hashKeys,
+			name:        pder.config.SecurityName,
+			compress:    pder.config.Compress,
+			maxlifetime: maxlifetime}
+	}
+	return nil
+}
+
+// generate missing blockKey and securityName, error if strictKeys is set.
+// they are hex, so the values of CookieGeneratedKeys can be pinned in config.
+func (pder *CookieProvider) generateKeys() error {
+	pder.generated = GeneratedCookieKeys{}
+	var missing []string
+	if pder.config.BlockKey == "" {
+		missing = append(missing, "blockKey")
+	}
+	if pder.config.SecurityName == "" {
+		missing = append(missing, "securityName")
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	names := strings.Join(missing, " and ")
+	if pder.config.StrictKeys {
+		return fmt.Errorf("session: cookie %s not set, they are required by strictKeys", names)
+	}
+	if pder.config.BlockKey == "" {
+		pder.config.BlockKey = hex.EncodeToString(generateRandomKey(16))
+		pder.generated.BlockKey = pder.config.BlockKey
+	}
+	if pder.config.SecurityName == "" {
+		pder.config.SecurityName = hex.EncodeToString(generateRandomKey(20))
+		pder.generated.SecurityName = pder.config.SecurityName
+	}
+	logf := pder.logf
+	if logf == nil {
+		logf = log.Printf
+	}
+	logf("session: cookie %s not set, generated random ones, sessions are lost on restart and not shared by instances. "+
+		"set them in config, session.CookieGeneratedKeys returns the generated values", names)
 	return nil
 }
 
+// get aes key of blockKey.
+// without raw, keys of aes size are kept so cookies of existing keys stay readable.
+func cookieBlockKey(blockKey string, raw *bool) ([]byte, error) {
+	key := []byte(blockKey)
+	switch len(key) {
+	case 16, 24, 32:
+		if raw == nil || *raw {
+			return key, nil
+		}
+	default:
+		if raw != nil && *raw {
+			return nil, fmt.Errorf("session: raw blockKey should be 16, 24 or 32 bytes, got %d", len(key))
+		}
+	}
+	sum := sha256.Sum256(key)
+	return sum[:], nil
+}
+
+// encode value of codec as cookie value.
+func (pder *CookieProvider) encodeValue(value string) string {
+	if pder.config.Encoding == "query" {
+		return url.QueryEscape(value)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+// decode cookie value by codec, it's url-safe base64 or the codec value itself,
+// the manager has query unescaped it already.
+func (pder *CookieProvider) decodeValue(value string) (map[interface{}]interface{}, error) {
+	if b, err := base64.RawURLEncoding.DecodeString(value); err == nil {
+		if maps, err := pder.codec.Decode(string(b)); err == nil {
+			return maps, nil
+		}
+	}
+	return pder.codec.Decode(value)
+}
+
 // Get SessionStore in cooke.
 // decode cooke string to map and put into SessionStore with sid.
 func (pder *CookieProvider) SessionRead(sid string) (SessionStore, error) {
-	maps, _ := decodeCookie(pder.block,
-		pder.config.SecurityKey,
-		pder.config.SecurityName,
-		sid, pder.maxlifetime)
+	maps, _ := pder.decodeValue(sid)
 	if maps == nil {
 		maps = make(map[interface{}]interface{})
 	}
-	rs := &CookieSessionStore{sid: sid, values: maps}
+	rs := &CookieSessionStore{sid: sid, values: InitCreated(maps), accessed: Now()}
 	return rs, nil
 }
 
@@ -142,16 +412,29 @@ func (pder *CookieProvider) SessionExist(sid string) bool {
 	return true
 }
 
-// Implement method, no used.
+// Generate new sid for cookie session.
+// cookie session keeps all data in cookie, so values of oldsid are moved to the new store.
 func (pder *CookieProvider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
-	return nil, nil
+	maps, _ := pder.decodeValue(oldsid)
+	if maps == nil {
+		maps = make(map[interface{}]interface{})
+	}
+	// values are moved to a new cookie, it must be written.
+	rs := &CookieSessionStore{sid: sid, values: InitCreated(maps), accessed: Now(), dirty: true}
+	return rs, nil
 }
 
-// Implement method, no used.
+// Cookie session has no server side data.
+// the cookie is expired by CookieSessionStore.DestroyCookie.
 func (pder *CookieProvider) SessionDestroy(sid string) error {
 	return nil
 }
 
+// cookie sessions are saved by the client, they can be regenerated but not destroyed, collected or counted.
+func (pder *CookieProvider) Capabilities() ProviderCapabilities {
+	return CapRegenerate
+}
+
 // Implement method, no used.
 func (pder *CookieProvider) SessionGC() {
 	return
@@ -163,10 +446,27 @@ func (pder *CookieProvider) SessionAll() int {
 }
 
 // Implement method, no used.
+// cookie session is re-emitted with fresh MaxAge and timestamp on every SessionRelease.
 func (pder *CookieProvider) SessionUpdate(sid string) error {
 	return nil
 }
 
+// parse SameSite config value to http.SameSite.
+// empty value means the attribute is not written.
+func parseSameSite(mode string) (http.SameSite, error) {
+	switch strings.ToLower(mode) {
+	case "":
+		return http.SameSiteDefaultMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return http.SameSiteDefaultMode, fmt.Errorf("session: unknown sameSite mode %q", mode)
+}
+
 func init() {
 	Register("cookie", cookiepder)
 }
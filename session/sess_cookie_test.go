@@ -1,10 +1,19 @@
 package session
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestCookie(t *testing.T) {
@@ -36,3 +45,568 @@ func TestCookie(t *testing.T) {
 		}
 	}
 }
+
+func TestCookieSameSite(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\",\"sameSite\":\"none\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	sess.SessionRelease(w)
+	cookiestr := w.Header().Get("Set-Cookie")
+	if !strings.Contains(cookiestr, "SameSite=None") {
+		t.Fatal("samesite error", cookiestr)
+	}
+	if !strings.Contains(cookiestr, "Secure") {
+		t.Fatal("samesite none must be secure", cookiestr)
+	}
+
+	config = `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\",\"sameSite\":\"foo\"}"}`
+	if _, err = NewManager("cookie", config); err == nil {
+		t.Fatal("unknown sameSite should return error")
+	}
+}
+
+func TestCookieDestroy(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	sess.SessionRelease(w)
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	w = httptest.NewRecorder()
+	sess = globalSessions.SessionStart(w, r)
+	if sess.Get("username") != "astaxie" {
+		t.Fatal("get username error")
+	}
+	sess.(*CookieSessionStore).DestroyCookie(w)
+	sess.SessionRelease(w)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatal("destroyed cookie should be written once, got", len(cookies))
+	}
+	if cookies[0].MaxAge >= 0 || cookies[0].Value != "" {
+		t.Fatal("cookie is not expired", w.Header().Get("Set-Cookie"))
+	}
+	if sess.Get("username") != nil {
+		t.Fatal("destroyed session should be empty")
+	}
+
+	w = httptest.NewRecorder()
+	globalSessions.SessionDestroy(w, r)
+	cookies = w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Fatal("manager SessionDestroy should expire cookie", w.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestCookieGCM(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\",\"mode\":\"gcm\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	sess.SessionRelease(w)
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	sess = globalSessions.SessionStart(httptest.NewRecorder(), r)
+	if sess.Get("username") != "astaxie" {
+		t.Fatal("get username error")
+	}
+
+	sess, err = cookiepder.SessionRead("invalid")
+	if err != nil {
+		t.Fatal("SessionRead error", err)
+	}
+	if sess.Get("username") != nil {
+		t.Fatal("invalid cookie should return empty session")
+	}
+}
+
+func TestCookieBlockKey(t *testing.T) {
+	for _, blockKey := range []string{`,\"blockKey\":\"short\"`, `,\"blockKey\":\"0123456789abcdef\",\"rawBlockKey\":true`, ``} {
+		config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"` + blockKey + `}"}`
+		globalSessions, err := NewManager("cookie", config)
+		if err != nil {
+			t.Fatal("init cookie session err", blockKey, err)
+		}
+		r, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		sess := globalSessions.SessionStart(w, r)
+		sess.Set("username", "astaxie")
+		sess.SessionRelease(w)
+
+		r, _ = http.NewRequest("GET", "/", nil)
+		r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+		sess = globalSessions.SessionStart(httptest.NewRecorder(), r)
+		if sess.Get("username") != "astaxie" {
+			t.Fatal("get username error", blockKey)
+		}
+	}
+
+	raw, derive := true, false
+	if _, err := cookieBlockKey("short", &raw); err == nil {
+		t.Fatal("raw blockKey of wrong size should return error")
+	}
+	if key, _ := cookieBlockKey("0123456789abcdef", nil); string(key) != "0123456789abcdef" {
+		t.Fatal("blockKey of aes size should be kept")
+	}
+	if key, _ := cookieBlockKey("0123456789abcdef", &derive); len(key) != 32 || string(key[:16]) == "0123456789abcdef" {
+		t.Fatal("blockKey should be hashed if rawBlockKey is false")
+	}
+}
+
+func TestCookieMaxSize(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("blob", strings.Repeat("a", 5000))
+	if err = sess.SessionRelease(w); err == nil {
+		t.Fatal("oversized cookie session should return error")
+	}
+	if cookiestr := w.Header().Get("Set-Cookie"); cookiestr != "" {
+		t.Fatal("oversized cookie should not be written", cookiestr)
+	}
+	sess.Set("blob", "small")
+	if err = sess.SessionRelease(w); err != nil {
+		t.Fatal("release error", err)
+	}
+}
+
+func TestCookieRegenerate(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	sess.SessionRelease(w)
+	oldsid := w.Result().Cookies()[0].Value
+	oldsid, _ = url.QueryUnescape(oldsid)
+
+	sess, err = cookiepder.SessionRegenerate(oldsid, "newsid")
+	if err != nil {
+		t.Fatal("SessionRegenerate error", err)
+	}
+	if sess == nil || sess.SessionID() != "newsid" {
+		t.Fatal("SessionRegenerate should return store with new sid")
+	}
+	if sess.Get("username") != "astaxie" {
+		t.Fatal("SessionRegenerate should keep values")
+	}
+}
+
+func TestCookieDomainPath(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\",\"cookieDomain\":\"example.com\",\"cookiePath\":\"/app\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.SessionRelease(w)
+	cookiestr := w.Header().Get("Set-Cookie")
+	if !strings.Contains(cookiestr, "Path=/app") || !strings.Contains(cookiestr, "Domain=example.com") {
+		t.Fatal("cookie domain and path error", cookiestr)
+	}
+}
+
+func TestCookieSetWithTTL(t *testing.T) {
+	sess := &CookieSessionStore{sid: "sid", values: make(map[interface{}]interface{})}
+	// cookie store doesn't support ttl, the value is set without expiration
+	if err := SetWithTTL(sess, "flash", "saved", time.Millisecond); err != nil {
+		t.Fatal("SetWithTTL error", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if sess.Get("flash") != "saved" {
+		t.Fatal("SetWithTTL should fall back to Set")
+	}
+}
+
+func TestCookieSecurityKeys(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"keya\",\"blockKey\":\"0123456789abcdef\",\"securityName\":\"beego\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	sess.SessionRelease(w)
+
+	config = `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKeys\":[\"keyb\",\"keya\"],\"blockKey\":\"0123456789abcdef\",\"securityName\":\"beego\"}"}`
+	globalSessions, err = NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	sess = globalSessions.SessionStart(httptest.NewRecorder(), r)
+	if sess.Get("username") != "astaxie" {
+		t.Fatal("session signed by old key should be valid after rotation")
+	}
+}
+
+func TestCookieReleaseOnce(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	sess.SessionRelease(w)
+	sess.SessionRelease(w)
+	if n := len(w.Header()["Set-Cookie"]); n != 1 {
+		t.Fatal("two releases should write one cookie, got", n)
+	}
+	sess.(*CookieSessionStore).Reset()
+	sess.SessionRelease(w)
+	if n := len(w.Header()["Set-Cookie"]); n != 2 {
+		t.Fatal("release after Reset should write cookie again, got", n)
+	}
+}
+
+func TestCookieCleanRelease(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Get("username")
+	if IsDirty(sess) {
+		t.Fatal("Get should leave the store clean")
+	}
+	globalSessions.SessionRelease(w, sess)
+	if n := len(w.Header()["Set-Cookie"]); n != 0 {
+		t.Fatal("clean store should not be written, got cookies", n)
+	}
+	sess.Set("username", "astaxie")
+	if !IsDirty(sess) {
+		t.Fatal("Set should make the store dirty")
+	}
+	globalSessions.SessionRelease(w, sess)
+	if n := len(w.Header()["Set-Cookie"]); n != 1 {
+		t.Fatal("dirty store should be written, got cookies", n)
+	}
+	if IsDirty(sess) {
+		t.Fatal("store should be clean after release")
+	}
+}
+
+func TestCookieSignOnly(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\",\"encryptData\":false}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("theme", "dark")
+	sess.SessionRelease(w)
+	cookie := w.Result().Cookies()[0]
+	raw, _ := base64.RawURLEncoding.DecodeString(cookie.Value)
+	value := string(raw)
+
+	// the value is "kid|date|base64(gob)|mac", gob is not encrypted
+	b, _ := decode([]byte(value))
+	parts := strings.SplitN(string(b), "|", 4)
+	data, _ := decode([]byte(parts[2]))
+	if dst, err := DecodeGob(data); err != nil || dst["theme"] != "dark" {
+		t.Fatal("signed only data should be plain gob", err)
+	}
+
+	st, _ := cookiepder.SessionRead(value)
+	if st.Get("theme") != "dark" {
+		t.Fatal("signed cookie should be read")
+	}
+	dst := map[interface{}]interface{}{"theme": "light"}
+	forged, _ := EncodeGob(dst)
+	parts[2] = string(encode(forged))
+	tampered := string(encode([]byte(strings.Join(parts, "|"))))
+	st, _ = cookiepder.SessionRead(tampered)
+	if st.Get("theme") != nil {
+		t.Fatal("tampered cookie should be rejected")
+	}
+}
+
+func TestCookieKeys(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	if sess.Len() != 0 || len(sess.Keys()) != 0 {
+		t.Fatal("new session should be empty")
+	}
+	sess.Set("username", "astaxie")
+	sess.Set("theme", "dark")
+	if sess.Len() != 2 {
+		t.Fatal("len should be 2, got", sess.Len())
+	}
+	keys := map[interface{}]bool{}
+	for _, k := range sess.Keys() {
+		keys[k] = true
+	}
+	if !keys["username"] || !keys["theme"] || len(keys) != 2 {
+		t.Fatal("keys error", sess.Keys())
+	}
+}
+
+func TestCookieAutoSecure(t *testing.T) {
+	config := `{"cookieName":"gosessionid","enableSetCookie":true,"gclifetime":3600,"autoSecure":true,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	if err = globalSessions.SessionReleaseRequest(w, r, sess); err != nil {
+		t.Fatal("release error", err)
+	}
+	for _, c := range w.Header()["Set-Cookie"] {
+		if strings.Contains(c, "Secure") {
+			t.Fatal("cookie of http request should not be secure", c)
+		}
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	w = httptest.NewRecorder()
+	sess = globalSessions.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	if err = globalSessions.SessionReleaseRequest(w, r, sess); err != nil {
+		t.Fatal("release error", err)
+	}
+	cookies := w.Header()["Set-Cookie"]
+	if len(cookies) == 0 {
+		t.Fatal("setcookie error")
+	}
+	for _, c := range cookies {
+		if !strings.Contains(c, "Secure") {
+			t.Fatal("cookie of forwarded https request should be secure", c)
+		}
+	}
+}
+
+// railsCodec is a codec of another framework, it signs base64 json with hmac-sha256 as "data--mac".
+type railsCodec struct {
+	key string
+}
+
+func (c railsCodec) mac(data string) string {
+	h := hmac.New(sha256.New, []byte(c.key))
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c railsCodec) Encode(values map[interface{}]interface{}) (string, error) {
+	m := make(map[string]string)
+	for k, v := range values {
+		// the other framework doesn't know creation time of beego.
+		if s, ok := v.(string); ok && k != CreatedKey {
+			m[fmt.Sprint(k)] = s
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	data := base64.StdEncoding.EncodeToString(b)
+	return data + "--" + c.mac(data), nil
+}
+
+func (c railsCodec) Decode(value string) (map[interface{}]interface{}, error) {
+	parts := strings.SplitN(value, "--", 2)
+	if len(parts) != 2 || !hmac.Equal([]byte(c.mac(parts[0])), []byte(parts[1])) {
+		return nil, errors.New("invalid signature")
+	}
+	b, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]string
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	values := make(map[interface{}]interface{})
+	for k, v := range m {
+		values[k] = v
+	}
+	return values, nil
+}
+
+func TestCookieCodec(t *testing.T) {
+	// cookie written by the other framework with secret "railssecret"
+	const blob = "eyJ0aGVtZSI6ImRhcmsiLCJ1c2VyX2lkIjoiNDIifQ==--10012387adea45e30a3192a661cf86c22e0d7abff78247bd1dd778df78f1e338"
+	SetCookieCodec(railsCodec{"railssecret"})
+	defer SetCookieCodec(nil)
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\",\"cookieEncoding\":\"query\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "gosessionid", Value: url.QueryEscape(blob)})
+	sess := globalSessions.SessionStart(httptest.NewRecorder(), r)
+	if sess.Get("theme") != "dark" || sess.Get("user_id") != "42" {
+		t.Fatal("cookie of the other framework should be read, got", sess.Get("theme"), sess.Get("user_id"))
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess = globalSessions.SessionStart(w, r)
+	sess.Set("theme", "dark")
+	sess.Set("user_id", "42")
+	if err = sess.SessionRelease(w); err != nil {
+		t.Fatal("release error", err)
+	}
+	cookie := w.Result().Cookies()[0]
+	if value, _ := url.QueryUnescape(cookie.Value); value != blob {
+		t.Fatal("cookie should be written in the format of the other framework, got", value)
+	}
+
+	st, _ := cookiepder.SessionRead(strings.Replace(blob, "eyJ0", "eyJ1", 1))
+	if st.Get("theme") != nil {
+		t.Fatal("tampered cookie should be rejected")
+	}
+}
+
+func TestCookieEncoding(t *testing.T) {
+	// base64 json of the codec contains + / and =
+	SetCookieCodec(railsCodec{"railssecret"})
+	defer SetCookieCodec(nil)
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("path", "a/b+c=~~~?")
+	if err = sess.SessionRelease(w); err != nil {
+		t.Fatal("release error", err)
+	}
+	value := w.Result().Cookies()[0].Value
+	raw, _ := base64.RawURLEncoding.DecodeString(value)
+	if !strings.ContainsAny(string(raw), "+/=") {
+		t.Fatal("codec value should contain + / or =", string(raw))
+	}
+	if url.QueryEscape(value) != value {
+		t.Fatal("cookie value should not be changed by escaping it again", value)
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "gosessionid", Value: value})
+	if sess = globalSessions.SessionStart(httptest.NewRecorder(), r); sess.Get("path") != "a/b+c=~~~?" {
+		t.Fatal("base64 cookie should round-trip, got", sess.Get("path"))
+	}
+
+	// cookies of earlier versions are query escaped
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "gosessionid", Value: url.QueryEscape(string(raw))})
+	if sess = globalSessions.SessionStart(httptest.NewRecorder(), r); sess.Get("path") != "a/b+c=~~~?" {
+		t.Fatal("query escaped cookie should be read, got", sess.Get("path"))
+	}
+
+	config = `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"cookieEncoding\":\"hex\"}"}`
+	if _, err = NewManager("cookie", config); err == nil {
+		t.Fatal("unknown cookieEncoding should fail")
+	}
+}
+
+func TestCookieStrictKeys(t *testing.T) {
+	var warnings []string
+	SetCookieLogger(func(format string, v ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, v...))
+	})
+	defer SetCookieLogger(nil)
+
+	config := `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\",\"strictKeys\":true}"}`
+	if _, err := NewManager("cookie", config); err == nil || !strings.Contains(err.Error(), "blockKey and securityName") {
+		t.Fatal("strictKeys should reject missing keys", err)
+	}
+	config = `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\",\"securityName\":\"beego\",\"strictKeys\":true}"}`
+	if _, err := NewManager("cookie", config); err == nil || !strings.Contains(err.Error(), "blockKey") || strings.Contains(err.Error(), "securityName") {
+		t.Fatal("strictKeys should reject missing blockKey", err)
+	}
+	config = `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\",\"securityName\":\"beego\",\"blockKey\":\"a passphrase\",\"strictKeys\":true}"}`
+	if _, err := NewManager("cookie", config); err != nil {
+		t.Fatal("strictKeys should accept configured keys", err)
+	}
+	if keys := CookieGeneratedKeys(); keys.BlockKey != "" || keys.SecurityName != "" || len(warnings) != 0 {
+		t.Fatal("configured keys should not be generated", keys, warnings)
+	}
+
+	// lenient config generates keys and warns
+	config = `{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"}"}`
+	globalSessions, err := NewManager("cookie", config)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "blockKey and securityName") {
+		t.Fatal("generated keys should be warned about", warnings)
+	}
+	keys := CookieGeneratedKeys()
+	if len(keys.BlockKey) != 32 || len(keys.SecurityName) != 40 {
+		t.Fatal("generated keys should be hex", keys)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	sess.SessionRelease(w)
+	cookie := w.Result().Cookies()[0]
+
+	// cookies are read after restart with the generated keys pinned
+	config = fmt.Sprintf(`{"cookieName":"gosessionid","enableSetCookie":false,"gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\",\"blockKey\":\"%s\",\"securityName\":\"%s\",\"strictKeys\":true}"}`,
+		keys.BlockKey, keys.SecurityName)
+	if globalSessions, err = NewManager("cookie", config); err != nil {
+		t.Fatal("pinned keys should be accepted", err)
+	}
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.AddCookie(cookie)
+	if sess = globalSessions.SessionStart(httptest.NewRecorder(), r); sess.Get("username") != "astaxie" {
+		t.Fatal("cookie should be read with pinned keys, got", sess.Get("username"))
+	}
+}
@@ -0,0 +1,31 @@
+package session
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// reserved session key saving the csrf token
+const csrfKey = "__beego_csrf__"
+
+// EnsureCSRFToken returns the csrf token saved in store.
+// a random token is created and saved if store has none,
+// it lives as long as the session and is moved with it by SessionRegenerateId.
+func EnsureCSRFToken(store SessionStore) string {
+	if token, ok := store.Get(csrfKey).(string); ok && token != "" {
+		return token
+	}
+	token := base64.RawURLEncoding.EncodeToString(generateRandomKey(32))
+	store.Set(csrfKey, token)
+	return token
+}
+
+// ValidateCSRFToken checks submitted against the csrf token saved in store.
+// it compares in constant time and fails if store has no token.
+func ValidateCSRFToken(store SessionStore, submitted string) bool {
+	token, ok := store.Get(csrfKey).(string)
+	if !ok || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(submitted)) == 1
+}
@@ -0,0 +1,39 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRFToken(t *testing.T) {
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	if ValidateCSRFToken(sess, "") {
+		t.Fatal("session without token should reject any token")
+	}
+	token := EnsureCSRFToken(sess)
+	if len(token) != 43 {
+		t.Fatal("token should be 32 random bytes in base64, got", token)
+	}
+	if EnsureCSRFToken(sess) != token {
+		t.Fatal("token should be reused")
+	}
+	if !ValidateCSRFToken(sess, token) {
+		t.Fatal("right token should be accepted")
+	}
+	wrong := token[:42] + "a"
+	if wrong == token {
+		wrong = token[:42] + "b"
+	}
+	if ValidateCSRFToken(sess, wrong) || ValidateCSRFToken(sess, "") {
+		t.Fatal("wrong token should be rejected")
+	}
+
+	sess = globalSessions.SessionRegenerateId(w, r)
+	if EnsureCSRFToken(sess) != token {
+		t.Fatal("token should survive SessionRegenerateId")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -16,14 +17,21 @@ import (
 var (
 	filepder      = &FileProvider{}
 	gcmaxlifetime int64
+
+	// stops the gc walk when the batch is done
+	errGCBatchDone = errors.New("session: gc batch done")
 )
 
+// suffix of temp files written by SessionRelease before renaming
+const tmpSuffix = ".tmp"
+
 // File session store
 type FileSessionStore struct {
-	f      *os.File
-	sid    string
-	lock   sync.RWMutex
-	values map[interface{}]interface{}
+	filename string // path of session file
+	sid      string
+	lock     sync.RWMutex
+	values   map[interface{}]interface{}
+	accessed time.Time // time of SessionRead
 }
 
 // Set value to file session
@@ -58,25 +66,89 @@ func (fs *FileSessionStore) Delete(key interface{}) error {
 func (fs *FileSessionStore) Flush() error {
 	fs.lock.Lock()
 	defer fs.lock.Unlock()
-	fs.values = make(map[interface{}]interface{})
+	fs.values = FlushValues(fs.values)
 	return nil
 }
 
+// get keys of file session
+func (fs *FileSessionStore) Keys() []interface{} {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	keys := make([]interface{}, 0, len(fs.values))
+	for k := range fs.values {
+		if IsReservedKey(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// get count of values in file session
+func (fs *FileSessionStore) Len() int {
+	return len(fs.Keys())
+}
+
+// get creation time of file session, zero if unknown
+func (fs *FileSessionStore) CreatedAt() time.Time {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	return CreatedTime(fs.values)
+}
+
+// get the time file session was read
+func (fs *FileSessionStore) LastAccessedAt() time.Time {
+	return fs.accessed
+}
+
 // Get file session store id
 func (fs *FileSessionStore) SessionID() string {
 	return fs.sid
 }
 
-// Write file session to local file with Gob string
-func (fs *FileSessionStore) SessionRelease(w http.ResponseWriter) {
-	defer fs.f.Close()
-	b, err := EncodeGob(fs.values)
+// Write file session to local file with Gob string.
+// data is written to a temp file in the same directory and renamed over the session file,
+// so a crash never leaves a partly written session.
+func (fs *FileSessionStore) SessionRelease(w http.ResponseWriter) error {
+	fs.lock.RLock()
+	b, err := serializer.Encode(fs.values)
+	fs.lock.RUnlock()
 	if err != nil {
-		return
+		return err
+	}
+	return writeFileAtomic(fs.filename, b)
+}
+
+// write data to a temp file and rename it to filename.
+func writeFileAtomic(filename string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+tmpSuffix)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
 	}
-	fs.f.Truncate(0)
-	fs.f.Seek(0, 0)
-	fs.f.Write(b)
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// decode session file data, broken data means an empty session.
+func decodeFileSession(b []byte) map[interface{}]interface{} {
+	if len(b) > 0 {
+		if kv, err := serializer.Decode(b); err == nil {
+			return kv
+		}
+	}
+	return make(map[interface{}]interface{})
 }
 
 // File session provider
@@ -84,6 +156,7 @@ type FileProvider struct {
 	lock        sync.RWMutex
 	maxlifetime int64
 	savePath    string
+	gcCursor    string // last file visited by SessionGCBatch
 }
 
 // Init file session provider.
@@ -95,7 +168,7 @@ func (fp *FileProvider) SessionInit(maxlifetime int64, savePath string) error {
 }
 
 // Read file session by sid.
-// if file is not exist, create it.
+// if file is not exist, create it. if its data can't be decoded, the session is empty.
 // the file path is generated from sid string.
 func (fp *FileProvider) SessionRead(sid string) (SessionStore, error) {
 	filepder.lock.Lock()
@@ -114,23 +187,17 @@ func (fp *FileProvider) SessionRead(sid string) (SessionStore, error) {
 	} else {
 		return nil, err
 	}
-	os.Chtimes(path.Join(fp.savePath, string(sid[0]), string(sid[1]), sid), time.Now(), time.Now())
-	var kv map[interface{}]interface{}
-	b, err := ioutil.ReadAll(f)
 	if err != nil {
 		return nil, err
 	}
-	if len(b) == 0 {
-		kv = make(map[interface{}]interface{})
-	} else {
-		kv, err = DecodeGob(b)
-		if err != nil {
-			return nil, err
-		}
-	}
+	os.Chtimes(path.Join(fp.savePath, string(sid[0]), string(sid[1]), sid), Now(), Now())
+	b, err := ioutil.ReadAll(f)
 	f.Close()
-	f, err = os.OpenFile(path.Join(fp.savePath, string(sid[0]), string(sid[1]), sid), os.O_WRONLY|os.O_CREATE, 0777)
-	ss := &FileSessionStore{f: f, sid: sid, values: kv}
+	if err != nil {
+		return nil, err
+	}
+	kv := decodeFileSession(b)
+	ss := &FileSessionStore{filename: path.Join(fp.savePath, string(sid[0]), string(sid[1]), sid), sid: sid, values: InitCreated(kv), accessed: Now()}
 	return ss, nil
 }
 
@@ -165,6 +232,60 @@ func (fp *FileProvider) SessionGC() {
 	filepath.Walk(fp.savePath, gcpath)
 }
 
+// Touch the file session to extend its lifetime.
+// file sessions expire by mtime, manager calls it on every access if renew is on.
+func (fp *FileProvider) SessionUpdate(sid string) error {
+	filepder.lock.Lock()
+	defer filepder.lock.Unlock()
+	return os.Chtimes(path.Join(fp.savePath, string(sid[0]), string(sid[1]), sid), Now(), Now())
+}
+
+// Recycle at most size files in save path, 0 is unlimited.
+// the next pass continues after the last visited file.
+func (fp *FileProvider) SessionGCBatch(size int) {
+	if size <= 0 {
+		fp.SessionGC()
+		return
+	}
+	filepder.lock.Lock()
+	defer filepder.lock.Unlock()
+
+	gcmaxlifetime = fp.maxlifetime
+	cursor, last, visited := fp.gcCursor, "", 0
+	err := filepath.Walk(fp.savePath, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// skip directories finished in previous passes
+			if p != fp.savePath && p < cursor && !strings.HasPrefix(cursor, p+string(filepath.Separator)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if p <= cursor {
+			return nil
+		}
+		if visited >= size {
+			return errGCBatchDone
+		}
+		visited++
+		last = p
+		return gcpath(p, info, nil)
+	})
+	if err == errGCBatchDone {
+		fp.gcCursor = last
+	} else {
+		// walked to the end, start over in next pass
+		fp.gcCursor = ""
+	}
+}
+
+// file session supports all capabilities.
+func (fp *FileProvider) Capabilities() ProviderCapabilities {
+	return CapAll
+}
+
 // Get active file session number.
 // it walks save path to count files.
 func (fp *FileProvider) SessionAll() int {
@@ -213,23 +334,14 @@ func (fp *FileProvider) SessionRegenerate(oldsid, sid string) (SessionStore, err
 	}
 	f.Close()
 	os.Remove(path.Join(fp.savePath, string(oldsid[0]), string(oldsid[1])))
-	os.Chtimes(path.Join(fp.savePath, string(sid[0]), string(sid[1]), sid), time.Now(), time.Now())
-	var kv map[interface{}]interface{}
-	b, err := ioutil.ReadAll(newf)
+	newf.Close()
+	os.Chtimes(path.Join(fp.savePath, string(sid[0]), string(sid[1]), sid), Now(), Now())
+	b, err := ioutil.ReadFile(path.Join(fp.savePath, string(sid[0]), string(sid[1]), sid))
 	if err != nil {
 		return nil, err
 	}
-	if len(b) == 0 {
-		kv = make(map[interface{}]interface{})
-	} else {
-		kv, err = DecodeGob(b)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	newf, err = os.OpenFile(path.Join(fp.savePath, string(sid[0]), string(sid[1]), sid), os.O_WRONLY|os.O_CREATE, 0777)
-	ss := &FileSessionStore{f: newf, sid: sid, values: kv}
+	kv := decodeFileSession(b)
+	ss := &FileSessionStore{filename: path.Join(fp.savePath, string(sid[0]), string(sid[1]), sid), sid: sid, values: InitCreated(kv), accessed: Now()}
 	return ss, nil
 }
 
@@ -241,7 +353,7 @@ func gcpath(path string, info os.FileInfo, err error) error {
 	if info.IsDir() {
 		return nil
 	}
-	if (info.ModTime().Unix() + gcmaxlifetime) < time.Now().Unix() {
+	if (info.ModTime().Unix() + gcmaxlifetime) < Now().Unix() {
 		os.Remove(path)
 	}
 	return nil
@@ -255,7 +367,8 @@ func (self *activeSession) visit(paths string, f os.FileInfo, err error) error {
 	if err != nil {
 		return err
 	}
-	if f.IsDir() {
+	// skip temp files of SessionRelease, gc removes those left by crashes
+	if f.IsDir() || strings.Contains(f.Name(), tmpSuffix) {
 		return nil
 	}
 	self.total = self.total + 1
@@ -0,0 +1,211 @@
+package session
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileTimestamps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "beego-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := &FileProvider{}
+	fp.SessionInit(3600, dir)
+
+	before := time.Now()
+	sess, err := fp.SessionRead("filesid")
+	if err != nil {
+		t.Fatal("SessionRead error", err)
+	}
+	created := sess.CreatedAt()
+	if created.Before(before) || sess.LastAccessedAt().Before(created) {
+		t.Fatal("timestamps of new session error", created, sess.LastAccessedAt())
+	}
+	sess.Set("username", "astaxie")
+	if sess.Len() != 1 {
+		t.Fatal("creation time should not be counted as value")
+	}
+	sess.SessionRelease(nil)
+
+	time.Sleep(5 * time.Millisecond)
+	sess, _ = fp.SessionRead("filesid")
+	if !sess.CreatedAt().Equal(created) {
+		t.Fatal("created time should be saved with values", sess.CreatedAt(), created)
+	}
+	if !sess.LastAccessedAt().After(created) {
+		t.Fatal("SessionRead should update last accessed time")
+	}
+	sess.Flush()
+	if !sess.CreatedAt().Equal(created) {
+		t.Fatal("Flush should keep created time")
+	}
+	sess.SessionRelease(nil)
+}
+
+// noTouchFileProvider is a file provider whose SessionRead keeps the mtime of the session file,
+// so only SessionUpdate extends the lifetime. it counts SessionUpdate calls.
+type noTouchFileProvider struct {
+	*FileProvider
+	updates int
+}
+
+func (p *noTouchFileProvider) SessionRead(sid string) (SessionStore, error) {
+	name := filepath.Join(p.savePath, sid[0:1], sid[1:2], sid)
+	fi, err := os.Stat(name)
+	st, rerr := p.FileProvider.SessionRead(sid)
+	if err == nil {
+		os.Chtimes(name, fi.ModTime(), fi.ModTime())
+	}
+	return st, rerr
+}
+
+func (p *noTouchFileProvider) SessionUpdate(sid string) error {
+	p.updates++
+	return p.FileProvider.SessionUpdate(sid)
+}
+
+func TestFileRenewLifetime(t *testing.T) {
+	for _, renew := range []bool{true, false} {
+		dir, err := ioutil.TempDir("", "beego-session")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		globalSessions, err := NewManager("file", `{"cookieName":"gosessionid","gclifetime":60,"renew":`+strconv.FormatBool(renew)+`,"providerConfig":"`+dir+`"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pder := &noTouchFileProvider{FileProvider: globalSessions.provider.(*FileProvider)}
+		globalSessions.provider = pder
+		var cookies []string
+		for i := 0; i < 2; i++ {
+			r, _ := http.NewRequest("GET", "/", nil)
+			w := httptest.NewRecorder()
+			sess := globalSessions.SessionStart(w, r)
+			sess.Set("username", "astaxie")
+			sess.SessionRelease(w)
+			cookies = append(cookies, w.Header().Get("Set-Cookie"))
+		}
+		sid := func(cookie string) string {
+			v, _ := url.QueryUnescape(strings.SplitN(strings.Split(cookie, ";")[0], "=", 2)[1])
+			return v
+		}
+		active, idle := sid(cookies[0]), sid(cookies[1])
+		filename := func(sid string) string {
+			return filepath.Join(dir, sid[0:1], sid[1:2], sid)
+		}
+		// both sessions were last used before maxlifetime
+		old := time.Now().Add(-2 * time.Minute)
+		os.Chtimes(filename(active), old, old)
+		os.Chtimes(filename(idle), old, old)
+
+		// the store is not released, so only renew can touch the file
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("Cookie", cookies[0])
+		sess := globalSessions.SessionStart(httptest.NewRecorder(), r)
+		if sess.Get("username") != "astaxie" {
+			t.Fatal("session should be read by cookie")
+		}
+
+		globalSessions.provider.SessionGC()
+		if renew && (pder.updates != 1 || !pder.SessionExist(active)) {
+			t.Fatal("renew should update accessed session, updates", pder.updates)
+		}
+		if !renew && (pder.updates != 0 || pder.SessionExist(active)) {
+			t.Fatal("accessed session should expire without renew, updates", pder.updates)
+		}
+		if pder.SessionExist(idle) {
+			t.Fatal("idle session should be expired")
+		}
+	}
+}
+
+func TestFileSessionUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "beego-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := &FileProvider{}
+	fp.SessionInit(60, dir)
+	sess, err := fp.SessionRead("filesid")
+	if err != nil {
+		t.Fatal("SessionRead error", err)
+	}
+	sess.SessionRelease(nil)
+	name := filepath.Join(dir, "f", "i", "filesid")
+	old := time.Now().Add(-2 * time.Minute)
+	os.Chtimes(name, old, old)
+	if err = fp.SessionUpdate("filesid"); err != nil {
+		t.Fatal("SessionUpdate error", err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(info.ModTime()) > time.Minute {
+		t.Fatal("SessionUpdate should touch the session file", info.ModTime())
+	}
+}
+
+func TestFileAtomicRelease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "beego-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fp := &FileProvider{}
+	fp.SessionInit(3600, dir)
+	name := filepath.Join(dir, "f", "i", "filesid")
+
+	sess, err := fp.SessionRead("filesid")
+	if err != nil {
+		t.Fatal("SessionRead error", err)
+	}
+	sess.Set("username", "astaxie")
+	if err = sess.SessionRelease(nil); err != nil {
+		t.Fatal("SessionRelease error", err)
+	}
+
+	// a crash while writing in place leaves a truncated file
+	b, _ := ioutil.ReadFile(name)
+	ioutil.WriteFile(name, b[:len(b)/2], 0777)
+	sess, err = fp.SessionRead("filesid")
+	if err != nil {
+		t.Fatal("broken session file should be read as empty session", err)
+	}
+	if sess.Len() != 0 {
+		t.Fatal("broken session file should be read as empty session")
+	}
+
+	// a crash before rename only leaves a temp file, the session file is intact
+	sess.Set("username", "astaxie")
+	if err = sess.SessionRelease(nil); err != nil {
+		t.Fatal("SessionRelease error", err)
+	}
+	ioutil.WriteFile(name+tmpSuffix+"123", []byte("garbage"), 0777)
+	sess, err = fp.SessionRead("filesid")
+	if err != nil {
+		t.Fatal("SessionRead error", err)
+	}
+	if sess.Get("username") != "astaxie" {
+		t.Fatal("session file should not be corrupted")
+	}
+	if n := fp.SessionAll(); n != 1 {
+		t.Fatal("temp files should not be counted as sessions", n)
+	}
+	files, _ := ioutil.ReadDir(filepath.Dir(name))
+	if len(files) != 2 {
+		t.Fatal("SessionRelease should not leave temp files", len(files))
+	}
+}
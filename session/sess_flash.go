@@ -0,0 +1,86 @@
+package session
+
+// reserved session key saving flash messages
+const flashKey = "__beego_flash__"
+
+// Flash keeps one-shot messages in session,
+// they survive a redirect and are removed once they are read.
+type Flash struct {
+	store SessionStore
+}
+
+// NewFlash returns Flash saving messages in store.
+func NewFlash(store SessionStore) *Flash {
+	return &Flash{store: store}
+}
+
+// Set appends message of kind to flash.
+func (f *Flash) Set(kind, msg string) error {
+	messages := flashMessages(f.store.Get(flashKey))
+	if messages == nil {
+		messages = make(map[string][]string)
+	}
+	messages[kind] = append(messages[kind], msg)
+	return f.store.Set(flashKey, messages)
+}
+
+// Success writes success message to flash.
+func (f *Flash) Success(msg string) error {
+	return f.Set("success", msg)
+}
+
+// Notice writes notice message to flash.
+func (f *Flash) Notice(msg string) error {
+	return f.Set("notice", msg)
+}
+
+// Warning writes warning message to flash.
+func (f *Flash) Warning(msg string) error {
+	return f.Set("warning", msg)
+}
+
+// Error writes error message to flash.
+func (f *Flash) Error(msg string) error {
+	return f.Set("error", msg)
+}
+
+// Read returns all flash messages and clears them.
+func (f *Flash) Read() map[string][]string {
+	return ReadFlash(f.store)
+}
+
+// ReadFlash returns all flash messages in store grouped by kind and clears them,
+// so the messages are read only once.
+func ReadFlash(store SessionStore) map[string][]string {
+	messages := flashMessages(store.Get(flashKey))
+	store.Delete(flashKey)
+	if messages == nil {
+		messages = make(map[string][]string)
+	}
+	return messages
+}
+
+// convert saved flash value to messages.
+// values decoded by JSONSerializer lose their types.
+func flashMessages(v interface{}) map[string][]string {
+	switch v := v.(type) {
+	case map[string][]string:
+		messages := make(map[string][]string, len(v))
+		for kind, msgs := range v {
+			messages[kind] = append([]string(nil), msgs...)
+		}
+		return messages
+	case map[string]interface{}:
+		messages := make(map[string][]string, len(v))
+		for kind, msgs := range v {
+			list, _ := msgs.([]interface{})
+			for _, msg := range list {
+				if s, ok := msg.(string); ok {
+					messages[kind] = append(messages[kind], s)
+				}
+			}
+		}
+		return messages
+	}
+	return nil
+}
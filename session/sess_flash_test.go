@@ -0,0 +1,65 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFlash(t *testing.T) {
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	flash := NewFlash(sess)
+	flash.Success("saved")
+	flash.Success("published")
+	flash.Error("failed")
+	if sess.Len() != 0 || len(sess.Keys()) != 0 {
+		t.Fatal("flash key should be hidden from Keys and Len", sess.Keys())
+	}
+	sess.SessionRelease(w)
+	cookie := w.Header().Get("Set-Cookie")
+
+	// next request reads the flash
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", cookie)
+	w = httptest.NewRecorder()
+	sess = globalSessions.SessionStart(w, r)
+	messages := ReadFlash(sess)
+	if len(messages["success"]) != 2 || messages["success"][0] != "saved" || messages["success"][1] != "published" {
+		t.Fatal("read success flash error", messages)
+	}
+	if len(messages["error"]) != 1 || messages["error"][0] != "failed" {
+		t.Fatal("read error flash error", messages)
+	}
+	sess.SessionRelease(w)
+
+	// flash is read only once
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", cookie)
+	sess = globalSessions.SessionStart(httptest.NewRecorder(), r)
+	if messages := NewFlash(sess).Read(); len(messages) != 0 {
+		t.Fatal("flash should be cleared after read", messages)
+	}
+}
+
+func TestFlashGob(t *testing.T) {
+	sess := &CookieSessionStore{sid: "sid", values: make(map[interface{}]interface{})}
+	NewFlash(sess).Notice("hello")
+	if sess.Len() != 0 {
+		t.Fatal("flash key should be hidden from Len", sess.Keys())
+	}
+	b, err := EncodeGob(sess.values)
+	if err != nil {
+		t.Fatal("encode flash error", err)
+	}
+	values, err := DecodeGob(b)
+	if err != nil {
+		t.Fatal("decode flash error", err)
+	}
+	sess.values = values
+	if messages := ReadFlash(sess); len(messages["notice"]) != 1 || messages["notice"][0] != "hello" {
+		t.Fatal("read flash after gob error", messages)
+	}
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"container/list"
+	"encoding/json"
 	"net/http"
 	"sync"
 	"time"
@@ -15,6 +16,9 @@ type MemSessionStore struct {
 	sid          string                      //session id
 	timeAccessed time.Time                   //last access time
 	value        map[interface{}]interface{} //session store
+	expires      map[interface{}]time.Time   //expiration of keys set by SetWithTTL
+	dirty        bool                        //values changed since last release
+	timeCreated  time.Time                   //creation time
 	lock         sync.RWMutex
 }
 
@@ -22,7 +26,22 @@ type MemSessionStore struct {
 func (st *MemSessionStore) Set(key, value interface{}) error {
 	st.lock.Lock()
 	defer st.lock.Unlock()
+	st.dirty = true
 	st.value[key] = value
+	delete(st.expires, key)
+	return nil
+}
+
+// set value to memory session which expires after ttl
+func (st *MemSessionStore) SetWithTTL(key, value interface{}, ttl time.Duration) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.dirty = true
+	st.value[key] = value
+	if st.expires == nil {
+		st.expires = make(map[interface{}]time.Time)
+	}
+	st.expires[key] = Now().Add(ttl)
 	return nil
 }
 
@@ -30,6 +49,9 @@ func (st *MemSessionStore) Set(key, value interface{}) error {
 func (st *MemSessionStore) Get(key interface{}) interface{} {
 	st.lock.RLock()
 	defer st.lock.RUnlock()
+	if t, ok := st.expires[key]; ok && !Now().Before(t) {
+		return nil
+	}
 	if v, ok := st.value[key]; ok {
 		return v
 	} else {
@@ -42,7 +64,9 @@ func (st *MemSessionStore) Get(key interface{}) interface{} {
 func (st *MemSessionStore) Delete(key interface{}) error {
 	st.lock.Lock()
 	defer st.lock.Unlock()
+	st.dirty = true
 	delete(st.value, key)
+	delete(st.expires, key)
 	return nil
 }
 
@@ -50,31 +74,94 @@ func (st *MemSessionStore) Delete(key interface{}) error {
 func (st *MemSessionStore) Flush() error {
 	st.lock.Lock()
 	defer st.lock.Unlock()
+	st.dirty = true
 	st.value = make(map[interface{}]interface{})
+	st.expires = nil
 	return nil
 }
 
+// check whether memory session values changed since last release
+func (st *MemSessionStore) IsDirty() bool {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.dirty
+}
+
+// get keys of memory session, keys expired by ttl are skipped
+func (st *MemSessionStore) Keys() []interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	now := Now()
+	keys := make([]interface{}, 0, len(st.value))
+	for k := range st.value {
+		if t, ok := st.expires[k]; (ok && !now.Before(t)) || IsReservedKey(k) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// get count of values in memory session
+func (st *MemSessionStore) Len() int {
+	return len(st.Keys())
+}
+
+// get creation time of memory session
+func (st *MemSessionStore) CreatedAt() time.Time {
+	return st.timeCreated
+}
+
+// get last access time of memory session
+func (st *MemSessionStore) LastAccessedAt() time.Time {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.timeAccessed
+}
+
 // get this id of memory session store
 func (st *MemSessionStore) SessionID() string {
 	return st.sid
 }
 
-// Implement method, no used.
-func (st *MemSessionStore) SessionRelease(w http.ResponseWriter) {
+// Implement method, values are already in memory.
+// it only resets the dirty flag.
+func (st *MemSessionStore) SessionRelease(w http.ResponseWriter) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.dirty = false
+	return nil
+}
+
+type memConfig struct {
+	MaxEntries int `json:"maxEntries"`
 }
 
 type MemProvider struct {
 	lock        sync.RWMutex             // locker
 	sessions    map[string]*list.Element // map in memory
-	list        *list.List               // for gc
+	list        *list.List               // for gc and lru, most recently used in front
 	maxlifetime int64
+	maxEntries  int                 // max count of sessions, 0 is unlimited
+	users       map[string][]string // sids of users, oldest first
 	savePath    string
 }
 
-// init memory session
+// init memory session.
+// savePath is optional json config, e.g. {"maxEntries":10000}.
+// when maxEntries is exceeded the least recently used session is evicted.
 func (pder *MemProvider) SessionInit(maxlifetime int64, savePath string) error {
+	cf := &memConfig{}
+	if savePath != "" {
+		if err := json.Unmarshal([]byte(savePath), cf); err != nil {
+			return err
+		}
+	}
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
 	pder.maxlifetime = maxlifetime
 	pder.savePath = savePath
+	pder.maxEntries = cf.MaxEntries
 	return nil
 }
 
@@ -82,15 +169,17 @@ func (pder *MemProvider) SessionInit(maxlifetime int64, savePath string) error {
 func (pder *MemProvider) SessionRead(sid string) (SessionStore, error) {
 	pder.lock.RLock()
 	if element, ok := pder.sessions[sid]; ok {
-		go pder.SessionUpdate(sid)
 		pder.lock.RUnlock()
+		pder.SessionUpdate(sid)
 		return element.Value.(*MemSessionStore), nil
 	} else {
 		pder.lock.RUnlock()
 		pder.lock.Lock()
-		newsess := &MemSessionStore{sid: sid, timeAccessed: time.Now(), value: make(map[interface{}]interface{})}
-		element := pder.list.PushBack(newsess)
+		now := Now()
+		newsess := &MemSessionStore{sid: sid, timeCreated: now, timeAccessed: now, value: make(map[interface{}]interface{})}
+		element := pder.list.PushFront(newsess)
 		pder.sessions[sid] = element
+		pder.evict()
 		pder.lock.Unlock()
 		return newsess, nil
 	}
@@ -123,9 +212,11 @@ func (pder *MemProvider) SessionRegenerate(oldsid, sid string) (SessionStore, er
 	} else {
 		pder.lock.RUnlock()
 		pder.lock.Lock()
-		newsess := &MemSessionStore{sid: sid, timeAccessed: time.Now(), value: make(map[interface{}]interface{})}
-		element := pder.list.PushBack(newsess)
+		now := Now()
+		newsess := &MemSessionStore{sid: sid, timeCreated: now, timeAccessed: now, value: make(map[interface{}]interface{})}
+		element := pder.list.PushFront(newsess)
 		pder.sessions[sid] = element
+		pder.evict()
 		pder.lock.Unlock()
 		return newsess, nil
 	}
@@ -146,37 +237,106 @@ func (pder *MemProvider) SessionDestroy(sid string) error {
 
 // clean expired session stores in memory session
 func (pder *MemProvider) SessionGC() {
-	pder.lock.RLock()
-	for {
+	pder.SessionGCBatch(0)
+}
+
+// clean at most size expired session stores in memory session, 0 is unlimited.
+func (pder *MemProvider) SessionGCBatch(size int) {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	for n := 0; size <= 0 || n < size; n++ {
 		element := pder.list.Back()
 		if element == nil {
 			break
 		}
-		if (element.Value.(*MemSessionStore).timeAccessed.Unix() + pder.maxlifetime) < time.Now().Unix() {
-			pder.lock.RUnlock()
-			pder.lock.Lock()
-			pder.list.Remove(element)
-			delete(pder.sessions, element.Value.(*MemSessionStore).sid)
-			pder.lock.Unlock()
-			pder.lock.RLock()
-		} else {
+		if (element.Value.(*MemSessionStore).timeAccessed.Unix() + pder.maxlifetime) >= Now().Unix() {
 			break
 		}
+		pder.list.Remove(element)
+		delete(pder.sessions, element.Value.(*MemSessionStore).sid)
 	}
-	pder.lock.RUnlock()
+}
+
+// memory session supports all capabilities.
+func (pder *MemProvider) Capabilities() ProviderCapabilities {
+	return CapAll
 }
 
 // get count number of memory session
 func (pder *MemProvider) SessionAll() int {
+	pder.lock.RLock()
+	defer pder.lock.RUnlock()
 	return pder.list.Len()
 }
 
+// remove least recently used sessions until count is not more than maxEntries.
+// the caller must hold the write lock.
+func (pder *MemProvider) evict() {
+	for pder.maxEntries > 0 && pder.list.Len() > pder.maxEntries {
+		element := pder.list.Back()
+		pder.list.Remove(element)
+		delete(pder.sessions, element.Value.(*MemSessionStore).sid)
+	}
+}
+
+// add sid to sids of userID, it returns all sids of userID.
+func (pder *MemProvider) IndexSession(userID, sid string) ([]string, error) {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	if pder.users == nil {
+		pder.users = make(map[string][]string)
+	}
+	sids := pder.users[userID]
+	for _, s := range sids {
+		if s == sid {
+			return append([]string{}, sids...), nil
+		}
+	}
+	pder.users[userID] = append(sids, sid)
+	return append([]string{}, pder.users[userID]...), nil
+}
+
+// get sids of userID, oldest first.
+func (pder *MemProvider) UserSessions(userID string) ([]string, error) {
+	pder.lock.RLock()
+	defer pder.lock.RUnlock()
+	return append([]string{}, pder.users[userID]...), nil
+}
+
+// remove sids from sids of userID.
+func (pder *MemProvider) UnindexSessions(userID string, sids ...string) error {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	var kept []string
+	for _, s := range pder.users[userID] {
+		removed := false
+		for _, r := range sids {
+			if s == r {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			kept = append(kept, s)
+		}
+	}
+	if len(kept) == 0 {
+		delete(pder.users, userID)
+	} else {
+		pder.users[userID] = kept
+	}
+	return nil
+}
+
 // expand time of session store by id in memory session
 func (pder *MemProvider) SessionUpdate(sid string) error {
 	pder.lock.Lock()
 	defer pder.lock.Unlock()
 	if element, ok := pder.sessions[sid]; ok {
-		element.Value.(*MemSessionStore).timeAccessed = time.Now()
+		st := element.Value.(*MemSessionStore)
+		st.lock.Lock()
+		st.timeAccessed = Now()
+		st.lock.Unlock()
 		pder.list.MoveToFront(element)
 		return nil
 	}
@@ -1,15 +1,20 @@
 package session
 
 import (
+	"container/list"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMem(t *testing.T) {
 	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
-	go globalSessions.GC()
+	globalSessions.GC()
+	defer globalSessions.Stop()
 	r, _ := http.NewRequest("GET", "/", nil)
 	w := httptest.NewRecorder()
 	sess := globalSessions.SessionStart(w, r)
@@ -33,3 +38,349 @@ func TestMem(t *testing.T) {
 		}
 	}
 }
+
+func TestMemRenew(t *testing.T) {
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10,"cookieLifeTime":3600,"renew":true}`)
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sess.SessionRelease(w)
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	w = httptest.NewRecorder()
+	sess = globalSessions.SessionStart(w, r)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge != 3600 {
+		t.Fatal("renew should reset cookie max age", w.Header().Get("Set-Cookie"))
+	}
+	if cookies[0].Value != sess.SessionID() {
+		t.Fatal("renew should keep session id")
+	}
+}
+
+func TestMemCookieDomainPath(t *testing.T) {
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	defer globalSessions.Stop()
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	globalSessions.SessionStart(w, r)
+	cookiestr := w.Header().Get("Set-Cookie")
+	if !strings.Contains(cookiestr, "Path=/") {
+		t.Fatal("default cookie path should be /", cookiestr)
+	}
+	if strings.Contains(cookiestr, "Domain") {
+		t.Fatal("empty domain should not be written", cookiestr)
+	}
+
+	globalSessions, _ = NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10,"cookieDomain":"example.com","cookiePath":"/app"}`)
+	defer globalSessions.Stop()
+	r, _ = http.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	globalSessions.SessionStart(w, r)
+	cookiestr = w.Header().Get("Set-Cookie")
+	if !strings.Contains(cookiestr, "Path=/app") || !strings.Contains(cookiestr, "Domain=example.com") {
+		t.Fatal("cookie domain and path error", cookiestr)
+	}
+}
+
+func TestMemMaxEntries(t *testing.T) {
+	globalSessions, err := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10,"providerConfig":"{\"maxEntries\":2}"}`)
+	if err != nil {
+		t.Fatal("init memory session err", err)
+	}
+	defer mempder.SessionInit(10, "")
+	for _, sid := range []string{"sid1", "sid2"} {
+		globalSessions.GetSessionStore(sid)
+	}
+	// sid1 is used recently, so sid2 is the oldest one
+	globalSessions.GetSessionStore("sid1")
+	globalSessions.GetSessionStore("sid3")
+	if mempder.SessionExist("sid2") {
+		t.Fatal("least recently used session should be evicted")
+	}
+	if !mempder.SessionExist("sid1") || !mempder.SessionExist("sid3") {
+		t.Fatal("recently used sessions should be kept")
+	}
+	if n := globalSessions.GetActiveSession(); n > 2 {
+		t.Fatal("active sessions should not exceed maxEntries, got", n)
+	}
+}
+
+func TestMemSetWithTTL(t *testing.T) {
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	clock := &fakeClock{now: time.Now()}
+	SetClock(clock)
+	defer SetClock(nil)
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	SetWithTTL(sess, "flash", "saved", 10*time.Millisecond)
+	sess.Set("username", "astaxie")
+	clock.Advance(9 * time.Millisecond)
+	if sess.Get("flash") != "saved" {
+		t.Fatal("get ttl value error")
+	}
+	clock.Advance(time.Millisecond)
+	if sess.Get("flash") != nil {
+		t.Fatal("expired key should be absent")
+	}
+	if sess.Get("username") != "astaxie" {
+		t.Fatal("key without ttl should not expire")
+	}
+	// Set again removes the ttl
+	SetWithTTL(sess, "flash", "saved", 10*time.Millisecond)
+	sess.Set("flash", "kept")
+	clock.Advance(time.Hour)
+	if sess.Get("flash") != "kept" {
+		t.Fatal("Set should remove ttl")
+	}
+}
+
+func TestMemGCBatch(t *testing.T) {
+	pder := &MemProvider{list: list.New(), sessions: make(map[string]*list.Element)}
+	pder.SessionInit(10, "")
+	for _, sid := range []string{"gc1", "gc2", "gc3"} {
+		sess, _ := pder.SessionRead(sid)
+		sess.(*MemSessionStore).timeAccessed = time.Now().Add(-time.Hour)
+	}
+	pder.SessionGCBatch(2)
+	if n := pder.SessionAll(); n != 1 {
+		t.Fatal("gc batch should remove 2 sessions, left", n)
+	}
+	pder.SessionGCBatch(2)
+	if n := pder.SessionAll(); n != 0 {
+		t.Fatal("second gc batch should remove the rest, left", n)
+	}
+}
+
+func TestManagerStop(t *testing.T) {
+	pder := &MemProvider{list: list.New(), sessions: make(map[string]*list.Element)}
+	pder.SessionInit(3600, "")
+	globalSessions := &Manager{provider: pder, config: &managerConfig{Gclifetime: 3600, GCInterval: 3600}}
+	globalSessions.GC()
+	globalSessions.Stop()
+	sess, _ := pder.SessionRead("stopped")
+	sess.(*MemSessionStore).timeAccessed = time.Now().Add(-2 * time.Hour)
+	globalSessions.GC()
+	if !pder.SessionExist("stopped") {
+		t.Fatal("gc should not run after Stop")
+	}
+}
+
+func TestMemIDGenerator(t *testing.T) {
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	if sid := sess.SessionID(); len(sid) != 32 || strings.ContainsAny(sid, "+/=") {
+		t.Fatal("default sid should be url-safe base64 of 24 bytes, got", sid)
+	}
+	sha1Sessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10,"sessionIDHashFunc":"sha1","sessionIDHashKey":"key"}`)
+	r, _ = http.NewRequest("GET", "/", nil)
+	if sid := sha1Sessions.SessionStart(httptest.NewRecorder(), r).SessionID(); len(sid) != 40 {
+		t.Fatal("sha1 sid should be hex of hmac-sha1, got", sid)
+	}
+
+	n := 0
+	globalSessions.SetIDGenerator(func() string {
+		n++
+		return fmt.Sprintf("shard1-%d", n)
+	})
+	r, _ = http.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	sess = globalSessions.SessionStart(w, r)
+	if sess.SessionID() != "shard1-1" {
+		t.Fatal("sid should come from generator, got", sess.SessionID())
+	}
+	sess = globalSessions.SessionRegenerateId(w, r)
+	if sess.SessionID() != "shard1-2" {
+		t.Fatal("regenerated sid should come from generator, got", sess.SessionID())
+	}
+}
+
+func TestMemKeys(t *testing.T) {
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	if sess.Len() != 0 || len(sess.Keys()) != 0 {
+		t.Fatal("new session should be empty")
+	}
+	sess.Set("username", "astaxie")
+	SetWithTTL(sess, "flash", "saved", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	keys := sess.Keys()
+	if len(keys) != 1 || keys[0] != "username" || sess.Len() != 1 {
+		t.Fatal("keys should only contain username, got", keys)
+	}
+}
+
+func TestMemTimestamps(t *testing.T) {
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	var zero SessionStore = &MemSessionStore{}
+	if !zero.CreatedAt().IsZero() || !zero.LastAccessedAt().IsZero() {
+		t.Fatal("unknown timestamps should be zero")
+	}
+	before := time.Now()
+	sess, _ := globalSessions.GetSessionStore("timesid")
+	created := sess.CreatedAt()
+	if created.Before(before) || sess.LastAccessedAt().Before(created) {
+		t.Fatal("timestamps of new session error", created, sess.LastAccessedAt())
+	}
+	time.Sleep(5 * time.Millisecond)
+	sess, _ = globalSessions.GetSessionStore("timesid")
+	if !sess.CreatedAt().Equal(created) {
+		t.Fatal("created time should not change")
+	}
+	if !sess.LastAccessedAt().After(created) {
+		t.Fatal("SessionRead should update last accessed time")
+	}
+}
+
+func TestMemEnforceSessionLimit(t *testing.T) {
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	var evicted []string
+	for _, sid := range []string{"usersid1", "usersid2", "usersid3"} {
+		globalSessions.GetSessionStore(sid)
+		e, err := globalSessions.EnforceSessionLimit("astaxie", sid, 2)
+		if err != nil {
+			t.Fatal("EnforceSessionLimit error", err)
+		}
+		evicted = append(evicted, e...)
+	}
+	if len(evicted) != 1 || evicted[0] != "usersid1" {
+		t.Fatal("oldest session should be evicted, got", evicted)
+	}
+	for _, sid := range evicted {
+		globalSessions.SessionDestroyId(sid)
+	}
+	// destroyed sids are dropped from index
+	globalSessions.SessionDestroyId("usersid2")
+	evicted, _ = globalSessions.EnforceSessionLimit("astaxie", "usersid3", 1)
+	if len(evicted) != 0 {
+		t.Fatal("destroyed session should not be evicted again, got", evicted)
+	}
+}
+
+func TestCookieLifeTime(t *testing.T) {
+	tests := []struct {
+		config string
+		maxAge int
+	}{
+		// session cookie, server data is kept for maxLifetime
+		{`{"cookieName":"gosessionid","gclifetime":10,"maxLifetime":86400}`, 0},
+		{`{"cookieName":"gosessionid","gclifetime":10,"maxLifetime":86400,"cookieLifeTime":0}`, 0},
+		{`{"cookieName":"gosessionid","gclifetime":10,"maxLifetime":86400,"cookieLifeTime":3600}`, 3600},
+		{`{"cookieName":"gosessionid","gclifetime":10,"maxLifetime":60,"cookieLifeTime":3600}`, 3600},
+	}
+	for _, test := range tests {
+		globalSessions, err := NewManager("memory", test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		globalSessions.SessionStart(w, r)
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatal("sid cookie should be set", test.config)
+		}
+		if cookies[0].MaxAge != test.maxAge {
+			t.Errorf("%s: cookie MaxAge should be %d, got %d", test.config, test.maxAge, cookies[0].MaxAge)
+		}
+		if test.maxAge == 0 && strings.Contains(w.Header().Get("Set-Cookie"), "Max-Age") {
+			t.Errorf("%s: session cookie should not have Max-Age", test.config)
+		}
+	}
+
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10,"maxLifetime":86400}`)
+	if globalSessions.config.Maxlifetime != 86400 {
+		t.Fatal("maxLifetime of data should not depend on cookieLifeTime")
+	}
+}
+
+func TestMemDestroyByUser(t *testing.T) {
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	for sid, user := range map[string]string{"devicesid1": "logoutuser", "devicesid2": "logoutuser", "devicesid3": "otheruser"} {
+		sess, _ := globalSessions.GetSessionStore(sid)
+		if err := globalSessions.SetUser(sess, user); err != nil {
+			t.Fatal("SetUser error", err)
+		}
+	}
+	sess, _ := globalSessions.GetSessionStore("devicesid1")
+	if sess.Get(UserKey) != "logoutuser" {
+		t.Fatal("user id should be saved in session")
+	}
+	var destroyed []string
+	globalSessions.OnSessionDestroyed(func(sid string) { destroyed = append(destroyed, sid) })
+
+	n, err := globalSessions.DestroyByUser("logoutuser")
+	if err != nil {
+		t.Fatal("DestroyByUser error", err)
+	}
+	if n != 2 || len(destroyed) != 2 {
+		t.Fatal("2 sessions should be destroyed, got", n, destroyed)
+	}
+	if mempder.SessionExist("devicesid1") || mempder.SessionExist("devicesid2") {
+		t.Fatal("sessions of user should be destroyed")
+	}
+	if !mempder.SessionExist("devicesid3") {
+		t.Fatal("session of other user should be kept")
+	}
+	if n, _ = globalSessions.DestroyByUser("logoutuser"); n != 0 {
+		t.Fatal("sessions should be unindexed, got", n)
+	}
+	if _, err = globalSessions.DestroyByUser("nobody"); err != nil {
+		t.Fatal("unknown user should not fail", err)
+	}
+}
+
+func TestMemSignSID(t *testing.T) {
+	if _, err := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10,"signSID":true}`); err == nil {
+		t.Fatal("signSID without sessionIDHashKey should return error")
+	}
+	globalSessions, _ := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10,"signSID":true,"sessionIDHashKey":"beegosignkey"}`)
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := globalSessions.SessionStart(w, r)
+	sid := sess.SessionID()
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !strings.HasPrefix(cookies[0].Value, sid+".") {
+		t.Fatal("sid cookie should be signed", w.Header().Get("Set-Cookie"))
+	}
+
+	// valid signed sid
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	if globalSessions.SessionStart(httptest.NewRecorder(), r).SessionID() != sid {
+		t.Fatal("signed sid should be accepted")
+	}
+
+	for name, value := range map[string]string{
+		"unsigned": sid,
+		"tampered": "a" + cookies[0].Value,
+		"forged":   sid[1:] + cookies[0].Value[len(sid):],
+	} {
+		r, _ = http.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: "gosessionid", Value: value})
+		w = httptest.NewRecorder()
+		if s := globalSessions.SessionStart(w, r); s.SessionID() == sid || w.Header().Get("Set-Cookie") == "" {
+			t.Fatal(name + " sid should start a new session")
+		}
+		w = httptest.NewRecorder()
+		globalSessions.SessionDestroy(w, r)
+		if !mempder.SessionExist(sid) {
+			t.Fatal(name + " sid should not destroy the session")
+		}
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	sess = globalSessions.SessionRegenerateId(w, r)
+	cookies = w.Result().Cookies()
+	if mempder.SessionExist(sid) || cookies[0].Value != url.QueryEscape(sess.SessionID()+"."+globalSessions.sidSignature(sess.SessionID())) {
+		t.Fatal("regenerated sid should be signed", cookies[0].Value)
+	}
+}
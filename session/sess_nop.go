@@ -0,0 +1,175 @@
+package session
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+var noppder = &NopProvider{sessions: make(map[string]*NopSessionStore)}
+
+// nop session store for tests.
+// values are kept in a map of the store, they never expire and are never saved anywhere.
+type NopSessionStore struct {
+	sid         string
+	value       map[interface{}]interface{}
+	timeCreated time.Time
+	lock        sync.RWMutex
+}
+
+// NewTestStore returns a nop session store with copied initial values,
+// so handlers can be tested with a session without provider, manager and cookies.
+func NewTestStore(initial map[interface{}]interface{}) SessionStore {
+	return newNopStore("test", initial)
+}
+
+func newNopStore(sid string, initial map[interface{}]interface{}) *NopSessionStore {
+	st := &NopSessionStore{sid: sid, value: make(map[interface{}]interface{}, len(initial)), timeCreated: Now()}
+	for k, v := range initial {
+		st.value[k] = v
+	}
+	return st
+}
+
+// set value to nop session
+func (st *NopSessionStore) Set(key, value interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.value[key] = value
+	return nil
+}
+
+// get value from nop session by key
+func (st *NopSessionStore) Get(key interface{}) interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.value[key]
+}
+
+// delete in nop session by key
+func (st *NopSessionStore) Delete(key interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	delete(st.value, key)
+	return nil
+}
+
+// clear all values in nop session
+func (st *NopSessionStore) Flush() error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.value = make(map[interface{}]interface{})
+	return nil
+}
+
+// get keys of nop session
+func (st *NopSessionStore) Keys() []interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	keys := make([]interface{}, 0, len(st.value))
+	for k := range st.value {
+		if !IsReservedKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// get count of values in nop session
+func (st *NopSessionStore) Len() int {
+	return len(st.Keys())
+}
+
+// get creation time of nop session
+func (st *NopSessionStore) CreatedAt() time.Time {
+	return st.timeCreated
+}
+
+// get last access time of nop session, it's not tracked.
+func (st *NopSessionStore) LastAccessedAt() time.Time {
+	return time.Time{}
+}
+
+// get this id of nop session store
+func (st *NopSessionStore) SessionID() string {
+	return st.sid
+}
+
+// Implement method, no used.
+func (st *NopSessionStore) SessionRelease(w http.ResponseWriter) error {
+	return nil
+}
+
+// nop session provider for tests.
+// it keeps stores by sid in memory for the whole process, sessions never expire.
+type NopProvider struct {
+	lock     sync.RWMutex
+	sessions map[string]*NopSessionStore
+}
+
+// init nop session, config is ignored.
+func (pder *NopProvider) SessionInit(maxlifetime int64, config string) error {
+	return nil
+}
+
+// get nop session store by sid, a new store is created if it's missing.
+func (pder *NopProvider) SessionRead(sid string) (SessionStore, error) {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	st, ok := pder.sessions[sid]
+	if !ok {
+		st = newNopStore(sid, nil)
+		pder.sessions[sid] = st
+	}
+	return st, nil
+}
+
+// check nop session exist by sid
+func (pder *NopProvider) SessionExist(sid string) bool {
+	pder.lock.RLock()
+	defer pder.lock.RUnlock()
+	_, ok := pder.sessions[sid]
+	return ok
+}
+
+// generate new sid for nop session, values of old sid are moved to it.
+func (pder *NopProvider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	st, ok := pder.sessions[oldsid]
+	if !ok {
+		st = newNopStore(sid, nil)
+	}
+	delete(pder.sessions, oldsid)
+	st.sid = sid
+	pder.sessions[sid] = st
+	return st, nil
+}
+
+// delete nop session by sid
+func (pder *NopProvider) SessionDestroy(sid string) error {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	delete(pder.sessions, sid)
+	return nil
+}
+
+// nop sessions never expire, there is nothing to collect.
+func (pder *NopProvider) Capabilities() ProviderCapabilities {
+	return CapRegenerate | CapDestroy | CapCount
+}
+
+// Implement method, nop sessions never expire.
+func (pder *NopProvider) SessionGC() {
+}
+
+// get count of nop sessions
+func (pder *NopProvider) SessionAll() int {
+	pder.lock.RLock()
+	defer pder.lock.RUnlock()
+	return len(pder.sessions)
+}
+
+func init() {
+	Register("nop", noppder)
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// addToCart is a handler helper using the session, tested without http.
+func addToCart(sess SessionStore, item string) int {
+	cart, _ := sess.Get("cart").([]string)
+	cart = append(cart, item)
+	sess.Set("cart", cart)
+	return len(cart)
+}
+
+func ExampleNewTestStore() {
+	sess := NewTestStore(map[interface{}]interface{}{"cart": []string{"book"}})
+	fmt.Println(addToCart(sess, "pen"))
+	fmt.Println(sess.Get("cart"))
+	// Output:
+	// 2
+	// [book pen]
+}
+
+func TestNop(t *testing.T) {
+	initial := map[interface{}]interface{}{"username": "astaxie"}
+	sess := NewTestStore(initial)
+	sess.Set("uid", 1)
+	if sess.Get("username") != "astaxie" || sess.Len() != 2 {
+		t.Fatal("test store should have initial and set values")
+	}
+	if len(initial) != 1 {
+		t.Fatal("initial values should be copied")
+	}
+
+	globalSessions, err := NewManager("nop", `{"cookieName":"gosessionid","gclifetime":10}`)
+	if err != nil {
+		t.Fatal("init nop session err", err)
+	}
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess = globalSessions.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	globalSessions.SessionRelease(w, sess)
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	w = httptest.NewRecorder()
+	if globalSessions.SessionStart(w, r).Get("username") != "astaxie" {
+		t.Fatal("nop session should be kept by sid")
+	}
+	sess = globalSessions.SessionRegenerateId(w, r)
+	if sess.Get("username") != "astaxie" || noppder.SessionAll() != 1 {
+		t.Fatal("regenerate should move values to new sid")
+	}
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	globalSessions.SessionDestroy(httptest.NewRecorder(), r)
+	if noppder.SessionExist(sess.SessionID()) {
+		t.Fatal("nop session should be destroyed")
+	}
+}
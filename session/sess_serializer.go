@@ -0,0 +1,71 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Serializer encodes session values to bytes and decodes them back.
+// providers which save sessions outside the process use it,
+// e.g. file, redis, mysql. memory session keeps values as they are.
+type Serializer interface {
+	Encode(values map[interface{}]interface{}) ([]byte, error)
+	Decode(data []byte) (map[interface{}]interface{}, error)
+}
+
+// GobSerializer encodes session values as gob. It's the default serializer.
+// custom types stored in session must be registered by gob.Register.
+type GobSerializer struct{}
+
+// Encode session values as gob.
+func (s GobSerializer) Encode(values map[interface{}]interface{}) ([]byte, error) {
+	return EncodeGob(values)
+}
+
+// Decode gob bytes to session values.
+func (s GobSerializer) Decode(data []byte) (map[interface{}]interface{}, error) {
+	return DecodeGob(data)
+}
+
+// JSONSerializer encodes session values as json object,
+// so services not written in Go can read the same session data.
+// It loses type fidelity: map keys are always decoded as string
+// (non-string keys are formatted by fmt.Sprint), numbers as float64,
+// and structs as map[string]interface{}.
+type JSONSerializer struct{}
+
+// Encode session values as json object.
+func (s JSONSerializer) Encode(values map[interface{}]interface{}) ([]byte, error) {
+	m := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		m[fmt.Sprint(k)] = v
+	}
+	return json.Marshal(m)
+}
+
+// Decode json object to session values.
+func (s JSONSerializer) Decode(data []byte) (map[interface{}]interface{}, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	values := make(map[interface{}]interface{}, len(m))
+	for k, v := range m {
+		values[k] = v
+	}
+	return values, nil
+}
+
+var serializer Serializer = GobSerializer{}
+
+// GetSerializer returns the serializer used by session providers.
+func GetSerializer() Serializer {
+	return serializer
+}
+
+// SetSerializer sets the serializer used by session providers to save values.
+// it should be called before any session is read, all managers and
+// providers share the same serializer.
+func SetSerializer(s Serializer) {
+	serializer = s
+}
@@ -1,9 +1,16 @@
 package session
 
 import (
+	"context"
 	"crypto/aes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func Test_gob(t *testing.T) {
@@ -64,6 +71,43 @@ func TestCookieEncodeDecode(t *testing.T) {
 	}
 }
 
+// fakeClock is a Clock advanced by tests instead of sleeping.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestClockCookieExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	block, _ := aes.NewCipher(generateRandomKey(16))
+	str, err := encodeCookie(block, "testhashKey", "name", map[interface{}]interface{}{"name": "astaxie"})
+	if err != nil {
+		t.Fatal("encodeCookie:", err)
+	}
+	clock.Advance(3599 * time.Second)
+	if _, err = decodeCookie(block, "testhashKey", "name", str, 3600); err != nil {
+		t.Fatal("cookie within maxlifetime should decode", err)
+	}
+	clock.Advance(2 * time.Second)
+	if _, err = decodeCookie(block, "testhashKey", "name", str, 3600); err == nil {
+		t.Fatal("cookie after maxlifetime should be expired")
+	}
+	clock.Advance(-2 * time.Hour)
+	if _, err = decodeCookie(block, "testhashKey", "name", str, 3600); err == nil {
+		t.Fatal("cookie from the future should be rejected")
+	}
+}
+
 func TestParseConfig(t *testing.T) {
 	s := `{"cookieName":"gosessionid","gclifetime":3600}`
 	cf := new(managerConfig)
@@ -107,3 +151,418 @@ func TestParseConfig(t *testing.T) {
 		t.Fatal("ProviderConfig get securityKey error")
 	}
 }
+
+func TestCookieEncodeDecodeMode(t *testing.T) {
+	hashKeys := []string{"testhashKey"}
+	securityName := string(generateRandomKey(20))
+	for _, mode := range []string{"ctr", "gcm"} {
+		block, err := aes.NewCipher(generateRandomKey(16))
+		if err != nil {
+			t.Fatal("NewCipher:", err)
+		}
+		c, err := newCookieCipher(block, mode)
+		if err != nil {
+			t.Fatal("newCookieCipher:", err)
+		}
+		val := make(map[interface{}]interface{})
+		val["name"] = "astaxie"
+		str, err := encodeCookieCipher(c, hashKeys, securityName, val, false)
+		if err != nil {
+			t.Fatal("encodeCookie:", mode, err)
+		}
+		dst, err := decodeCookieCipher(c, hashKeys, securityName, str, 3600)
+		if err != nil {
+			t.Fatal("decodeCookie", mode, err)
+		}
+		if dst["name"] != "astaxie" {
+			t.Fatal("dst get map error", mode)
+		}
+	}
+
+	// same hash key but another block key must fail gcm authentication.
+	block, _ := aes.NewCipher(generateRandomKey(16))
+	c, _ := newCookieCipher(block, "gcm")
+	str, err := encodeCookieCipher(c, hashKeys, securityName, map[interface{}]interface{}{"name": "astaxie"}, false)
+	if err != nil {
+		t.Fatal("encodeCookie:", err)
+	}
+	other, _ := aes.NewCipher(generateRandomKey(16))
+	c, _ = newCookieCipher(other, "gcm")
+	if _, err = decodeCookieCipher(c, hashKeys, securityName, str, 3600); err == nil {
+		t.Fatal("gcm decode with wrong key should fail")
+	}
+
+	if _, err = newCookieCipher(block, "cbc"); err == nil {
+		t.Fatal("unknown mode should return error")
+	}
+}
+
+func TestJSONSerializer(t *testing.T) {
+	a := make(map[interface{}]interface{})
+	a["username"] = "astaxie"
+	a[12] = 234
+	var s Serializer = JSONSerializer{}
+	b, err := s.Encode(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["username"] != "astaxie" {
+		t.Fatal("decode string error")
+	}
+	// json keys are string and numbers are float64
+	if c["12"] != float64(234) {
+		t.Fatal("decode int error", c["12"])
+	}
+}
+
+func TestCreatedTimeJSON(t *testing.T) {
+	values := InitCreated(make(map[interface{}]interface{}))
+	created := CreatedTime(values)
+	b, err := JSONSerializer{}.Encode(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err = JSONSerializer{}.Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// float64 keeps unix nano to about a microsecond
+	if d := CreatedTime(values).Sub(created); d > time.Microsecond || d < -time.Microsecond {
+		t.Fatal("created time should be read after json round-trip", CreatedTime(values), created)
+	}
+	values[CreatedKey] = json.Number(strconv.FormatInt(created.UnixNano(), 10))
+	if !CreatedTime(values).Equal(created) {
+		t.Fatal("created time should be read from json.Number", CreatedTime(values), created)
+	}
+}
+
+func TestCookieKeyRotation(t *testing.T) {
+	block, _ := aes.NewCipher(generateRandomKey(16))
+	c := ctrCipher{block}
+	securityName := string(generateRandomKey(20))
+	val := map[interface{}]interface{}{"name": "astaxie"}
+
+	// signed with key a
+	str, err := encodeCookieCipher(c, []string{"keya"}, securityName, val, false)
+	if err != nil {
+		t.Fatal("encodeCookie:", err)
+	}
+	// key b is added for signing, key a still verifies
+	dst, err := decodeCookieCipher(c, []string{"keyb", "keya"}, securityName, str, 3600)
+	if err != nil {
+		t.Fatal("decode after rotation error", err)
+	}
+	if dst["name"] != "astaxie" {
+		t.Fatal("dst get map error")
+	}
+	// key a is removed
+	if _, err = decodeCookieCipher(c, []string{"keyb"}, securityName, str, 3600); err == nil {
+		t.Fatal("decode with removed key should fail")
+	}
+	// same key id but wrong mac
+	if _, err = decodeCookieCipher(c, []string{"keya"}, "othername", str, 3600); err == nil {
+		t.Fatal("decode with wrong name should fail")
+	}
+}
+
+func TestCookieDecodeWithoutKeyID(t *testing.T) {
+	block, _ := aes.NewCipher(generateRandomKey(16))
+	securityName := string(generateRandomKey(20))
+	b, _ := EncodeGob(map[interface{}]interface{}{"name": "astaxie"})
+	b, _ = encrypt(block, b)
+	// value encoded before key id was introduced: "date|value|mac"
+	b = []byte(fmt.Sprintf("%s|%d|%s|", securityName, time.Now().UTC().Unix(), encode(b)))
+	b = append(b, macValue("keya", b)...)[len(securityName)+1:]
+	str := string(encode(b))
+
+	dst, err := decodeCookieCipher(ctrCipher{block}, []string{"keyb", "keya"}, securityName, str, 3600)
+	if err != nil {
+		t.Fatal("decode legacy value error", err)
+	}
+	if dst["name"] != "astaxie" {
+		t.Fatal("dst get map error")
+	}
+}
+
+// pingProvider is a memory provider with a stubbed Ping
+type pingProvider struct {
+	*MemProvider
+	err error
+}
+
+func (pp *pingProvider) Ping() error {
+	return pp.err
+}
+
+func TestHealthCheck(t *testing.T) {
+	pp := &pingProvider{MemProvider: mempder, err: errors.New("connection refused")}
+	manager := &Manager{provider: pp, config: &managerConfig{}}
+	if err := manager.HealthCheck(); err != pp.err {
+		t.Fatal("HealthCheck should return ping error, got", err)
+	}
+	pp.err = nil
+	if err := manager.HealthCheck(); err != nil {
+		t.Fatal("HealthCheck should pass, got", err)
+	}
+	manager = &Manager{provider: mempder, config: &managerConfig{}}
+	if err := manager.HealthCheck(); err != nil {
+		t.Fatal("provider without Ping should be healthy, got", err)
+	}
+}
+
+func TestCookieEncodeDecodeCompress(t *testing.T) {
+	block, _ := aes.NewCipher(generateRandomKey(16))
+	c := ctrCipher{block}
+	hashKeys := []string{"hashkey"}
+	securityName := string(generateRandomKey(20))
+	val := make(map[interface{}]interface{})
+	for i := 0; i < 50; i++ {
+		val[fmt.Sprintf("item%d", i)] = "the same permission string for every item"
+	}
+	plain, err := encodeCookieCipher(c, hashKeys, securityName, val, false)
+	if err != nil {
+		t.Fatal("encode error", err)
+	}
+	compressed, err := encodeCookieCipher(c, hashKeys, securityName, val, true)
+	if err != nil {
+		t.Fatal("compress encode error", err)
+	}
+	if len(compressed) >= len(plain) {
+		t.Fatal("compressed value should be shorter", len(compressed), len(plain))
+	}
+	t.Logf("encoded length %d, compressed %d", len(plain), len(compressed))
+	// both old and new values decode
+	for _, str := range []string{plain, compressed} {
+		dst, err := decodeCookieCipher(c, hashKeys, securityName, str, 3600)
+		if err != nil {
+			t.Fatal("decode error", err)
+		}
+		if dst["item49"] != val["item49"] || len(dst) != len(val) {
+			t.Fatal("dst get map error")
+		}
+	}
+}
+
+// slowProvider is a memory provider whose read hangs until unblock is closed
+type slowProvider struct {
+	*MemProvider
+	unblock chan struct{}
+}
+
+func (sp *slowProvider) SessionReadContext(ctx context.Context, sid string) (SessionStore, error) {
+	return ReadContext(ctx, func() (SessionStore, error) {
+		<-sp.unblock
+		return sp.SessionRead(sid)
+	})
+}
+
+func TestSessionReadContext(t *testing.T) {
+	sp := &slowProvider{MemProvider: mempder, unblock: make(chan struct{})}
+	defer close(sp.unblock)
+	manager := &Manager{provider: sp, config: &managerConfig{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := manager.GetSessionStoreContext(ctx, "slow"); err != context.Canceled {
+		t.Fatal("read should be canceled, got", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatal("canceled read should return promptly, took", d)
+	}
+
+	// providers without SessionReadContext check ctx before reading
+	if _, err := SessionReadContext(ctx, mempder, "slow"); err != context.Canceled {
+		t.Fatal("read with canceled ctx should fail, got", err)
+	}
+	st, err := SessionReadContext(context.Background(), mempder, "fast")
+	if err != nil || st.SessionID() != "fast" {
+		t.Fatal("read without ctx deadline error", err)
+	}
+}
+
+func TestSessionHooks(t *testing.T) {
+	manager, err := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+	var created, destroyed, regenerated []string
+	manager.OnSessionCreated(func(sid string) { created = append(created, sid) })
+	manager.OnSessionDestroyed(func(sid string) { destroyed = append(destroyed, sid) })
+	manager.OnSessionRegenerated(func(oldsid, sid string) { regenerated = append(regenerated, oldsid+"->"+sid) })
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := manager.SessionStart(w, r)
+	sid := sess.SessionID()
+	sess.SessionRelease(w)
+	if len(created) != 1 || created[0] != sid {
+		t.Fatal("created hook should fire once for new session", created)
+	}
+
+	// existing session doesn't fire created
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	manager.SessionStart(httptest.NewRecorder(), r)
+	if len(created) != 1 {
+		t.Fatal("created hook should not fire for existing session", created)
+	}
+
+	w = httptest.NewRecorder()
+	sess = manager.SessionRegenerateId(w, r)
+	newsid := sess.SessionID()
+	if len(regenerated) != 1 || regenerated[0] != sid+"->"+newsid {
+		t.Fatal("regenerated hook should fire once with old and new sid", regenerated)
+	}
+	if len(created) != 1 || len(destroyed) != 0 {
+		t.Fatal("regenerate should only fire regenerated hook", created, destroyed)
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	manager.SessionDestroy(httptest.NewRecorder(), r)
+	if len(destroyed) != 1 || destroyed[0] != newsid {
+		t.Fatal("destroyed hook should fire once for destroyed session", destroyed)
+	}
+	manager.SessionDestroyId("othersid")
+	if len(destroyed) != 2 || destroyed[1] != "othersid" {
+		t.Fatal("destroyed hook should fire for SessionDestroyId", destroyed)
+	}
+}
+
+// failingProvider is a memory provider failing to read, regenerate and destroy sessions.
+type failingProvider struct {
+	*MemProvider
+}
+
+var errProvider = errors.New("provider unavailable")
+
+func (fp *failingProvider) SessionRead(sid string) (SessionStore, error) {
+	return nil, errProvider
+}
+
+func (fp *failingProvider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
+	return nil, errProvider
+}
+
+func (fp *failingProvider) SessionDestroy(sid string) error {
+	return errProvider
+}
+
+func TestSessionHooksProviderError(t *testing.T) {
+	manager, err := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Stop()
+	manager.provider = &failingProvider{MemProvider: mempder}
+	fired := 0
+	manager.OnSessionCreated(func(sid string) { fired++ })
+	manager.OnSessionDestroyed(func(sid string) { fired++ })
+	manager.OnSessionRegenerated(func(oldsid, sid string) { fired++ })
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	manager.SessionStart(httptest.NewRecorder(), r)
+	manager.SessionRegenerateId(httptest.NewRecorder(), r)
+	r.AddCookie(&http.Cookie{Name: "gosessionid", Value: manager.cookieValue("oldsid")})
+	manager.SessionRegenerateId(httptest.NewRecorder(), r)
+	manager.SessionDestroy(httptest.NewRecorder(), r)
+	if err = manager.SessionDestroyId("othersid"); err != errProvider {
+		t.Fatal("SessionDestroyId should return provider error, got", err)
+	}
+	if fired != 0 {
+		t.Fatal("hooks should not fire when provider fails, fired", fired)
+	}
+}
+
+// countSink counts increments by counter name.
+type countSink struct {
+	counts map[string]int
+}
+
+func (s *countSink) Inc(counter string) {
+	s.counts[counter]++
+}
+
+func TestManagerStats(t *testing.T) {
+	manager, err := NewManager("memory", `{"cookieName":"gosessionid","gclifetime":10}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &countSink{counts: make(map[string]int)}
+	manager.SetStatsSink(sink)
+	before := manager.Stats()
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	sess := manager.SessionStart(w, r)
+	sess.Set("username", "astaxie")
+	if err = manager.SessionRelease(w, sess); err != nil {
+		t.Fatal(err)
+	}
+	stats := manager.Stats()
+	if stats.Creates-before.Creates != 1 || stats.Writes-before.Writes != 1 {
+		t.Fatal("create and write should be counted", stats)
+	}
+	if stats.Active < 1 {
+		t.Fatal("active sessions should be counted by provider", stats)
+	}
+
+	r, _ = http.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+	sess = manager.SessionStart(httptest.NewRecorder(), r)
+	// clean session is not written
+	manager.SessionRelease(httptest.NewRecorder(), sess)
+	manager.SessionDestroy(httptest.NewRecorder(), r)
+	manager.GC()
+	manager.Stop()
+
+	stats = manager.Stats()
+	if stats.Reads-before.Reads != 1 {
+		t.Fatal("read should be counted", stats)
+	}
+	if stats.Writes-before.Writes != 1 {
+		t.Fatal("skipped write should not be counted", stats)
+	}
+	if stats.Destroys-before.Destroys != 1 || stats.GC-before.GC != 1 {
+		t.Fatal("destroy and gc should be counted", stats)
+	}
+	if stats.Errors != before.Errors {
+		t.Fatal("no error should be counted", stats)
+	}
+	for name, n := range map[string]int{StatCreates: 1, StatWrites: 1, StatReads: 1, StatDestroys: 1, StatGC: 1} {
+		if sink.counts[name] != n {
+			t.Errorf("sink should receive %d %s, got %d", n, name, sink.counts[name])
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	caps := Capabilities(&CookieProvider{})
+	if !caps.Has(CapRegenerate) || caps.Has(CapDestroy) || caps.Has(CapGC) || caps.Has(CapCount) {
+		t.Fatal("cookie provider should only support regenerate, got", caps)
+	}
+	if caps := Capabilities(&MemProvider{}); caps != CapAll {
+		t.Fatal("memory provider should support all, got", caps)
+	}
+	chain := &ChainProvider{primary: &MemProvider{}, secondary: &CookieProvider{}}
+	if caps := Capabilities(chain); caps != CapRegenerate {
+		t.Fatal("chain provider should support what both providers support, got", caps)
+	}
+	// providers without Capabilities are assumed to support all.
+	if caps := Capabilities(struct{ Provider }{}); caps != CapAll {
+		t.Fatal("unknown provider should support all, got", caps)
+	}
+
+	globalSessions, err := NewManager("cookie", `{"cookieName":"gosessionid","gclifetime":3600,"ProviderConfig":"{\"cookieName\":\"gosessionid\",\"securityKey\":\"beegocookiehashkey\"}"}`)
+	if err != nil {
+		t.Fatal("init cookie session err", err)
+	}
+	if globalSessions.Capabilities().Has(CapCount) {
+		t.Fatal("manager should report capabilities of its provider")
+	}
+}
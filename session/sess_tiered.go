@@ -0,0 +1,362 @@
+package session
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var tieredpder = &TieredProvider{}
+
+// default count of session stores cached in memory.
+const defaultTieredSize = 10000
+
+type tieredConfig struct {
+	Backend       string `json:"backend"`
+	BackendConfig string `json:"backendConfig"`
+	Size          int    `json:"size"`
+	TTL           int64  `json:"ttl"`
+}
+
+// session store cached in memory.
+// st is shared by requests, it's only changed by SessionRelease of their copies with lock held.
+type tieredEntry struct {
+	sid     string
+	st      SessionStore
+	expires time.Time
+	lock    sync.RWMutex
+}
+
+// Tiered session provider.
+// it caches session stores read from backend in a memory lru for a short ttl,
+// so hot sessions aren't read and decoded from e.g. redis on every request.
+// every read gets its own copy of the cached values, SessionRelease of the copy
+// writes them to backend through the cached store.
+// changes made to backend by other processes aren't seen until the cached store expires,
+// so it fits read-mostly sessions those tolerate stale reads within ttl.
+type TieredProvider struct {
+	lock     sync.Mutex
+	backend  Provider
+	size     int
+	ttl      time.Duration
+	list     *list.List // most recently used in front
+	sessions map[string]*list.Element
+}
+
+// Init tiered session provider with maxlifetime and config json, e.g.
+// {"backend":"redis","backendConfig":"127.0.0.1:6379","size":10000,"ttl":5}
+// backend must be registered and is initialized with backendConfig.
+// size is the max count of cached stores, default is 10000.
+// ttl is the seconds a store is cached, 0 disables the cache.
+func (pder *TieredProvider) SessionInit(maxlifetime int64, config string) error {
+	cf := &tieredConfig{}
+	if err := json.Unmarshal([]byte(config), cf); err != nil {
+		return err
+	}
+	if cf.Backend == "tiered" {
+		return errors.New("session: tiered provider can not cache itself")
+	}
+	backend, ok := provides[cf.Backend]
+	if !ok {
+		return fmt.Errorf("session: unknown backend provide %q (forgotten import?)", cf.Backend)
+	}
+	if err := backend.SessionInit(maxlifetime, cf.BackendConfig); err != nil {
+		return err
+	}
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	pder.backend = backend
+	pder.size = cf.Size
+	if pder.size <= 0 {
+		pder.size = defaultTieredSize
+	}
+	pder.ttl = time.Duration(cf.TTL) * time.Second
+	pder.list = list.New()
+	pder.sessions = make(map[string]*list.Element)
+	return nil
+}
+
+// Read session from memory, or from backend if it's not cached or expired.
+func (pder *TieredProvider) SessionRead(sid string) (SessionStore, error) {
+	if entry := pder.get(sid); entry != nil {
+		return pder.copyOf(entry), nil
+	}
+	st, err := pder.backend.SessionRead(sid)
+	if err != nil {
+		return nil, err
+	}
+	return pder.put(sid, st), nil
+}
+
+// Check session exist in memory or backend.
+func (pder *TieredProvider) SessionExist(sid string) bool {
+	return pder.get(sid) != nil || pder.backend.SessionExist(sid)
+}
+
+// Generate new sid in backend, the cached store of old sid is dropped.
+func (pder *TieredProvider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
+	pder.remove(oldsid)
+	pder.remove(sid)
+	st, err := pder.backend.SessionRegenerate(oldsid, sid)
+	if err != nil {
+		return nil, err
+	}
+	return pder.put(sid, st), nil
+}
+
+// Destroy session in memory and backend.
+func (pder *TieredProvider) SessionDestroy(sid string) error {
+	pder.remove(sid)
+	return pder.backend.SessionDestroy(sid)
+}
+
+// Extend session lifetime in backend.
+func (pder *TieredProvider) SessionUpdate(sid string) error {
+	if su, ok := pder.backend.(sessionUpdater); ok {
+		return su.SessionUpdate(sid)
+	}
+	return nil
+}
+
+// Clean expired sessions in backend and expired stores in memory.
+func (pder *TieredProvider) SessionGC() {
+	pder.backend.SessionGC()
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	now := Now()
+	for sid, element := range pder.sessions {
+		if !now.Before(element.Value.(*tieredEntry).expires) {
+			pder.list.Remove(element)
+			delete(pder.sessions, sid)
+		}
+	}
+}
+
+// Get active session count of backend.
+func (pder *TieredProvider) SessionAll() int {
+	return pder.backend.SessionAll()
+}
+
+// capabilities of backend.
+func (pder *TieredProvider) Capabilities() ProviderCapabilities {
+	return Capabilities(pder.backend)
+}
+
+// get cached store of sid, nil if it's missing or expired.
+func (pder *TieredProvider) get(sid string) *tieredEntry {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	element, ok := pder.sessions[sid]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*tieredEntry)
+	if !Now().Before(entry.expires) {
+		pder.list.Remove(element)
+		delete(pder.sessions, sid)
+		return nil
+	}
+	pder.list.MoveToFront(element)
+	return entry
+}
+
+// cache store of sid and return a copy of it, the least recently used store is evicted if cache is full.
+// st is returned as is if cache is disabled.
+func (pder *TieredProvider) put(sid string, st SessionStore) SessionStore {
+	if pder.ttl <= 0 {
+		return st
+	}
+	entry := &tieredEntry{sid: sid, st: st, expires: Now().Add(pder.ttl)}
+	pder.lock.Lock()
+	if element, ok := pder.sessions[sid]; ok {
+		element.Value = entry
+		pder.list.MoveToFront(element)
+	} else {
+		pder.sessions[sid] = pder.list.PushFront(entry)
+		for pder.list.Len() > pder.size {
+			element := pder.list.Back()
+			pder.list.Remove(element)
+			delete(pder.sessions, element.Value.(*tieredEntry).sid)
+		}
+	}
+	pder.lock.Unlock()
+	return pder.copyOf(entry)
+}
+
+// copy values of cached store for one request.
+func (pder *TieredProvider) copyOf(entry *tieredEntry) *tieredStore {
+	entry.lock.RLock()
+	defer entry.lock.RUnlock()
+	ts := &tieredStore{pder: pder, entry: entry, values: make(map[interface{}]interface{}),
+		changed: make(map[interface{}]bool), expires: make(map[interface{}]time.Time)}
+	copyValues(ts.values, entry.st)
+	if v, ok := entry.st.(Versioner); ok {
+		ts.version = v.Version()
+	}
+	return ts
+}
+
+// drop cached store of sid.
+func (pder *TieredProvider) remove(sid string) {
+	pder.lock.Lock()
+	defer pder.lock.Unlock()
+	if element, ok := pder.sessions[sid]; ok {
+		pder.list.Remove(element)
+		delete(pder.sessions, sid)
+	}
+}
+
+// session store of one request with its own copy of cached values.
+// only the keys changed by this copy are written back, so values and ttls
+// of other keys in the cached store are kept.
+type tieredStore struct {
+	pder    *TieredProvider
+	entry   *tieredEntry
+	values  map[interface{}]interface{}
+	changed map[interface{}]bool      // keys set or deleted since copied
+	expires map[interface{}]time.Time // expiration of keys set by SetWithTTL
+	flushed bool
+	version string // version of cached store when it was copied
+	dirty   bool
+	lock    sync.RWMutex
+}
+
+func (st *tieredStore) Set(key, value interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.dirty = true
+	st.values[key] = value
+	st.changed[key] = true
+	delete(st.expires, key)
+	return nil
+}
+
+// set value which expires after ttl, it's set with the ttl to the cached store by SessionRelease.
+func (st *tieredStore) SetWithTTL(key, value interface{}, ttl time.Duration) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.dirty = true
+	st.values[key] = value
+	st.changed[key] = true
+	st.expires[key] = Now().Add(ttl)
+	return nil
+}
+
+func (st *tieredStore) Get(key interface{}) interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.values[key]
+}
+
+func (st *tieredStore) Delete(key interface{}) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.dirty = true
+	delete(st.values, key)
+	st.changed[key] = true
+	delete(st.expires, key)
+	return nil
+}
+
+func (st *tieredStore) Flush() error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	st.dirty = true
+	st.values = make(map[interface{}]interface{})
+	st.changed = make(map[interface{}]bool)
+	st.expires = make(map[interface{}]time.Time)
+	st.flushed = true
+	return nil
+}
+
+func (st *tieredStore) Keys() []interface{} {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	keys := make([]interface{}, 0, len(st.values))
+	for k := range st.values {
+		if !IsReservedKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (st *tieredStore) Len() int {
+	return len(st.Keys())
+}
+
+func (st *tieredStore) SessionID() string {
+	return st.entry.sid
+}
+
+func (st *tieredStore) CreatedAt() time.Time {
+	st.entry.lock.RLock()
+	defer st.entry.lock.RUnlock()
+	return st.entry.st.CreatedAt()
+}
+
+func (st *tieredStore) LastAccessedAt() time.Time {
+	st.entry.lock.RLock()
+	defer st.entry.lock.RUnlock()
+	return st.entry.st.LastAccessedAt()
+}
+
+func (st *tieredStore) IsDirty() bool {
+	st.lock.RLock()
+	defer st.lock.RUnlock()
+	return st.dirty
+}
+
+func (st *tieredStore) Version() string {
+	return st.version
+}
+
+// write changed values to cached store and release it.
+// it returns ErrConflict if another copy of a versioned store was released since this one was copied.
+// the cached store is dropped on errors, so the next read gets the session from backend.
+func (st *tieredStore) SessionRelease(w http.ResponseWriter) error {
+	st.lock.Lock()
+	defer st.lock.Unlock()
+	entry := st.entry
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if v, ok := entry.st.(Versioner); ok && v.Version() != st.version {
+		st.pder.remove(entry.sid)
+		return ErrConflict
+	}
+	if st.dirty {
+		if st.flushed {
+			entry.st.Flush()
+		}
+		for k := range st.changed {
+			v, ok := st.values[k]
+			if !ok {
+				entry.st.Delete(k)
+			} else if t, ok := st.expires[k]; ok {
+				SetWithTTL(entry.st, k, v, t.Sub(Now()))
+			} else {
+				entry.st.Set(k, v)
+			}
+		}
+	}
+	if err := entry.st.SessionRelease(w); err != nil {
+		st.pder.remove(entry.sid)
+		return err
+	}
+	st.dirty = false
+	st.flushed = false
+	st.changed = make(map[interface{}]bool)
+	st.expires = make(map[interface{}]time.Time)
+	if v, ok := entry.st.(Versioner); ok {
+		st.version = v.Version()
+	}
+	return nil
+}
+
+func init() {
+	Register("tiered", tieredpder)
+}
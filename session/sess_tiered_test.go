@@ -0,0 +1,240 @@
+package session
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// countingProvider is a memory provider counting backend reads.
+type countingProvider struct {
+	*MemProvider
+	reads int
+}
+
+func (p *countingProvider) SessionRead(sid string) (SessionStore, error) {
+	p.reads++
+	return p.MemProvider.SessionRead(sid)
+}
+
+func (p *countingProvider) SessionRegenerate(oldsid, sid string) (SessionStore, error) {
+	p.reads++
+	return p.MemProvider.SessionRegenerate(oldsid, sid)
+}
+
+var countingpder = &countingProvider{MemProvider: &MemProvider{list: list.New(), sessions: make(map[string]*list.Element)}}
+
+// versionedProvider is a memory provider with stores increasing their version by every release.
+type versionedProvider struct {
+	*MemProvider
+	stores map[string]*versionedStore
+}
+
+type versionedStore struct {
+	*MemSessionStore
+	version int
+	err     error // returned by next release
+}
+
+func (st *versionedStore) Version() string {
+	return strconv.Itoa(st.version)
+}
+
+func (st *versionedStore) SessionRelease(w http.ResponseWriter) error {
+	if err := st.err; err != nil {
+		st.err = nil
+		return err
+	}
+	st.version++
+	return st.MemSessionStore.SessionRelease(w)
+}
+
+func (p *versionedProvider) SessionRead(sid string) (SessionStore, error) {
+	if st, ok := p.stores[sid]; ok {
+		return st, nil
+	}
+	st, err := p.MemProvider.SessionRead(sid)
+	if err != nil {
+		return nil, err
+	}
+	p.stores[sid] = &versionedStore{MemSessionStore: st.(*MemSessionStore)}
+	return p.stores[sid], nil
+}
+
+var versionedpder = &versionedProvider{
+	MemProvider: &MemProvider{list: list.New(), sessions: make(map[string]*list.Element)},
+	stores:      make(map[string]*versionedStore),
+}
+
+func init() {
+	Register("counting", countingpder)
+	Register("versioned", versionedpder)
+}
+
+func TestTieredRead(t *testing.T) {
+	pder := &TieredProvider{}
+	if err := pder.SessionInit(3600, `{"backend":"counting","size":2,"ttl":60}`); err != nil {
+		t.Fatal("init tiered session err", err)
+	}
+	countingpder.reads = 0
+
+	st, _ := pder.SessionRead("tieredsid1")
+	st.Set("username", "astaxie")
+	st.SessionRelease(nil)
+	st, _ = pder.SessionRead("tieredsid1")
+	if countingpder.reads != 1 {
+		t.Fatal("second read within ttl should hit memory, backend reads", countingpder.reads)
+	}
+	if st.Get("username") != "astaxie" {
+		t.Fatal("cached store should have the value")
+	}
+
+	// expired in memory
+	pder.sessions["tieredsid1"].Value.(*tieredEntry).expires = time.Now().Add(-time.Second)
+	pder.SessionRead("tieredsid1")
+	if countingpder.reads != 2 {
+		t.Fatal("read after ttl should go to backend, backend reads", countingpder.reads)
+	}
+
+	// tieredsid1 is the least recently used one and evicted.
+	pder.SessionRead("tieredsid2")
+	pder.SessionRead("tieredsid3")
+	pder.SessionRead("tieredsid1")
+	if countingpder.reads != 5 {
+		t.Fatal("evicted session should be read from backend, backend reads", countingpder.reads)
+	}
+	if pder.list.Len() != 2 {
+		t.Fatal("cache should have at most size stores, got", pder.list.Len())
+	}
+}
+
+func TestTieredInvalidate(t *testing.T) {
+	pder := &TieredProvider{}
+	if err := pder.SessionInit(3600, `{"backend":"counting","ttl":60}`); err != nil {
+		t.Fatal("init tiered session err", err)
+	}
+	countingpder.reads = 0
+
+	pder.SessionRead("tieredsid4")
+	if err := pder.SessionDestroy("tieredsid4"); err != nil {
+		t.Fatal("destroy error", err)
+	}
+	if pder.SessionExist("tieredsid4") {
+		t.Fatal("destroyed session should not exist")
+	}
+	pder.SessionRead("tieredsid4")
+	if countingpder.reads != 2 {
+		t.Fatal("destroy should drop cached store, backend reads", countingpder.reads)
+	}
+
+	st, _ := pder.SessionRead("tieredsid4")
+	st.Set("username", "astaxie")
+	st.SessionRelease(nil)
+	st, err := pder.SessionRegenerate("tieredsid4", "tieredsid5")
+	if err != nil {
+		t.Fatal("regenerate error", err)
+	}
+	if st.Get("username") != "astaxie" {
+		t.Fatal("values should be moved to new sid")
+	}
+	if _, ok := pder.sessions["tieredsid4"]; ok {
+		t.Fatal("regenerate should drop cached store of old sid")
+	}
+	if pder.SessionExist("tieredsid4") {
+		t.Fatal("old sid should not exist")
+	}
+
+	if err = pder.SessionInit(3600, `{"backend":"tiered"}`); err == nil {
+		t.Fatal("tiered backend should return error")
+	}
+	if err = pder.SessionInit(3600, `{"backend":"unknown"}`); err == nil {
+		t.Fatal("unknown backend should return error")
+	}
+}
+
+func TestTieredCopies(t *testing.T) {
+	pder := &TieredProvider{}
+	if err := pder.SessionInit(3600, `{"backend":"versioned","ttl":60}`); err != nil {
+		t.Fatal("init tiered session err", err)
+	}
+
+	st1, _ := pder.SessionRead("tieredsid6")
+	st2, _ := pder.SessionRead("tieredsid6")
+	st1.Set("username", "astaxie")
+	if st2.Get("username") != nil {
+		t.Fatal("requests should not see values of each other before release")
+	}
+	if err := st1.SessionRelease(nil); err != nil {
+		t.Fatal("release error", err)
+	}
+	if st, _ := pder.SessionRead("tieredsid6"); st.Get("username") != "astaxie" {
+		t.Fatal("read after release should have the released values")
+	}
+
+	// st2 was copied before st1 was released
+	st2.Set("username", "slene")
+	if err := st2.SessionRelease(nil); err != ErrConflict {
+		t.Fatal("release of stale copy should conflict, got", err)
+	}
+	if _, ok := pder.sessions["tieredsid6"]; ok {
+		t.Fatal("conflict should drop cached store")
+	}
+	if st, _ := pder.SessionRead("tieredsid6"); st.Get("username") != "astaxie" {
+		t.Fatal("conflicting release should not write values")
+	}
+
+	// conflicts of backend drop cached store too
+	st, _ := pder.SessionRead("tieredsid6")
+	versionedpder.stores["tieredsid6"].err = ErrConflict
+	st.Set("username", "slene")
+	if err := st.SessionRelease(nil); err != ErrConflict {
+		t.Fatal("backend conflict should be returned, got", err)
+	}
+	if _, ok := pder.sessions["tieredsid6"]; ok {
+		t.Fatal("backend conflict should drop cached store")
+	}
+}
+
+func TestTieredTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	SetClock(clock)
+	defer SetClock(nil)
+	pder := &TieredProvider{}
+	if err := pder.SessionInit(3600, `{"backend":"counting","ttl":60}`); err != nil {
+		t.Fatal("init tiered session err", err)
+	}
+
+	st, _ := pder.SessionRead("tieredsid7")
+	SetWithTTL(st, "flash", "saved", 10*time.Millisecond)
+	st.Set("cart", "apple")
+	st.SessionRelease(nil)
+
+	// another copy only changing username keeps the ttl of flash.
+	st, _ = pder.SessionRead("tieredsid7")
+	st.Set("username", "astaxie")
+	st.SessionRelease(nil)
+	backend, _ := countingpder.MemProvider.SessionRead("tieredsid7")
+	if backend.Get("flash") != "saved" || backend.Get("username") != "astaxie" {
+		t.Fatal("released values should be written to backend")
+	}
+	clock.Advance(10 * time.Millisecond)
+	if backend.Get("flash") != nil {
+		t.Fatal("ttl of key should be kept by releases of other keys")
+	}
+
+	st, _ = pder.SessionRead("tieredsid7")
+	st.Delete("cart")
+	st.SessionRelease(nil)
+	if backend.Get("cart") != nil || backend.Get("username") != "astaxie" {
+		t.Fatal("only the deleted key should be removed from backend")
+	}
+	st, _ = pder.SessionRead("tieredsid7")
+	st.Flush()
+	st.Set("cart", "pear")
+	st.SessionRelease(nil)
+	if backend.Get("username") != nil || backend.Get("cart") != "pear" {
+		t.Fatal("flush should clear backend before changed keys are written")
+	}
+}
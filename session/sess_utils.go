@@ -2,18 +2,19 @@ package session
 
 import (
 	"bytes"
+	"compress/flate"
 	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha1"
-	"crypto/subtle"
 	"encoding/base64"
 	"encoding/gob"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strconv"
-	"time"
 )
 
 func init() {
@@ -25,6 +26,7 @@ func init() {
 	gob.Register(map[int]string{})
 	gob.Register(map[int]int{})
 	gob.Register(map[int]int64{})
+	gob.Register(map[string][]string{})
 }
 
 func EncodeGob(obj map[interface{}]interface{}) ([]byte, error) {
@@ -94,23 +96,149 @@ func decrypt(block cipher.Block, value []byte) ([]byte, error) {
 	return nil, errors.New("decrypt: the value could not be decrypted")
 }
 
+// encryptGCM encrypts and authenticates a value using the given AEAD.
+//
+// A random nonce with the length of the AEAD nonce size is prepended to the
+// resulting ciphertext.
+func encryptGCM(aead cipher.AEAD, value []byte) ([]byte, error) {
+	nonce := generateRandomKey(aead.NonceSize())
+	if nonce == nil {
+		return nil, errors.New("encrypt: failed to generate random nonce")
+	}
+	return aead.Seal(nonce, nonce, value, nil), nil
+}
+
+// decryptGCM decrypts and authenticates a value using the given AEAD.
+//
+// The value to be decrypted must be prepended by the nonce used to encrypt it.
+func decryptGCM(aead cipher.AEAD, value []byte) ([]byte, error) {
+	size := aead.NonceSize()
+	if len(value) > size {
+		b, err := aead.Open(nil, value[:size], value[size:], nil)
+		if err != nil {
+			return nil, errors.New("decrypt: the value could not be authenticated")
+		}
+		return b, nil
+	}
+	return nil, errors.New("decrypt: the value could not be decrypted")
+}
+
+// cookieCipher encrypts and decrypts the encoded cookie session values.
+type cookieCipher interface {
+	encrypt(value []byte) ([]byte, error)
+	decrypt(value []byte) ([]byte, error)
+}
+
+// ctrCipher uses the block cipher in counter mode.
+type ctrCipher struct {
+	block cipher.Block
+}
+
+func (c ctrCipher) encrypt(value []byte) ([]byte, error) {
+	return encrypt(c.block, value)
+}
+
+func (c ctrCipher) decrypt(value []byte) ([]byte, error) {
+	return decrypt(c.block, value)
+}
+
+// plainCipher doesn't encrypt, values are only signed.
+type plainCipher struct{}
+
+func (c plainCipher) encrypt(value []byte) ([]byte, error) {
+	return value, nil
+}
+
+func (c plainCipher) decrypt(value []byte) ([]byte, error) {
+	return value, nil
+}
+
+// gcmCipher uses the block cipher in galois counter mode.
+type gcmCipher struct {
+	aead cipher.AEAD
+}
+
+func (c gcmCipher) encrypt(value []byte) ([]byte, error) {
+	return encryptGCM(c.aead, value)
+}
+
+func (c gcmCipher) decrypt(value []byte) ([]byte, error) {
+	return decryptGCM(c.aead, value)
+}
+
+// newCookieCipher creates the cookieCipher for mode.
+// mode is ctr (default) or gcm.
+func newCookieCipher(block cipher.Block, mode string) (cookieCipher, error) {
+	switch mode {
+	case "", "ctr":
+		return ctrCipher{block}, nil
+	case "gcm":
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		return gcmCipher{aead}, nil
+	}
+	return nil, fmt.Errorf("session: unknown cookie encryption mode %q", mode)
+}
+
+// CookieCodec encodes session values to the cookie value and decodes them back.
+// cookie provider uses the beego format by default, set a codec by SetCookieCodec
+// to share the session cookie with another framework.
+// Decode must verify the value, e.g. by its signature, it's sent by the client.
+type CookieCodec interface {
+	Encode(values map[interface{}]interface{}) (string, error)
+	Decode(value string) (map[interface{}]interface{}, error)
+}
+
+// beegoCodec is the default CookieCodec: gob values, optionally compressed and encrypted,
+// signed with timestamp and key id.
+type beegoCodec struct {
+	crypt       cookieCipher
+	hashKeys    []string
+	name        string
+	compress    bool
+	maxlifetime int64
+}
+
+func (c *beegoCodec) Encode(values map[interface{}]interface{}) (string, error) {
+	return encodeCookieCipher(c.crypt, c.hashKeys, c.name, values, c.compress)
+}
+
+func (c *beegoCodec) Decode(value string) (map[interface{}]interface{}, error) {
+	return decodeCookieCipher(c.crypt, c.hashKeys, c.name, value, c.maxlifetime)
+}
+
 func encodeCookie(block cipher.Block, hashKey, name string, value map[interface{}]interface{}) (string, error) {
+	return encodeCookieCipher(ctrCipher{block}, []string{hashKey}, name, value, false)
+}
+
+// encodeCookieCipher signs the value with the first of hashKeys.
+// the id of the signing key is prepended, so decode can pick the key directly.
+// if compress is set, gob data is compressed before encryption.
+func encodeCookieCipher(c cookieCipher, hashKeys []string, name string, value map[interface{}]interface{}, compress bool) (string, error) {
 	var err error
 	var b []byte
+	if len(hashKeys) == 0 {
+		return "", errors.New("Encode: no hash key")
+	}
 	// 1. EncodeGob.
 	if b, err = EncodeGob(value); err != nil {
 		return "", err
 	}
+	if compress {
+		if b, err = compressValue(b); err != nil {
+			return "", err
+		}
+	}
 	// 2. Encrypt (optional).
-	if b, err = encrypt(block, b); err != nil {
+	if b, err = c.encrypt(b); err != nil {
 		return "", err
 	}
 	b = encode(b)
-	// 3. Create MAC for "name|date|value". Extra pipe to be used later.
-	b = []byte(fmt.Sprintf("%s|%d|%s|", name, time.Now().UTC().Unix(), b))
-	h := hmac.New(sha1.New, []byte(hashKey))
-	h.Write(b)
-	sig := h.Sum(nil)
+	// 3. Create MAC for "name|kid|date|value". Extra pipe to be used later.
+	b = []byte(fmt.Sprintf("%s|k%s|%d|%s|", name, keyID(hashKeys[0]), Now().UTC().Unix(), b))
+	sig := macValue(hashKeys[0], b)
 	// Append mac, remove name.
 	b = append(b, sig...)[len(name)+1:]
 	// 4. Encode to base64.
@@ -120,22 +248,52 @@ func encodeCookie(block cipher.Block, hashKey, name string, value map[interface{
 }
 
 func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime int64) (map[interface{}]interface{}, error) {
+	return decodeCookieCipher(ctrCipher{block}, []string{hashKey}, name, value, gcmaxlifetime)
+}
+
+// decodeCookieCipher verifies the value with the key its id points to.
+// values signed without key id are verified with any of hashKeys.
+func decodeCookieCipher(c cookieCipher, hashKeys []string, name, value string, gcmaxlifetime int64) (map[interface{}]interface{}, error) {
 	// 1. Decode from base64.
 	b, err := decode([]byte(value))
 	if err != nil {
 		return nil, err
 	}
-	// 2. Verify MAC. Value is "date|value|mac".
-	parts := bytes.SplitN(b, []byte("|"), 3)
-	if len(parts) != 3 {
-		return nil, errors.New("Decode: invalid value %v")
+	// 2. Verify MAC. Value is "kid|date|value|mac", or "date|value|mac" without key id.
+	var parts [][]byte
+	keys := hashKeys
+	if bytes.HasPrefix(b, []byte("k")) {
+		parts = bytes.SplitN(b, []byte("|"), 4)
+		if len(parts) != 4 {
+			return nil, errors.New("Decode: invalid value")
+		}
+		keys = nil
+		for _, k := range hashKeys {
+			if "k"+keyID(k) == string(parts[0]) {
+				keys = []string{k}
+				break
+			}
+		}
+		if keys == nil {
+			return nil, errors.New("Decode: unknown key id")
+		}
+		parts = parts[1:]
+	} else {
+		parts = bytes.SplitN(b, []byte("|"), 3)
+		if len(parts) != 3 {
+			return nil, errors.New("Decode: invalid value")
+		}
 	}
 
 	b = append([]byte(name+"|"), b[:len(b)-len(parts[2])]...)
-	h := hmac.New(sha1.New, []byte(hashKey))
-	h.Write(b)
-	sig := h.Sum(nil)
-	if len(sig) != len(parts[2]) || subtle.ConstantTimeCompare(sig, parts[2]) != 1 {
+	valid := false
+	for _, k := range keys {
+		if hmac.Equal(macValue(k, b), parts[2]) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
 		return nil, errors.New("Decode: the value is not valid")
 	}
 	// 3. Verify date ranges.
@@ -143,7 +301,7 @@ func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime
 	if t1, err = strconv.ParseInt(string(parts[0]), 10, 64); err != nil {
 		return nil, errors.New("Decode: invalid timestamp")
 	}
-	t2 := time.Now().UTC().Unix()
+	t2 := Now().UTC().Unix()
 	if t1 > t2 {
 		return nil, errors.New("Decode: timestamp is too new")
 	}
@@ -155,9 +313,14 @@ func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime
 	if err != nil {
 		return nil, err
 	}
-	if b, err = decrypt(block, b); err != nil {
+	if b, err = c.decrypt(b); err != nil {
 		return nil, err
 	}
+	if len(b) > 0 && b[0] == compressFlag {
+		if b, err = decompressValue(b); err != nil {
+			return nil, err
+		}
+	}
 	// 5. DecodeGob.
 	if dst, err := DecodeGob(b); err != nil {
 		return nil, err
@@ -168,6 +331,47 @@ func decodeCookie(block cipher.Block, hashKey, name, value string, gcmaxlifetime
 	return nil, nil
 }
 
+// compressFlag is the header byte of compressed cookie data.
+// gob data never starts with 0, so values without the header are plain gob.
+const compressFlag = 0
+
+// compressValue deflates gob data and prepends compressFlag.
+func compressValue(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(compressFlag)
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(value); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressValue inflates data created by compressValue.
+func decompressValue(value []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(value[1:]))
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// macValue creates hmac-sha1 of value with hashKey.
+func macValue(hashKey string, value []byte) []byte {
+	h := hmac.New(sha1.New, []byte(hashKey))
+	h.Write(value)
+	return h.Sum(nil)
+}
+
+// keyID identifies hashKey in encoded cookie without revealing it.
+func keyID(hashKey string) string {
+	sum := sha1.Sum([]byte(hashKey))
+	return hex.EncodeToString(sum[:4])
+}
+
 // Encoding -------------------------------------------------------------------
 
 // encode encodes a value using base64.
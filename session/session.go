@@ -1,27 +1,134 @@
 package session
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
 // SessionStore contains all data for one session process with specific id.
 type SessionStore interface {
-	Set(key, value interface{}) error     //set session value
-	Get(key interface{}) interface{}      //get session value
-	Delete(key interface{}) error         //delete session value
-	SessionID() string                    //back current sessionID
-	SessionRelease(w http.ResponseWriter) // release the resource & save data to provider & return the data
-	Flush() error                         //delete all data
+	Set(key, value interface{}) error           //set session value
+	Get(key interface{}) interface{}            //get session value
+	Delete(key interface{}) error               //delete session value
+	SessionID() string                          //back current sessionID
+	SessionRelease(w http.ResponseWriter) error // release the resource & save data to provider & return the data
+	Flush() error                               //delete all data
+	Keys() []interface{}                        //get all keys
+	Len() int                                   //get count of values
+	CreatedAt() time.Time                       //get creation time, zero if unknown
+	LastAccessedAt() time.Time                  //get time of last read, zero if unknown
+}
+
+// ErrConflict is returned by SessionRelease of versioned stores
+// if the session was written by another request since it was read.
+var ErrConflict = errors.New("session: session was changed by another request")
+
+// Versioner is implemented by stores writing conditionally on the version loaded by SessionRead,
+// their SessionRelease returns ErrConflict if the saved session has another version.
+// redis, mysql and postgresql stores support it, Manager.UpdateSession retries on conflicts.
+type Versioner interface {
+	Version() string // version of the session when it was read
+}
+
+// TTLSetter is implemented by SessionStores which can expire a single key
+// before the whole session expires. memory and redis stores support it.
+type TTLSetter interface {
+	SetWithTTL(key, value interface{}, ttl time.Duration) error // set session value which expires after ttl
+}
+
+// SetWithTTL sets session value which expires after ttl.
+// if the store doesn't implement TTLSetter, the ttl is ignored
+// and the value lives as long as the session.
+func SetWithTTL(store SessionStore, key, value interface{}, ttl time.Duration) error {
+	if ts, ok := store.(TTLSetter); ok {
+		return ts.SetWithTTL(key, value, ttl)
+	}
+	return store.Set(key, value)
+}
+
+// CreatedKey is the reserved session key saving unix nano creation time
+// for stores which save nothing but the values, it's skipped by Keys.
+const CreatedKey = "__beego_session_created__"
+
+// session keys reserved by this package, they are read by Get but skipped by Keys and Len.
+var reservedKeys = []interface{}{CreatedKey, UserKey, flashKey}
+
+// IsReservedKey reports whether key is reserved by this package, e.g. CreatedKey.
+// stores skip reserved keys in Keys, so Len counts only the values set by users.
+func IsReservedKey(key interface{}) bool {
+	for _, k := range reservedKeys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// copy values of src to dst, the reserved keys except CreatedKey are copied too.
+func copyValues(dst map[interface{}]interface{}, src SessionStore) {
+	for _, k := range src.Keys() {
+		dst[k] = src.Get(k)
+	}
+	for _, k := range reservedKeys {
+		if k == CreatedKey {
+			continue
+		}
+		if v := src.Get(k); v != nil {
+			dst[k] = v
+		}
+	}
+}
+
+// InitCreated saves current time as creation time in values of a new session.
+// values read before CreatedKey was saved keep an unknown creation time.
+func InitCreated(values map[interface{}]interface{}) map[interface{}]interface{} {
+	if len(values) == 0 {
+		values[CreatedKey] = Now().UnixNano()
+	}
+	return values
+}
+
+// CreatedTime returns creation time saved in values, zero if unknown.
+// numbers decoded by JSONSerializer are float64 or json.Number, they are accepted too.
+func CreatedTime(values map[interface{}]interface{}) time.Time {
+	switch t := values[CreatedKey].(type) {
+	case int64:
+		return time.Unix(0, t)
+	case float64:
+		return time.Unix(0, int64(t))
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return time.Unix(0, n)
+		}
+		if f, err := t.Float64(); err == nil {
+			return time.Unix(0, int64(f))
+		}
+	}
+	return time.Time{}
+}
+
+// FlushValues returns empty values keeping creation time of values.
+func FlushValues(values map[interface{}]interface{}) map[interface{}]interface{} {
+	kv := make(map[interface{}]interface{})
+	if t, ok := values[CreatedKey]; ok {
+		kv[CreatedKey] = t
+	}
+	return kv
 }
 
 // Provider contains global session methods and saved SessionStores.
@@ -36,6 +143,142 @@ type Provider interface {
 	SessionGC()
 }
 
+// sessionUpdater is implemented by providers which can extend the
+// expiration of an existing session. it's used when manager config renew is on.
+type sessionUpdater interface {
+	SessionUpdate(sid string) error
+}
+
+// batchGC is implemented by providers which can limit the work of one gc pass.
+// it's used when manager config gcBatchSize is set.
+type batchGC interface {
+	SessionGCBatch(size int)
+}
+
+// ProviderCapabilities are bit flags of the operations a provider really does,
+// the methods of missing ones are no-ops, e.g. SessionAll of cookie provider always returns 0.
+type ProviderCapabilities uint8
+
+const (
+	CapRegenerate ProviderCapabilities = 1 << iota // SessionRegenerate keeps the values under the new sid
+	CapDestroy                                     // SessionDestroy removes the session saved by the provider
+	CapGC                                          // expired sessions are removed, by SessionGC or by the storage itself
+	CapCount                                       // SessionAll returns the count of active sessions
+
+	CapAll = CapRegenerate | CapDestroy | CapGC | CapCount
+)
+
+// Has reports whether all of flags are set.
+func (c ProviderCapabilities) Has(flags ProviderCapabilities) bool {
+	return c&flags == flags
+}
+
+// CapabilityReporter is implemented by providers which report their capabilities.
+type CapabilityReporter interface {
+	Capabilities() ProviderCapabilities
+}
+
+// Capabilities returns the capabilities of p.
+// providers not implementing CapabilityReporter are assumed to support everything.
+func Capabilities(p Provider) ProviderCapabilities {
+	if cr, ok := p.(CapabilityReporter); ok {
+		return cr.Capabilities()
+	}
+	return CapAll
+}
+
+// ContextReader is implemented by providers whose SessionRead can be canceled,
+// e.g. by the deadline of the request.
+type ContextReader interface {
+	SessionReadContext(ctx context.Context, sid string) (SessionStore, error)
+}
+
+// SessionReadContext reads session by sid from provider with ctx.
+// providers not implementing ContextReader only check ctx before reading.
+func SessionReadContext(ctx context.Context, p Provider, sid string) (SessionStore, error) {
+	if cr, ok := p.(ContextReader); ok {
+		return cr.SessionReadContext(ctx, sid)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return p.SessionRead(sid)
+}
+
+// ReadContext runs read in a new goroutine and returns when it's done or ctx is done.
+// it's for providers whose client can not cancel a query, the read keeps running after ctx is done.
+func ReadContext(ctx context.Context, read func() (SessionStore, error)) (SessionStore, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type result struct {
+		st  SessionStore
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		st, err := read()
+		ch <- result{st, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.st, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// DirtyChecker is implemented by stores which track changes of values.
+// IsDirty returns true if Set, Delete or Flush was called since the store was read.
+type DirtyChecker interface {
+	IsDirty() bool
+}
+
+// IsDirty reports whether the store needs to be written.
+// stores not implementing DirtyChecker are always dirty.
+func IsDirty(st SessionStore) bool {
+	if dc, ok := st.(DirtyChecker); ok {
+		return dc.IsDirty()
+	}
+	return true
+}
+
+// SessionIndexer is implemented by providers which keep the sids of every user,
+// it's used by Manager.EnforceSessionLimit.
+type SessionIndexer interface {
+	// add sid to sids of userID and return all of them, oldest first.
+	IndexSession(userID, sid string) ([]string, error)
+	// remove sids from sids of userID.
+	UnindexSessions(userID string, sids ...string) error
+}
+
+// UserIndexer is implemented by session indexers which can list the sids of a user,
+// it's used by Manager.DestroyByUser.
+type UserIndexer interface {
+	SessionIndexer
+	// get sids of userID, oldest first.
+	UserSessions(userID string) ([]string, error)
+}
+
+// UserKey is the reserved session key saving the user id set by Manager.SetUser.
+const UserKey = "__beego_session_user__"
+
+// Pinger is implemented by providers backed by an external service,
+// Ping checks the connection to it.
+type Pinger interface {
+	Ping() error
+}
+
+// secureReleaser is implemented by stores writing cookies in SessionRelease.
+type secureReleaser interface {
+	sessionReleaseSecure(w http.ResponseWriter, secure bool) error
+}
+
+// resetter is implemented by stores which keep per request state.
+type resetter interface {
+	Reset()
+}
+
 var provides = make(map[string]Provider)
 
 // Register makes a session provide available by the provided name.
@@ -61,12 +304,29 @@ type managerConfig struct {
 	SessionIDHashKey  string `json:"sessionIDHashKey"`
 	CookieLifeTime    int    `json:"cookieLifeTime"`
 	ProviderConfig    string `json:"providerConfig"`
+	Renew             bool   `json:"renew"`
+	CookieDomain      string `json:"cookieDomain"`
+	CookiePath        string `json:"cookiePath"`
+	GCInterval        int    `json:"gcInterval"`
+	GCBatchSize       int    `json:"gcBatchSize"`
+	AutoSecure        bool   `json:"autoSecure"`
+	SignSID           bool   `json:"signSID"`
 }
 
 // Manager contains Provider and its configuration.
 type Manager struct {
-	provider Provider
-	config   *managerConfig
+	stats     managerStats // first field, 64 bit aligned for atomic
+	provider  Provider
+	config    *managerConfig
+	gcLock    sync.Mutex
+	gcTimer   *time.Timer
+	gcStopped bool
+	idGen     func() string
+
+	hookLock      sync.RWMutex
+	onCreated     []func(sid string)
+	onDestroyed   []func(sid string)
+	onRegenerated []func(oldsid, sid string)
 }
 
 // Create new Manager with provider name and json config string.
@@ -78,9 +338,18 @@ type Manager struct {
 // 5. mysql
 // json config:
 // 1. is https  default false
-// 2. hashfunc  default sha1
+// 2. hashfunc  default none, sid is random url-safe base64. md5 or sha1 hash it to hex
 // 3. hashkey default beegosessionkey
-// 4. maxage default is none
+// 4. cookieLifeTime default 0, MaxAge of sid cookie, 0 is a browser session cookie.
+// it's separated from maxLifetime, the lifetime of session data in provider which defaults to gclifetime.
+// 5. renew default false, extend session expiration on every request
+// 6. cookieDomain default is none
+// 7. cookiePath default is /
+// 8. gcInterval default is gclifetime, seconds between gc passes
+// 9. gcBatchSize default is none, max sessions swept by one gc pass
+// 10. autoSecure default false, set Secure of cookies if the request is https, it overrides secure
+// 11. signSID default false, sign the sid cookie by hmac with hashkey, which must be set.
+// cookies with a bad signature are treated as no session without reading the provider.
 func NewManager(provideName, config string) (*Manager, error) {
 	provider, ok := provides[provideName]
 	if !ok {
@@ -99,16 +368,23 @@ func NewManager(provideName, config string) (*Manager, error) {
 	if err != nil {
 		return nil, err
 	}
-	if cf.SessionIDHashFunc == "" {
-		cf.SessionIDHashFunc = "sha1"
+	if cf.CookiePath == "" {
+		cf.CookiePath = "/"
+	}
+	if cf.SignSID && cf.SessionIDHashKey == "" {
+		return nil, errors.New("session: signSID needs sessionIDHashKey")
 	}
 	if cf.SessionIDHashKey == "" {
 		cf.SessionIDHashKey = string(generateRandomKey(16))
 	}
 
+	if cf.GCInterval <= 0 {
+		cf.GCInterval = int(cf.Gclifetime)
+	}
+
 	return &Manager{
-		provider,
-		cf,
+		provider: provider,
+		config:   cf,
 	}, nil
 }
 
@@ -116,14 +392,24 @@ func NewManager(provideName, config string) (*Manager, error) {
 // if session id exists, return SessionStore with this id.
 func (manager *Manager) SessionStart(w http.ResponseWriter, r *http.Request) (session SessionStore) {
 	cookie, err := r.Cookie(manager.config.CookieName)
-	if err != nil || cookie.Value == "" {
-		sid := manager.sessionId(r)
-		session, _ = manager.provider.SessionRead(sid)
+	sid, ok := "", false
+	if err == nil && cookie.Value != "" {
+		sid, ok = manager.cookieSID(cookie)
+	}
+	if !ok {
+		sid = manager.sessionId(r)
+		session, err = manager.provider.SessionRead(sid)
+		manager.countErr(err)
+		manager.count(statCreates)
+		if err == nil {
+			manager.fireCreated(sid)
+		}
 		cookie = &http.Cookie{Name: manager.config.CookieName,
-			Value:    url.QueryEscape(sid),
-			Path:     "/",
+			Value:    manager.cookieValue(sid),
+			Path:     manager.config.CookiePath,
+			Domain:   manager.config.CookieDomain,
 			HttpOnly: true,
-			Secure:   manager.config.Secure}
+			Secure:   manager.isSecure(r)}
 		if manager.config.CookieLifeTime >= 0 {
 			cookie.MaxAge = manager.config.CookieLifeTime
 		}
@@ -132,17 +418,27 @@ func (manager *Manager) SessionStart(w http.ResponseWriter, r *http.Request) (se
 		}
 		r.AddCookie(cookie)
 	} else {
-		sid, _ := url.QueryUnescape(cookie.Value)
 		if manager.provider.SessionExist(sid) {
-			session, _ = manager.provider.SessionRead(sid)
+			session, err = manager.provider.SessionRead(sid)
+			manager.countErr(err)
+			manager.count(statReads)
+			if manager.config.Renew {
+				manager.renew(w, r, cookie, sid)
+			}
 		} else {
 			sid = manager.sessionId(r)
-			session, _ = manager.provider.SessionRead(sid)
+			session, err = manager.provider.SessionRead(sid)
+			manager.countErr(err)
+			manager.count(statCreates)
+			if err == nil {
+				manager.fireCreated(sid)
+			}
 			cookie = &http.Cookie{Name: manager.config.CookieName,
-				Value:    url.QueryEscape(sid),
-				Path:     "/",
+				Value:    manager.cookieValue(sid),
+				Path:     manager.config.CookiePath,
+				Domain:   manager.config.CookieDomain,
 				HttpOnly: true,
-				Secure:   manager.config.Secure}
+				Secure:   manager.isSecure(r)}
 			if manager.config.CookieLifeTime >= 0 {
 				cookie.MaxAge = manager.config.CookieLifeTime
 			}
@@ -152,19 +448,54 @@ func (manager *Manager) SessionStart(w http.ResponseWriter, r *http.Request) (se
 			r.AddCookie(cookie)
 		}
 	}
+	if rs, ok := session.(resetter); ok {
+		rs.Reset()
+	}
 	return
 }
 
+// extend expiration of the session in provider and the sid cookie,
+// so the session expires maxlifetime after last access instead of after creation.
+func (manager *Manager) renew(w http.ResponseWriter, r *http.Request, cookie *http.Cookie, sid string) {
+	if u, ok := manager.provider.(sessionUpdater); ok {
+		u.SessionUpdate(sid)
+	}
+	if manager.config.EnableSetCookie && manager.config.CookieLifeTime > 0 {
+		cookie = &http.Cookie{Name: manager.config.CookieName,
+			Value:    cookie.Value,
+			Path:     manager.config.CookiePath,
+			Domain:   manager.config.CookieDomain,
+			HttpOnly: true,
+			Secure:   manager.isSecure(r),
+			MaxAge:   manager.config.CookieLifeTime}
+		http.SetCookie(w, cookie)
+	}
+}
+
 // Destroy session by its id in http request cookie.
 func (manager *Manager) SessionDestroy(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie(manager.config.CookieName)
 	if err != nil || cookie.Value == "" {
 		return
 	} else {
-		manager.provider.SessionDestroy(cookie.Value)
+		sid, ok := manager.cookieSID(cookie)
+		if !ok {
+			return
+		}
+		err = manager.countErr(manager.provider.SessionDestroy(sid))
+		manager.count(statDestroys)
+		if err == nil {
+			manager.fireDestroyed(sid)
+		}
+		// cookie session keeps all data in the client cookie, expire it.
+		if _, ok := manager.provider.(*CookieProvider); ok {
+			(&CookieSessionStore{sid: sid}).DestroyCookie(w)
+			return
+		}
 		expiration := time.Now()
 		cookie := http.Cookie{Name: manager.config.CookieName,
-			Path:     "/",
+			Path:     manager.config.CookiePath,
+			Domain:   manager.config.CookieDomain,
 			HttpOnly: true,
 			Expires:  expiration,
 			MaxAge:   -1}
@@ -175,35 +506,217 @@ func (manager *Manager) SessionDestroy(w http.ResponseWriter, r *http.Request) {
 // Get SessionStore by its id.
 func (manager *Manager) GetSessionStore(sid string) (sessions SessionStore, err error) {
 	sessions, err = manager.provider.SessionRead(sid)
+	manager.countErr(err)
+	manager.count(statReads)
 	return
 }
 
+// Get SessionStore by its id, the read is canceled when ctx is done.
+func (manager *Manager) GetSessionStoreContext(ctx context.Context, sid string) (SessionStore, error) {
+	s, err := SessionReadContext(ctx, manager.provider, sid)
+	manager.countErr(err)
+	manager.count(statReads)
+	return s, err
+}
+
+// attempts of UpdateSession before it returns ErrConflict.
+const updateAttempts = 5
+
+// Read session by sid, run fn with it and release it.
+// if the release returns ErrConflict, the session is read again and fn runs again with it,
+// so fn must only change the session and not have other side effects.
+func (manager *Manager) UpdateSession(w http.ResponseWriter, sid string, fn func(SessionStore) error) error {
+	for i := 0; ; i++ {
+		st, err := manager.GetSessionStore(sid)
+		if err != nil {
+			return err
+		}
+		if err = fn(st); err != nil {
+			return err
+		}
+		err = manager.SessionRelease(w, st)
+		if err != ErrConflict || i+1 >= updateAttempts {
+			return err
+		}
+	}
+}
+
 // Start session gc process.
-// it can do gc in times after gc lifetime.
+// it can do gc in times after gc interval until Stop is called.
 func (manager *Manager) GC() {
-	manager.provider.SessionGC()
-	time.AfterFunc(time.Duration(manager.config.Gclifetime)*time.Second, func() { manager.GC() })
+	manager.gcLock.Lock()
+	defer manager.gcLock.Unlock()
+	if manager.gcStopped {
+		return
+	}
+	manager.count(statGC)
+	if bp, ok := manager.provider.(batchGC); ok && manager.config.GCBatchSize > 0 {
+		bp.SessionGCBatch(manager.config.GCBatchSize)
+	} else {
+		manager.provider.SessionGC()
+	}
+	manager.gcTimer = time.AfterFunc(time.Duration(manager.config.GCInterval)*time.Second, func() { manager.GC() })
+}
+
+// Release session store at the end of request.
+// clean stores are not written unless renew is on,
+// so requests only reading the session don't write to provider.
+func (manager *Manager) SessionRelease(w http.ResponseWriter, session SessionStore) error {
+	return manager.SessionReleaseRequest(w, nil, session)
+}
+
+// Release session store at the end of request r.
+// it's SessionRelease, but cookie sessions are written with Secure detected from r if autoSecure is on.
+func (manager *Manager) SessionReleaseRequest(w http.ResponseWriter, r *http.Request, session SessionStore) error {
+	if !manager.config.Renew && !IsDirty(session) {
+		return nil
+	}
+	manager.count(statWrites)
+	if sr, ok := session.(secureReleaser); ok && manager.config.AutoSecure && r != nil {
+		return manager.countErr(sr.sessionReleaseSecure(w, manager.isSecure(r)))
+	}
+	return manager.countErr(session.SessionRelease(w))
+}
+
+// Destroy session by its id, e.g. sessions evicted by EnforceSessionLimit.
+func (manager *Manager) SessionDestroyId(sid string) error {
+	if err := manager.countErr(manager.provider.SessionDestroy(sid)); err != nil {
+		return err
+	}
+	manager.count(statDestroys)
+	manager.fireDestroyed(sid)
+	return nil
+}
+
+// Add sid to the sessions of userID and limit them to max.
+// it returns the oldest sids exceeding max, the caller should destroy them by SessionDestroyId.
+// sids which don't exist anymore are removed from the index and not returned.
+// the provider must implement SessionIndexer, memory and redis do.
+func (manager *Manager) EnforceSessionLimit(userID string, sid string, max int) ([]string, error) {
+	idx, ok := manager.provider.(SessionIndexer)
+	if !ok {
+		return nil, errors.New("session: provider can't index sessions of users")
+	}
+	sids, err := idx.IndexSession(userID, sid)
+	if err != nil {
+		return nil, err
+	}
+	var live, gone []string
+	for _, s := range sids {
+		if s == sid || manager.provider.SessionExist(s) {
+			live = append(live, s)
+		} else {
+			gone = append(gone, s)
+		}
+	}
+	var evicted []string
+	for i := 0; i < len(live) && len(live)-len(evicted) > max; i++ {
+		if live[i] != sid {
+			evicted = append(evicted, live[i])
+		}
+	}
+	if drop := append(gone, evicted...); len(drop) > 0 {
+		if err = idx.UnindexSessions(userID, drop...); err != nil {
+			return nil, err
+		}
+	}
+	return evicted, nil
+}
+
+// Set userID as the owner of session, e.g. after login.
+// the user id is saved in session by UserKey and sid is added to the sessions of userID,
+// so DestroyByUser can destroy it. the provider must implement SessionIndexer.
+func (manager *Manager) SetUser(session SessionStore, userID string) error {
+	idx, ok := manager.provider.(SessionIndexer)
+	if !ok {
+		return errors.New("session: provider can't index sessions of users")
+	}
+	if _, err := idx.IndexSession(userID, session.SessionID()); err != nil {
+		return err
+	}
+	return session.Set(UserKey, userID)
+}
+
+// Destroy all sessions of userID, e.g. to log the user out on every device.
+// sessions are added to userID by SetUser or EnforceSessionLimit.
+// it returns the count of destroyed sessions, sids which don't exist anymore aren't counted.
+// the provider must implement UserIndexer, memory and redis do.
+// database providers (mysql, postgresql, ...) don't keep an index of users, they're not supported.
+func (manager *Manager) DestroyByUser(userID string) (int, error) {
+	idx, ok := manager.provider.(UserIndexer)
+	if !ok {
+		return 0, errors.New("session: provider can't list sessions of users")
+	}
+	sids, err := idx.UserSessions(userID)
+	if err != nil || len(sids) == 0 {
+		return 0, err
+	}
+	n := 0
+	for _, sid := range sids {
+		if !manager.provider.SessionExist(sid) {
+			continue
+		}
+		if err = manager.SessionDestroyId(sid); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, idx.UnindexSessions(userID, sids...)
+}
+
+// Check connectivity of the session provider.
+// it returns nil if the provider is not a Pinger, e.g. memory and cookie.
+func (manager *Manager) HealthCheck() error {
+	if p, ok := manager.provider.(Pinger); ok {
+		return p.Ping()
+	}
+	return nil
+}
+
+// Stop session gc process.
+// it waits for the running gc pass, no gc runs after it returns.
+func (manager *Manager) Stop() {
+	manager.gcLock.Lock()
+	defer manager.gcLock.Unlock()
+	manager.gcStopped = true
+	if manager.gcTimer != nil {
+		manager.gcTimer.Stop()
+	}
 }
 
 // Regenerate a session id for this SessionStore who's id is saving in http request.
 func (manager *Manager) SessionRegenerateId(w http.ResponseWriter, r *http.Request) (session SessionStore) {
 	sid := manager.sessionId(r)
 	cookie, err := r.Cookie(manager.config.CookieName)
-	if err != nil && cookie.Value == "" {
+	oldsid, ok := "", false
+	if err == nil && cookie.Value != "" {
+		oldsid, ok = manager.cookieSID(cookie)
+	}
+	if !ok {
 		//delete old cookie
-		session, _ = manager.provider.SessionRead(sid)
+		session, err = manager.provider.SessionRead(sid)
+		manager.countErr(err)
+		manager.count(statCreates)
+		if err == nil {
+			manager.fireCreated(sid)
+		}
 		cookie = &http.Cookie{Name: manager.config.CookieName,
-			Value:    url.QueryEscape(sid),
-			Path:     "/",
+			Value:    manager.cookieValue(sid),
+			Path:     manager.config.CookiePath,
+			Domain:   manager.config.CookieDomain,
 			HttpOnly: true,
-			Secure:   manager.config.Secure,
+			Secure:   manager.isSecure(r),
 		}
 	} else {
-		oldsid, _ := url.QueryUnescape(cookie.Value)
-		session, _ = manager.provider.SessionRegenerate(oldsid, sid)
-		cookie.Value = url.QueryEscape(sid)
+		session, err = manager.provider.SessionRegenerate(oldsid, sid)
+		manager.countErr(err)
+		if err == nil {
+			manager.fireRegenerated(oldsid, sid)
+		}
+		cookie.Value = manager.cookieValue(sid)
 		cookie.HttpOnly = true
-		cookie.Path = "/"
+		cookie.Path = manager.config.CookiePath
+		cookie.Domain = manager.config.CookieDomain
 	}
 	if manager.config.CookieLifeTime >= 0 {
 		cookie.MaxAge = manager.config.CookieLifeTime
@@ -213,7 +726,67 @@ func (manager *Manager) SessionRegenerateId(w http.ResponseWriter, r *http.Reque
 	return
 }
 
+// OnSessionCreated adds a hook called with the sid of every new session,
+// started by SessionStart or SessionRegenerateId without a session.
+// hooks are only called after the provider succeeded, e.g. not if SessionRead failed.
+// hooks are called synchronously in the request, they must be fast,
+// slow work like writing audit logs should be done in a goroutine.
+func (manager *Manager) OnSessionCreated(fn func(sid string)) {
+	manager.hookLock.Lock()
+	defer manager.hookLock.Unlock()
+	manager.onCreated = append(manager.onCreated, fn)
+}
+
+// OnSessionDestroyed adds a hook called with the sid destroyed by SessionDestroy or SessionDestroyId.
+// sessions expired by gc are not reported. hooks must be fast like OnSessionCreated.
+func (manager *Manager) OnSessionDestroyed(fn func(sid string)) {
+	manager.hookLock.Lock()
+	defer manager.hookLock.Unlock()
+	manager.onDestroyed = append(manager.onDestroyed, fn)
+}
+
+// OnSessionRegenerated adds a hook called with the old and new sid by SessionRegenerateId.
+// hooks must be fast like OnSessionCreated.
+func (manager *Manager) OnSessionRegenerated(fn func(oldsid, sid string)) {
+	manager.hookLock.Lock()
+	defer manager.hookLock.Unlock()
+	manager.onRegenerated = append(manager.onRegenerated, fn)
+}
+
+func (manager *Manager) fireCreated(sid string) {
+	manager.hookLock.RLock()
+	hooks := manager.onCreated
+	manager.hookLock.RUnlock()
+	for _, fn := range hooks {
+		fn(sid)
+	}
+}
+
+func (manager *Manager) fireDestroyed(sid string) {
+	manager.hookLock.RLock()
+	hooks := manager.onDestroyed
+	manager.hookLock.RUnlock()
+	for _, fn := range hooks {
+		fn(sid)
+	}
+}
+
+func (manager *Manager) fireRegenerated(oldsid, sid string) {
+	manager.hookLock.RLock()
+	hooks := manager.onRegenerated
+	manager.hookLock.RUnlock()
+	for _, fn := range hooks {
+		fn(oldsid, sid)
+	}
+}
+
+// Get capabilities of the session provider.
+func (manager *Manager) Capabilities() ProviderCapabilities {
+	return Capabilities(manager.provider)
+}
+
 // Get all active sessions count number.
+// it's always 0 if the provider doesn't have CapCount.
 func (manager *Manager) GetActiveSession() int {
 	return manager.provider.SessionAll()
 }
@@ -224,17 +797,81 @@ func (manager *Manager) SetHashFunc(hasfunc, hashkey string) {
 	manager.config.SessionIDHashKey = hashkey
 }
 
+// Set the function generating session id, it's used by SessionStart and SessionRegenerateId.
+// ids must be unpredictable, use crypto/rand and at least 128 bits of entropy.
+func (manager *Manager) SetIDGenerator(gen func() string) {
+	manager.idGen = gen
+}
+
 // Set cookie with https.
 func (manager *Manager) SetSecure(secure bool) {
 	manager.config.Secure = secure
 }
 
-// generate session id with rand string, unix nano time, remote addr by hash function.
+// check whether cookies written for r should be Secure.
+// with autoSecure, r is https if it's over TLS or X-Forwarded-Proto is https.
+// X-Forwarded-Proto is sent by any client, autoSecure must only be used
+// behind a proxy which overwrites it.
+func (manager *Manager) isSecure(r *http.Request) bool {
+	if !manager.config.AutoSecure || r == nil {
+		return manager.config.Secure
+	}
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// check whether sid cookies are signed, cookie sessions are authenticated by the provider.
+func (manager *Manager) signSID() bool {
+	if _, ok := manager.provider.(*CookieProvider); ok {
+		return false
+	}
+	return manager.config.SignSID
+}
+
+// hmac of sid with hashkey, url-safe base64.
+func (manager *Manager) sidSignature(sid string) string {
+	h := hmac.New(sha256.New, []byte(manager.config.SessionIDHashKey))
+	h.Write([]byte(sid))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// value of the sid cookie, sid.signature if signSID is on.
+func (manager *Manager) cookieValue(sid string) string {
+	if manager.signSID() {
+		sid += "." + manager.sidSignature(sid)
+	}
+	return url.QueryEscape(sid)
+}
+
+// get sid from the sid cookie, false if signSID is on and the signature is missing or wrong.
+func (manager *Manager) cookieSID(cookie *http.Cookie) (string, bool) {
+	sid, _ := url.QueryUnescape(cookie.Value)
+	if !manager.signSID() {
+		return sid, true
+	}
+	i := strings.LastIndex(sid, ".")
+	if i < 0 {
+		return "", false
+	}
+	if !hmac.Equal([]byte(sid[i+1:]), []byte(manager.sidSignature(sid[:i]))) {
+		return "", false
+	}
+	return sid[:i], true
+}
+
+// generate session id with the id generator if it's set.
+// otherwise use random bytes, or hash them with unix nano time and remote addr by hash function.
 func (manager *Manager) sessionId(r *http.Request) (sid string) {
+	if manager.idGen != nil {
+		return manager.idGen()
+	}
 	bs := make([]byte, 24)
 	if _, err := io.ReadFull(rand.Reader, bs); err != nil {
 		return ""
 	}
+	if manager.config.SessionIDHashFunc == "" {
+		// 192 bits from crypto/rand
+		return base64.RawURLEncoding.EncodeToString(bs)
+	}
 	sig := fmt.Sprintf("%s%d%s", r.RemoteAddr, time.Now().UnixNano(), bs)
 	if manager.config.SessionIDHashFunc == "md5" {
 		h := md5.New()
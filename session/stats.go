@@ -0,0 +1,87 @@
+package session
+
+import (
+	"sync/atomic"
+)
+
+// names of manager counters, they are passed to StatsSink.
+const (
+	StatReads    = "reads"    // existing sessions read
+	StatWrites   = "writes"   // sessions written by SessionRelease
+	StatCreates  = "creates"  // new sessions
+	StatDestroys = "destroys" // sessions destroyed
+	StatGC       = "gc"       // gc passes
+	StatErrors   = "errors"   // errors returned by provider
+)
+
+const (
+	statReads = iota
+	statWrites
+	statCreates
+	statDestroys
+	statGC
+	statErrors
+	statCount
+)
+
+var statNames = [statCount]string{StatReads, StatWrites, StatCreates, StatDestroys, StatGC, StatErrors}
+
+// StatsSink receives every counter increment of manager, e.g. to feed prometheus counters.
+// Inc is called in the request and must be fast.
+type StatsSink interface {
+	Inc(counter string)
+}
+
+// Stats is a snapshot of counters of manager since it's created.
+type Stats struct {
+	Reads    int64
+	Writes   int64
+	Creates  int64
+	Destroys int64
+	GC       int64
+	Errors   int64
+	// Active is SessionAll of provider, 0 if provider can't count sessions, e.g. cookie.
+	Active int
+}
+
+// counters of manager, updated atomically.
+type managerStats struct {
+	counts [statCount]int64
+	sink   atomic.Value // StatsSink
+}
+
+// Stats returns counters of manager.
+// Active is counted by provider on every call, it may walk all sessions, e.g. file.
+func (manager *Manager) Stats() Stats {
+	c := &manager.stats.counts
+	return Stats{
+		Reads:    atomic.LoadInt64(&c[statReads]),
+		Writes:   atomic.LoadInt64(&c[statWrites]),
+		Creates:  atomic.LoadInt64(&c[statCreates]),
+		Destroys: atomic.LoadInt64(&c[statDestroys]),
+		GC:       atomic.LoadInt64(&c[statGC]),
+		Errors:   atomic.LoadInt64(&c[statErrors]),
+		Active:   manager.provider.SessionAll(),
+	}
+}
+
+// SetStatsSink sets the sink receiving counter increments.
+func (manager *Manager) SetStatsSink(sink StatsSink) {
+	manager.stats.sink.Store(&sink)
+}
+
+// increase counter n
+func (manager *Manager) count(n int) {
+	atomic.AddInt64(&manager.stats.counts[n], 1)
+	if s, ok := manager.stats.sink.Load().(*StatsSink); ok && *s != nil {
+		(*s).Inc(statNames[n])
+	}
+}
+
+// count err of provider, it returns err.
+func (manager *Manager) countErr(err error) error {
+	if err != nil {
+		manager.count(statErrors)
+	}
+	return err
+}
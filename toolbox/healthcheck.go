@@ -1,5 +1,14 @@
 package toolbox
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
 //type DatabaseCheck struct {
 //}
 
@@ -16,16 +25,115 @@ package toolbox
 // health checker map
 var AdminCheckList map[string]HealthChecker
 
+// max time to wait for one health check in HealthCheckHandler
+var HealthCheckTimeout = 5 * time.Second
+
+var checkLock sync.RWMutex
+
 // health checker interface
 type HealthChecker interface {
 	Check() error
 }
 
+// HealthCheckFunc is a function used as HealthChecker, e.g.
+// AddHealthCheck("session", toolbox.HealthCheckFunc(globalSessions.HealthCheck))
+type HealthCheckFunc func() error
+
+func (f HealthCheckFunc) Check() error {
+	return f()
+}
+
 // add health checker with name string
 func AddHealthCheck(name string, hc HealthChecker) {
+	checkLock.Lock()
+	defer checkLock.Unlock()
 	AdminCheckList[name] = hc
 }
 
+// result of one health check in HealthCheckHandler response
+type CheckResult struct {
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// HealthStatus is the json response of HealthCheckHandler.
+type HealthStatus struct {
+	Status string                  `json:"status"` // ok or fail
+	Checks map[string]*CheckResult `json:"checks"`
+}
+
+// run all health checks in parallel, a check fails if it doesn't return in timeout.
+func RunHealthChecks(timeout time.Duration) *HealthStatus {
+	checkLock.RLock()
+	checks := make(map[string]HealthChecker, len(AdminCheckList))
+	for name, hc := range AdminCheckList {
+		checks[name] = hc
+	}
+	checkLock.RUnlock()
+
+	status := &HealthStatus{Status: "ok", Checks: make(map[string]*CheckResult, len(checks))}
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for name, hc := range checks {
+		wg.Add(1)
+		go func(name string, hc HealthChecker) {
+			defer wg.Done()
+			start := time.Now()
+			err := runCheck(hc, timeout)
+			result := &CheckResult{OK: err == nil, Duration: time.Since(start).String()}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			lock.Lock()
+			status.Checks[name] = result
+			if err != nil {
+				status.Status = "fail"
+			}
+			lock.Unlock()
+		}(name, hc)
+	}
+	wg.Wait()
+	return status
+}
+
+// run check, the check keeps running after timeout but its result is dropped.
+func runCheck(hc HealthChecker, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				done <- fmt.Errorf("health check panic: %v", err)
+			}
+		}()
+		done <- hc.Check()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return errors.New("health check timeout after " + timeout.String())
+	}
+}
+
+// HealthCheckHandler runs all health checks and writes HealthStatus as json.
+// status code is 503 if any check fails, or 200 if all pass.
+// each check must return in HealthCheckTimeout, e.g.
+// http.HandleFunc("/health", toolbox.HealthCheckHandler)
+func HealthCheckHandler(rw http.ResponseWriter, r *http.Request) {
+	status := RunHealthChecks(HealthCheckTimeout)
+	data, err := json.Marshal(status)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if status.Status != "ok" {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}
+	rw.Write(data)
+}
+
 func init() {
 	AdminCheckList = make(map[string]HealthChecker)
 }
@@ -0,0 +1,73 @@
+package toolbox
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func runHealthCheckHandler(t *testing.T) (int, *HealthStatus) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/health", nil)
+	HealthCheckHandler(w, r)
+	status := &HealthStatus{}
+	if err := json.Unmarshal(w.Body.Bytes(), status); err != nil {
+		t.Fatal("response should be json", err, w.Body.String())
+	}
+	return w.Code, status
+}
+
+func TestHealthCheckPass(t *testing.T) {
+	AdminCheckList = make(map[string]HealthChecker)
+	AddHealthCheck("db", HealthCheckFunc(func() error { return nil }))
+	AddHealthCheck("cache", HealthCheckFunc(func() error { return nil }))
+
+	code, status := runHealthCheckHandler(t)
+	if code != http.StatusOK || status.Status != "ok" {
+		t.Fatal("all checks pass should be 200 ok, got", code, status.Status)
+	}
+	if len(status.Checks) != 2 || !status.Checks["db"].OK || !status.Checks["cache"].OK {
+		t.Fatal("results of checks should be ok", status.Checks)
+	}
+	if status.Checks["db"].Duration == "" {
+		t.Fatal("duration should be set")
+	}
+}
+
+func TestHealthCheckFail(t *testing.T) {
+	AdminCheckList = make(map[string]HealthChecker)
+	defer func(timeout time.Duration) { HealthCheckTimeout = timeout }(HealthCheckTimeout)
+	HealthCheckTimeout = 50 * time.Millisecond
+
+	AddHealthCheck("db", HealthCheckFunc(func() error { return nil }))
+	AddHealthCheck("cache", HealthCheckFunc(func() error { return errors.New("connection refused") }))
+	AddHealthCheck("slow", HealthCheckFunc(func() error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+	AddHealthCheck("panic", HealthCheckFunc(func() error { panic("boom") }))
+
+	start := time.Now()
+	code, status := runHealthCheckHandler(t)
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatal("checks should run in parallel with timeout, took", d)
+	}
+	if code != http.StatusServiceUnavailable || status.Status != "fail" {
+		t.Fatal("failed check should be 503 fail, got", code, status.Status)
+	}
+	if !status.Checks["db"].OK {
+		t.Fatal("passed check should be ok")
+	}
+	if c := status.Checks["cache"]; c.OK || c.Error != "connection refused" {
+		t.Fatal("failed check should have error", c)
+	}
+	if c := status.Checks["slow"]; c.OK || c.Error != "health check timeout after 50ms" {
+		t.Fatal("slow check should time out", c)
+	}
+	if c := status.Checks["panic"]; c.OK || c.Error != "health check panic: boom" {
+		t.Fatal("panic check should fail", c)
+	}
+}
@@ -41,6 +41,63 @@ func init() {
 	}
 }
 
+// CustomFunc is a validation function registered by AddCustomFunc.
+// it returns false if obj is invalid, key is "Field.Name" of the struct field.
+type CustomFunc func(v *Validation, obj interface{}, key string) bool
+
+// AddCustomFunc registers a validation function used by struct tag,
+// e.g. `valid:"Required;IBAN"` calls the function registered as IBAN.
+// if the function returns false without setting an error, an error keyed by the field is set,
+// its message is MessageTmpls[name] if defined.
+// it should be called in init, it returns error if the name has been registered.
+func AddCustomFunc(name string, fn CustomFunc) error {
+	if fn == nil {
+		return fmt.Errorf("validation: custom function %s is nil", name)
+	}
+	if unFuncs[name] {
+		return fmt.Errorf("validation: invalid custom function name %s", name)
+	}
+	if _, ok := funcs[name]; ok {
+		return fmt.Errorf("validation: function %s has been registered", name)
+	}
+	funcs[name] = reflect.ValueOf(func(v *Validation, obj interface{}, key string) *ValidationResult {
+		n := len(v.Errors)
+		if fn(v, obj, key) {
+			return &ValidationResult{Ok: true}
+		}
+		if len(v.Errors) > n {
+			return &ValidationResult{Ok: false, Error: v.Errors[n]}
+		}
+		return v.apply(customValidator{name, key}, obj)
+	})
+	return nil
+}
+
+// customValidator reports the failure of custom function with apply.
+type customValidator struct {
+	name string
+	key  string
+}
+
+func (c customValidator) IsSatisfied(obj interface{}) bool {
+	return false
+}
+
+func (c customValidator) DefaultMessage() string {
+	if tmpl, ok := MessageTmpls[c.name]; ok {
+		return tmpl
+	}
+	return "Must pass " + c.name
+}
+
+func (c customValidator) GetKey() string {
+	return c.key
+}
+
+func (c customValidator) GetLimitValue() interface{} {
+	return nil
+}
+
 // Valid function type
 type ValidFunc struct {
 	Name   string
@@ -83,7 +140,7 @@ func isStructPtr(t reflect.Type) bool {
 
 func getValidFuncs(f reflect.StructField) (vfs []ValidFunc, err error) {
 	tag := f.Tag.Get(VALIDTAG)
-	if len(tag) == 0 {
+	if len(tag) == 0 || tag == "-" {
 		return
 	}
 	if vfs, tag, err = getRegFuncs(tag, f.Name); err != nil {
@@ -84,3 +84,50 @@ func TestCall(t *testing.T) {
 		t.Error("age out of range should be has an error")
 	}
 }
+
+func TestAddCustomFunc(t *testing.T) {
+	// simplified iban check, 2 letters country code and 2 check digits.
+	err := AddCustomFunc("IBAN", func(v *Validation, obj interface{}, key string) bool {
+		s, ok := obj.(string)
+		return ok && len(s) > 4 && Alpha{}.IsSatisfied(s[:2]) && Numeric{}.IsSatisfied(s[2:4])
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = AddCustomFunc("IBAN", func(v *Validation, obj interface{}, key string) bool { return true }); err == nil {
+		t.Fatal("registering IBAN twice should fail")
+	}
+	if err = AddCustomFunc("Required", func(v *Validation, obj interface{}, key string) bool { return true }); err == nil {
+		t.Fatal("registering builtin function name should fail")
+	}
+
+	type account struct {
+		Name string `valid:"Required"`
+		IBAN string `valid:"Required;IBAN"`
+	}
+	valid := Validation{}
+	b, err := valid.Valid(account{Name: "astaxie", IBAN: "DE89370400440532013000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b || valid.HasErrors() {
+		t.Fatal("valid iban should pass", valid.Errors)
+	}
+
+	b, err = valid.Valid(account{Name: "astaxie", IBAN: "89DE370400440532013000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b {
+		t.Fatal("invalid iban should not pass")
+	}
+	if len(valid.Errors) != 1 {
+		t.Fatal("invalid iban should set one error", valid.Errors)
+	}
+	if e := valid.Errors[0]; e.Key != "IBAN.IBAN" || e.Field != "IBAN" || e.Name != "IBAN" {
+		t.Fatal("error should be keyed by field", e.Key, e.Field, e.Name)
+	}
+	if valid.ErrorMap()["IBAN"] != valid.Errors[0] {
+		t.Fatal("error should be in ErrorMap by field")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -196,10 +198,10 @@ func (v *Validation) apply(chk Validator, obj interface{}) *ValidationResult {
 	Name := key
 	Field := ""
 
-	parts := strings.Split(key, ".")
-	if len(parts) == 2 {
-		Field = parts[0]
-		Name = parts[1]
+	// key of nested field is "Path.To.Field.Name"
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		Field = key[:i]
+		Name = key[i+1:]
 	}
 
 	err := &ValidationError{
@@ -253,37 +255,123 @@ func (v *Validation) Check(obj interface{}, checks ...Validator) *ValidationResu
 
 // Validate a struct.
 // the obj parameter must be a struct or a struct pointer
+// exported struct fields, and structs in slice, array and map fields, are validated too,
+// keys of their errors are prefixed with the field path, e.g. "Address.Zip.Required" or "Items.0.Name.Required".
+// tag `valid:"-"` skips a field and its subtree. ValidFormer is only called for obj.
 func (v *Validation) Valid(obj interface{}) (b bool, err error) {
 	objT := reflect.TypeOf(obj)
 	objV := reflect.ValueOf(obj)
 	switch {
 	case isStruct(objT):
 	case isStructPtr(objT):
-		objT = objT.Elem()
 		objV = objV.Elem()
 	default:
 		err = fmt.Errorf("%v must be a struct or a struct pointer", obj)
 		return
 	}
 
+	if err = v.validStruct(objV, "", make(map[uintptr]bool)); err != nil {
+		return
+	}
+
+	if !v.HasErrors() {
+		if form, ok := obj.(ValidFormer); ok {
+			form.Valid(v)
+		}
+	}
+
+	return !v.HasErrors(), nil
+}
+
+// validate fields of struct objV, keys of errors are prefixed with prefix.
+// seen has the addresses of structs being validated, so cyclic pointers are validated once.
+func (v *Validation) validStruct(objV reflect.Value, prefix string, seen map[uintptr]bool) error {
+	objT := objV.Type()
 	for i := 0; i < objT.NumField(); i++ {
-		var vfs []ValidFunc
-		if vfs, err = getValidFuncs(objT.Field(i)); err != nil {
-			return
+		field := objT.Field(i)
+		if field.Tag.Get(VALIDTAG) == "-" {
+			continue
+		}
+		vfs, err := getValidFuncs(field)
+		if err != nil {
+			return err
 		}
 		for _, vf := range vfs {
+			if prefix != "" {
+				// the key is the last param
+				n := len(vf.Params) - 1
+				vf.Params[n] = prefix + vf.Params[n].(string)
+			}
 			if _, err = funcs.Call(vf.Name,
 				mergeParam(v, objV.Field(i).Interface(), vf.Params)...); err != nil {
-				return
+				return err
+			}
+		}
+		if field.PkgPath == "" {
+			if err = v.validNested(objV.Field(i), prefix+field.Name, seen); err != nil {
+				return err
 			}
 		}
 	}
+	return nil
+}
 
-	if !v.HasErrors() {
-		if form, ok := obj.(ValidFormer); ok {
-			form.Valid(v)
+// validate structs in value fv of field path.
+func (v *Validation) validNested(fv reflect.Value, path string, seen map[uintptr]bool) error {
+	switch fv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if fv.IsNil() {
+			return nil
+		}
+		if fv.Kind() == reflect.Ptr {
+			if seen[fv.Pointer()] {
+				return nil
+			}
+			seen[fv.Pointer()] = true
+			defer delete(seen, fv.Pointer())
+		}
+		return v.validNested(fv.Elem(), path, seen)
+	case reflect.Struct:
+		return v.validStruct(fv, path+".", seen)
+	case reflect.Slice, reflect.Array:
+		if !canNest(fv.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < fv.Len(); i++ {
+			if err := v.validNested(fv.Index(i), path+"."+strconv.Itoa(i), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if !canNest(fv.Type().Elem()) {
+			return nil
+		}
+		// sorted keys keep the order of errors
+		keys := make(map[string]reflect.Value, fv.Len())
+		names := make([]string, 0, fv.Len())
+		for _, k := range fv.MapKeys() {
+			name := fmt.Sprint(k.Interface())
+			keys[name] = k
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := v.validNested(fv.MapIndex(keys[name]), path+"."+name, seen); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
+}
 
-	return !v.HasErrors(), nil
+// check values of type t may have structs to validate.
+func canNest(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Interface, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
 }
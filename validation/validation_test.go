@@ -329,3 +329,77 @@ func TestValid(t *testing.T) {
 		t.Errorf("Message key should be `Name.Match` but got %s", valid.Errors[0].Key)
 	}
 }
+
+func TestValidNested(t *testing.T) {
+	type zip struct {
+		Code string `valid:"Required;Length(5)"`
+	}
+	type address struct {
+		City string `valid:"Required"`
+		Zip  zip
+	}
+	type item struct {
+		Name  string `valid:"Required"`
+		Count int    `valid:"Range(1, 10)"`
+	}
+	type node struct {
+		Name string `valid:"Required"`
+		Next *node
+	}
+	type order struct {
+		Id       int
+		Address  address
+		Shipping *address
+		Items    []item
+		Extra    map[string]*item
+		Tags     []string
+		Skipped  address `valid:"-"`
+		Head     *node
+	}
+
+	o := &order{
+		Address:  address{City: "Beijing", Zip: zip{Code: "123"}},
+		Shipping: &address{Zip: zip{Code: "10000"}},
+		Items:    []item{{Name: "book", Count: 1}, {Count: 20}},
+		Extra:    map[string]*item{"gift": {Name: "card", Count: 0}, "nil": nil},
+		Tags:     []string{"a"},
+		Head:     &node{},
+	}
+	// cyclic list is validated once
+	o.Head.Next = o.Head
+
+	valid := Validation{}
+	b, err := valid.Valid(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b {
+		t.Error("validation should not be passed")
+	}
+	keys := []string{
+		"Address.Zip.Code.Length",
+		"Shipping.City.Required",
+		"Items.1.Name.Required",
+		"Items.1.Count.Range",
+		"Extra.gift.Count.Range",
+		"Head.Name.Required",
+	}
+	if len(valid.Errors) != len(keys) {
+		for _, e := range valid.Errors {
+			t.Log(e.Key, e.Message)
+		}
+		t.Fatalf("valid errors len should be %d but got %d", len(keys), len(valid.Errors))
+	}
+	for i, key := range keys {
+		if valid.Errors[i].Key != key {
+			t.Errorf("error key should be %s but got %s", key, valid.Errors[i].Key)
+		}
+	}
+	e := valid.Errors[0]
+	if e.Field != "Address.Zip.Code" || e.Name != "Length" {
+		t.Errorf("error field should be Address.Zip.Code and name Length but got %s %s", e.Field, e.Name)
+	}
+	if valid.ErrorsMap["Items.1.Name"] == nil {
+		t.Error("errors map should have the field path")
+	}
+}